@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/moinsen-dev/machinist/internal/apply"
+	"github.com/moinsen-dev/machinist/internal/drift"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyPrune  string
+	applyDryRun bool
+	applyYes    bool
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply <manifest.toml>",
+	Short: "Make this machine match a manifest",
+	Long: "Converge this machine to a manifest: install the formulae, casks, VS Code extensions,\n" +
+		"npm, cargo and gem globals and LaunchAgents it lacks, upgrade packages pinned to\n" +
+		"a newer version and, for the categories given to --prune, remove what the manifest\n" +
+		"does not list. The plan is shown first and runs after confirmation.\n\n" +
+		"Prune categories: " + strings.Join(apply.Categories, ", "),
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prune := parseCSV(applyPrune)
+		if err := apply.CheckPrune(prune); err != nil {
+			return err
+		}
+		manifest, err := loadManifest(cmd, args[0])
+		if err != nil {
+			return fmt.Errorf("load %s: %w", args[0], err)
+		}
+		reg, err := newRegistry()
+		if err != nil {
+			return err
+		}
+		homeDir := homeOverride
+		if homeDir == "" {
+			homeDir, _ = os.UserHomeDir()
+		}
+		runner, err := commandRunner(homeDir)
+		if err != nil {
+			return err
+		}
+		ctx := context.Background()
+
+		fmt.Fprintln(cmd.ErrOrStderr(), "Scanning environment...")
+		report, err := drift.Detect(ctx, reg, apply.Scope(manifest), drift.Options{
+			HomeDir:  homeDir,
+			Progress: newProgressWriter(cmd.ErrOrStderr()),
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.ErrOrStderr())
+		printDiagnostics(cmd.ErrOrStderr(), report.Diagnostics)
+
+		agentsPath := apply.AgentsPath(homeDir)
+		owned, err := apply.ReadAgents(agentsPath)
+		if err != nil {
+			return fmt.Errorf("read applied LaunchAgents: %w", err)
+		}
+		plan, err := apply.NewPlan(ctx, runner, manifest, report, apply.Options{
+			Prune:       prune,
+			BundleDir:   filepath.Dir(args[0]),
+			HomeDir:     homeDir,
+			OwnedAgents: owned,
+		})
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		printPlan(w, args[0], plan)
+		if plan.Empty() || applyDryRun {
+			return nil
+		}
+		if !applyYes && !confirm(cmd.InOrStdin(), w, "\nApply this plan? [y/N] ") {
+			fmt.Fprintln(w, "Apply cancelled.")
+			return nil
+		}
+
+		fmt.Fprintln(w)
+		cmd.SilenceUsage = true
+		// Record the LaunchAgents apply installed, the ones a later
+		// --prune launchagents may remove.
+		var done []apply.Step
+		progress := newStepWriter(w)
+		err = plan.Execute(ctx, runner, func(e apply.Event) {
+			progress(e)
+			if e.Done && e.Err == nil {
+				done = append(done, e.Step)
+			}
+		})
+		if slices.ContainsFunc(done, func(s apply.Step) bool { return s.Category == "launchagents" }) {
+			if werr := apply.WriteAgents(agentsPath, apply.OwnAgents(owned, done)); werr != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: record applied LaunchAgents: %v\n", werr)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "\nApply complete: %s\n", planSummary(plan))
+		return nil
+	},
+}
+
+// printPlan writes the plan for people, one line per step, followed by the
+// extra items kept because their category was not pruned and the version
+// differences apply leaves alone.
+func printPlan(w io.Writer, manifest string, p *apply.Plan) {
+	if p.Empty() {
+		fmt.Fprintf(w, "No changes. This machine matches %s.\n", manifest)
+	} else {
+		fmt.Fprintf(w, "machinist will perform the following actions:\n\n")
+		for _, s := range p.Steps {
+			fmt.Fprintf(w, "  %s\n", stepColor(s.Action, describeStep(s)))
+		}
+		fmt.Fprintf(w, "\nPlan: %s.\n", planSummary(p))
+	}
+
+	if len(p.Kept) > 0 {
+		var categories []string
+		for _, s := range p.Kept {
+			if !slices.Contains(categories, s.Category) {
+				categories = append(categories, s.Category)
+			}
+		}
+		fmt.Fprintln(w, dimStyle.Render(fmt.Sprintf("%d extra item(s) kept; remove them with --prune %s",
+			len(p.Kept), strings.Join(categories, ","))))
+	}
+
+	if len(p.Unmanaged) > 0 {
+		fmt.Fprintln(w, dimStyle.Render(fmt.Sprintf("%d version difference(s) left alone:", len(p.Unmanaged))))
+		for _, s := range p.Unmanaged {
+			fmt.Fprintln(w, dimStyle.Render(fmt.Sprintf("  %-12s %s %s → %s (%s)", s.Category, s.Name, s.From, s.To, s.Reason)))
+		}
+	}
+}
+
+// planSummary counts a plan's steps by action.
+func planSummary(p *apply.Plan) string {
+	return fmt.Sprintf("%d to install, %d to upgrade, %d to remove",
+		p.Count(apply.Install), p.Count(apply.Upgrade), p.Count(apply.Remove))
+}
+
+// stepMarks prefix a step like a change in a diff.
+var stepMarks = map[apply.Action]string{apply.Install: "+", apply.Upgrade: "~", apply.Remove: "-"}
+
+// describeStep names a step, e.g. "~ npm          upgrade typescript 5.3.3 → 5.4.5".
+func describeStep(s apply.Step) string {
+	line := fmt.Sprintf("%s %-12s %s %s", stepMarks[s.Action], s.Category, s.Action, s.Name)
+	switch {
+	case s.From != "" && s.To != "":
+		line += " " + s.From + " → " + s.To
+	case s.To != "":
+		line += " " + s.To
+	}
+	return line
+}
+
+// stepColor styles a plan line by its action.
+func stepColor(a apply.Action, line string) string {
+	switch a {
+	case apply.Install:
+		return successStyle.Render(line)
+	case apply.Remove:
+		return errorStyle.Render(line)
+	}
+	return warningStyle.Render(line)
+}
+
+// newStepWriter returns an apply.ProgressFunc that writes one line per step
+// to w, finished with its outcome.
+func newStepWriter(w io.Writer) apply.ProgressFunc {
+	return func(e apply.Event) {
+		if !e.Done {
+			counter := counterStyle.Render(fmt.Sprintf("[%d/%d]", e.Index+1, e.Total))
+			fmt.Fprintf(w, "  %s %s ...", counter, describeStep(e.Step))
+			return
+		}
+		if e.Err != nil {
+			fmt.Fprintf(w, " %s %s\n", errorStyle.Render("✗"), dimStyle.Render(e.Err.Error()))
+			return
+		}
+		fmt.Fprintf(w, " %s\n", successStyle.Render("✓"))
+	}
+}
+
+// confirm asks question on w and reports whether the answer read from r is yes.
+func confirm(r io.Reader, w io.Writer, question string) bool {
+	fmt.Fprint(w, question)
+	line, _ := bufio.NewReader(r).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+func init() {
+	applyCmd.Flags().StringVar(&applyPrune, "prune", "", "Comma-separated categories whose items missing from the manifest are removed")
+	applyCmd.Flags().BoolVar(&applyDryRun, "dry-run", false, "Show the plan without applying it")
+	applyCmd.Flags().BoolVarP(&applyYes, "yes", "y", false, "Apply without asking for confirmation")
+	rootCmd.AddCommand(applyCmd)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// applyFixture is a replay fixture of a machine with git and htop installed,
+// on which installing jq works and uninstalling htop fails.
+const applyFixture = `{"kind":"lookpath","name":"brew","found":true}
+{"kind":"run","name":"brew","args":["list","--formula","--versions"],"stdout":"git 2.47.0\nhtop 3.3\n"}
+{"kind":"run","name":"brew","args":["list","--cask"]}
+{"kind":"run","name":"brew","args":["tap"]}
+{"kind":"run","name":"brew","args":["services","list"]}
+{"kind":"run","name":"brew","args":["leaves"],"stdout":"git\nhtop\n"}
+{"kind":"run","name":"brew","args":["install","jq"]}
+{"kind":"run","name":"brew","args":["uninstall","htop"],"exit_code":1,"stderr":"Error: htop is pinned\n"}
+{"kind":"run","name":"brew","args":["autoremove"]}
+`
+
+// writeApplyFixture returns a manifest listing git 2.48.0 and jq, a home
+// directory and the replay fixture.
+func writeApplyFixture(t *testing.T) (manifest, home, fixture string) {
+	t.Helper()
+	dir := t.TempDir()
+	home = filepath.Join(dir, "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	fixture = filepath.Join(dir, "fixture.jsonl")
+	if err := os.WriteFile(fixture, []byte(applyFixture), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manifest = filepath.Join(dir, "lab.toml")
+	if err := os.WriteFile(manifest, []byte("[homebrew]\nformulae = [{name = \"git\", version = \"2.48.0\"}, {name = \"jq\"}]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return manifest, home, fixture
+}
+
+func resetApplyFlags() {
+	applyPrune, applyDryRun, applyYes = "", false, false
+	homeOverride, replayPath = "", ""
+	rootCmd.SetIn(nil)
+}
+
+func TestApplyDryRun(t *testing.T) {
+	resetApplyFlags()
+	defer resetApplyFlags()
+	manifest, home, fixture := writeApplyFixture(t)
+
+	output, err := executeCommand("apply", manifest, "--home", home, "--replay", fixture, "--dry-run")
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output)
+	}
+	for _, want := range []string{
+		"+ homebrew     install jq",
+		"Plan: 1 to install, 0 to upgrade, 0 to remove.",
+		"1 extra item(s) kept; remove them with --prune homebrew",
+		"homebrew     git 2.47.0 → 2.48.0 (Homebrew cannot install a given version)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "Apply this plan?") {
+		t.Errorf("dry run must not ask for confirmation, got:\n%s", output)
+	}
+}
+
+func TestApplyCancelled(t *testing.T) {
+	resetApplyFlags()
+	defer resetApplyFlags()
+	manifest, home, fixture := writeApplyFixture(t)
+
+	rootCmd.SetIn(strings.NewReader("n\n"))
+	output, err := executeCommand("apply", manifest, "--home", home, "--replay", fixture, "--prune", "homebrew")
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "- homebrew     remove htop") {
+		t.Errorf("expected htop to be pruned, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Apply cancelled.") || strings.Contains(output, "[1/3]") {
+		t.Errorf("expected nothing to run without confirmation, got:\n%s", output)
+	}
+}
+
+func TestApplyYes(t *testing.T) {
+	resetApplyFlags()
+	defer resetApplyFlags()
+	manifest, home, fixture := writeApplyFixture(t)
+
+	output, err := executeCommand("apply", manifest, "--home", home, "--replay", fixture, "--prune", "homebrew", "--yes")
+	if err == nil || err.Error() != "1 of 3 steps failed" {
+		t.Fatalf("expected the failed uninstall to fail apply, got %v\n%s", err, output)
+	}
+	for _, want := range []string{
+		"[1/3] + homebrew     install jq ... ✓",
+		"[2/3] - homebrew     remove htop ... ✗ brew uninstall htop: exit status 1: Error: htop is pinned",
+		"[3/3] - homebrew     remove unused dependencies ... ✓",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestApplyUnknownPruneCategory(t *testing.T) {
+	resetApplyFlags()
+	defer resetApplyFlags()
+
+	_, err := executeCommand("apply", "missing.toml", "--prune", "homebrew,pip")
+	if err == nil || !strings.Contains(err.Error(), `unknown category "pip"`) {
+		t.Fatalf("expected an unknown category error before loading the manifest, got %v", err)
+	}
+}
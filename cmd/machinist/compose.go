@@ -11,48 +11,58 @@ import (
 
 var (
 	composeOutput   string
-	composeAdd      string
-	composeFromFile string
+	composeAdd      []string
+	composeRemove   []string
+	composeFromFile []string
 )
 
 var composeCmd = &cobra.Command{
-	Use:   "compose [profile]",
-	Short: "Compose a manifest from a built-in profile or existing manifest file",
-	Long:  "Build a setup manifest starting from a built-in profile or an existing manifest file, optionally adding extra packages.\nUse 'machinist list profiles' to see available profiles.",
+	Use:   "compose [profile...]",
+	Short: "Compose a manifest from built-in profiles and existing manifest files",
+	Long: "Build a setup manifest from one or more built-in profiles and manifest files, optionally adding or removing items.\n" +
+		"Use 'machinist list profiles' to see available profiles.\n\n" +
+		"Layers are deep-merged in order: the profiles as given (go-dev devops, or go-dev+devops), then each --from-file.\n" +
+		"Values a later layer overrides are reported, and [[meta.layers]] records where each section came from.\n\n" +
+		"Items for --add and --remove are written <type>:<value>; a bare name is a Homebrew formula:\n" +
+		profiles.ItemTypes(),
 	Args: func(cmd *cobra.Command, args []string) error {
-		if composeFromFile != "" {
-			return cobra.MaximumNArgs(0)(cmd, args)
+		if len(composeFromFile) > 0 {
+			return nil
 		}
-		return cobra.ExactArgs(1)(cmd, args)
+		return cobra.MinimumNArgs(1)(cmd, args)
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		var snap *domain.Snapshot
-		var err error
-
-		if composeFromFile != "" {
-			snap, err = domain.ReadManifest(composeFromFile)
-			if err != nil {
-				return fmt.Errorf("read manifest: %w", err)
-			}
-		} else {
-			name := strings.TrimPrefix(args[0], "profile://")
-			snap, err = profiles.Get(name)
-			if err != nil {
-				available, _ := profiles.List()
-				return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(available, ", "))
+		var refs []string
+		for _, arg := range args {
+			for _, name := range strings.Split(arg, "+") {
+				name = strings.TrimPrefix(strings.TrimSpace(name), "profile://")
+				if _, err := profiles.Get(name); err != nil {
+					available, _ := profiles.List()
+					return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(available, ", "))
+				}
+				refs = append(refs, profiles.ProfilePrefix+name)
 			}
 		}
+		refs = append(refs, composeFromFile...)
+
+		snap, conflicts, err := profiles.Compose(refs, "")
+		if err != nil {
+			return fmt.Errorf("read manifest: %w", err)
+		}
+		for _, c := range conflicts {
+			fmt.Fprintf(cmd.ErrOrStderr(), "override: %s\n", c)
+		}
 
-		if composeAdd != "" {
-			extras := strings.Split(composeAdd, ",")
-			if snap.Homebrew == nil {
-				snap.Homebrew = &domain.HomebrewSection{}
+		if items := parseCSV(strings.Join(composeAdd, ",")); len(items) > 0 {
+			snap, err = profiles.AddItems(snap, items)
+			if err != nil {
+				return fmt.Errorf("--add: %w", err)
 			}
-			for _, pkg := range extras {
-				pkg = strings.TrimSpace(pkg)
-				if pkg != "" {
-					snap.Homebrew.Formulae = append(snap.Homebrew.Formulae, domain.Package{Name: pkg})
-				}
+		}
+		if items := parseCSV(strings.Join(composeRemove, ",")); len(items) > 0 {
+			snap, err = profiles.RemoveItems(snap, items)
+			if err != nil {
+				return fmt.Errorf("--remove: %w", err)
 			}
 		}
 
@@ -67,7 +77,8 @@ var composeCmd = &cobra.Command{
 
 func init() {
 	composeCmd.Flags().StringVarP(&composeOutput, "output", "o", "composed-manifest.toml", "Output file path")
-	composeCmd.Flags().StringVar(&composeAdd, "add", "", "Comma-separated packages to add (e.g. docker,postgres)")
-	composeCmd.Flags().StringVar(&composeFromFile, "from-file", "", "Use an existing manifest file as base")
+	composeCmd.Flags().StringSliceVar(&composeAdd, "add", nil, "Items to add, comma-separated or repeated (e.g. cask:docker,postgresql@16,npm:pnpm)")
+	composeCmd.Flags().StringSliceVar(&composeRemove, "remove", nil, "Items to remove, comma-separated or repeated (e.g. cask:iterm2,vscode:ms-python.python)")
+	composeCmd.Flags().StringArrayVar(&composeFromFile, "from-file", nil, "Manifest file to merge as a layer after the profiles (repeatable)")
 	rootCmd.AddCommand(composeCmd)
 }
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+)
+
+func resetComposeFlags() {
+	composeAdd = nil
+	composeRemove = nil
+	composeFromFile = nil
+}
+
+func TestComposeTypedItems(t *testing.T) {
+	resetComposeFlags()
+	out := filepath.Join(t.TempDir(), "setup.toml")
+
+	_, err := executeCommand("compose", "minimal", "-o", out,
+		"--add", "cask:docker,npm:pnpm,vscode:golang.go", "--remove", "formula:jq,cask:iterm2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := domain.UnmarshalManifest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var casks []string
+	for _, c := range snap.Homebrew.Casks {
+		casks = append(casks, c.Name)
+	}
+	if got := strings.Join(casks, ","); got != "visual-studio-code,docker" {
+		t.Errorf("casks = %s, want visual-studio-code,docker", got)
+	}
+	for _, f := range snap.Homebrew.Formulae {
+		if f.Name == "jq" || f.Name == "docker" {
+			t.Errorf("unexpected formula %q", f.Name)
+		}
+	}
+	if snap.Node == nil || len(snap.Node.GlobalPackages) != 1 || snap.Node.GlobalPackages[0].Name != "pnpm" {
+		t.Errorf("node packages = %+v, want pnpm", snap.Node)
+	}
+	if snap.VSCode == nil || len(snap.VSCode.Extensions) != 1 {
+		t.Errorf("vscode = %+v, want golang.go", snap.VSCode)
+	}
+}
+
+func TestComposeRepeatedItemFlags(t *testing.T) {
+	resetComposeFlags()
+	out := filepath.Join(t.TempDir(), "setup.toml")
+
+	_, err := executeCommand("compose", "minimal", "-o", out,
+		"--add", "cask:docker", "--add", "htop,npm:pnpm", "--remove", "formula:jq", "--remove", "cask:iterm2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := domain.UnmarshalManifest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var casks, formulae []string
+	for _, c := range snap.Homebrew.Casks {
+		casks = append(casks, c.Name)
+	}
+	for _, f := range snap.Homebrew.Formulae {
+		formulae = append(formulae, f.Name)
+	}
+	if got := strings.Join(casks, ","); got != "visual-studio-code,docker" {
+		t.Errorf("casks = %s, want visual-studio-code,docker", got)
+	}
+	if got := strings.Join(formulae, ","); !strings.Contains(got, "htop") || strings.Contains(got, "jq") {
+		t.Errorf("formulae = %s, want htop and no jq", got)
+	}
+	if snap.Node == nil || len(snap.Node.GlobalPackages) != 1 || snap.Node.GlobalPackages[0].Name != "pnpm" {
+		t.Errorf("node packages = %+v, want pnpm", snap.Node)
+	}
+}
+
+func TestComposeUnknownItemType(t *testing.T) {
+	resetComposeFlags()
+	out := filepath.Join(t.TempDir(), "setup.toml")
+
+	_, err := executeCommand("compose", "minimal", "-o", out, "--add", "pip:requests")
+	if err == nil || !strings.Contains(err.Error(), "unknown item type") {
+		t.Fatalf("expected an unknown item type error, got: %v", err)
+	}
+}
+
+func TestComposeLayers(t *testing.T) {
+	resetComposeFlags()
+	dir := t.TempDir()
+	personal := filepath.Join(dir, "personal.toml")
+	if err := os.WriteFile(personal, []byte("[go]\nversion = \"1.25\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "setup.toml")
+
+	output, err := executeCommand("compose", "go-dev+devops", "minimal", "--from-file", personal, "-o", out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "override: go.version") || !strings.Contains(output, "(profile:go-dev) overridden by") {
+		t.Errorf("expected the go.version override to be reported, got:\n%s", output)
+	}
+
+	snap, err := domain.ReadManifest(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Go == nil || snap.Go.Version != "1.25" {
+		t.Errorf("go = %+v, want version 1.25 from the last layer", snap.Go)
+	}
+	var sources []string
+	for _, l := range snap.Meta.Layers {
+		sources = append(sources, l.Source)
+	}
+	want := "profile:go-dev,profile:devops,profile:minimal," + personal
+	if got := strings.Join(sources, ","); got != want {
+		t.Errorf("layers = %s, want %s", got, want)
+	}
+}
+
+func TestComposeUnknownProfile(t *testing.T) {
+	resetComposeFlags()
+	_, err := executeCommand("compose", "go-dev+nope", "-o", filepath.Join(t.TempDir(), "setup.toml"))
+	if err == nil || !strings.Contains(err.Error(), `unknown profile "nope"`) {
+		t.Fatalf("expected an unknown profile error, got: %v", err)
+	}
+}
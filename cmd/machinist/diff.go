@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/moinsen-dev/machinist/internal/diff"
+	"github.com/spf13/cobra"
+)
+
+var diffFormat string
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <a.toml> <b.toml>",
+	Short: "Compare two manifests section by section",
+	Long: "Compare two manifests, e.g. an old and a new machine or two team members, down to\n" +
+		"single values: packages and their versions, extensions, repositories by remote,\n" +
+		"macOS defaults, config files by content hash and runtime versions. Both manifests\n" +
+		"are resolved first. --format picks a colored report (text), json or patch.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch diffFormat {
+		case "text", "json", "patch":
+		default:
+			return fmt.Errorf("unknown diff format %q (supported: text, json, patch)", diffFormat)
+		}
+		a, err := loadManifest(cmd, args[0])
+		if err != nil {
+			return fmt.Errorf("load %s: %w", args[0], err)
+		}
+		b, err := loadManifest(cmd, args[1])
+		if err != nil {
+			return fmt.Errorf("load %s: %w", args[1], err)
+		}
+
+		report := diff.Manifests(a, b)
+		opts := diff.Options{NameA: args[0], NameB: args[1], Color: diffColor}
+		w := cmd.OutOrStdout()
+		switch diffFormat {
+		case "json":
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(report)
+		case "patch":
+			diff.WritePatch(w, report, opts)
+		default:
+			diff.WriteText(w, report, opts)
+		}
+		return nil
+	},
+}
+
+// diffColor styles a diff line by its change kind.
+func diffColor(k diff.Kind, line string) string {
+	switch k {
+	case diff.Added:
+		return successStyle.Render(line)
+	case diff.Removed:
+		return errorStyle.Render(line)
+	}
+	return warningStyle.Render(line)
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffFormat, "format", "text", "Output format (text, json, patch)")
+	rootCmd.AddCommand(diffCmd)
+}
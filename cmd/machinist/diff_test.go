@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/diff"
+)
+
+func writeDiffManifests(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	a := filepath.Join(dir, "old.toml")
+	b := filepath.Join(dir, "new.toml")
+	if err := os.WriteFile(a, []byte("[meta]\nschema_version = 2\n\n[[homebrew.formulae]]\nname = \"node\"\nversion = \"20\"\n\n[[homebrew.formulae]]\nname = \"wget\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte("[meta]\nschema_version = 2\n\n[[homebrew.formulae]]\nname = \"node\"\nversion = \"22\"\n\n[go]\nversion = \"1.25\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return a, b
+}
+
+func TestDiffCommand(t *testing.T) {
+	defer func() { diffFormat = "text" }()
+	a, b := writeDiffManifests(t)
+
+	output, err := executeCommand("diff", a, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"Only in " + b + ": go", `~ formulae[node].version: "20" → "22"`, "- formulae[wget]"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	output, err = executeCommand("diff", a, b, "--format", "json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report diff.Report
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if len(report.Changes) != 3 {
+		t.Errorf("expected 3 changes, got %+v", report.Changes)
+	}
+
+	output, err = executeCommand("diff", a, b, "--format", "patch")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, `+go.version = "1.25"`) {
+		t.Errorf("expected a patch line for go.version, got:\n%s", output)
+	}
+}
+
+func TestDiffUnknownFormat(t *testing.T) {
+	defer func() { diffFormat = "text" }()
+	a, b := writeDiffManifests(t)
+	_, err := executeCommand("diff", a, b, "--format", "yaml")
+	if err == nil || !strings.Contains(err.Error(), "unknown diff format") {
+		t.Fatalf("expected an unknown format error, got %v", err)
+	}
+}
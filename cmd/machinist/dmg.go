@@ -5,9 +5,7 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"runtime"
 	"strings"
-	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/moinsen-dev/machinist/internal/bundler"
@@ -32,38 +30,42 @@ var dmgCmd = &cobra.Command{
 		ctx := context.Background()
 
 		var snap *domain.Snapshot
-		var errs []error
+		scanned := false
 
 		if len(args) == 1 {
-			// Load from existing manifest file
-			data, err := os.ReadFile(args[0])
-			if err != nil {
-				return fmt.Errorf("read manifest: %w", err)
-			}
-			snap, err = domain.UnmarshalManifest(data)
+			// Load from existing manifest file, resolving its extends chain
+			var err error
+			snap, err = loadManifest(cmd, args[0])
 			if err != nil {
-				return fmt.Errorf("parse manifest: %w", err)
+				return fmt.Errorf("load manifest: %w", err)
 			}
 			fmt.Fprintf(cmd.OutOrStdout(), "Loaded manifest from %s (%d stages)\n", args[0], snap.StageCount())
 		} else if dmgInteractive {
-			reg := newRegistry()
-			var err error
-			snap, errs, err = runInteractiveScan(cmd, reg, ctx)
+			reg, err := newRegistry()
+			if err != nil {
+				return err
+			}
+			snap, err = runInteractiveScan(cmd, reg, ctx)
 			if err != nil {
 				return err
 			}
 			if snap == nil {
 				return nil // cancelled
 			}
+			scanned = true
 		} else {
-			reg := newRegistry()
+			reg, err := newRegistry()
+			if err != nil {
+				return err
+			}
 			fmt.Fprintln(cmd.OutOrStdout(), "Scanning environment...")
 			progress := newProgressWriter(cmd.OutOrStdout())
-			snap, errs = reg.ScanAllWithProgress(ctx, progress)
+			snap, _ = reg.ScanAllWithProgress(ctx, progress)
+			scanned = true
 		}
 
-		for _, e := range errs {
-			fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", e)
+		if scanned {
+			printDiagnostics(cmd.ErrOrStderr(), snap.Meta.Diagnostics)
 		}
 
 		// Prompt for age encryption passphrase if snapshot has encrypted sections
@@ -93,9 +95,10 @@ var dmgCmd = &cobra.Command{
 		fmt.Fprintf(cmd.OutOrStdout(), "\nBuilding DMG bundle...")
 		runner := &util.RealCommandRunner{}
 		opts := bundler.BundleOptions{
-			Password:   dmgPassword,
-			Passphrase: passphrase,
-			VolumeName: "Machinist Restore",
+			Password:        dmgPassword,
+			Passphrase:      passphrase,
+			VolumeName:      "Machinist Restore",
+			ConfigSourceDir: homeOverride,
 		}
 		if err := bundler.Bundle(ctx, runner, snap, dmgOutput, opts); err != nil {
 			return fmt.Errorf("create DMG bundle: %w", err)
@@ -106,7 +109,7 @@ var dmgCmd = &cobra.Command{
 }
 
 // runInteractiveScan presents a TUI for scanner selection and runs selected scanners.
-func runInteractiveScan(cmd *cobra.Command, reg *scanner.Registry, ctx context.Context) (*domain.Snapshot, []error, error) {
+func runInteractiveScan(cmd *cobra.Command, reg *scanner.Registry, ctx context.Context) (*domain.Snapshot, error) {
 	scanners := reg.List()
 
 	items := make([]tui.ScannerItem, len(scanners))
@@ -122,37 +125,24 @@ func runInteractiveScan(cmd *cobra.Command, reg *scanner.Registry, ctx context.C
 	p := tea.NewProgram(selectModel)
 	finalModel, err := p.Run()
 	if err != nil {
-		return nil, nil, fmt.Errorf("interactive selection: %w", err)
+		return nil, fmt.Errorf("interactive selection: %w", err)
 	}
 
 	result := finalModel.(tui.ScannerSelectModel)
 	if result.Quitted() {
 		fmt.Fprintln(cmd.OutOrStdout(), "Cancelled.")
-		return nil, nil, nil
+		return nil, nil
 	}
 
 	selected := result.Selected()
 	if len(selected) == 0 {
 		fmt.Fprintln(cmd.OutOrStdout(), "No scanners selected.")
-		return nil, nil, nil
-	}
-
-	start := time.Now()
-	hostname, _ := os.Hostname()
-	snap := domain.NewSnapshot(hostname, runtime.GOOS, runtime.GOARCH, "")
-
-	var errs []error
-	for _, name := range selected {
-		scanResult, scanErr := reg.ScanOne(ctx, name)
-		if scanErr != nil {
-			errs = append(errs, scanErr)
-			continue
-		}
-		scanner.ApplyResult(snap, scanResult)
+		return nil, nil
 	}
 
-	snap.Meta.ScanDurationSecs = time.Since(start).Seconds()
-	return snap, errs, nil
+	fmt.Fprintln(cmd.OutOrStdout(), "Scanning environment...")
+	snap, _ := reg.ScanSelected(ctx, selected, newProgressWriter(cmd.OutOrStdout()))
+	return snap, nil
 }
 
 // hasEncryptedSections returns true if the snapshot contains sections marked as encrypted
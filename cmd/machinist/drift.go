@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/moinsen-dev/machinist/internal/diff"
+	"github.com/moinsen-dev/machinist/internal/drift"
+	"github.com/spf13/cobra"
+)
+
+// driftExitCode is the exit status when the machine drifted from the
+// manifest; 1 stays reserved for errors.
+const driftExitCode = 2
+
+var (
+	driftJSON  bool
+	driftQuiet bool
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift <manifest.toml>",
+	Short: "Compare this machine against a manifest",
+	Long: "Run the scanners for the sections a manifest covers and report what the manifest\n" +
+		"requires but this machine lacks (formulae, casks, extensions, repositories), values\n" +
+		"that differ (pinned runtime versions, config file content hashes) and what the\n" +
+		"machine has on top of the manifest. Exits 0 without drift, 2 with drift and 1 on\n" +
+		"errors, so it can run from a LaunchAgent or CI.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		manifest, err := loadManifest(cmd, args[0])
+		if err != nil {
+			return fmt.Errorf("load %s: %w", args[0], err)
+		}
+		reg, err := newRegistry()
+		if err != nil {
+			return err
+		}
+
+		opts := drift.Options{HomeDir: homeOverride}
+		human := !driftJSON && !driftQuiet
+		if human {
+			fmt.Fprintln(cmd.ErrOrStderr(), "Scanning environment...")
+			opts.Progress = newProgressWriter(cmd.ErrOrStderr())
+		}
+		report, err := drift.Detect(context.Background(), reg, manifest, opts)
+		if err != nil {
+			return err
+		}
+		// The report is the output; drift is not a usage error, and Execute
+		// prints the summary below.
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+
+		w := cmd.OutOrStdout()
+		switch {
+		case driftJSON:
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		case human:
+			fmt.Fprintln(cmd.ErrOrStderr())
+			printDiagnostics(cmd.ErrOrStderr(), report.Diagnostics)
+			printDrift(w, args[0], report)
+		}
+
+		if report.Drifted() {
+			exit := &exitError{code: driftExitCode}
+			if !driftQuiet {
+				exit.err = fmt.Errorf("%s: %d missing, %d changed", args[0], len(report.Missing), len(report.Changed))
+			}
+			return exit
+		}
+		return nil
+	},
+}
+
+// printDrift writes a drift report for people.
+func printDrift(w io.Writer, manifest string, r *drift.Report) {
+	if !r.Drifted() {
+		fmt.Fprintf(w, "No drift from %s\n", manifest)
+	}
+	groups := []struct {
+		title   string
+		mark    string
+		kind    diff.Kind
+		changes []diff.Change
+	}{
+		{"Missing", "-", diff.Removed, r.Missing},
+		{"Changed", "~", diff.Changed, r.Changed},
+		{"Extra", "+", diff.Added, r.Extra},
+	}
+	for _, g := range groups {
+		if len(g.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s (%d):\n", g.title, len(g.changes))
+		for _, c := range g.changes {
+			fmt.Fprintf(w, "  %s\n", diffColor(g.kind, g.mark+" "+c.String()))
+		}
+	}
+	if len(r.Unchecked) > 0 {
+		fmt.Fprintf(w, "%s\n", dimStyle.Render("Not checked (no scanner available): "+strings.Join(r.Unchecked, ", ")))
+	}
+}
+
+func init() {
+	driftCmd.Flags().BoolVar(&driftJSON, "json", false, "Print the report as JSON")
+	driftCmd.Flags().BoolVarP(&driftQuiet, "quiet", "q", false, "Print nothing; report drift through the exit status only")
+	rootCmd.AddCommand(driftCmd)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/config"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/drift"
+	"github.com/moinsen-dev/machinist/internal/util"
+)
+
+// writeDriftFixture sets up a home directory with a tmux config and a
+// replay fixture in which tmux is installed.
+func writeDriftFixture(t *testing.T) (home, fixture string) {
+	t.Helper()
+	dir := t.TempDir()
+	home = filepath.Join(dir, "home")
+	if err := os.MkdirAll(home, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".tmux.conf"), []byte("set -g @plugin 'tmux-plugins/tpm'\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fixture = filepath.Join(dir, "fixture.jsonl")
+	if err := os.WriteFile(fixture, []byte(`{"kind":"lookpath","name":"tmux","found":true}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return home, fixture
+}
+
+func resetDriftFlags() {
+	driftJSON, driftQuiet = false, false
+	homeOverride, replayPath = "", ""
+}
+
+func TestDriftCommand(t *testing.T) {
+	resetDriftFlags()
+	defer resetDriftFlags()
+	home, fixture := writeDriftFixture(t)
+	manifest := filepath.Join(t.TempDir(), "team.toml")
+	if err := os.WriteFile(manifest, []byte("[meta]\nschema_version = 2\n\n[tmux]\ntpm_plugins = [\"tmux-plugins/tpm\", \"tmux-plugins/tmux-sensible\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := executeCommand("drift", manifest, "--home", home, "--replay", fixture)
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != driftExitCode {
+		t.Fatalf("expected exit code %d, got %v", driftExitCode, err)
+	}
+	if !strings.Contains(output, "- tmux.tpm_plugins[tmux-plugins/tmux-sensible]") {
+		t.Errorf("expected the missing plugin to be reported, got:\n%s", output)
+	}
+	if !strings.Contains(output, "+ tmux.config_files[.tmux.conf]") {
+		t.Errorf("expected the unlisted config file to be reported as extra, got:\n%s", output)
+	}
+
+	output, _ = executeCommand("drift", manifest, "--home", home, "--replay", fixture, "--json")
+	var report drift.Report
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if len(report.Missing) != 1 || len(report.Extra) != 1 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestDriftCommandNoDrift(t *testing.T) {
+	resetDriftFlags()
+	defer resetDriftFlags()
+	home, fixture := writeDriftFixture(t)
+	manifest := filepath.Join(t.TempDir(), "team.toml")
+	if err := os.WriteFile(manifest, []byte("[meta]\nschema_version = 2\n\n[tmux]\ntpm_plugins = [\"tmux-plugins/tpm\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := executeCommand("drift", manifest, "--home", home, "--replay", fixture, "-q")
+	if err != nil {
+		t.Fatalf("expected no drift, got %v", err)
+	}
+	if output != "" {
+		t.Errorf("expected no output with --quiet, got:\n%s", output)
+	}
+}
+
+// TestSectionsHaveScanners makes sure drift can check every section: each
+// one must be produced by a built-in scanner.
+func TestSectionsHaveScanners(t *testing.T) {
+	snap := &domain.Snapshot{}
+	v := reflect.ValueOf(snap).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.Pointer && v.Type().Field(i).Name != "Profile" {
+			f.Set(reflect.New(f.Type().Elem()))
+		}
+	}
+
+	reg := buildRegistry(config.Default(), t.TempDir(), &util.RealCommandRunner{})
+	if _, missing := reg.ScannersFor(snap); len(missing) > 0 {
+		t.Errorf("sections without a built-in scanner: %v", missing)
+	}
+}
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 
 	"github.com/moinsen-dev/machinist/profiles"
 	"github.com/spf13/cobra"
@@ -11,7 +14,10 @@ var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available scanners and profiles",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		reg := newRegistry()
+		reg, err := newRegistry()
+		if err != nil {
+			return err
+		}
 
 		fmt.Fprintln(cmd.OutOrStdout(), "Scanners:")
 		for _, s := range reg.List() {
@@ -20,14 +26,7 @@ var listCmd = &cobra.Command{
 
 		fmt.Fprintln(cmd.OutOrStdout())
 		fmt.Fprintln(cmd.OutOrStdout(), "Profiles:")
-		names, err := profiles.List()
-		if err != nil {
-			return err
-		}
-		for _, name := range names {
-			fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", name)
-		}
-		return nil
+		return printProfiles(cmd.OutOrStdout())
 	},
 }
 
@@ -35,7 +34,10 @@ var listScannersCmd = &cobra.Command{
 	Use:   "scanners",
 	Short: "List available scanners",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		reg := newRegistry()
+		reg, err := newRegistry()
+		if err != nil {
+			return err
+		}
 		for _, s := range reg.List() {
 			fmt.Fprintf(cmd.OutOrStdout(), "  %-20s %s\n", s.Name(), s.Description())
 		}
@@ -43,23 +45,67 @@ var listScannersCmd = &cobra.Command{
 	},
 }
 
+var (
+	listProfilesJSON bool
+	listProfilesTag  string
+)
+
 var listProfilesCmd = &cobra.Command{
 	Use:   "profiles",
 	Short: "List available profiles",
+	Long:  "List the available profiles with their description and tags. --tag keeps the profiles carrying a tag; --json prints the full [profile] metadata.",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		names, err := profiles.List()
+		infos, err := profiles.Search(listProfilesTag)
 		if err != nil {
 			return err
 		}
-		for _, name := range names {
-			fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", name)
+		if listProfilesJSON {
+			enc := json.NewEncoder(cmd.OutOrStdout())
+			enc.SetIndent("", "  ")
+			return enc.Encode(infos)
 		}
+		printProfileInfos(cmd.OutOrStdout(), infos)
 		return nil
 	},
 }
 
+// printProfiles lists all available profiles.
+func printProfiles(w io.Writer) error {
+	infos, err := profiles.Search("")
+	if err != nil {
+		return err
+	}
+	printProfileInfos(w, infos)
+	return nil
+}
+
+// printProfileInfos prints one line per profile: its name, description and
+// tags, and the file of those that do not come built in. Descriptions line
+// up after the longest name.
+func printProfileInfos(w io.Writer, infos []profiles.Info) {
+	width := 16
+	for _, p := range infos {
+		width = max(width, len(p.Name))
+	}
+	for _, p := range infos {
+		line := fmt.Sprintf("  %-*s %s", width, p.Name, p.Description)
+		if p.Error != "" {
+			line = fmt.Sprintf("  %-*s (invalid: %s)", width, p.Name, p.Error)
+		}
+		if len(p.Tags) > 0 {
+			line += " [" + strings.Join(p.Tags, ", ") + "]"
+		}
+		if p.Source != "embedded" {
+			line += " (" + p.Source + ")"
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
+
 func init() {
 	listCmd.AddCommand(listScannersCmd)
+	listProfilesCmd.Flags().BoolVar(&listProfilesJSON, "json", false, "Print the profiles and their metadata as JSON")
+	listProfilesCmd.Flags().StringVar(&listProfilesTag, "tag", "", "Only list profiles with this tag")
 	listCmd.AddCommand(listProfilesCmd)
 	rootCmd.AddCommand(listCmd)
 }
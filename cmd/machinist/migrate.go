@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/profiles"
+	"github.com/spf13/cobra"
+)
+
+var migrateOutput string
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate <manifest.toml>",
+	Short: "Upgrade a manifest to the current schema version",
+	Long: "Upgrade a manifest written by an older machinist to the current schema version.\n" +
+		"The migrated manifest is printed to stdout unless -o is given. Other commands\n" +
+		"migrate older manifests in memory when they load them.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("read manifest: %w", err)
+		}
+		snap, err := domain.UnmarshalManifest(data)
+		if err != nil {
+			return fmt.Errorf("migrate %s: %w", args[0], err)
+		}
+
+		if migrateOutput == "" {
+			out, err := domain.MarshalManifest(snap)
+			if err != nil {
+				return fmt.Errorf("marshal: %w", err)
+			}
+			_, err = cmd.OutOrStdout().Write(out)
+			return err
+		}
+		if err := domain.WriteManifest(snap, migrateOutput); err != nil {
+			return fmt.Errorf("write manifest: %w", err)
+		}
+		if from := snap.Meta.MigratedFrom; from == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s is already at schema version %d; written to %s\n", args[0], domain.CurrentSchemaVersion, migrateOutput)
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "Migrated %s from schema version %d to %d: %s\n", args[0], from, domain.CurrentSchemaVersion, migrateOutput)
+		}
+		return nil
+	},
+}
+
+// loadManifest reads a manifest and resolves its extends chain like
+// profiles.Load, warning when the file was migrated from an older schema.
+func loadManifest(cmd *cobra.Command, path string) (*domain.Snapshot, error) {
+	snap, err := profiles.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	warnMigrated(cmd, path, snap)
+	return snap, nil
+}
+
+// warnMigrated tells the user that the manifest at path was upgraded in
+// memory, so the file itself still has the old layout until it is migrated.
+func warnMigrated(cmd *cobra.Command, path string, snap *domain.Snapshot) {
+	if from := snap.Meta.MigratedFrom; from != 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: migrated %s from schema version %d to %d; run 'machinist migrate %s -o %s' to update the file\n",
+			path, from, domain.CurrentSchemaVersion, path, path)
+	}
+}
+
+func init() {
+	migrateCmd.Flags().StringVarP(&migrateOutput, "output", "o", "", "Output file path (default stdout)")
+	rootCmd.AddCommand(migrateCmd)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateLegacyManifest(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "old.toml")
+	out := filepath.Join(dir, "new.toml")
+	legacy := "[meta]\nsource_hostname = \"old-mac\"\n\n[homebrew]\ntaps = [\"homebrew/core\"]\n"
+	if err := os.WriteFile(in, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := executeCommand("migrate", in, "-o", out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "schema version") {
+		t.Errorf("expected a summary line, got: %s", output)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"schema_version = 2", `source_hostname = "old-mac"`, "homebrew/core"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("migrated manifest missing %q:\n%s", want, data)
+		}
+	}
+}
+
+func TestMigrateRejectsNewerSchema(t *testing.T) {
+	in := filepath.Join(t.TempDir(), "future.toml")
+	if err := os.WriteFile(in, []byte("[meta]\nschema_version = 999\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := executeCommand("migrate", in, "-o", "")
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("expected a newer-schema error, got: %v", err)
+	}
+}
+
+func TestLoadWarnsOnMigratedManifest(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "old.toml")
+	if err := os.WriteFile(legacy, []byte("[homebrew]\ntaps = [\"homebrew/core\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	current := filepath.Join(dir, "current.toml")
+	if err := os.WriteFile(current, []byte("[meta]\nschema_version = 2\n\n[homebrew]\ntaps = [\"homebrew/core\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := executeCommand("resolve", legacy)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Warning: migrated "+legacy+" from schema version 1 to 2") {
+		t.Errorf("expected a migration warning, got:\n%s", output)
+	}
+
+	output, err = executeCommand("resolve", current)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(output, "Warning") {
+		t.Errorf("expected no warning for a current manifest, got:\n%s", output)
+	}
+}
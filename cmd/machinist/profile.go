@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/tui"
+	"github.com/moinsen-dev/machinist/profiles"
+	"github.com/spf13/cobra"
+)
+
+var (
+	extractName         string
+	extractOutput       string
+	extractDescription  string
+	extractTags         []string
+	extractSections     string
+	extractKeepVersions bool
+	extractInteractive  bool
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Work with profiles",
+}
+
+var profileExtractCmd = &cobra.Command{
+	Use:   "extract <snapshot>",
+	Short: "Turn a snapshot into a shareable profile",
+	Long: "Turn a real machine's snapshot into a profile a team can share.\n\n" +
+		"SSH, GPG, env files, network and hosts file sections are dropped, as are the computer name,\n" +
+		"content hashes, sensitive or encrypted config files and package versions (keep them with\n" +
+		"--keep-versions). Paths in the home directory become ~-relative. Pick sections with\n" +
+		"--sections or --interactive. Put the result in a profile directory to use it.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snap, err := domain.ReadManifest(args[0])
+		if err != nil {
+			return fmt.Errorf("read manifest: %w", err)
+		}
+		warnMigrated(cmd, args[0], snap)
+
+		sections := parseCSV(extractSections)
+		if extractInteractive {
+			sections, err = pickSections(snap)
+			if err != nil || sections == nil {
+				return err
+			}
+		}
+
+		homeDir := homeOverride
+		if homeDir == "" {
+			homeDir, _ = os.UserHomeDir()
+		}
+		profile, err := profiles.Extract(snap, profiles.ExtractOptions{
+			Name: extractName,
+			Info: domain.ProfileInfo{
+				Description: extractDescription,
+				Tags:        extractTags,
+			},
+			Sections:     sections,
+			KeepVersions: extractKeepVersions,
+			HomeDir:      homeDir,
+		})
+		if err != nil {
+			return err
+		}
+
+		output := extractOutput
+		if output == "" {
+			output = extractName + ".toml"
+		}
+		if err := domain.WriteManifest(profile, output); err != nil {
+			return fmt.Errorf("write profile: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Profile %s written to %s\n", extractName, output)
+		return nil
+	},
+}
+
+// pickSections lets the user choose which of the snapshot's shareable
+// sections go into the profile. It returns nil when the user cancels.
+func pickSections(snap *domain.Snapshot) ([]string, error) {
+	var items []tui.ScannerItem
+	for _, s := range domain.Sections() {
+		if !s.Present(snap) || slices.Contains(profiles.MachineSections, s.Key) {
+			continue
+		}
+		items = append(items, tui.ScannerItem{Name: s.Key, Description: s.Stage, Category: s.Group})
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("the snapshot has no sections to share")
+	}
+
+	model := tui.NewScannerSelectModel(items).WithTitle("Select sections to include in the profile:")
+	final, err := tea.NewProgram(model).Run()
+	if err != nil {
+		return nil, fmt.Errorf("interactive selection: %w", err)
+	}
+	result := final.(tui.ScannerSelectModel)
+	if result.Quitted() {
+		return nil, nil
+	}
+	selected := result.Selected()
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no sections selected")
+	}
+	return selected, nil
+}
+
+func init() {
+	profileExtractCmd.Flags().StringVar(&extractName, "name", "", "Profile name (required)")
+	profileExtractCmd.Flags().StringVarP(&extractOutput, "output", "o", "", "Output file (default <name>.toml)")
+	profileExtractCmd.Flags().StringVar(&extractDescription, "description", "", "Profile description")
+	profileExtractCmd.Flags().StringSliceVar(&extractTags, "tag", nil, "Profile tag (repeatable)")
+	profileExtractCmd.Flags().StringVar(&extractSections, "sections", "", "Comma-separated sections to keep (default: all shareable sections)")
+	profileExtractCmd.Flags().BoolVar(&extractKeepVersions, "keep-versions", false, "Keep package versions and installed runtime versions")
+	profileExtractCmd.Flags().BoolVarP(&extractInteractive, "interactive", "i", false, "Pick the sections to keep interactively")
+	_ = profileExtractCmd.MarkFlagRequired("name")
+	profileCmd.AddCommand(profileExtractCmd)
+	rootCmd.AddCommand(profileCmd)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+)
+
+func resetExtractFlags() {
+	extractName = ""
+	extractOutput = ""
+	extractDescription = ""
+	extractTags = nil
+	extractSections = ""
+	extractKeepVersions = false
+	extractInteractive = false
+	homeOverride = ""
+	profileExtractCmd.Flags().Lookup("name").Changed = false
+}
+
+func TestProfileExtract(t *testing.T) {
+	resetExtractFlags()
+	defer resetExtractFlags()
+	dir := t.TempDir()
+	snapPath := filepath.Join(dir, "snapshot.toml")
+	snap := &domain.Snapshot{
+		Meta:     domain.Meta{SourceHostname: "alice-mbp"},
+		Homebrew: &domain.HomebrewSection{Formulae: []domain.Package{{Name: "go", Version: "1.25.1"}}},
+		SSH:      &domain.SSHSection{Keys: []string{"id_ed25519"}},
+		GitRepos: &domain.GitReposSection{Repositories: []domain.Repository{
+			{Remote: "git@github.com:team/api.git", Path: "/Users/alice/Code/api"},
+		}},
+	}
+	if err := domain.WriteManifest(snap, snapPath); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "team.toml")
+	output, err := executeCommand("profile", "extract", snapPath, "--name", "team", "-o", out, "--tag", "backend")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "Profile team written to") {
+		t.Errorf("unexpected output: %s", output)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{`source_hostname = "profile:team"`, `tags = ["backend"]`, `"~/Code/api"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected profile to contain %s, got:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"alice", "1.25.1", "[ssh]"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("expected profile not to contain %s, got:\n%s", unwanted, got)
+		}
+	}
+}
+
+func TestProfileExtractRequiresName(t *testing.T) {
+	resetExtractFlags()
+	_, err := executeCommand("profile", "extract", "snapshot.toml")
+	if err == nil || !strings.Contains(err.Error(), "name") {
+		t.Fatalf("expected a missing --name error, got %v", err)
+	}
+}
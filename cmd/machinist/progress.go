@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/moinsen-dev/machinist/internal/domain"
 	"github.com/moinsen-dev/machinist/internal/scanner"
 )
 
@@ -13,31 +14,51 @@ var (
 	dimStyle     = lipgloss.NewStyle().Faint(true)
 	successStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))  // green
 	errorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))  // red
+	warningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))  // yellow
 	scannerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("4"))  // blue
 	counterStyle = lipgloss.NewStyle().Faint(true)
 )
 
 // newProgressWriter returns a ProgressFunc that writes scan progress to w.
+// Scanners run concurrently, so one line is printed as each scanner finishes.
 func newProgressWriter(w io.Writer) scanner.ProgressFunc {
 	return func(e scanner.ProgressEvent) {
 		if !e.Done {
-			// Starting a scanner — print on same line
-			counter := counterStyle.Render(fmt.Sprintf("[%d/%d]", e.Index+1, e.Total))
-			name := scannerStyle.Render(e.Name)
-			fmt.Fprintf(w, "  %s Scanning %s...", counter, name)
 			return
 		}
 
-		// Scanner finished
+		counter := counterStyle.Render(fmt.Sprintf("[%d/%d]", e.Completed, e.Total))
+		name := scannerStyle.Render(e.Name)
+		dur := dimStyle.Render(fmt.Sprintf("(%.1fs)", e.Duration.Seconds()))
+		if e.Cached {
+			dur = dimStyle.Render("(cached)")
+		}
 		if e.Err != nil {
 			mark := errorStyle.Render("✗")
-			dur := dimStyle.Render(fmt.Sprintf("(%.1fs)", e.Duration.Seconds()))
 			reason := dimStyle.Render(shortError(e.Err))
-			fmt.Fprintf(w, " %s %s %s\n", mark, dur, reason)
+			fmt.Fprintf(w, "  %s %s %s %s %s\n", counter, mark, name, dur, reason)
+		} else if e.Warnings > 0 {
+			mark := warningStyle.Render("!")
+			note := dimStyle.Render(fmt.Sprintf("%d warning(s)", e.Warnings))
+			fmt.Fprintf(w, "  %s %s %s %s %s\n", counter, mark, name, dur, note)
 		} else {
 			mark := successStyle.Render("✓")
-			dur := dimStyle.Render(fmt.Sprintf("(%.1fs)", e.Duration.Seconds()))
-			fmt.Fprintf(w, " %s %s\n", mark, dur)
+			fmt.Fprintf(w, "  %s %s %s %s\n", counter, mark, name, dur)
+		}
+	}
+}
+
+// printDiagnostics writes one line per diagnostic to w, followed by the
+// suggested fix when there is one.
+func printDiagnostics(w io.Writer, diags []domain.Diagnostic) {
+	for _, d := range diags {
+		msg := d.Error()
+		if d.Command != "" {
+			msg += dimStyle.Render(" (" + d.Command + ")")
+		}
+		fmt.Fprintf(w, "%s: %s\n", d.Severity, msg)
+		if d.Fix != "" {
+			fmt.Fprintf(w, "  %s %s\n", dimStyle.Render("fix:"), d.Fix)
 		}
 	}
 }
@@ -1,90 +1,271 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 
+	"github.com/moinsen-dev/machinist/internal/config"
 	"github.com/moinsen-dev/machinist/internal/scanner"
 	"github.com/moinsen-dev/machinist/internal/scanner/cloud"
 	"github.com/moinsen-dev/machinist/internal/scanner/editors"
 	gitscanner "github.com/moinsen-dev/machinist/internal/scanner/git"
 	"github.com/moinsen-dev/machinist/internal/scanner/packages"
+	"github.com/moinsen-dev/machinist/internal/scanner/plugin"
+	"github.com/moinsen-dev/machinist/internal/scanner/probe"
 	"github.com/moinsen-dev/machinist/internal/scanner/runtimes"
 	"github.com/moinsen-dev/machinist/internal/scanner/security"
 	"github.com/moinsen-dev/machinist/internal/scanner/shell"
 	"github.com/moinsen-dev/machinist/internal/scanner/system"
 	"github.com/moinsen-dev/machinist/internal/scanner/tools"
 	"github.com/moinsen-dev/machinist/internal/util"
+	"github.com/moinsen-dev/machinist/profiles"
+	"github.com/spf13/cobra"
 )
 
-func newRegistry() *scanner.Registry {
-	cmd := &util.RealCommandRunner{}
-	homeDir, _ := os.UserHomeDir()
+// newRegistry loads the user config and builds a registry of all built-in
+// scanners, the probe scanners and any machinist-scanner-* plugins found on
+// this machine.
+func newRegistry() (*scanner.Registry, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	homeDir := homeOverride
+	if homeDir == "" {
+		homeDir, _ = os.UserHomeDir()
+	}
+	cmd, err := commandRunner(homeDir)
+	if err != nil {
+		return nil, err
+	}
+	reg := buildRegistry(cfg, homeDir, cmd)
+	// Recording and replaying must see every command, so they bypass the cache.
+	if recordPath == "" && replayPath == "" {
+		cache := scanner.NewCache(config.CacheDir(), cmd, Version)
+		cache.SetRefresh(noCache)
+		reg.SetCache(cache)
+	}
+	registerProbes(reg, cfg, loadProbes(), homeDir)
+	// Plugins are executables of the machine being scanned; a replayed scan
+	// only has the recorded commands, so plugin discovery is skipped.
+	if replayPath == "" {
+		registerPlugins(reg, cfg, discoverPlugins(cfg, homeDir, cmd))
+	}
+	return reg, nil
+}
 
-	// Default git repo search paths
-	searchPaths := []string{
-		filepath.Join(homeDir, "Code"),
-		filepath.Join(homeDir, "Projects"),
-		filepath.Join(homeDir, "Developer"),
-		filepath.Join(homeDir, "work"),
+// recorder is the runner writing to --record, shared by every registry built
+// during this invocation.
+var recorder *util.RecordingRunner
+
+// commandRunner returns the runner scanners use: a recorder for --record, a
+// fixture replay for --replay, or the real runner. Fixtures refer to homeDir
+// by a placeholder, so they replay under another --home.
+func commandRunner(homeDir string) (util.CommandRunner, error) {
+	switch {
+	case replayPath != "":
+		f, err := os.Open(replayPath)
+		if err != nil {
+			return nil, fmt.Errorf("open fixture: %w", err)
+		}
+		defer f.Close()
+		runner, err := util.LoadFixture(f, homeDir)
+		if err != nil {
+			return nil, fmt.Errorf("load fixture %s: %w", replayPath, err)
+		}
+		return runner, nil
+	case recordPath != "":
+		if recorder != nil {
+			return recorder, nil
+		}
+		f, err := os.Create(recordPath)
+		if err != nil {
+			return nil, fmt.Errorf("create fixture: %w", err)
+		}
+		recorder = util.NewRecordingRunner(f, homeDir)
+		cobra.OnFinalize(func() {
+			if err := recorder.Err(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: fixture %s is incomplete: %v\n", recordPath, err)
+			}
+			f.Close()
+		})
+		return recorder, nil
+	}
+	return &util.RealCommandRunner{}, nil
+}
+
+// loadProbes returns the built-in probe definitions merged with the user's
+// definitions in the probes directory next to the config file. Invalid user
+// definitions are reported on stderr and ignored.
+func loadProbes() []probe.Definition {
+	builtin, err := probe.Builtin()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: built-in probes: %v\n", err)
+	}
+	dir := filepath.Join(config.Dir(), "probes")
+	user, err := probe.LoadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: invalid probe definitions in %s: %v\n", dir, err)
+	}
+	return probe.Merge(builtin, user)
+}
+
+// registerProbes adds a probe scanner per definition unless disabled in cfg.
+// A probe whose name clashes with an already registered scanner is skipped
+// with a warning.
+func registerProbes(reg *scanner.Registry, cfg *config.Config, defs []probe.Definition, homeDir string) {
+	for _, def := range defs {
+		if cfg.IsDisabled(def.Name) {
+			continue
+		}
+		if err := reg.Register(probe.New(def, homeDir)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping probe %s: %v\n", def.Name, err)
+		}
+	}
+}
+
+// discoverPlugins loads the scanner plugins from the configured plugin
+// directories and $PATH. Broken plugins are reported on stderr and skipped.
+func discoverPlugins(cfg *config.Config, homeDir string, cmd util.CommandRunner) []*plugin.Scanner {
+	dirs := plugin.SearchDirs(cfg.ResolvedPluginDirs(homeDir))
+	plugins, errs := plugin.Discover(context.Background(), dirs, homeDir, cmd)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "Warning: skipping scanner plugin: %v\n", err)
+	}
+	return plugins
+}
+
+// registerPlugins adds plugins to reg unless disabled in cfg. A plugin whose
+// name clashes with an already registered scanner is skipped with a warning.
+func registerPlugins(reg *scanner.Registry, cfg *config.Config, plugins []*plugin.Scanner) {
+	for _, p := range plugins {
+		if cfg.IsDisabled(p.Name()) {
+			continue
+		}
+		if err := reg.Register(p); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping scanner plugin %s: %v\n", p.Path(), err)
+		}
+	}
+}
+
+// loadConfig reads the file given by --config, or the default config file if present.
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadOrDefault(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+	return cfg, nil
+}
+
+// useProfileDirs adds the configured profile directories to the profile
+// search path. A config file that fails to load is reported by the commands
+// that read it, so the defaults are used here instead. A ~ in the configured
+// directories expands against --home when it is given.
+func useProfileDirs() {
+	cfg, err := loadConfig()
+	if err != nil {
+		cfg = config.Default()
+	}
+	homeDir := homeOverride
+	if homeDir == "" {
+		homeDir, _ = os.UserHomeDir()
+	}
+	profiles.SetSearchPaths(cfg.ResolvedProfileDirs(homeDir))
+}
+
+// buildRegistry registers every built-in scanner, configured by cfg.
+// Scanners listed in cfg.DisabledScanners are left out.
+func buildRegistry(cfg *config.Config, homeDir string, cmd util.CommandRunner) *scanner.Registry {
+	searchPaths := cfg.ResolvedSearchPaths(homeDir)
+	excludes := cfg.ResolvedExcludes(homeDir)
+
+	gitRepos := gitscanner.NewGitReposScanner(searchPaths, cmd)
+	gitRepos.SetExcludes(excludes)
+
+	envFiles := tools.NewEnvFilesScanner(homeDir)
+	envFiles.SetWorkspaceDirs(searchPaths)
+	envFiles.SetExcludes(excludes)
+
+	xdg := tools.NewXDGConfigScanner(homeDir)
+	for _, name := range cfg.XDGTools {
+		xdg.AddKnownTool(name)
+	}
+
+	macosDefaults := system.NewMacOSDefaultsScanner(cmd)
+	for _, d := range cfg.CustomDefaults {
+		macosDefaults.AddCustomDefault(d.Domain, d.Key, d.ValueType)
+	}
+
+	all := []scanner.Scanner{
+		packages.NewHomebrewScanner(homeDir, cmd),
+		shell.NewShellConfigScanner(homeDir, cmd),
+		gitRepos,
+		runtimes.NewNodeScanner(homeDir, cmd),
+		runtimes.NewPythonScanner(homeDir, cmd),
+		runtimes.NewRustScanner(homeDir, cmd),
+		runtimes.NewGoScanner(homeDir, cmd),
+		runtimes.NewJavaScanner(homeDir, cmd),
+		runtimes.NewFlutterScanner(homeDir, cmd),
+		runtimes.NewDenoScanner(homeDir, cmd),
+		runtimes.NewBunScanner(homeDir, cmd),
+		runtimes.NewRubyScanner(homeDir, cmd),
+		runtimes.NewAsdfScanner(homeDir, cmd),
+		editors.NewVSCodeScanner(homeDir, cmd),
+		editors.NewCursorScanner(homeDir, cmd),
+		editors.NewJetBrainsScanner(homeDir),
+		editors.NewNeovimScanner(homeDir, cmd),
+		editors.NewXcodeScanner(homeDir, cmd),
+		gitscanner.NewGitConfigScanner(homeDir, cmd),
+		gitscanner.NewGitHubCLIScanner(homeDir, cmd),
+		security.NewSSHScanner(homeDir),
+		security.NewGPGScanner(homeDir, cmd),
+		shell.NewTerminalScanner(homeDir),
+		shell.NewTmuxScanner(homeDir, cmd),
+		cloud.NewDockerScanner(homeDir, cmd),
+		cloud.NewAWSScanner(homeDir, cmd),
+		cloud.NewKubernetesScanner(homeDir, cmd),
+		cloud.NewTerraformScanner(homeDir, cmd),
+		cloud.NewAzureScanner(homeDir, cmd),
+		cloud.NewFirebaseScanner(homeDir, cmd),
+		cloud.NewCloudflareScanner(homeDir, cmd),
+		macosDefaults,
+		system.NewFontsScanner(homeDir, cmd),
+		system.NewFoldersScanner(homeDir),
+		system.NewScheduledScanner(homeDir, cmd),
+		system.NewAppsScanner(cmd),
+		system.NewLocaleScanner(cmd),
+		system.NewLoginItemsScanner(cmd),
+		system.NewHostsFileScanner(),
+		system.NewNetworkScanner(cmd),
+		tools.NewOnePasswordScanner(homeDir, cmd),
+		tools.NewDatabasesScanner(homeDir),
+		tools.NewRegistriesScanner(homeDir),
+		tools.NewBrowserScanner(cmd),
+		tools.NewAIToolsScanner(homeDir, cmd),
+		tools.NewAPIToolsScanner(homeDir, cmd),
+		xdg,
+		envFiles,
 	}
 
 	reg := scanner.NewRegistry()
-	reg.Register(packages.NewHomebrewScanner(cmd))
-	reg.Register(shell.NewShellConfigScanner(homeDir, cmd))
-	reg.Register(gitscanner.NewGitReposScanner(searchPaths, cmd))
-	reg.Register(runtimes.NewNodeScanner(homeDir, cmd))
-	reg.Register(runtimes.NewPythonScanner(cmd))
-	reg.Register(runtimes.NewRustScanner(cmd))
-	reg.Register(runtimes.NewGoScanner(cmd))
-	reg.Register(runtimes.NewJavaScanner(homeDir, cmd))
-	reg.Register(runtimes.NewFlutterScanner(cmd))
-	reg.Register(runtimes.NewDenoScanner(homeDir, cmd))
-	reg.Register(runtimes.NewBunScanner(cmd))
-	reg.Register(runtimes.NewRubyScanner(cmd))
-	reg.Register(runtimes.NewAsdfScanner(homeDir, cmd))
-	reg.Register(editors.NewVSCodeScanner(homeDir, cmd))
-	reg.Register(editors.NewCursorScanner(homeDir, cmd))
-	reg.Register(editors.NewJetBrainsScanner(homeDir))
-	reg.Register(editors.NewNeovimScanner(homeDir, cmd))
-	reg.Register(editors.NewXcodeScanner(homeDir, cmd))
-	reg.Register(gitscanner.NewGitConfigScanner(homeDir, cmd))
-	reg.Register(gitscanner.NewGitHubCLIScanner(homeDir, cmd))
-	reg.Register(security.NewSSHScanner(homeDir))
-	reg.Register(security.NewGPGScanner(homeDir, cmd))
-	reg.Register(shell.NewTerminalScanner(homeDir))
-	reg.Register(shell.NewTmuxScanner(homeDir, cmd))
-	reg.Register(cloud.NewDockerScanner(homeDir, cmd))
-	reg.Register(cloud.NewAWSScanner(homeDir, cmd))
-	reg.Register(cloud.NewKubernetesScanner(homeDir, cmd))
-	reg.Register(cloud.NewTerraformScanner(homeDir, cmd))
-	reg.Register(cloud.NewVercelScanner(homeDir, cmd))
-	reg.Register(cloud.NewGCPScanner(homeDir, cmd))
-	reg.Register(cloud.NewAzureScanner(homeDir, cmd))
-	reg.Register(cloud.NewFlyioScanner(homeDir, cmd))
-	reg.Register(cloud.NewFirebaseScanner(homeDir, cmd))
-	reg.Register(cloud.NewCloudflareScanner(homeDir, cmd))
-	reg.Register(system.NewMacOSDefaultsScanner(cmd))
-	reg.Register(system.NewFontsScanner(homeDir, cmd))
-	reg.Register(system.NewFoldersScanner(homeDir))
-	reg.Register(system.NewScheduledScanner(homeDir, cmd))
-	reg.Register(system.NewAppsScanner(cmd))
-	reg.Register(system.NewLocaleScanner(cmd))
-	reg.Register(system.NewLoginItemsScanner(cmd))
-	reg.Register(system.NewHostsFileScanner())
-	reg.Register(system.NewNetworkScanner(cmd))
-	reg.Register(tools.NewRaycastScanner(homeDir))
-	reg.Register(tools.NewAlfredScanner(homeDir))
-	reg.Register(tools.NewKarabinerScanner(homeDir))
-	reg.Register(tools.NewRectangleScanner(homeDir))
-	reg.Register(tools.NewBetterTouchToolScanner(homeDir))
-	reg.Register(tools.NewOnePasswordScanner(homeDir, cmd))
-	reg.Register(tools.NewDatabasesScanner(homeDir))
-	reg.Register(tools.NewRegistriesScanner(homeDir))
-	reg.Register(tools.NewBrowserScanner(cmd))
-	reg.Register(tools.NewAIToolsScanner(homeDir, cmd))
-	reg.Register(tools.NewAPIToolsScanner(homeDir, cmd))
-	reg.Register(tools.NewXDGConfigScanner(homeDir))
-	reg.Register(tools.NewEnvFilesScanner(homeDir))
+	reg.SetConcurrency(scanConcurrency)
+	if !rootCmd.PersistentFlags().Changed("concurrency") && cfg.Concurrency > 0 {
+		reg.SetConcurrency(cfg.Concurrency)
+	}
+	reg.SetTimeout(scanTimeout)
+	if !rootCmd.PersistentFlags().Changed("scanner-timeout") && cfg.ScannerTimeout.Duration > 0 {
+		reg.SetTimeout(cfg.ScannerTimeout.Duration)
+	}
+	for name, d := range cfg.ScannerTimeouts {
+		reg.SetScannerTimeout(name, d.Duration)
+	}
+	for _, s := range all {
+		if cfg.IsDisabled(s.Name()) {
+			continue
+		}
+		reg.Register(s)
+	}
 	return reg
 }
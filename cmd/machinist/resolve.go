@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/spf13/cobra"
+)
+
+var resolveOutput string
+
+var resolveCmd = &cobra.Command{
+	Use:   "resolve <manifest.toml>",
+	Short: "Print a manifest with its extends chain merged in",
+	Long: "Resolve the manifests listed in [meta] extends, recursively, and print the\n" +
+		"flattened result. Entries are \"profile:<name>\" for a built-in profile or a path\n" +
+		"relative to the manifest that lists it. Later layers override earlier ones and\n" +
+		"the manifest itself is applied last. restore and dmg resolve manifests the same way.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		snap, err := loadManifest(cmd, args[0])
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", args[0], err)
+		}
+
+		if resolveOutput == "" {
+			out, err := domain.MarshalManifest(snap)
+			if err != nil {
+				return fmt.Errorf("marshal: %w", err)
+			}
+			_, err = cmd.OutOrStdout().Write(out)
+			return err
+		}
+		if err := domain.WriteManifest(snap, resolveOutput); err != nil {
+			return fmt.Errorf("write manifest: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Resolved manifest written to %s\n", resolveOutput)
+		return nil
+	},
+}
+
+func init() {
+	resolveCmd.Flags().StringVarP(&resolveOutput, "output", "o", "", "Output file path (default stdout)")
+	rootCmd.AddCommand(resolveCmd)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveFlattensExtends(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.toml")
+	if err := os.WriteFile(base, []byte("[rust]\ndefault_toolchain = \"stable\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	setup := filepath.Join(dir, "setup.toml")
+	content := "[meta]\nsource_hostname = \"me\"\nextends = [\"profile:go-dev\", \"base.toml\"]\n"
+	if err := os.WriteFile(setup, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := executeCommand("resolve", setup, "-o", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{`name = "go"`, `default_toolchain = "stable"`, `source_hostname = "me"`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("resolved manifest missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "extends") {
+		t.Errorf("resolved manifest should not extend anything:\n%s", output)
+	}
+}
+
+func TestResolveReportsCycles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "self.toml")
+	if err := os.WriteFile(path, []byte("[meta]\nextends = [\"self.toml\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := executeCommand("resolve", path, "-o", "")
+	if err == nil || !strings.Contains(err.Error(), "extends cycle") {
+		t.Fatalf("expected a cycle error, got: %v", err)
+	}
+}
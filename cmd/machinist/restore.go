@@ -1,14 +1,23 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/moinsen-dev/machinist/internal/bundler"
 	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/plan"
+	"github.com/moinsen-dev/machinist/internal/restore"
 	"github.com/spf13/cobra"
 )
 
@@ -18,18 +27,31 @@ var (
 	restoreDryRun bool
 	restoreYes    bool
 	restoreList   bool
+	restoreJSON   bool
+
+	restoreResume      bool
+	restoreRetryFailed bool
+	restoreReport      string
 )
 
+// restoreFailedExitCode is the exit status when stages of a restore failed;
+// 1 stays reserved for errors that keep a restore from running.
+const restoreFailedExitCode = 2
+
 var restoreCmd = &cobra.Command{
 	Use:   "restore [manifest.toml]",
 	Short: "Restore environment from manifest",
 	Args:  cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if restoreList {
-			fmt.Fprintln(cmd.OutOrStdout(), "Available restore groups:")
-			for _, g := range domain.RestoreGroups() {
-				fmt.Fprintf(cmd.OutOrStdout(), "  %-20s %s\n", g.Name, g.Label)
+			var snap *domain.Snapshot
+			if len(args) > 0 {
+				var err error
+				if snap, err = loadManifest(cmd, args[0]); err != nil {
+					return fmt.Errorf("read manifest: %w", err)
+				}
 			}
+			printStages(cmd.OutOrStdout(), snap)
 			return nil
 		}
 
@@ -41,87 +63,96 @@ var restoreCmd = &cobra.Command{
 		if restoreSkip != "" && restoreOnly != "" {
 			return fmt.Errorf("--skip and --only are mutually exclusive; use one or the other")
 		}
+		if restoreResume && restoreRetryFailed {
+			return fmt.Errorf("--resume and --retry-failed are mutually exclusive; use one or the other")
+		}
+		if restoreReport != "" {
+			if err := restore.CheckReportFile(restoreReport); err != nil {
+				return err
+			}
+		}
+		if restoreJSON && !restoreDryRun {
+			return fmt.Errorf("--json prints the dry-run plan; use it with --dry-run")
+		}
 
-		snap, err := domain.ReadManifest(manifestPath)
+		snap, err := loadManifest(cmd, manifestPath)
 		if err != nil {
 			return fmt.Errorf("read manifest: %w", err)
 		}
 
-		// Build selected groups: all groups with data, filtered by --only/--skip
-		allGroups := domain.RestoreGroups()
-		var selected []domain.RestoreGroup
+		stageNames, err := domain.SelectStages(snap, parseCSV(restoreOnly), parseCSV(restoreSkip))
+		if err != nil {
+			return err
+		}
 
-		if restoreOnly != "" {
-			only := parseCSV(restoreOnly)
-			validNames := make(map[string]bool)
-			for _, n := range domain.GroupNames() {
-				validNames[n] = true
-			}
-			var unknown []string
-			for _, n := range only {
-				if !validNames[n] {
-					unknown = append(unknown, n)
-				}
-			}
-			if len(unknown) > 0 {
-				return fmt.Errorf("unknown group(s): %s (valid: %s)", strings.Join(unknown, ", "), strings.Join(domain.GroupNames(), ", "))
+		hash, err := restore.ManifestHash(snap)
+		if err != nil {
+			return fmt.Errorf("hash manifest: %w", err)
+		}
+		home, _ := os.UserHomeDir()
+		statePath := restore.StatePath(home)
+		state := restore.NewState(hash)
+		if restoreResume || restoreRetryFailed {
+			prev, err := restore.ReadState(statePath)
+			if err != nil {
+				return fmt.Errorf("read restore state: %w", err)
 			}
-			onlySet := make(map[string]bool, len(only))
-			for _, name := range only {
-				onlySet[name] = true
+			if prev.For(hash) {
+				state = prev
 			}
-			for _, g := range allGroups {
-				if onlySet[g.Name] && g.HasData(snap) {
-					selected = append(selected, g)
-				}
+			stageNames, err = resumeStages(cmd.OutOrStdout(), prev, hash, stageNames)
+			if err != nil {
+				return err
 			}
-		} else if restoreSkip != "" {
-			skip := parseCSV(restoreSkip)
-			validNames := make(map[string]bool)
-			for _, n := range domain.GroupNames() {
-				validNames[n] = true
+			if len(stageNames) == 0 {
+				return nil
 			}
-			var unknown []string
-			for _, n := range skip {
-				if !validNames[n] {
-					unknown = append(unknown, n)
-				}
+		}
+
+		var selected []domain.RestoreGroup
+		for _, g := range domain.RestoreGroups() {
+			if len(groupStages(g, snap, stageNames)) > 0 {
+				selected = append(selected, g)
 			}
-			if len(unknown) > 0 {
-				return fmt.Errorf("unknown group(s): %s (valid: %s)", strings.Join(unknown, ", "), strings.Join(domain.GroupNames(), ", "))
+		}
+
+		if restoreDryRun {
+			reg, err := newRegistry()
+			if err != nil {
+				return err
 			}
-			skipSet := make(map[string]bool, len(skip))
-			for _, name := range skip {
-				skipSet[name] = true
+			opts := plan.Options{Stages: stageNames, HomeDir: homeOverride, BundleDir: filepath.Dir(manifestPath)}
+			if !restoreJSON {
+				fmt.Fprintln(cmd.ErrOrStderr(), "Probing this machine...")
+				opts.Progress = newProgressWriter(cmd.ErrOrStderr())
 			}
-			for _, g := range allGroups {
-				if !skipSet[g.Name] && g.HasData(snap) {
-					selected = append(selected, g)
-				}
+			p, err := plan.Build(context.Background(), reg, snap, opts)
+			if err != nil {
+				return fmt.Errorf("plan restore: %w", err)
 			}
-		} else {
-			for _, g := range allGroups {
-				if g.HasData(snap) {
-					selected = append(selected, g)
-				}
+			if restoreJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				return enc.Encode(p)
 			}
-		}
+			fmt.Fprintln(cmd.ErrOrStderr())
+			printDiagnostics(cmd.ErrOrStderr(), p.Diagnostics)
 
-		if restoreDryRun {
 			fmt.Fprintf(cmd.OutOrStdout(), "Dry-run mode: restore plan\n")
 			fmt.Fprintf(cmd.OutOrStdout(), "Manifest: %s\n", manifestPath)
 			fmt.Fprintf(cmd.OutOrStdout(), "Host: %s (%s)\n", snap.Meta.SourceHostname, snap.Meta.SourceArch)
 			fmt.Fprintf(cmd.OutOrStdout(), "Groups to execute: %d\n", len(selected))
 			for i, g := range selected {
-				fmt.Fprintf(cmd.OutOrStdout(), "  %d. %s (%d stages)\n", i+1, g.Name, g.StageCount(snap))
+				fmt.Fprintf(cmd.OutOrStdout(), "  %d. %s (%s)\n", i+1, g.Name, describeGroupStages(g, snap, stageNames))
 			}
+			printRestorePlan(cmd.OutOrStdout(), selected, p)
 			fmt.Fprintln(cmd.OutOrStdout(), "\nNo changes were made (dry-run).")
 			return nil
 		}
 
 		fmt.Fprintf(cmd.OutOrStdout(), "Restoring %d groups from %s\n", len(selected), manifestPath)
 		for _, g := range selected {
-			fmt.Fprintf(cmd.OutOrStdout(), "  - %s\n", g.Name)
+			fmt.Fprintf(cmd.OutOrStdout(), "  - %s (%s)\n", g.Name, describeGroupStages(g, snap, stageNames))
 		}
 
 		if !restoreYes {
@@ -131,6 +162,34 @@ var restoreCmd = &cobra.Command{
 
 		// Execute: for each selected group, find script in bundle dir or generate on-the-fly
 		bundleDir := filepath.Dir(manifestPath)
+		cmd.SilenceUsage = true
+
+		// A fresh restore starts a new state; a resumed one keeps the
+		// outcomes recorded so far.
+		if err := state.Write(statePath); err != nil {
+			return fmt.Errorf("write restore state: %w", err)
+		}
+		// The group scripts create the results file with their first
+		// result; scripts bundled before restore reports never do.
+		resultsDir, err := os.MkdirTemp("", "machinist-results-*")
+		if err != nil {
+			return fmt.Errorf("create restore results: %w", err)
+		}
+		defer os.RemoveAll(resultsDir)
+		resultsPath := filepath.Join(resultsDir, "results.tsv")
+		env := append(os.Environ(), "MACHINIST_MANIFEST_HASH="+hash, "MACHINIST_RESULTS="+resultsPath)
+		if restoreOnly != "" || restoreSkip != "" || restoreResume || restoreRetryFailed {
+			env = append(env, "MACHINIST_STAGES="+strings.Join(stageNames, ","))
+		}
+		hostname, _ := os.Hostname()
+		report := &restore.Report{
+			Manifest:     manifestPath,
+			ManifestHash: hash,
+			Host:         hostname,
+			StartedAt:    time.Now().UTC().Truncate(time.Second),
+		}
+		// groupErrors explain why a group's stages have no results.
+		groupErrors := make(map[string]string)
 
 		// Try to use pre-existing group scripts from the bundle
 		// Fall back to generating them on-the-fly
@@ -156,6 +215,7 @@ var restoreCmd = &cobra.Command{
 				content, ok := scripts[g.ScriptName]
 				if !ok {
 					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: no script for group %s, skipping\n", g.Name)
+					groupErrors[g.Name] = "no restore script for group " + g.Name
 					continue
 				}
 				tmpPath := filepath.Join(os.TempDir(), "machinist-"+g.ScriptName)
@@ -169,18 +229,256 @@ var restoreCmd = &cobra.Command{
 			fmt.Fprintf(cmd.OutOrStdout(), "==> Running %s ...\n", g.Name)
 			execCmd := exec.CommandContext(cmd.Context(), "bash", scriptPath)
 			execCmd.Dir = bundleDir
+			execCmd.Env = env
 			execCmd.Stdout = cmd.OutOrStdout()
 			execCmd.Stderr = cmd.ErrOrStderr()
+			started := time.Now()
 			if runErr := execCmd.Run(); runErr != nil {
 				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: group %s failed: %v\n", g.Name, runErr)
+				groupErrors[g.Name] = fmt.Sprintf("the %s script stopped before the stage finished: %v", g.ScriptName, runErr)
+			}
+			if err := recordUnfinished(statePath, hash, groupStages(g, snap, stageNames), started); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: record restore state: %v\n", err)
 			}
 		}
 
-		fmt.Fprintln(cmd.OutOrStdout(), "\nRestore complete.")
+		report.Stages, err = stageResults(snap, stageNames, resultsPath, home, groupErrors)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(cmd.OutOrStdout(), "\nNo stage results were recorded, so there is no restore report; see the logs in %s\n",
+				filepath.Join(home, ".machinist"))
+			if len(groupErrors) > 0 {
+				return &exitError{code: restoreFailedExitCode, err: fmt.Errorf("%d of %d groups failed", len(groupErrors), len(selected))}
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read restore results: %w", err)
+		}
+		report.Finish(time.Now())
+		reportPath := restore.ReportPath(home)
+		if err := report.WriteJSON(reportPath); err != nil {
+			return fmt.Errorf("write restore report: %w", err)
+		}
+		if restoreReport != "" {
+			if err := report.WriteFile(restoreReport); err != nil {
+				return fmt.Errorf("write restore report: %w", err)
+			}
+		}
+
+		summary := fmt.Sprintf("%d completed, %d failed, %d skipped",
+			report.Count(restore.Completed), report.Count(restore.Failed), report.Count(restore.Skipped))
+		if report.Status == restore.Failed {
+			fmt.Fprintf(cmd.OutOrStdout(), "\nRestore failed: %s\n", summary)
+			for _, st := range report.Stages {
+				if st.Status == restore.Failed {
+					fmt.Fprintf(cmd.OutOrStdout(), "  %s %s (%s)\n", errorStyle.Render("✗"), st.Label, st.Group)
+				}
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Report: %s\n", reportPath)
+			return &exitError{
+				code: restoreFailedExitCode,
+				err:  fmt.Errorf("%d of %d stages failed; rerun them with --retry-failed", report.Count(restore.Failed), len(stageNames)),
+			}
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "\nRestore complete: %s\n", summary)
+		fmt.Fprintf(cmd.OutOrStdout(), "Report: %s\n", reportPath)
 		return nil
 	},
 }
 
+// stageResults lists the outcome of every stage of snap for the report of
+// a run of the stages named names: what the group scripts recorded in the
+// results file, with an excerpt of the group's log under home, or why a
+// stage has no outcome.
+func stageResults(snap *domain.Snapshot, names []string, resultsPath, home string, groupErrors map[string]string) ([]restore.StageResult, error) {
+	recorded, err := restore.ReadResults(resultsPath)
+	if err != nil {
+		return nil, err
+	}
+	var out []restore.StageResult
+	for _, g := range domain.RestoreGroups() {
+		logPath := filepath.Join(home, ".machinist", "restore-"+g.ID+".log")
+		for _, st := range g.Stages(snap) {
+			r := restore.StageResult{Stage: st.Template, Status: restore.Skipped}
+			if rec := recorded[st.Template]; rec != nil {
+				r = *rec
+			}
+			r.Group, r.Label = g.Name, st.Label
+			switch {
+			case !slices.Contains(names, st.Template):
+				r.Status = restore.Skipped
+			case r.Status == restore.Completed || r.Status == restore.Failed:
+				if err := r.ReadLog(logPath); err != nil {
+					r.Log = []string{fmt.Sprintf("read %s: %v", logPath, err)}
+				}
+			default:
+				r.Status = restore.Failed
+				r.Error = groupErrors[g.Name]
+				if r.Error == "" {
+					r.Error = "the stage did not report an outcome"
+				}
+			}
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// resumeStages narrows stages, the stages selected for a restore, to those
+// --resume (all but the completed ones) or --retry-failed (just the failed
+// ones) run again according to state. A state recorded for another
+// manifest no longer applies.
+func resumeStages(w io.Writer, state *restore.State, hash string, stages []string) ([]string, error) {
+	if !state.For(hash) {
+		if restoreRetryFailed {
+			return nil, fmt.Errorf("no restore of this manifest is recorded; nothing to retry")
+		}
+		fmt.Fprintln(w, "No restore of this manifest is recorded; restoring every stage.")
+		return stages, nil
+	}
+	if restoreRetryFailed {
+		failed := state.Select(stages, restore.Failed)
+		if len(failed) == 0 {
+			fmt.Fprintln(w, "No failed stages to retry.")
+		} else {
+			fmt.Fprintf(w, "Retrying %d failed stage(s): %s\n", len(failed), strings.Join(failed, ", "))
+		}
+		return failed, nil
+	}
+	done := state.Select(stages, restore.Completed)
+	rest := slices.DeleteFunc(slices.Clone(stages), func(name string) bool { return slices.Contains(done, name) })
+	switch {
+	case len(rest) == 0:
+		fmt.Fprintln(w, "Nothing to resume: every stage completed.")
+	case len(done) > 0:
+		fmt.Fprintf(w, "Resuming: skipping %d completed stage(s)\n", len(done))
+	}
+	return rest, nil
+}
+
+// recordUnfinished records the stages a group script was due to run but
+// that have no outcome since started, e.g. because the script stopped, as
+// failed in the state at path.
+func recordUnfinished(path, hash string, stages []domain.Stage, started time.Time) error {
+	state, err := restore.ReadState(path)
+	if err != nil || !state.For(hash) {
+		state = restore.NewState(hash)
+	}
+	since := started.UTC().Truncate(time.Second)
+	changed := false
+	for _, st := range stages {
+		if rec, ok := state.Stages[st.Template]; !ok || rec.FinishedAt.Before(since) {
+			state.Record(st.Template, restore.Failed, time.Now())
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return state.Write(path)
+}
+
+// groupStages returns the stages of g that restore runs for snap when it
+// runs the stages named names.
+func groupStages(g domain.RestoreGroup, snap *domain.Snapshot, names []string) []domain.Stage {
+	var out []domain.Stage
+	for _, st := range g.Stages(snap) {
+		if slices.Contains(names, st.Template) {
+			out = append(out, st)
+		}
+	}
+	return out
+}
+
+// describeGroupStages counts the stages of g restore runs, naming them when
+// a selection leaves some out, e.g. "2 of 30 stages: git-config, vscode".
+func describeGroupStages(g domain.RestoreGroup, snap *domain.Snapshot, names []string) string {
+	run := groupStages(g, snap, names)
+	total := g.StageCount(snap)
+	if len(run) == total {
+		return fmt.Sprintf("%d stages", total)
+	}
+	stageNames := make([]string, len(run))
+	for i, st := range run {
+		stageNames[i] = st.Template
+	}
+	return fmt.Sprintf("%d of %d stages: %s", len(run), total, strings.Join(stageNames, ", "))
+}
+
+// printStages writes the restore groups with their stages, the sections
+// each stage restores and their category. With a manifest, only the stages
+// it has data for are listed.
+func printStages(w io.Writer, snap *domain.Snapshot) {
+	if snap == nil {
+		fmt.Fprintln(w, "Available restore groups and stages:")
+	} else {
+		fmt.Fprintln(w, "Restore groups and stages in the manifest:")
+	}
+	for _, g := range domain.RestoreGroups() {
+		if snap != nil && !g.HasData(snap) {
+			continue
+		}
+		fmt.Fprintf(w, "  %-20s %s\n", g.Name, g.Label)
+		var order []string
+		stages := make(map[string][]domain.Section)
+		for _, s := range g.Sections() {
+			if snap != nil && !s.Present(snap) {
+				continue
+			}
+			if _, ok := stages[s.Template]; !ok {
+				order = append(order, s.Template)
+			}
+			stages[s.Template] = append(stages[s.Template], s)
+		}
+		for _, name := range order {
+			sections := stages[name]
+			line := strings.TrimRight(fmt.Sprintf("    %-18s %-24s %s", name, sections[0].Stage, strings.Join(sections[0].Categories(snap), ", ")), " ")
+			if len(sections) > 1 || strings.ReplaceAll(sections[0].Key, "_", "-") != name {
+				keys := make([]string, len(sections))
+				for i, s := range sections {
+					keys[i] = s.Key
+				}
+				line += dimStyle.Render(fmt.Sprintf(" [%s]", strings.Join(keys, ", ")))
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	fmt.Fprintf(w, "\nSelect stages with --only or --skip by group, stage or section name, a glob such\n"+
+		"as '*-tools', or %s<name> with one of: %s.\n", domain.CategoryPrefix, strings.Join(domain.Categories(snap), ", "))
+}
+
+// planMarks prefix a restore plan action by its status.
+var planMarks = map[plan.Status]string{
+	plan.Satisfied: dimStyle.Render("✓"),
+	plan.Install:   successStyle.Render("+"),
+	plan.Overwrite: warningStyle.Render("~"),
+	plan.Unknown:   dimStyle.Render("?"),
+}
+
+// printRestorePlan writes the actions of p by group, each marked with its
+// status, followed by the totals.
+func printRestorePlan(w io.Writer, groups []domain.RestoreGroup, p *plan.Plan) {
+	for _, g := range groups {
+		fmt.Fprintf(w, "\n%s (%s):\n", g.Label, g.Name)
+		for _, a := range p.Actions {
+			if a.Group != g.Name {
+				continue
+			}
+			line := a.Description
+			if a.Status == plan.Satisfied || a.Status == plan.Unknown {
+				line = dimStyle.Render(line)
+			}
+			fmt.Fprintf(w, "  %s %s\n", planMarks[a.Status], line)
+		}
+	}
+	fmt.Fprintf(w, "\nPlan: %d to install, %d to overwrite, %d already satisfied",
+		p.Count(plan.Install), p.Count(plan.Overwrite), p.Count(plan.Satisfied))
+	if n := p.Count(plan.Unknown); n > 0 {
+		fmt.Fprintf(w, ", %d not checked (no scanner for %s)", n, strings.Join(p.Unchecked, ", "))
+	}
+	fmt.Fprintln(w, ".")
+}
+
 // parseCSV splits a comma-separated string into trimmed, non-empty tokens.
 func parseCSV(s string) []string {
 	parts := strings.Split(s, ",")
@@ -195,10 +493,14 @@ func parseCSV(s string) []string {
 }
 
 func init() {
-	restoreCmd.Flags().StringVar(&restoreSkip, "skip", "", "Comma-separated list of groups to skip")
-	restoreCmd.Flags().StringVar(&restoreOnly, "only", "", "Comma-separated list of groups to run (exclusive with --skip)")
+	restoreCmd.Flags().StringVar(&restoreSkip, "skip", "", "Comma-separated groups, stages, sections, globs or category:<name> to skip")
+	restoreCmd.Flags().StringVar(&restoreOnly, "only", "", "Comma-separated groups, stages, sections, globs or category:<name> to run (exclusive with --skip)")
 	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "Show what would be executed without doing it")
 	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Skip confirmation prompt")
-	restoreCmd.Flags().BoolVar(&restoreList, "list", false, "List available restore groups")
+	restoreCmd.Flags().BoolVar(&restoreList, "list", false, "List the restore groups and stages, or those of the given manifest")
+	restoreCmd.Flags().BoolVar(&restoreJSON, "json", false, "Print the dry-run plan as JSON")
+	restoreCmd.Flags().BoolVar(&restoreResume, "resume", false, "Skip the stages an earlier restore of the same manifest completed")
+	restoreCmd.Flags().StringVar(&restoreReport, "report", "", "Also render the restore report to this .md or .html file")
+	restoreCmd.Flags().BoolVar(&restoreRetryFailed, "retry-failed", false, "Rerun only the stages an earlier restore of the same manifest failed")
 	rootCmd.AddCommand(restoreCmd)
 }
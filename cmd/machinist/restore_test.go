@@ -1,10 +1,18 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/config"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/restore"
+	"github.com/moinsen-dev/machinist/internal/util"
 )
 
 // resetRestoreFlags resets package-level restore flag vars to defaults so
@@ -15,6 +23,10 @@ func resetRestoreFlags() {
 	restoreDryRun = false
 	restoreYes = false
 	restoreList = false
+	restoreJSON = false
+	restoreResume, restoreRetryFailed = false, false
+	restoreReport = ""
+	homeOverride, replayPath = "", ""
 }
 
 func TestRestoreNonExistentFile(t *testing.T) {
@@ -188,8 +200,8 @@ casks = []
 	if err == nil {
 		t.Fatal("expected error for unknown group name, got nil")
 	}
-	if !strings.Contains(err.Error(), "unknown group(s)") {
-		t.Errorf("expected error to contain 'unknown group(s)', got: %s", err.Error())
+	if !strings.Contains(err.Error(), "unknown stage(s)") {
+		t.Errorf("expected error to contain 'unknown stage(s)', got: %s", err.Error())
 	}
 	if !strings.Contains(err.Error(), "bogus") {
 		t.Errorf("expected error to mention 'bogus', got: %s", err.Error())
@@ -201,14 +213,95 @@ casks = []
 	if err == nil {
 		t.Fatal("expected error for unknown group name in --skip, got nil")
 	}
-	if !strings.Contains(err.Error(), "unknown group(s)") {
-		t.Errorf("expected error to contain 'unknown group(s)', got: %s", err.Error())
+	if !strings.Contains(err.Error(), "unknown stage(s)") {
+		t.Errorf("expected error to contain 'unknown stage(s)', got: %s", err.Error())
 	}
 	if !strings.Contains(err.Error(), "nope") {
 		t.Errorf("expected error to mention 'nope', got: %s", err.Error())
 	}
 }
 
+func TestRestoreDryRunStageSelection(t *testing.T) {
+	resetRestoreFlags()
+	defer resetRestoreFlags()
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.toml")
+	content := `[homebrew]
+formulae = [{name = "git"}]
+
+[vscode]
+extensions = ["golang.go"]
+
+[kubernetes]
+config_file = ".kube/config"
+
+[tmux]
+config_files = [{source = ".tmux.conf", bundle_path = "configs/tmux/.tmux.conf"}]
+`
+	if err := os.WriteFile(manifest, []byte(content), 0644); err != nil {
+		t.Fatalf("write test manifest: %v", err)
+	}
+
+	output, err := executeCommand("restore", manifest, "--dry-run", "--skip", "homebrew,vscode,category:cloud", "--home", t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "1. configs (1 of 3 stages: tmux)") {
+		t.Errorf("expected only the tmux stage of configs to run, got:\n%s", output)
+	}
+	if strings.Contains(output, "brew install git") || strings.Contains(output, "golang.go") {
+		t.Errorf("expected skipped stages to be left out of the plan, got:\n%s", output)
+	}
+
+	resetRestoreFlags()
+	_, err = executeCommand("restore", manifest, "--dry-run", "--only", "vscode,fonts")
+	if err == nil || !strings.Contains(err.Error(), "not in the manifest: fonts") {
+		t.Errorf("expected a stage missing from the manifest to be rejected, got %v", err)
+	}
+}
+
+func TestRestoreListManifest(t *testing.T) {
+	resetRestoreFlags()
+	defer resetRestoreFlags()
+	manifest := filepath.Join(t.TempDir(), "manifest.toml")
+	if err := os.WriteFile(manifest, []byte("[git]\nconfig_files = []\n\n[launchagents]\nplists = []\n"), 0644); err != nil {
+		t.Fatalf("write test manifest: %v", err)
+	}
+
+	output, err := executeCommand("restore", "--list", manifest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"git-config", "scheduled", "launchagents", "category:<name>"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "homebrew") || strings.Contains(output, "vscode") {
+		t.Errorf("expected only the manifest's stages, got:\n%s", output)
+	}
+}
+
+// TestSectionCategoriesMatchScanners keeps the categories stages are
+// selected by in line with the categories of the scanners. Plugin and
+// probe entries record the category of the scanner that produced them.
+func TestSectionCategoriesMatchScanners(t *testing.T) {
+	reg := buildRegistry(config.Default(), t.TempDir(), &util.RealCommandRunner{})
+	for _, s := range domain.Sections() {
+		if s.Scanner() == "" {
+			continue
+		}
+		sc, err := reg.Get(s.Scanner())
+		if err != nil {
+			t.Errorf("section %q: %v", s.Key, err)
+			continue
+		}
+		if sc.Category() != s.Category() {
+			t.Errorf("section %q has category %q, its scanner %q", s.Key, s.Category(), sc.Category())
+		}
+	}
+}
+
 func TestRestoreDryRunWithSkip(t *testing.T) {
 	resetRestoreFlags()
 	dir := t.TempDir()
@@ -245,3 +338,296 @@ path = "~/work/repo"
 		t.Errorf("expected 'repos' to be filtered out, got:\n%s", output)
 	}
 }
+
+func TestRestoreDryRunPlan(t *testing.T) {
+	resetRestoreFlags()
+	defer resetRestoreFlags()
+	dir := t.TempDir()
+	home := filepath.Join(dir, "home")
+	if err := os.MkdirAll(filepath.Join(home, "Code", "api"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[user]\n\tname = Me\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manifest := filepath.Join(dir, "manifest.toml")
+	content := `[meta]
+schema_version = 2
+
+[homebrew]
+taps = ["homebrew/cask-fonts"]
+formulae = [{name = "git"}, {name = "jq"}]
+
+[git]
+config_files = [{source = ".gitconfig", bundle_path = "configs/git/.gitconfig"}]
+
+[[git_repos.repositories]]
+remote = "git@github.com:team/api.git"
+path = "~/Code/api"
+`
+	if err := os.WriteFile(manifest, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fixture := filepath.Join(dir, "fixture.jsonl")
+	entries := `{"kind":"lookpath","name":"brew","found":true}
+{"kind":"run","name":"brew","args":["list","--formula","--versions"],"stdout":"git 2.47.0\n"}
+{"kind":"run","name":"brew","args":["tap"],"stdout":"homebrew/cask-fonts\n"}
+`
+	if err := os.WriteFile(fixture, []byte(entries), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := executeCommand("restore", manifest, "--dry-run", "--home", home, "--replay", fixture)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output)
+	}
+	for _, want := range []string{
+		"✓ brew tap homebrew/cask-fonts",
+		"+ brew install jq",
+		"~ copy configs/git/.gitconfig → ~/.gitconfig",
+		"✓ git clone git@github.com:team/api.git → ~/Code/api",
+		"Plan: 1 to install, 1 to overwrite, 3 already satisfied.",
+		"No changes were made",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output, got:\n%s", want, output)
+		}
+	}
+
+	output, err = executeCommand("restore", manifest, "--dry-run", "--json", "--only", "homebrew", "--home", home, "--replay", fixture)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output)
+	}
+	var p struct {
+		Groups  []string `json:"groups"`
+		Actions []struct {
+			Key    string `json:"key"`
+			Status string `json:"status"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(output), &p); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if len(p.Groups) != 1 || len(p.Actions) != 3 || p.Actions[2].Key != "homebrew.formulae[jq]" || p.Actions[2].Status != "install" {
+		t.Errorf("unexpected plan: %+v", p)
+	}
+}
+
+func TestRestoreJSONRequiresDryRun(t *testing.T) {
+	resetRestoreFlags()
+	defer resetRestoreFlags()
+	_, err := executeCommand("restore", "manifest.toml", "--json")
+	if err == nil || !strings.Contains(err.Error(), "--dry-run") {
+		t.Fatalf("expected --json to require --dry-run, got %v", err)
+	}
+}
+
+func TestRestoreResume(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	resetRestoreFlags()
+	defer resetRestoreFlags()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	manifest := filepath.Join(t.TempDir(), "manifest.toml")
+	if err := os.WriteFile(manifest, []byte("[tmux]\n\n[folders]\nstructure = [\"Code\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A file in the way makes the folders stage fail.
+	if err := os.WriteFile(filepath.Join(home, "Code"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := executeCommand("restore", manifest, "--yes")
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != restoreFailedExitCode {
+		t.Fatalf("expected the failed stage to exit %d, got %v\n%s", restoreFailedExitCode, err, output)
+	}
+	state, err := restore.ReadState(restore.StatePath(home))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Stages["tmux"].Status != restore.Completed || state.Stages["folders"].Status != restore.Failed {
+		t.Fatalf("expected tmux completed and folders failed, got %+v\n%s", state.Stages, output)
+	}
+
+	resetRestoreFlags()
+	output, err = executeCommand("restore", manifest, "--dry-run", "--resume", "--home", home)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "Resuming: skipping 1 completed stage(s)") || !strings.Contains(output, "1 of 2 stages: folders") {
+		t.Errorf("expected only the failed stage to be resumed, got:\n%s", output)
+	}
+
+	if err := os.Remove(filepath.Join(home, "Code")); err != nil {
+		t.Fatal(err)
+	}
+	resetRestoreFlags()
+	output, err = executeCommand("restore", manifest, "--retry-failed", "--yes")
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "Retrying 1 failed stage(s): folders") || strings.Contains(output, "=== tmux ===") {
+		t.Errorf("expected just the folders stage to run, got:\n%s", output)
+	}
+
+	resetRestoreFlags()
+	output, err = executeCommand("restore", manifest, "--resume", "--yes")
+	if err != nil || !strings.Contains(output, "Nothing to resume: every stage completed.") {
+		t.Errorf("expected nothing left to resume, got %v\n%s", err, output)
+	}
+
+	// Changing the manifest invalidates the recorded state.
+	if err := os.WriteFile(manifest, []byte("[tmux]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resetRestoreFlags()
+	_, err = executeCommand("restore", manifest, "--retry-failed", "--dry-run")
+	if err == nil || !strings.Contains(err.Error(), "no restore of this manifest is recorded") {
+		t.Errorf("expected a changed manifest to have no state, got %v", err)
+	}
+}
+
+func TestRestoreResumeAfterProfileChange(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	resetRestoreFlags()
+	defer resetRestoreFlags()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.toml")
+	base := filepath.Join(dir, "base.toml")
+	if err := os.WriteFile(manifest, []byte("[meta]\nextends = [\"base.toml\"]\n\n[tmux]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base, []byte("[folders]\nstructure = [\"Code\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := executeCommand("restore", manifest, "--yes"); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output)
+	}
+
+	// The manifest file is unchanged, but what it resolves to is not.
+	if err := os.WriteFile(base, []byte("[folders]\nstructure = [\"Code\", \"Notes\"]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	resetRestoreFlags()
+	output, err := executeCommand("restore", manifest, "--dry-run", "--resume", "--home", home)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "No restore of this manifest is recorded") {
+		t.Errorf("expected a changed profile to start the restore over, got:\n%s", output)
+	}
+}
+
+func TestRestoreResumeAndRetryFailedMutuallyExclusive(t *testing.T) {
+	resetRestoreFlags()
+	defer resetRestoreFlags()
+	_, err := executeCommand("restore", "manifest.toml", "--resume", "--retry-failed")
+	if err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Fatalf("expected --resume and --retry-failed to be exclusive, got %v", err)
+	}
+}
+
+func TestRestoreReport(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	resetRestoreFlags()
+	defer resetRestoreFlags()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.toml")
+	if err := os.WriteFile(manifest, []byte("[tmux]\n\n[folders]\nstructure = [\"Code\"]\n\n[git]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "Code"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rendered := filepath.Join(dir, "report.md")
+	output, err := executeCommand("restore", manifest, "--yes", "--skip", "git", "--report", rendered)
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.code != restoreFailedExitCode {
+		t.Fatalf("expected exit code %d, got %v\n%s", restoreFailedExitCode, err, output)
+	}
+	if !strings.Contains(output, "Restore failed: 1 completed, 1 failed, 1 skipped") {
+		t.Errorf("expected a summary of the run, got:\n%s", output)
+	}
+
+	data, err := os.ReadFile(restore.ReportPath(home))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report restore.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != restore.Failed || len(report.Stages) != 3 {
+		t.Fatalf("expected a failed report of 3 stages, got:\n%s", data)
+	}
+	stages := make(map[string]restore.StageResult)
+	for _, s := range report.Stages {
+		stages[s.Stage] = s
+	}
+	if stages["tmux"].Status != restore.Completed || stages["git-config"].Status != restore.Skipped {
+		t.Errorf("expected tmux completed and git-config skipped, got:\n%s", data)
+	}
+	folders := stages["folders"]
+	if folders.Status != restore.Failed || folders.Group != "configs" || len(folders.FailedCommands) == 0 || len(folders.Log) == 0 {
+		t.Errorf("expected the folders failure with its commands and log, got:\n%s", data)
+	}
+
+	md, err := os.ReadFile(rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(md), "## Folder Structure failed") {
+		t.Errorf("expected the Markdown report to detail the failure, got:\n%s", md)
+	}
+}
+
+func TestRestoreWithoutResults(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	resetRestoreFlags()
+	defer resetRestoreFlags()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "manifest.toml")
+	if err := os.WriteFile(manifest, []byte("[tmux]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A bundled script from before restore reports records no results.
+	if err := os.WriteFile(filepath.Join(dir, "03-configs.sh"), []byte("echo restoring tmux\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := executeCommand("restore", manifest, "--yes")
+	if err != nil {
+		t.Fatalf("expected a run without results to succeed, got %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "No stage results were recorded") || strings.Contains(output, "failed") {
+		t.Errorf("expected no report rather than failed stages, got:\n%s", output)
+	}
+	if _, err := os.Stat(restore.ReportPath(home)); !os.IsNotExist(err) {
+		t.Errorf("expected no report to be written, got %v", err)
+	}
+}
+
+func TestRestoreReportExtension(t *testing.T) {
+	resetRestoreFlags()
+	defer resetRestoreFlags()
+	_, err := executeCommand("restore", "manifest.toml", "--yes", "--report", "report.pdf")
+	if err == nil || !strings.Contains(err.Error(), "use a .md or .html file") {
+		t.Fatalf("expected an unsupported report format to be rejected, got %v", err)
+	}
+}
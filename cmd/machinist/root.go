@@ -1,21 +1,82 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"time"
 
+	"github.com/moinsen-dev/machinist/internal/scanner"
 	"github.com/spf13/cobra"
 )
 
+var (
+	configPath      string
+	scanConcurrency int
+	scanTimeout     time.Duration
+	recordPath      string
+	replayPath      string
+	homeOverride    string
+	noCache         bool
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "machinist",
 	Short: "Mac Developer Environment Snapshot & Restore CLI",
 	Long:  "machinist scans your Mac developer environment and generates a restore bundle.",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Scanners, fixtures and restore plans join paths onto the home
+		// directory, so a relative --home is resolved once here.
+		if homeOverride != "" {
+			abs, err := filepath.Abs(homeOverride)
+			if err != nil {
+				return fmt.Errorf("--home: %w", err)
+			}
+			homeOverride = abs
+		}
+		useProfileDirs()
+		return nil
+	},
 }
 
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		var exit *exitError
+		if !errors.As(err, &exit) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if exit.err != nil {
+			fmt.Fprintln(os.Stderr, exit.err)
+		}
+		os.Exit(exit.code)
 	}
 }
+
+// exitError makes Execute exit with code instead of 1, for commands whose
+// exit status is part of their output, like drift. Execute prints err
+// unless it is nil.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+func (e *exitError) Unwrap() error { return e.err }
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file (default ~/.config/machinist/config.toml)")
+	rootCmd.PersistentFlags().IntVarP(&scanConcurrency, "concurrency", "j", scanner.DefaultConcurrency, "Number of scanners to run in parallel")
+	rootCmd.PersistentFlags().DurationVar(&scanTimeout, "scanner-timeout", scanner.DefaultTimeout, "Deadline for each scanner (0 disables)")
+	rootCmd.PersistentFlags().StringVar(&recordPath, "record", "", "Record every command scanners run to a fixture file")
+	rootCmd.PersistentFlags().StringVar(&replayPath, "replay", "", "Answer scanner commands from a recorded fixture instead of running them")
+	rootCmd.PersistentFlags().StringVar(&homeOverride, "home", "", "Scan this directory as the home directory (default $HOME)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Rescan everything instead of reusing cached scanner results")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+}
@@ -2,8 +2,13 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/moinsen-dev/machinist/profiles"
 )
 
 func executeCommand(args ...string) (string, error) {
@@ -85,8 +90,191 @@ func TestRestoreNoArgs(t *testing.T) {
 }
 
 func TestComposeNoArgs(t *testing.T) {
+	resetComposeFlags()
 	_, err := executeCommand("compose")
 	if err == nil {
 		t.Fatal("expected error when no argument is provided, got nil")
 	}
 }
+
+func TestConfigDisablesScanners(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(`disabled_scanners = ["homebrew"]`), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	defer func() { configPath = "" }()
+
+	output, err := executeCommand("list", "scanners", "--config", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(output, "homebrew") {
+		t.Errorf("expected homebrew to be disabled, got:\n%s", output)
+	}
+	if !strings.Contains(output, "shell") {
+		t.Errorf("expected other scanners to stay registered, got:\n%s", output)
+	}
+}
+
+func TestConfigMissingFile(t *testing.T) {
+	defer func() { configPath = "" }()
+	_, err := executeCommand("list", "scanners", "--config", "/tmp/does-not-exist-machinist/config.toml")
+	if err == nil {
+		t.Fatal("expected error for missing --config file, got nil")
+	}
+	if !strings.Contains(err.Error(), "load config") {
+		t.Errorf("expected error to contain 'load config', got: %s", err.Error())
+	}
+}
+
+func TestListScannersIncludesProbes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(`disabled_scanners = ["bartender"]`), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	defer func() { configPath = "" }()
+
+	output, err := executeCommand("list", "scanners", "--config", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "hammerspoon") {
+		t.Errorf("expected built-in probe hammerspoon to be listed, got:\n%s", output)
+	}
+	if strings.Contains(output, "bartender") {
+		t.Errorf("expected disabled probe bartender to be left out, got:\n%s", output)
+	}
+}
+
+func TestProfileDirs(t *testing.T) {
+	dir := t.TempDir()
+	team := filepath.Join(dir, "team")
+	if err := os.MkdirAll(team, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(team, "backend-onboarding.toml"), []byte("[go]\nversion = \"1.25\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte(`profile_dirs = ["`+team+`"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { configPath = "" }()
+
+	output, err := executeCommand("list", "profiles", "--config", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "backend-onboarding") || !strings.Contains(output, team) {
+		t.Errorf("expected the team profile and its directory to be listed, got:\n%s", output)
+	}
+
+	resetComposeFlags()
+	out := filepath.Join(dir, "setup.toml")
+	if _, err := executeCommand("compose", "minimal+backend-onboarding", "--config", path, "-o", out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `version = "1.25"`) {
+		t.Errorf("expected the team profile to be composed, got:\n%s", data)
+	}
+}
+
+func TestProfileDirsUnderHomeOverride(t *testing.T) {
+	t.Cleanup(func() { configPath, homeOverride = "", "" })
+	home := t.TempDir()
+	team := filepath.Join(home, "team-profiles")
+	if err := os.MkdirAll(team, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(team, "home-onboarding.toml"), []byte("[go]\nversion = \"1.25\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(`profile_dirs = ["~/team-profiles"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := executeCommand("list", "profiles", "--config", path, "--home", home)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "home-onboarding") {
+		t.Errorf("expected ~ in profile_dirs to expand against --home, got:\n%s", output)
+	}
+}
+
+func TestListProfilesAlignsLongNames(t *testing.T) {
+	dir := t.TempDir()
+	long := "platform-team-backend-onboarding"
+	if err := os.WriteFile(filepath.Join(dir, long+".toml"), []byte("[profile]\ndescription = \"Backend onboarding\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte(`profile_dirs = ["`+dir+`"]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { configPath = "" }()
+
+	output, err := executeCommand("list", "profiles", "--config", path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	columns := map[string]int{}
+	for _, line := range strings.Split(output, "\n") {
+		for name, desc := range map[string]string{long: "Backend onboarding", "minimal": "Homebrew, Git"} {
+			if strings.HasPrefix(line, "  "+name+" ") {
+				columns[name] = strings.Index(line, desc)
+			}
+		}
+	}
+	if len(columns) != 2 || columns[long] != columns["minimal"] {
+		t.Errorf("expected descriptions to start in one column, got %v in:\n%s", columns, output)
+	}
+}
+
+func TestListProfilesTagJSON(t *testing.T) {
+	defer func() { listProfilesJSON, listProfilesTag = false, "" }()
+
+	output, err := executeCommand("list", "profiles", "--tag", "mobile", "--json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var infos []profiles.Info
+	if err := json.Unmarshal([]byte(output), &infos); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if len(infos) != 1 || infos[0].Name != "flutter-ios" || infos[0].Description == "" {
+		t.Errorf("expected flutter-ios with its description, got %+v", infos)
+	}
+}
+
+func TestScanReplayUnderAnotherHome(t *testing.T) {
+	t.Cleanup(func() { homeOverride, replayPath = "", "" })
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "fakehome", "Code", "api", ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// Recorded on another machine, with its home directory replaced by the
+	// placeholder.
+	fixture := filepath.Join(dir, "fixture.jsonl")
+	content := `{"kind":"lookpath","name":"git","found":true}
+{"kind":"run","name":"git","args":["-C","{{home}}/Code/api","remote","get-url","origin"],"stdout":"git@github.com:team/api.git\n"}
+{"kind":"run","name":"git","args":["-C","{{home}}/Code/api","branch","--show-current"],"stdout":"main\n"}
+`
+	if err := os.WriteFile(fixture, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := executeCommand("scan", "git-repos", "--home", "./fakehome", "--replay", fixture)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, `remote = "git@github.com:team/api.git"`) || !strings.Contains(output, `branch = "main"`) {
+		t.Errorf("expected the recorded repository to replay under ./fakehome, got:\n%s", output)
+	}
+}
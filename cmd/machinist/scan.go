@@ -19,11 +19,13 @@ var scanCmd = &cobra.Command{
 	Short: "Run a single scanner",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		reg := newRegistry()
+		reg, err := newRegistry()
+		if err != nil {
+			return err
+		}
 		ctx := context.Background()
 
 		var result *scanner.ScanResult
-		var err error
 
 		if scanSearchPaths != "" && args[0] == "git-repos" {
 			paths := strings.Split(scanSearchPaths, ",")
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/moinsen-dev/machinist/internal/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	schemaFormat string
+	schemaOutput string
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print a machine-readable description of the manifest format",
+	Long: "Print a JSON Schema for machinist manifests, generated from the manifest types.\n" +
+		"Point Taplo (Even Better TOML) at it for completion and checks while editing:\n\n" +
+		"  machinist schema -o machinist.schema.json\n" +
+		"  # then add '#:schema ./machinist.schema.json' as the first line of setup.toml",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if schemaFormat != "jsonschema" {
+			return fmt.Errorf("unknown schema format %q (supported: jsonschema)", schemaFormat)
+		}
+		data, err := schema.JSONSchema()
+		if err != nil {
+			return fmt.Errorf("generate schema: %w", err)
+		}
+		if schemaOutput == "" {
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		}
+		if err := os.WriteFile(schemaOutput, data, 0o644); err != nil {
+			return fmt.Errorf("write schema: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Schema written to %s\n", schemaOutput)
+		return nil
+	},
+}
+
+func init() {
+	schemaCmd.Flags().StringVar(&schemaFormat, "format", "jsonschema", "Schema format (jsonschema)")
+	schemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "", "Output file path (default stdout)")
+	rootCmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchemaJSON(t *testing.T) {
+	output, err := executeCommand("schema", "--format", "jsonschema", "-o", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var s struct {
+		Schema     string         `json:"$schema"`
+		Properties map[string]any `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(output), &s); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	for _, key := range []string{"meta", "homebrew", "plugins"} {
+		if _, ok := s.Properties[key]; !ok {
+			t.Errorf("schema has no %q property", key)
+		}
+	}
+}
+
+func TestSchemaUnknownFormat(t *testing.T) {
+	_, err := executeCommand("schema", "--format", "yaml", "-o", "")
+	if err == nil || !strings.Contains(err.Error(), "unknown schema format") {
+		t.Fatalf("expected an unknown-format error, got: %v", err)
+	}
+}
@@ -18,7 +18,10 @@ var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Run as MCP server",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		reg := newRegistry()
+		reg, err := newRegistry()
+		if err != nil {
+			return err
+		}
 		srv := mcpserver.NewMachinistServer(reg)
 
 		port, _ := cmd.Flags().GetInt("port")
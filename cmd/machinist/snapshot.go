@@ -20,20 +20,21 @@ var snapshotCmd = &cobra.Command{
 	Use:   "snapshot",
 	Short: "Scan environment and generate TOML manifest",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		reg := newRegistry()
+		reg, err := newRegistry()
+		if err != nil {
+			return err
+		}
 		ctx := context.Background()
 
 		if snapshotInteractive {
-			snap, errs, err := runInteractiveScan(cmd, reg, ctx)
+			snap, err := runInteractiveScan(cmd, reg, ctx)
 			if err != nil {
 				return err
 			}
 			if snap == nil {
 				return nil // cancelled
 			}
-			for _, e := range errs {
-				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", e)
-			}
+			printDiagnostics(cmd.ErrOrStderr(), snap.Meta.Diagnostics)
 			if err := domain.WriteManifest(snap, snapshotOutput); err != nil {
 				return fmt.Errorf("write manifest: %w", err)
 			}
@@ -43,11 +44,9 @@ var snapshotCmd = &cobra.Command{
 
 		fmt.Fprintln(cmd.OutOrStdout(), "Scanning environment...")
 		progress := newProgressWriter(cmd.OutOrStdout())
-		snap, errs := reg.ScanAllWithProgress(ctx, progress)
-		for _, e := range errs {
-			fmt.Fprintf(cmd.ErrOrStderr(), "warning: %v\n", e)
-		}
+		snap, _ := reg.ScanAllWithProgress(ctx, progress)
 		fmt.Fprintln(cmd.OutOrStdout())
+		printDiagnostics(cmd.ErrOrStderr(), snap.Meta.Diagnostics)
 
 		if snapshotDryRun {
 			data, err := domain.MarshalManifest(snap)
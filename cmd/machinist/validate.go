@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/validate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateJSON       bool
+	validateStrict     bool
+	validateCheckFiles bool
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate <manifest.toml>",
+	Short: "Check a manifest for errors before restoring it",
+	Long: "Check a manifest for syntax errors, unknown keys, duplicate or invalid package\n" +
+		"names, config paths that escape the bundle or the home directory, and sections\n" +
+		"that cannot restore anything. Exits non-zero when errors are found (or warnings,\n" +
+		"with --strict). --check-files also reports config files missing from --home.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("read manifest: %w", err)
+		}
+		opts := validate.Options{Path: args[0]}
+		if validateCheckFiles {
+			opts.HomeDir = homeOverride
+			if opts.HomeDir == "" {
+				opts.HomeDir, _ = os.UserHomeDir()
+			}
+		}
+		report := validate.Manifest(data, opts)
+		// The report is the output; a failed check is not a usage error, and
+		// Execute prints the summary below.
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+
+		w := cmd.OutOrStdout()
+		errs := report.Count(domain.SeverityError)
+		warnings := report.Count(domain.SeverityWarning)
+		if validateJSON {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			for _, issue := range report.Issues {
+				printIssue(w, args[0], issue)
+			}
+			if len(report.Issues) == 0 {
+				fmt.Fprintf(w, "%s: ok (%d sections)\n", args[0], len(report.Sections))
+			} else {
+				fmt.Fprintf(w, "%s: %d error(s), %d warning(s)\n", args[0], errs, warnings)
+			}
+		}
+
+		switch {
+		case errs > 0:
+			return fmt.Errorf("%s: %d error(s)", args[0], errs)
+		case validateStrict && warnings > 0:
+			return fmt.Errorf("%s: %d warning(s) (--strict)", args[0], warnings)
+		}
+		return nil
+	},
+}
+
+// printIssue prints an issue in the file:line: form editors and CI
+// annotations understand.
+func printIssue(w io.Writer, file string, issue validate.Issue) {
+	loc := file
+	if issue.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", file, issue.Line)
+	}
+	msg := issue.Message
+	if issue.Key != "" {
+		msg = issue.Key + ": " + msg
+	}
+	sev := string(issue.Severity)
+	if issue.Severity == domain.SeverityError {
+		sev = errorStyle.Render(sev)
+	} else {
+		sev = warningStyle.Render(sev)
+	}
+	fmt.Fprintf(w, "%s: %s: %s %s\n", loc, sev, msg, dimStyle.Render("["+issue.Code+"]"))
+}
+
+func init() {
+	validateCmd.Flags().BoolVar(&validateJSON, "json", false, "Print the report as JSON")
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Treat warnings as errors")
+	validateCmd.Flags().BoolVar(&validateCheckFiles, "check-files", false, "Report config files missing from the home directory")
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeManifest(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "manifest.toml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateReportsLocatedErrors(t *testing.T) {
+	path := writeManifest(t, "[homebrew]\ntaps = [\"homebrew/core\"]\ntapz = [\"x/y\"]\n")
+
+	output, err := executeCommand("validate", path, "--json=false", "--strict=false", "--check-files=false")
+	if err == nil || !strings.Contains(err.Error(), "1 error(s)") {
+		t.Fatalf("expected a validation error, got: %v", err)
+	}
+	if !strings.Contains(output, path+":3:") || !strings.Contains(output, "homebrew.tapz") {
+		t.Errorf("expected a located unknown-key issue, got: %s", output)
+	}
+	if strings.Contains(output, "Error:") {
+		t.Errorf("the summary should only be printed once, by Execute, got: %s", output)
+	}
+}
+
+func TestValidateStrictFailsOnWarnings(t *testing.T) {
+	path := writeManifest(t, "[homebrew]\ntaps = [\"homebrew/core\", \"homebrew/core\"]\n")
+
+	if _, err := executeCommand("validate", path, "--json=false", "--strict=false", "--check-files=false"); err != nil {
+		t.Fatalf("warnings should pass without --strict: %v", err)
+	}
+	if _, err := executeCommand("validate", path, "--json=false", "--strict", "--check-files=false"); err == nil {
+		t.Fatal("expected --strict to fail on warnings")
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	path := writeManifest(t, "[meta]\nschema_version = 2\n\n[git]\n[[git.config_files]]\nsource = \".gitconfig\"\nbundle_path = \"configs/git/.gitconfig\"\n")
+	home := t.TempDir()
+	t.Cleanup(func() { homeOverride = "" })
+
+	output, err := executeCommand("validate", path, "--json", "--strict=false", "--check-files", "--home", home)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var report struct {
+		Valid  bool `json:"valid"`
+		Issues []struct {
+			Code string `json:"code"`
+			Line int    `json:"line"`
+		} `json:"issues"`
+	}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, output)
+	}
+	if !report.Valid || len(report.Issues) != 1 || report.Issues[0].Code != "missing-file" || report.Issues[0].Line != 6 {
+		t.Errorf("expected one missing-file warning on line 6, got: %+v", report)
+	}
+}
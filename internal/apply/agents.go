@@ -0,0 +1,74 @@
+package apply
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// AgentsFile is the file in ~/.machinist where apply records the
+// LaunchAgents it installed. Those are the only agents --prune launchagents
+// removes: any other was installed by hand or by a tool that manages it,
+// such as brew services or an app updater.
+const AgentsFile = "apply-launchagents.json"
+
+// AgentsPath returns the location of the LaunchAgents record under home.
+func AgentsPath(home string) string {
+	return filepath.Join(home, ".machinist", AgentsFile)
+}
+
+// agentsRecord is the on-disk form of the LaunchAgents record.
+type agentsRecord struct {
+	Sources []string `json:"sources"`
+}
+
+// ReadAgents returns the sources of the LaunchAgents recorded at path. A
+// missing file records none.
+func ReadAgents(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var r agentsRecord
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return r.Sources, nil
+}
+
+// WriteAgents records sources at path.
+func WriteAgents(path string, sources []string) error {
+	data, err := json.MarshalIndent(agentsRecord{Sources: append([]string{}, sources...)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// OwnAgents returns owned, the LaunchAgents a previous apply installed,
+// updated by the steps that ran successfully: agents installed or upgraded
+// are added and removed ones dropped. The result is sorted.
+func OwnAgents(owned []string, done []Step) []string {
+	out := slices.Clone(owned)
+	for _, s := range done {
+		if s.Category != "launchagents" {
+			continue
+		}
+		out = slices.DeleteFunc(out, func(name string) bool { return name == s.Name })
+		if s.Action != Remove {
+			out = append(out, s.Name)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
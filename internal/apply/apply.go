@@ -0,0 +1,480 @@
+// Package apply converges a machine to a manifest: it installs the packages,
+// extensions and LaunchAgents the manifest lists and the machine lacks,
+// upgrades packages pinned to a newer version and, for the categories asked
+// for, removes what the machine has on top of the manifest.
+package apply
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/moinsen-dev/machinist/internal/diff"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/drift"
+	"github.com/moinsen-dev/machinist/internal/util"
+)
+
+// Action is what a step does to one item.
+type Action string
+
+const (
+	Install Action = "install"
+	Upgrade Action = "upgrade"
+	Remove  Action = "remove"
+)
+
+// Step is one item to install, upgrade or remove and the commands that do it.
+type Step struct {
+	Action   Action `json:"action"`
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	// From and To are the machine's and the manifest's version of an
+	// upgraded package. To is also the version an install pins.
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+	// Commands run in order; the step stops at the first that fails.
+	Commands [][]string `json:"commands"`
+	// Reason says why an unmanaged version difference is left alone.
+	Reason string `json:"reason,omitempty"`
+
+	list string
+}
+
+// Plan is the list of steps that make a machine match a manifest.
+type Plan struct {
+	Steps []Step `json:"steps"`
+	// Kept are the removals of categories that were not pruned.
+	Kept []Step `json:"kept"`
+	// Unmanaged are the version differences apply leaves alone, without
+	// commands: Homebrew only installs a formula's or cask's current
+	// version, and a package newer than the manifest's is not downgraded.
+	Unmanaged []Step `json:"unmanaged"`
+}
+
+// Empty reports whether the plan has nothing to do.
+func (p *Plan) Empty() bool { return len(p.Steps) == 0 }
+
+// Count returns the number of steps with action a.
+func (p *Plan) Count(a Action) int {
+	n := 0
+	for _, s := range p.Steps {
+		if s.Action == a {
+			n++
+		}
+	}
+	return n
+}
+
+// Categories are the names --prune accepts, in plan order.
+var Categories = []string{"homebrew", "vscode", "npm", "cargo", "gem", "launchagents"}
+
+// CheckPrune returns an error naming the first of categories that is not
+// one of Categories.
+func CheckPrune(categories []string) error {
+	for _, c := range categories {
+		if !slices.Contains(Categories, c) {
+			return fmt.Errorf("unknown category %q (valid: %s)", c, strings.Join(Categories, ", "))
+		}
+	}
+	return nil
+}
+
+// item is a manifest list item as far as commands need it.
+type item struct {
+	name       string
+	version    string
+	bundlePath string
+}
+
+// list is a manifest list apply manages.
+type list struct {
+	key      string
+	category string
+	commands func(a Action, it item, opts Options) [][]string
+}
+
+// lists are the manifest lists apply manages.
+var lists = []list{
+	{"homebrew.formulae", "homebrew", formulaCommands},
+	{"homebrew.casks", "homebrew", caskCommands},
+	{"vscode.extensions", "vscode", extensionCommands},
+	{"node.global_packages", "npm", npmCommands},
+	{"rust.cargo_packages", "cargo", cargoCommands},
+	{"ruby.global_gems", "gem", gemCommands},
+	{"launchagents.plists", "launchagents", launchAgentCommands},
+}
+
+// Options configure NewPlan.
+type Options struct {
+	// Prune are the categories whose extra items are removed.
+	Prune []string
+	// BundleDir is the directory LaunchAgent bundle paths are relative to,
+	// usually the manifest's.
+	BundleDir string
+	// HomeDir is the home directory LaunchAgents are installed in.
+	HomeDir string
+	// OwnedAgents are the sources of the LaunchAgents a previous apply
+	// installed, as read by ReadAgents. Pruning removes no other agent.
+	OwnedAgents []string
+}
+
+// Scope returns a manifest with just the sections apply manages, so
+// detecting drift does not scan anything else.
+func Scope(manifest *domain.Snapshot) *domain.Snapshot {
+	return &domain.Snapshot{
+		Homebrew:     manifest.Homebrew,
+		VSCode:       manifest.VSCode,
+		Node:         manifest.Node,
+		Rust:         manifest.Rust,
+		Ruby:         manifest.Ruby,
+		LaunchAgents: manifest.LaunchAgents,
+	}
+}
+
+// NewPlan turns the drift report of manifest into steps: a missing item is
+// installed, a package pinned to a newer version than the machine's is
+// upgraded and an extra item is removed if its category is in opts.Prune.
+// Other version differences are listed as unmanaged. Drift apply does not
+// manage, such as a runtime version, a tap or a LaunchAgent apply did not
+// install, is left alone.
+//
+// Only formulae nothing else depends on are removed, followed by
+// `brew autoremove` for the dependencies that leaves unused; runner lists
+// them with `brew leaves`.
+func NewPlan(ctx context.Context, runner util.CommandRunner, manifest *domain.Snapshot, report *drift.Report, opts Options) (*Plan, error) {
+	if err := CheckPrune(opts.Prune); err != nil {
+		return nil, err
+	}
+
+	var installs, upgrades, removals, unmanaged []Step
+	for _, c := range report.Missing {
+		if l, it, ok := managed(c, c.Old); ok && c.Item != "" {
+			installs = append(installs, l.step(Install, it, opts))
+		}
+	}
+	for _, c := range report.Changed {
+		l, it, ok := managed(c, nil)
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(c.Key, "].version"):
+			s := l.step(Upgrade, item{name: it.name, version: fmt.Sprint(c.Old)}, opts)
+			s.From = fmt.Sprint(c.New)
+			if s.Reason = unmanagedReason(l, s.From, s.To); s.Reason == "" {
+				upgrades = append(upgrades, s)
+				continue
+			}
+			s.Commands = [][]string{}
+			unmanaged = append(unmanaged, s)
+		case l.category == "launchagents" && strings.HasSuffix(c.Key, "].content_hash"):
+			it.bundlePath = plistBundlePath(manifest, it.name)
+			upgrades = append(upgrades, l.step(Upgrade, it, opts))
+		}
+	}
+	for _, c := range report.Extra {
+		l, it, ok := managed(c, c.New)
+		if !ok || (l.category == "launchagents" && !slices.Contains(opts.OwnedAgents, it.name)) || installed(installs, l, it.name) {
+			continue
+		}
+		removals = append(removals, l.step(Remove, it, opts))
+	}
+
+	removals, err := keepDependencies(ctx, runner, removals, opts.Prune)
+	if err != nil {
+		return nil, err
+	}
+	p := &Plan{Steps: []Step{}, Kept: []Step{}, Unmanaged: append([]Step{}, unmanaged...)}
+	p.Steps = append(append(p.Steps, installs...), upgrades...)
+	for _, s := range removals {
+		if slices.Contains(opts.Prune, s.Category) {
+			p.Steps = append(p.Steps, s)
+		} else {
+			p.Kept = append(p.Kept, s)
+		}
+	}
+	return p, nil
+}
+
+// installed reports whether installs has a step for the item of l with
+// name. Homebrew items match on the last segment of a tap-qualified name,
+// so a removal never undoes an install.
+func installed(installs []Step, l list, name string) bool {
+	short := func(name string) string {
+		if l.category == "homebrew" {
+			return name[strings.LastIndex(name, "/")+1:]
+		}
+		return name
+	}
+	return slices.ContainsFunc(installs, func(s Step) bool {
+		return s.list == l.key && short(s.Name) == short(name)
+	})
+}
+
+// keepDependencies drops the formulae other formulae depend on from
+// removals and ends a pruned Homebrew's removals with `brew autoremove`.
+func keepDependencies(ctx context.Context, runner util.CommandRunner, removals []Step, prune []string) ([]Step, error) {
+	if !slices.Contains(prune, "homebrew") || !slices.ContainsFunc(removals, isFormula) {
+		return removals, nil
+	}
+	leaves, err := runner.RunLines(ctx, "brew", "leaves")
+	if err != nil {
+		return nil, fmt.Errorf("list formulae without dependents: %w", err)
+	}
+	out := removals[:0]
+	for _, s := range removals {
+		if !isFormula(s) || slices.Contains(leaves, s.Name) {
+			out = append(out, s)
+		}
+	}
+	return append(out, Step{
+		Action:   Remove,
+		Category: "homebrew",
+		Name:     "unused dependencies",
+		Commands: [][]string{{"brew", "autoremove"}},
+		list:     "homebrew.formulae",
+	}), nil
+}
+
+func isFormula(s Step) bool { return s.list == "homebrew.formulae" }
+
+// unmanagedReason says why apply cannot move a package of list from version
+// from to version to, or returns "" when an upgrade does.
+func unmanagedReason(l list, from, to string) string {
+	if l.category == "homebrew" {
+		return "Homebrew cannot install a given version"
+	}
+	order, ok := compareVersions(to, from)
+	switch {
+	case !ok:
+		return "versions cannot be compared"
+	case order < 0:
+		return "installed version is newer"
+	case order == 0:
+		return "versions are equivalent"
+	}
+	return ""
+}
+
+// compareVersions orders dotted versions such as "5.4.5" or "2.47.0_1" by
+// their numeric parts, ignoring a leading "v". ok is false when a part is
+// not a number, as in "1.0-beta", because the order is then unknown.
+func compareVersions(a, b string) (order int, ok bool) {
+	split := func(v string) []string {
+		return strings.FieldsFunc(strings.TrimPrefix(v, "v"), func(r rune) bool {
+			return r == '.' || r == '_' || r == '-' || r == '+'
+		})
+	}
+	pa, pb := split(a), split(b)
+	for i := 0; i < max(len(pa), len(pb)); i++ {
+		var x, y int
+		var err error
+		if i < len(pa) {
+			if x, err = strconv.Atoi(pa[i]); err != nil {
+				return 0, false
+			}
+		}
+		if i < len(pb) {
+			if y, err = strconv.Atoi(pb[i]); err != nil {
+				return 0, false
+			}
+		}
+		if x != y {
+			return cmp.Compare(x, y), true
+		}
+	}
+	return 0, true
+}
+
+// plistBundlePath returns the bundle path of the manifest's LaunchAgent
+// with source, written in ~ form.
+func plistBundlePath(manifest *domain.Snapshot, source string) string {
+	if manifest.LaunchAgents == nil {
+		return ""
+	}
+	for _, f := range manifest.LaunchAgents.Plists {
+		for _, prefix := range []string{"$HOME/", "${HOME}/"} {
+			if rest, ok := strings.CutPrefix(f.Source, prefix); ok {
+				f.Source = "~/" + rest
+			}
+		}
+		if f.Source == source {
+			return f.BundlePath
+		}
+	}
+	return ""
+}
+
+// managed returns the list a change belongs to and the item it names, with
+// the version and bundle path from value when it is a whole item.
+func managed(c diff.Change, value any) (list, item, bool) {
+	open := strings.Index(c.Key, "[")
+	end := strings.LastIndex(c.Key, "]")
+	if open < 0 || end < open {
+		return list{}, item{}, false
+	}
+	i := slices.IndexFunc(lists, func(l list) bool { return l.key == c.Key[:open] })
+	if i < 0 {
+		return list{}, item{}, false
+	}
+	l := lists[i]
+	it := item{name: c.Key[open+1 : end]}
+	if m, ok := value.(map[string]any); ok {
+		it.version, _ = m["version"].(string)
+		it.bundlePath, _ = m["bundle_path"].(string)
+	}
+	return l, it, true
+}
+
+func (l list) step(a Action, it item, opts Options) Step {
+	s := Step{Action: a, Category: l.category, Name: it.name, Commands: l.commands(a, it, opts), list: l.key}
+	if a != Remove {
+		s.To = it.version
+	}
+	return s
+}
+
+// formulaCommands installs or removes a formula. Homebrew has no command to
+// install a given version, so formulae are never upgraded.
+func formulaCommands(a Action, it item, _ Options) [][]string {
+	if a == Remove {
+		return [][]string{{"brew", "uninstall", it.name}}
+	}
+	return [][]string{{"brew", "install", it.name}}
+}
+
+// caskCommands installs or removes a cask; like formulae, casks are never
+// upgraded.
+func caskCommands(a Action, it item, _ Options) [][]string {
+	if a == Remove {
+		return [][]string{{"brew", "uninstall", "--cask", it.name}}
+	}
+	return [][]string{{"brew", "install", "--cask", it.name}}
+}
+
+func extensionCommands(a Action, it item, _ Options) [][]string {
+	if a == Remove {
+		return [][]string{{"code", "--uninstall-extension", it.name}}
+	}
+	return [][]string{{"code", "--install-extension", it.name, "--force"}}
+}
+
+func npmCommands(a Action, it item, _ Options) [][]string {
+	if a == Remove {
+		return [][]string{{"npm", "uninstall", "-g", it.name}}
+	}
+	spec := it.name
+	if it.version != "" {
+		spec += "@" + it.version
+	}
+	return [][]string{{"npm", "install", "-g", spec}}
+}
+
+func cargoCommands(a Action, it item, _ Options) [][]string {
+	if a == Remove {
+		return [][]string{{"cargo", "uninstall", it.name}}
+	}
+	if it.version != "" {
+		return [][]string{{"cargo", "install", it.name, "--version", it.version}}
+	}
+	return [][]string{{"cargo", "install", it.name}}
+}
+
+func gemCommands(a Action, it item, _ Options) [][]string {
+	if a == Remove {
+		return [][]string{{"gem", "uninstall", "--all", "--executables", it.name}}
+	}
+	if it.version != "" {
+		return [][]string{{"gem", "install", it.name, "--version", it.version}}
+	}
+	return [][]string{{"gem", "install", it.name}}
+}
+
+// launchAgentCommands copies a plist from the bundle into place and loads it,
+// or unloads and deletes it. it.name is the plist's source path, relative to
+// the home directory.
+func launchAgentCommands(a Action, it item, opts Options) [][]string {
+	dst := it.name
+	if rest, ok := strings.CutPrefix(dst, "~/"); ok {
+		dst = rest
+	}
+	if !filepath.IsAbs(dst) {
+		dst = filepath.Join(opts.HomeDir, dst)
+	}
+	unload := []string{"launchctl", "unload", dst}
+	load := []string{"launchctl", "load", "-w", dst}
+	switch a {
+	case Install:
+		return [][]string{{"cp", filepath.Join(opts.BundleDir, it.bundlePath), dst}, load}
+	case Upgrade:
+		return [][]string{unload, {"cp", filepath.Join(opts.BundleDir, it.bundlePath), dst}, load}
+	}
+	return [][]string{unload, {"rm", dst}}
+}
+
+// Event describes a step starting (Done=false) or finishing (Done=true).
+type Event struct {
+	Step  Step
+	Index int
+	Total int
+	Done  bool
+	// Err is why a finished step failed.
+	Err error
+}
+
+// ProgressFunc is called before and after each step.
+type ProgressFunc func(Event)
+
+// Execute runs the steps of p in order with runner. A failed step does not
+// stop the ones after it; the returned error counts the failures.
+func (p *Plan) Execute(ctx context.Context, runner util.CommandRunner, onProgress ProgressFunc) error {
+	if onProgress == nil {
+		onProgress = func(Event) {}
+	}
+	failed := 0
+	for i, s := range p.Steps {
+		e := Event{Step: s, Index: i, Total: len(p.Steps)}
+		onProgress(e)
+		for _, argv := range s.Commands {
+			if e.Err = run(ctx, runner, argv); e.Err != nil {
+				failed++
+				break
+			}
+		}
+		e.Done = true
+		onProgress(e)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d steps failed", failed, len(p.Steps))
+	}
+	return nil
+}
+
+// run runs argv, adding the last line of its error output to a failure.
+func run(ctx context.Context, runner util.CommandRunner, argv []string) error {
+	_, err := runner.Run(ctx, argv[0], argv[1:]...)
+	if err == nil {
+		return nil
+	}
+	var stderr string
+	var exitErr *exec.ExitError
+	var replayErr *util.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		stderr = string(exitErr.Stderr)
+	case errors.As(err, &replayErr):
+		stderr = replayErr.Stderr
+	}
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return fmt.Errorf("%s: %w: %s", strings.Join(argv, " "), err, last)
+	}
+	return fmt.Errorf("%s: %w", strings.Join(argv, " "), err)
+}
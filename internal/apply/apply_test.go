@@ -0,0 +1,294 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/diff"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/drift"
+	"github.com/moinsen-dev/machinist/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func labManifest() *domain.Snapshot {
+	return &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{
+			Formulae: []domain.Package{{Name: "git"}, {Name: "jq"}},
+			Casks:    []domain.Package{{Name: "docker"}},
+		},
+		VSCode: &domain.VSCodeSection{Extensions: []string{"golang.go"}},
+		Node: &domain.NodeSection{
+			Versions:       []string{"22.3.0"},
+			GlobalPackages: []domain.Package{{Name: "typescript", Version: "5.4.5"}},
+		},
+		LaunchAgents: &domain.LaunchAgentsSection{Plists: []domain.ConfigFile{
+			{Source: "Library/LaunchAgents/com.lab.reset.plist", BundlePath: "configs/launchagents/com.lab.reset.plist", ContentHash: "aaa"},
+		}},
+	}
+}
+
+func labMachine() *domain.Snapshot {
+	return &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{
+			Formulae: []domain.Package{{Name: "git", Version: "2.47.0"}, {Name: "htop", Version: "3.3"}, {Name: "pcre2", Version: "10.44"}},
+			Casks:    []domain.Package{{Name: "slack"}},
+		},
+		VSCode: &domain.VSCodeSection{Extensions: []string{"golang.go", "vscodevim.vim"}},
+		Node: &domain.NodeSection{
+			Versions:       []string{"20.1.0"},
+			GlobalPackages: []domain.Package{{Name: "typescript", Version: "5.3.3"}, {Name: "yarn", Version: "1.22.22"}},
+		},
+	}
+}
+
+func plan(t *testing.T, prune ...string) (*Plan, *util.MockCommandRunner) {
+	t.Helper()
+	report, err := drift.Compare(labManifest(), labMachine(), "/Users/lab", nil)
+	require.NoError(t, err)
+	runner := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		"brew leaves": {Output: "git\nhtop"},
+	}}
+	p, err := NewPlan(context.Background(), runner, labManifest(), report, Options{
+		Prune:     prune,
+		BundleDir: "/Volumes/Lab",
+		HomeDir:   "/Users/lab",
+	})
+	require.NoError(t, err)
+	return p, runner
+}
+
+func names(steps []Step) []string {
+	out := make([]string, len(steps))
+	for i, s := range steps {
+		out[i] = string(s.Action) + " " + s.Category + " " + s.Name
+	}
+	return out
+}
+
+func TestNewPlan(t *testing.T) {
+	p, runner := plan(t)
+
+	assert.Equal(t, []string{
+		"install homebrew jq",
+		"install homebrew docker",
+		"install launchagents Library/LaunchAgents/com.lab.reset.plist",
+		"upgrade npm typescript",
+	}, names(p.Steps))
+	assert.Equal(t, []string{
+		"remove homebrew htop",
+		"remove homebrew pcre2",
+		"remove homebrew slack",
+		"remove vscode vscodevim.vim",
+		"remove npm yarn",
+	}, names(p.Kept), "extras are only removed when their category is pruned")
+	assert.Empty(t, runner.Calls, "brew leaves only runs when Homebrew is pruned")
+
+	assert.Equal(t, [][]string{{"brew", "install", "--cask", "docker"}}, p.Steps[1].Commands)
+	assert.Equal(t, [][]string{
+		{"cp", "/Volumes/Lab/configs/launchagents/com.lab.reset.plist", "/Users/lab/Library/LaunchAgents/com.lab.reset.plist"},
+		{"launchctl", "load", "-w", "/Users/lab/Library/LaunchAgents/com.lab.reset.plist"},
+	}, p.Steps[2].Commands)
+
+	upgrade := p.Steps[3]
+	assert.Equal(t, "5.3.3", upgrade.From)
+	assert.Equal(t, "5.4.5", upgrade.To)
+	assert.Equal(t, [][]string{{"npm", "install", "-g", "typescript@5.4.5"}}, upgrade.Commands)
+
+	assert.Equal(t, 3, p.Count(Install))
+	assert.Equal(t, 1, p.Count(Upgrade))
+	assert.Equal(t, 0, p.Count(Remove))
+}
+
+func TestNewPlan_Prune(t *testing.T) {
+	p, runner := plan(t, "homebrew", "vscode")
+
+	assert.Equal(t, []string{
+		"remove homebrew htop",
+		"remove homebrew slack",
+		"remove vscode vscodevim.vim",
+		"remove homebrew unused dependencies",
+	}, names(p.Steps[4:]), "pcre2 is a dependency and is left to brew autoremove")
+	assert.Equal(t, []string{"remove npm yarn"}, names(p.Kept))
+	assert.Equal(t, []string{"brew leaves"}, runner.Calls)
+	assert.Equal(t, [][]string{{"code", "--uninstall-extension", "vscodevim.vim"}}, p.Steps[6].Commands)
+}
+
+func TestNewPlan_UnknownCategory(t *testing.T) {
+	_, err := NewPlan(context.Background(), &util.MockCommandRunner{}, labManifest(), &drift.Report{}, Options{Prune: []string{"pip"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown category "pip"`)
+}
+
+func TestNewPlan_LaunchAgentChanged(t *testing.T) {
+	machine := labManifest()
+	machine.LaunchAgents.Plists[0].ContentHash = "bbb"
+	manifest := labManifest()
+	report, err := drift.Compare(manifest, machine, "/Users/lab", nil)
+	require.NoError(t, err)
+
+	p, err := NewPlan(context.Background(), &util.MockCommandRunner{}, manifest, report, Options{BundleDir: "/Volumes/Lab", HomeDir: "/Users/lab"})
+	require.NoError(t, err)
+	require.Len(t, p.Steps, 1)
+	assert.Equal(t, [][]string{
+		{"launchctl", "unload", "/Users/lab/Library/LaunchAgents/com.lab.reset.plist"},
+		{"cp", "/Volumes/Lab/configs/launchagents/com.lab.reset.plist", "/Users/lab/Library/LaunchAgents/com.lab.reset.plist"},
+		{"launchctl", "load", "-w", "/Users/lab/Library/LaunchAgents/com.lab.reset.plist"},
+	}, p.Steps[0].Commands)
+}
+
+func TestNewPlan_VersionDifferences(t *testing.T) {
+	manifest := &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{Formulae: []domain.Package{{Name: "jq", Version: "1.8.0"}}},
+		Node: &domain.NodeSection{GlobalPackages: []domain.Package{
+			{Name: "typescript", Version: "5.4.5"},
+			{Name: "pnpm", Version: "9.1.0"},
+		}},
+	}
+	machine := &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{Formulae: []domain.Package{{Name: "jq", Version: "1.7.1"}}},
+		Node: &domain.NodeSection{GlobalPackages: []domain.Package{
+			{Name: "typescript", Version: "5.10.0"},
+			{Name: "pnpm", Version: "9.0.6"},
+		}},
+	}
+	report, err := drift.Compare(manifest, machine, "/Users/lab", nil)
+	require.NoError(t, err)
+
+	p, err := NewPlan(context.Background(), &util.MockCommandRunner{}, manifest, report, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"upgrade npm pnpm"}, names(p.Steps), "a newer installed version produces no step")
+	assert.Equal(t, [][]string{{"npm", "install", "-g", "pnpm@9.1.0"}}, p.Steps[0].Commands)
+
+	require.Len(t, p.Unmanaged, 2)
+	assert.Equal(t, "jq", p.Unmanaged[0].Name)
+	assert.Equal(t, "Homebrew cannot install a given version", p.Unmanaged[0].Reason)
+	assert.Equal(t, "typescript", p.Unmanaged[1].Name)
+	assert.Equal(t, "5.10.0", p.Unmanaged[1].From)
+	assert.Equal(t, "installed version is newer", p.Unmanaged[1].Reason)
+	assert.Empty(t, p.Unmanaged[1].Commands)
+}
+
+func TestNewPlan_PruneKeepsTapFormulae(t *testing.T) {
+	manifest := &domain.Snapshot{Homebrew: &domain.HomebrewSection{
+		Formulae: []domain.Package{{Name: "hashicorp/tap/terraform"}},
+	}}
+	machine := &domain.Snapshot{Homebrew: &domain.HomebrewSection{
+		Formulae: []domain.Package{{Name: "terraform", Version: "1.9.8"}},
+	}}
+	report, err := drift.Compare(manifest, machine, "/Users/lab", nil)
+	require.NoError(t, err)
+	assert.Empty(t, report.Missing)
+	assert.Empty(t, report.Extra)
+
+	runner := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		"brew leaves": {Output: "terraform"},
+	}}
+	p, err := NewPlan(context.Background(), runner, manifest, report, Options{Prune: []string{"homebrew"}})
+	require.NoError(t, err)
+	assert.Empty(t, p.Steps, "brew lists a tap formula by its bare name")
+
+	// A removal never undoes an install of the same formula.
+	report = &drift.Report{
+		Missing: []diff.Change{{Kind: diff.Removed, Key: "homebrew.formulae[hashicorp/tap/terraform]", Item: "hashicorp/tap/terraform"}},
+		Extra:   []diff.Change{{Kind: diff.Added, Key: "homebrew.formulae[terraform]", Item: "terraform"}},
+	}
+	p, err = NewPlan(context.Background(), runner, manifest, report, Options{Prune: []string{"homebrew"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"install homebrew hashicorp/tap/terraform"}, names(p.Steps))
+	assert.Empty(t, runner.Calls)
+}
+
+func TestNewPlan_PrunesOnlyOwnedAgents(t *testing.T) {
+	manifest := &domain.Snapshot{LaunchAgents: &domain.LaunchAgentsSection{}}
+	machine := &domain.Snapshot{LaunchAgents: &domain.LaunchAgentsSection{Plists: []domain.ConfigFile{
+		{Source: "Library/LaunchAgents/homebrew.mxcl.postgresql@16.plist", ContentHash: "a"},
+		{Source: "Library/LaunchAgents/com.vendor.helper.plist", ContentHash: "b"},
+		{Source: "Library/LaunchAgents/com.old.sync.plist", ContentHash: "c"},
+	}}}
+	report, err := drift.Compare(manifest, machine, "/Users/lab", nil)
+	require.NoError(t, err)
+
+	p, err := NewPlan(context.Background(), &util.MockCommandRunner{}, manifest, report, Options{
+		Prune:       []string{"launchagents"},
+		HomeDir:     "/Users/lab",
+		OwnedAgents: []string{"Library/LaunchAgents/com.old.sync.plist"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"remove launchagents Library/LaunchAgents/com.old.sync.plist"}, names(p.Steps))
+	assert.Empty(t, p.Kept)
+
+	p, err = NewPlan(context.Background(), &util.MockCommandRunner{}, manifest, report, Options{Prune: []string{"launchagents"}, HomeDir: "/Users/lab"})
+	require.NoError(t, err)
+	assert.Empty(t, p.Steps, "agents apply did not install are never pruned")
+}
+
+func TestAgents(t *testing.T) {
+	path := AgentsPath(t.TempDir())
+	owned, err := ReadAgents(path)
+	require.NoError(t, err)
+	assert.Empty(t, owned)
+
+	owned = OwnAgents([]string{"Library/LaunchAgents/b.plist", "Library/LaunchAgents/c.plist"}, []Step{
+		{Action: Install, Category: "launchagents", Name: "Library/LaunchAgents/a.plist"},
+		{Action: Upgrade, Category: "launchagents", Name: "Library/LaunchAgents/b.plist"},
+		{Action: Remove, Category: "launchagents", Name: "Library/LaunchAgents/c.plist"},
+		{Action: Install, Category: "homebrew", Name: "jq"},
+	})
+	assert.Equal(t, []string{"Library/LaunchAgents/a.plist", "Library/LaunchAgents/b.plist"}, owned)
+
+	require.NoError(t, WriteAgents(path, owned))
+	got, err := ReadAgents(path)
+	require.NoError(t, err)
+	assert.Equal(t, owned, got)
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tc := range []struct {
+		a, b  string
+		order int
+		ok    bool
+	}{
+		{"5.4.5", "5.3.3", 1, true},
+		{"5.10.0", "5.9.9", 1, true},
+		{"1.2", "1.2.0", 0, true},
+		{"v1.3", "1.2", 1, true},
+		{"2.47.0", "2.47.0_1", -1, true},
+		{"1.0-beta", "1.0", 0, false},
+	} {
+		order, ok := compareVersions(tc.a, tc.b)
+		assert.Equal(t, tc.ok, ok, "%s vs %s", tc.a, tc.b)
+		if tc.ok {
+			assert.Equal(t, tc.order, order, "%s vs %s", tc.a, tc.b)
+		}
+	}
+}
+
+func TestExecute(t *testing.T) {
+	p := &Plan{Steps: []Step{
+		{Action: Install, Category: "homebrew", Name: "jq", Commands: [][]string{{"brew", "install", "jq"}}},
+		{Action: Remove, Category: "launchagents", Name: "a.plist", Commands: [][]string{{"launchctl", "unload", "a.plist"}, {"rm", "a.plist"}}},
+		{Action: Install, Category: "vscode", Name: "golang.go", Commands: [][]string{{"code", "--install-extension", "golang.go", "--force"}}},
+	}}
+	runner := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		"brew install jq":                            {},
+		"launchctl unload a.plist":                   {Err: &util.ExitError{Code: 5, Stderr: "Unload failed: 5: Input/output error\n"}},
+		"code --install-extension golang.go --force": {},
+	}}
+
+	var events []Event
+	err := p.Execute(context.Background(), runner, func(e Event) { events = append(events, e) })
+	require.EqualError(t, err, "1 of 3 steps failed")
+	assert.Equal(t, []string{"brew install jq", "launchctl unload a.plist", "code --install-extension golang.go --force"}, runner.Calls,
+		"a failed command ends its step but not the plan")
+
+	require.Len(t, events, 6)
+	failed := events[3]
+	assert.True(t, failed.Done)
+	var exit *util.ExitError
+	assert.True(t, errors.As(failed.Err, &exit))
+	assert.EqualError(t, failed.Err, "launchctl unload a.plist: exit status 5: Unload failed: 5: Input/output error")
+	assert.NoError(t, events[5].Err)
+}
@@ -220,99 +220,13 @@ func Bundle(ctx context.Context, cmd util.CommandRunner, snapshot *domain.Snapsh
 	return nil
 }
 
-// collectConfigFiles gathers all ConfigFile entries from every snapshot section.
-// Sections with []ConfigFile are appended directly. Sections with a single
-// ConfigFile string are wrapped into a ConfigFile struct with a derived BundlePath.
+// collectConfigFiles gathers the ConfigFile entries of every populated
+// snapshot section, as registered in domain.Sections().
 func collectConfigFiles(snapshot *domain.Snapshot) []domain.ConfigFile {
 	var files []domain.ConfigFile
-
-	// Helper to wrap a single config file path string into a ConfigFile.
-	wrap := func(source, bundlePrefix string) domain.ConfigFile {
-		return domain.ConfigFile{
-			Source:     source,
-			BundlePath: filepath.Join("configs", bundlePrefix, filepath.Base(source)),
-		}
-	}
-
-	// Sections with []ConfigFile
-	if s := snapshot.Shell; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-	if s := snapshot.Terminal; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-	if s := snapshot.Tmux; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-	if s := snapshot.Git; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-	if s := snapshot.VSCode; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-	if s := snapshot.Cursor; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-	if s := snapshot.Xcode; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-	if s := snapshot.GPG; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-	if s := snapshot.LaunchAgents; s != nil {
-		files = append(files, s.Plists...)
-	}
-	if s := snapshot.Network; s != nil {
-		files = append(files, s.VPNConfigs...)
-	}
-	if s := snapshot.APITools; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-	if s := snapshot.Databases; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-	if s := snapshot.Registries; s != nil {
-		files = append(files, s.ConfigFiles...)
-	}
-
-	// Sections with a single ConfigFile string
-	if s := snapshot.Docker; s != nil && s.ConfigFile != "" {
-		files = append(files, wrap(s.ConfigFile, "docker"))
-	}
-	if s := snapshot.AWS; s != nil && s.ConfigFile != "" {
-		files = append(files, wrap(s.ConfigFile, "aws"))
-	}
-	if s := snapshot.Kubernetes; s != nil && s.ConfigFile != "" {
-		files = append(files, wrap(s.ConfigFile, "kubernetes"))
-	}
-	if s := snapshot.Terraform; s != nil && s.ConfigFile != "" {
-		files = append(files, wrap(s.ConfigFile, "terraform"))
-	}
-	if s := snapshot.Flyio; s != nil && s.ConfigFile != "" {
-		files = append(files, wrap(s.ConfigFile, "flyio"))
-	}
-	if s := snapshot.Rectangle; s != nil && s.ConfigFile != "" {
-		files = append(files, wrap(s.ConfigFile, "rectangle"))
+	for _, s := range domain.Sections() {
+		files = append(files, s.ConfigFiles(snapshot)...)
 	}
-	if s := snapshot.BetterTouchTool; s != nil && s.ConfigFile != "" {
-		files = append(files, wrap(s.ConfigFile, "bettertouchtool"))
-	}
-	if s := snapshot.Raycast; s != nil && s.ExportFile != "" {
-		files = append(files, wrap(s.ExportFile, "raycast"))
-	}
-	if s := snapshot.AITools; s != nil && s.ClaudeCodeConfig != "" {
-		files = append(files, wrap(s.ClaudeCodeConfig, "ai-tools"))
-	}
-
-	// Custom fonts
-	if s := snapshot.Fonts; s != nil {
-		for _, font := range s.CustomFonts {
-			if font.BundlePath != "" {
-				files = append(files, wrap(font.BundlePath, "fonts"))
-			}
-		}
-	}
-
 	return files
 }
 
@@ -322,55 +236,13 @@ type configDirEntry struct {
 	BundleDir   string // relative to bundle root, e.g. "configs/github-cli"
 }
 
-// collectConfigDirs gathers all ConfigDir entries from sections that store
-// their configuration as a directory rather than individual files.
+// collectConfigDirs gathers the config directories of every populated
+// snapshot section, as registered in domain.Sections().
 func collectConfigDirs(snapshot *domain.Snapshot) []configDirEntry {
 	var dirs []configDirEntry
-
-	add := func(sourceDir, bundlePrefix string) {
-		dirs = append(dirs, configDirEntry{
-			SourceDir: sourceDir,
-			BundleDir: filepath.Join("configs", bundlePrefix),
-		})
-	}
-
-	if s := snapshot.GitHubCLI; s != nil && s.ConfigDir != "" {
-		add(s.ConfigDir, "github-cli")
-	}
-	if s := snapshot.Neovim; s != nil && s.ConfigDir != "" {
-		add(s.ConfigDir, "neovim")
-	}
-	if s := snapshot.Vercel; s != nil && s.ConfigDir != "" {
-		add(s.ConfigDir, "vercel")
-	}
-	if s := snapshot.GCP; s != nil && s.ConfigDir != "" {
-		add(s.ConfigDir, "gcp")
-	}
-	if s := snapshot.Azure; s != nil && s.ConfigDir != "" {
-		add(s.ConfigDir, "azure")
-	}
-	if s := snapshot.Firebase; s != nil && s.ConfigDir != "" {
-		add(s.ConfigDir, "firebase")
-	}
-	if s := snapshot.CloudflareWrangler; s != nil && s.ConfigDir != "" {
-		add(s.ConfigDir, "cloudflare")
-	}
-	if s := snapshot.Karabiner; s != nil && s.ConfigDir != "" {
-		add(s.ConfigDir, "karabiner")
-	}
-	if s := snapshot.Alfred; s != nil && s.ConfigDir != "" {
-		add(s.ConfigDir, "alfred")
-	}
-	if s := snapshot.OnePassword; s != nil && s.ConfigDir != "" {
-		add(s.ConfigDir, "onepassword")
-	}
-	if s := snapshot.XDGConfig; s != nil {
-		if s.ConfigDir != "" {
-			add(s.ConfigDir, "xdg-config")
-		}
-		// Also bundle each auto-detected XDG tool subdirectory individually.
-		for _, name := range s.AutoDetected {
-			add(filepath.Join(".config", name), filepath.Join("xdg-config", name))
+	for _, s := range domain.Sections() {
+		for _, d := range s.ConfigDirs(snapshot) {
+			dirs = append(dirs, configDirEntry{SourceDir: d.Source, BundleDir: d.BundlePath})
 		}
 	}
 	return dirs
@@ -406,21 +406,17 @@ func TestPrepareBundleDir_SensitiveFileWarning(t *testing.T) {
 
 func TestCollectConfigFiles_SingleStringFields(t *testing.T) {
 	snap := &domain.Snapshot{
-		Docker:          &domain.DockerSection{ConfigFile: ".docker/config.json"},
-		AWS:             &domain.AWSSection{ConfigFile: ".aws/config"},
-		Kubernetes:      &domain.KubernetesSection{ConfigFile: ".kube/config"},
-		Terraform:       &domain.TerraformSection{ConfigFile: ".terraformrc"},
-		Flyio:           &domain.FlyioSection{ConfigFile: ".fly/config.yml"},
-		Rectangle:       &domain.RectangleSection{ConfigFile: "Library/Preferences/com.knollsoft.Rectangle.plist"},
-		BetterTouchTool: &domain.BetterTouchToolSection{ConfigFile: "Library/Application Support/BetterTouchTool/btt_data.json"},
-		Raycast:         &domain.RaycastSection{ExportFile: "raycast-export.json"},
+		Docker:     &domain.DockerSection{ConfigFile: ".docker/config.json"},
+		AWS:        &domain.AWSSection{ConfigFile: ".aws/config"},
+		Kubernetes: &domain.KubernetesSection{ConfigFile: ".kube/config"},
+		Terraform:  &domain.TerraformSection{ConfigFile: ".terraformrc"},
 		// Note: AITools.ClaudeCodeConfig is a directory, handled by collectConfigDirs
 	}
 
 	files := collectConfigFiles(snap)
 
-	// Should have exactly 8 files from single-string fields
-	assert.Len(t, files, 8)
+	// Should have exactly 4 files from single-string fields
+	assert.Len(t, files, 4)
 
 	// Verify each has a proper BundlePath under configs/
 	sources := make(map[string]string)
@@ -431,7 +427,7 @@ func TestCollectConfigFiles_SingleStringFields(t *testing.T) {
 	assert.Equal(t, "configs/docker/config.json", sources[".docker/config.json"])
 	assert.Equal(t, "configs/aws/config", sources[".aws/config"])
 	assert.Equal(t, "configs/kubernetes/config", sources[".kube/config"])
-	assert.Equal(t, "configs/raycast/raycast-export.json", sources["raycast-export.json"])
+	assert.Equal(t, "configs/terraform/.terraformrc", sources[".terraformrc"])
 }
 
 func TestCollectConfigFiles_IncludesFonts(t *testing.T) {
@@ -456,19 +452,15 @@ func TestCollectConfigDirs(t *testing.T) {
 	snap := &domain.Snapshot{
 		GitHubCLI:          &domain.GitHubCLISection{ConfigDir: ".config/gh"},
 		Neovim:             &domain.NeovimSection{ConfigDir: ".config/nvim"},
-		Vercel:             &domain.VercelSection{ConfigDir: ".vercel"},
-		GCP:                &domain.GCPSection{ConfigDir: ".config/gcloud"},
 		Azure:              &domain.AzureSection{ConfigDir: ".azure"},
 		Firebase:           &domain.FirebaseSection{ConfigDir: ".config/firebase"},
 		CloudflareWrangler: &domain.CloudflareSection{ConfigDir: ".config/.wrangler"},
-		Karabiner:          &domain.KarabinerSection{ConfigDir: ".config/karabiner"},
-		Alfred:             &domain.AlfredSection{ConfigDir: "Library/Application Support/Alfred"},
 		OnePassword:        &domain.OnePasswordSection{ConfigDir: ".config/op"},
 		XDGConfig:          &domain.XDGConfigSection{ConfigDir: ".config"},
 	}
 
 	dirs := collectConfigDirs(snap)
-	assert.Len(t, dirs, 11)
+	assert.Len(t, dirs, 7)
 
 	// Verify bundle dir prefixes
 	dirMap := make(map[string]string)
@@ -477,7 +469,7 @@ func TestCollectConfigDirs(t *testing.T) {
 	}
 	assert.Equal(t, "configs/github-cli", dirMap[".config/gh"])
 	assert.Equal(t, "configs/neovim", dirMap[".config/nvim"])
-	assert.Equal(t, "configs/gcp", dirMap[".config/gcloud"])
+	assert.Equal(t, "configs/azure", dirMap[".azure"])
 }
 
 func TestCopyConfigDir(t *testing.T) {
@@ -600,3 +592,36 @@ func TestPrepareBundleDir_CopiesConfigDirs(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "editor: vim\n", string(content))
 }
+
+func TestPrepareBundleDir_Probes(t *testing.T) {
+	configSourceDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configSourceDir, ".hammerspoon"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(configSourceDir, ".hammerspoon", "init.lua"), []byte("-- hs\n"), 0644))
+
+	snap := &domain.Snapshot{
+		Meta: newMeta(),
+		Probes: map[string]*domain.ProbeSection{
+			"hammerspoon": {
+				ConfigDirs: []domain.ConfigFile{{Source: ".hammerspoon", BundlePath: "configs/hammerspoon/.hammerspoon"}},
+				Cask:       "hammerspoon",
+				Checklist:  []string{"Grant Hammerspoon accessibility permissions"},
+			},
+		},
+	}
+
+	bundleDir := filepath.Join(t.TempDir(), "bundle")
+	require.NoError(t, PrepareBundleDir(snap, bundleDir, configSourceDir, ""))
+
+	content, err := os.ReadFile(filepath.Join(bundleDir, "configs", "hammerspoon", ".hammerspoon", "init.lua"))
+	require.NoError(t, err)
+	assert.Equal(t, "-- hs\n", string(content))
+
+	checklist, err := os.ReadFile(filepath.Join(bundleDir, "POST_RESTORE_CHECKLIST.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(checklist), "- [ ] Grant Hammerspoon accessibility permissions")
+
+	configs, err := os.ReadFile(filepath.Join(bundleDir, "03-configs.sh"))
+	require.NoError(t, err)
+	assert.Contains(t, string(configs), "brew install --cask hammerspoon")
+	assert.Contains(t, string(configs), `cp -R "configs/hammerspoon/.hammerspoon/" "$HOME/.hammerspoon/"`)
+}
@@ -3,12 +3,14 @@ package bundler
 import "github.com/moinsen-dev/machinist/internal/domain"
 
 // GroupTemplateData embeds the Snapshot and adds group-specific fields
-// needed by group templates (GroupLabel, GroupID, StageCount).
+// needed by group templates (GroupLabel, GroupID, StageCount, Stages).
+// Stages shadows Snapshot.Stages so the group script renders only its own.
 type GroupTemplateData struct {
 	*domain.Snapshot
 	GroupLabel string
 	GroupID    string
 	StageCount int
+	Stages     []domain.Stage
 }
 
 // NewGroupTemplateData creates the template data for a group.
@@ -18,5 +20,6 @@ func NewGroupTemplateData(snap *domain.Snapshot, group domain.RestoreGroup) Grou
 		GroupLabel: group.Label,
 		GroupID:    group.ID,
 		StageCount: group.StageCount(snap),
+		Stages:     group.Stages(snap),
 	}
 }
@@ -15,13 +15,7 @@ func TestNewGroupTemplateData_SetsFields(t *testing.T) {
 		},
 	}
 
-	group := domain.RestoreGroup{
-		ID:             "01-homebrew",
-		Name:           "homebrew",
-		Label:          "Homebrew Packages",
-		ScriptName:     "01-homebrew.sh",
-		SnapshotFields: []string{"Homebrew"},
-	}
+	group, _ := domain.GroupByName("homebrew")
 
 	data := NewGroupTemplateData(snap, group)
 
@@ -38,13 +32,7 @@ func TestNewGroupTemplateData_SnapshotFieldsAccessible(t *testing.T) {
 		},
 	}
 
-	group := domain.RestoreGroup{
-		ID:             "01-homebrew",
-		Name:           "homebrew",
-		Label:          "Homebrew Packages",
-		ScriptName:     "01-homebrew.sh",
-		SnapshotFields: []string{"Homebrew"},
-	}
+	group, _ := domain.GroupByName("homebrew")
 
 	data := NewGroupTemplateData(snap, group)
 
@@ -59,13 +47,7 @@ func TestNewGroupTemplateData_ZeroStageCount(t *testing.T) {
 		Meta: newMeta(),
 	}
 
-	group := domain.RestoreGroup{
-		ID:             "04-runtimes",
-		Name:           "runtimes",
-		Label:          "Runtime Installers",
-		ScriptName:     "04-runtimes.sh",
-		SnapshotFields: []string{"Node", "Python", "Rust"},
-	}
+	group, _ := domain.GroupByName("runtimes")
 
 	data := NewGroupTemplateData(snap, group)
 
@@ -11,15 +11,42 @@ import (
 	"github.com/moinsen-dev/machinist/internal/domain"
 )
 
-// GenerateRestoreScript renders the restore shell script from a Snapshot
-// using the embedded templates.
-func GenerateRestoreScript(snapshot *domain.Snapshot) (string, error) {
+// parseScriptTemplates parses the restore script templates matching
+// patterns. Besides "base", the templates get a "stage" function that
+// renders a stage template chosen at run time, which is how the generic
+// "stages" template renders each domain.Stage.
+func parseScriptTemplates(patterns ...string) (*template.Template, error) {
+	var tmpl *template.Template
 	funcMap := template.FuncMap{
 		"base": filepath.Base,
+		// home makes a ~-relative path usable inside double quotes.
+		"home": func(path string) string {
+			if path == "~" || strings.HasPrefix(path, "~/") {
+				return "$HOME" + path[1:]
+			}
+			return path
+		},
+		"stage": func(name string, data any) (string, error) {
+			var buf bytes.Buffer
+			if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+				return "", err
+			}
+			return buf.String(), nil
+		},
 	}
-	tmpl, err := template.New("").Funcs(funcMap).ParseFS(machinist.TemplateFS, "templates/*.tmpl", "templates/stages/*.tmpl")
+	tmpl, err := template.New("").Funcs(funcMap).ParseFS(machinist.TemplateFS, patterns...)
 	if err != nil {
-		return "", fmt.Errorf("parse templates: %w", err)
+		return nil, fmt.Errorf("parse templates: %w", err)
+	}
+	return tmpl, nil
+}
+
+// GenerateRestoreScript renders the restore shell script from a Snapshot
+// using the embedded templates.
+func GenerateRestoreScript(snapshot *domain.Snapshot) (string, error) {
+	tmpl, err := parseScriptTemplates("templates/*.tmpl", "templates/stages/*.tmpl")
+	if err != nil {
+		return "", err
 	}
 
 	var buf bytes.Buffer
@@ -62,17 +89,13 @@ func GenerateReadme(snapshot *domain.Snapshot) (string, error) {
 // filename -> content (e.g. "01-foundation.sh" -> "#!/bin/bash ...").
 // Groups with no data in the snapshot are skipped.
 func GenerateRestoreScripts(snapshot *domain.Snapshot) (map[string]string, error) {
-	funcMap := template.FuncMap{
-		"base": filepath.Base,
-	}
-	tmpl, err := template.New("").Funcs(funcMap).ParseFS(
-		machinist.TemplateFS,
+	tmpl, err := parseScriptTemplates(
 		"templates/*.tmpl",
 		"templates/stages/*.tmpl",
 		"templates/groups/*.tmpl",
 	)
 	if err != nil {
-		return nil, fmt.Errorf("parse templates: %w", err)
+		return nil, err
 	}
 
 	scripts := make(map[string]string)
@@ -84,7 +107,7 @@ func GenerateRestoreScripts(snapshot *domain.Snapshot) (map[string]string, error
 		}
 
 		data := NewGroupTemplateData(snapshot, group)
-		tmplName := group.ScriptName + ".tmpl"
+		tmplName := "group.sh.tmpl"
 
 		var buf bytes.Buffer
 		if err := tmpl.ExecuteTemplate(&buf, tmplName, data); err != nil {
@@ -1,11 +1,15 @@
 package bundler
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/restore"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -420,15 +424,6 @@ func TestGenerateRestoreScript_ConfigFileBundlePaths(t *testing.T) {
 		Terraform: &domain.TerraformSection{
 			ConfigFile: ".terraformrc",
 		},
-		Flyio: &domain.FlyioSection{
-			ConfigFile: ".fly/config.yml",
-		},
-		Rectangle: &domain.RectangleSection{
-			ConfigFile: "Library/Preferences/com.knollsoft.Rectangle.plist",
-		},
-		BetterTouchTool: &domain.BetterTouchToolSection{
-			ConfigFile: "Library/Application Support/BetterTouchTool/btt_data.json",
-		},
 	}
 
 	script, err := GenerateRestoreScript(snap)
@@ -439,9 +434,6 @@ func TestGenerateRestoreScript_ConfigFileBundlePaths(t *testing.T) {
 	assert.Contains(t, script, `"configs/aws/config"`)
 	assert.Contains(t, script, `"configs/kubernetes/config"`)
 	assert.Contains(t, script, `"configs/terraform/.terraformrc"`)
-	assert.Contains(t, script, `"configs/flyio/config.yml"`)
-	assert.Contains(t, script, `"configs/rectangle/com.knollsoft.Rectangle.plist"`)
-	assert.Contains(t, script, `configs/bettertouchtool/btt_data.json`)
 }
 
 func TestGenerateRestoreScript_ConfigDirBundlePaths(t *testing.T) {
@@ -453,21 +445,12 @@ func TestGenerateRestoreScript_ConfigDirBundlePaths(t *testing.T) {
 		Neovim: &domain.NeovimSection{
 			ConfigDir: ".config/nvim",
 		},
-		Vercel: &domain.VercelSection{
-			ConfigDir: ".vercel",
-		},
 		Firebase: &domain.FirebaseSection{
 			ConfigDir: ".config/firebase",
 		},
 		CloudflareWrangler: &domain.CloudflareSection{
 			ConfigDir: ".config/.wrangler",
 		},
-		Karabiner: &domain.KarabinerSection{
-			ConfigDir: ".config/karabiner",
-		},
-		Alfred: &domain.AlfredSection{
-			ConfigDir: "Library/Application Support/Alfred",
-		},
 		OnePassword: &domain.OnePasswordSection{
 			ConfigDir: ".config/op",
 		},
@@ -478,11 +461,8 @@ func TestGenerateRestoreScript_ConfigDirBundlePaths(t *testing.T) {
 
 	assert.Contains(t, script, `"configs/github-cli/"`)
 	assert.Contains(t, script, `"configs/neovim/"`)
-	assert.Contains(t, script, `"configs/vercel/"`)
 	assert.Contains(t, script, `"configs/firebase/"`)
 	assert.Contains(t, script, `"configs/cloudflare/"`)
-	assert.Contains(t, script, `"configs/karabiner/"`)
-	assert.Contains(t, script, `configs/alfred/`)
 	assert.Contains(t, script, `"configs/onepassword/"`)
 }
 
@@ -640,3 +620,190 @@ func TestGenerateRestoreScripts_GroupStageCount(t *testing.T) {
 	// Secrets has SSH + GPG = 2 stages (EnvFiles is nil)
 	assert.Contains(t, secrets, "STAGE_TOTAL=2")
 }
+
+func TestGenerateRestoreScripts_Plugins(t *testing.T) {
+	snap := &domain.Snapshot{
+		Meta: newMeta(),
+		Plugins: map[string]*domain.PluginSection{
+			"corp": {
+				ConfigFiles: []domain.ConfigFile{{Source: ".corp/cli.toml", BundlePath: "configs/plugins/corp/cli.toml"}},
+				Packages:    []domain.Package{{Name: "corp-cli"}},
+				Restore:     []string{"corp-cli login --sso"},
+			},
+		},
+	}
+
+	scripts, err := GenerateRestoreScripts(snap)
+	require.NoError(t, err)
+
+	configs := scripts["03-configs.sh"]
+	require.NotEmpty(t, configs)
+	assert.Contains(t, configs, `run_stage "Scanner Plugins" do_plugins`)
+	assert.Contains(t, configs, "brew install corp-cli")
+	assert.Contains(t, configs, `cp "configs/plugins/corp/cli.toml" "$HOME/.corp/cli.toml"`)
+	assert.Contains(t, configs, "corp-cli login --sso")
+}
+
+func TestGenerateRestoreScripts_StageSelection(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	snap := &domain.Snapshot{
+		Meta:    newMeta(),
+		Folders: &domain.FoldersSection{Structure: []string{"Code"}},
+		Tmux:    &domain.TmuxSection{},
+	}
+	scripts, err := GenerateRestoreScripts(snap)
+	require.NoError(t, err)
+
+	dir, home := t.TempDir(), t.TempDir()
+	script := filepath.Join(dir, "03-configs.sh")
+	require.NoError(t, os.WriteFile(script, []byte(scripts["03-configs.sh"]), 0o755))
+
+	cmd := exec.Command(bash, script)
+	cmd.Env = append(os.Environ(), "HOME="+home, "MACHINIST_STAGES=tmux")
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+
+	assert.Contains(t, string(out), "--- Folder Structure skipped ---")
+	assert.Contains(t, string(out), "=== tmux ===")
+	assert.Contains(t, string(out), "Skipped: 1 stages skipped")
+	assert.NoDirExists(t, filepath.Join(home, "Code"))
+}
+
+func TestGenerateRestoreScripts_RecordsStageState(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	snap := &domain.Snapshot{
+		Meta:    newMeta(),
+		Folders: &domain.FoldersSection{Structure: []string{"Code"}},
+		Tmux:    &domain.TmuxSection{},
+	}
+	scripts, err := GenerateRestoreScripts(snap)
+	require.NoError(t, err)
+
+	dir, home := t.TempDir(), t.TempDir()
+	script := filepath.Join(dir, "03-configs.sh")
+	require.NoError(t, os.WriteFile(script, []byte(scripts["03-configs.sh"]), 0o755))
+	// A file in the way makes the folders stage fail.
+	require.NoError(t, os.WriteFile(filepath.Join(home, "Code"), nil, 0o644))
+
+	statePath := restore.StatePath(home)
+	prev := restore.NewState("abc")
+	prev.Record("homebrew", restore.Completed, time.Now())
+	require.NoError(t, prev.Write(statePath))
+
+	run := func(hash string) {
+		cmd := exec.Command(bash, script)
+		cmd.Env = append(os.Environ(), "HOME="+home, "MACHINIST_MANIFEST_HASH="+hash)
+		out, err := cmd.CombinedOutput()
+		require.Error(t, err, string(out))
+	}
+
+	run("abc")
+	state, err := restore.ReadState(statePath)
+	require.NoError(t, err)
+	assert.True(t, state.For("abc"))
+	assert.Equal(t, restore.Completed, state.Stages["homebrew"].Status)
+	assert.Equal(t, restore.Failed, state.Stages["folders"].Status)
+	assert.Equal(t, restore.Completed, state.Stages["tmux"].Status)
+
+	// A run for another manifest starts the state over.
+	run("def")
+	state, err = restore.ReadState(statePath)
+	require.NoError(t, err)
+	assert.True(t, state.For("def"))
+	assert.NotContains(t, state.Stages, "homebrew")
+	assert.Len(t, state.Stages, 2)
+}
+
+func TestGenerateRestoreScript_HomeRelativePaths(t *testing.T) {
+	snap := &domain.Snapshot{
+		Meta: newMeta(),
+		GitRepos: &domain.GitReposSection{
+			Repositories: []domain.Repository{
+				{Remote: "git@github.com:team/api.git", Path: "~/Code/api"},
+			},
+		},
+	}
+
+	script, err := GenerateRestoreScript(snap)
+	require.NoError(t, err)
+
+	assert.Contains(t, script, `"$HOME/Code/api"`)
+	assert.NotContains(t, script, `"~/Code/api"`)
+}
+
+func TestGenerateRestoreScript_WritesResults(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	snap := &domain.Snapshot{
+		Meta:    newMeta(),
+		Folders: &domain.FoldersSection{Structure: []string{"Code"}},
+		Tmux:    &domain.TmuxSection{},
+	}
+	script, err := GenerateRestoreScript(snap)
+	require.NoError(t, err)
+
+	dir, home := t.TempDir(), t.TempDir()
+	path := filepath.Join(dir, "install.command")
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "Code"), nil, 0o644))
+
+	resultsPath := filepath.Join(dir, "results")
+	cmd := exec.Command(bash, path)
+	cmd.Env = append(os.Environ(), "HOME="+home, "MACHINIST_RESULTS="+resultsPath)
+	cmd.Stdin = strings.NewReader("")
+	out, err := cmd.CombinedOutput()
+	require.Error(t, err, string(out))
+
+	results, err := restore.ReadResults(resultsPath)
+	require.NoError(t, err)
+	require.Contains(t, results, "folders", string(out))
+	assert.Equal(t, restore.Failed, results["folders"].Status)
+	require.Len(t, results["folders"].FailedCommands, 1)
+	assert.Equal(t, `mkdir -p "$HOME/Code"`, results["folders"].FailedCommands[0].Command)
+	assert.Equal(t, restore.Completed, results["tmux"].Status)
+}
+
+func TestGenerateRestoreScripts_SubshellFailureFailsStage(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not available")
+	}
+	snap := &domain.Snapshot{
+		Meta: newMeta(),
+		Plugins: map[string]*domain.PluginSection{
+			"corp": {Restore: []string{"token=$(false; echo none)"}},
+		},
+	}
+	scripts, err := GenerateRestoreScripts(snap)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "03-configs.sh")
+	require.NoError(t, os.WriteFile(script, []byte(scripts["03-configs.sh"]), 0o755))
+
+	// The failure inside the command substitution is recorded, though the
+	// assignment itself succeeds.
+	resultsPath := filepath.Join(dir, "results")
+	for _, env := range []string{"MACHINIST_RESULTS=" + resultsPath, "MACHINIST_RESULTS="} {
+		cmd := exec.Command(bash, script)
+		cmd.Env = append(os.Environ(), "HOME="+t.TempDir(), env)
+		out, err := cmd.CombinedOutput()
+		require.Error(t, err, string(out))
+		assert.Contains(t, string(out), "!! Scanner Plugins failed", env)
+	}
+
+	results, err := restore.ReadResults(resultsPath)
+	require.NoError(t, err)
+	require.Contains(t, results, "plugins")
+	assert.Equal(t, restore.Failed, results["plugins"].Status)
+	require.Len(t, results["plugins"].FailedCommands, 1)
+	assert.Equal(t, "false", results["plugins"].FailedCommands[0].Command)
+}
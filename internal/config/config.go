@@ -0,0 +1,221 @@
+// Package config loads the user configuration file that tunes how machinist
+// scans a machine: where to look for repositories, which scanners to skip,
+// extra XDG tools, custom macOS defaults and paths to exclude.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DefaultSearchPaths are the workspace directories searched for git
+// repositories and .env files when the config does not set search_paths.
+var DefaultSearchPaths = []string{"~/Code", "~/Projects", "~/Developer", "~/work"}
+
+// Config is the decoded form of ~/.config/machinist/config.toml.
+type Config struct {
+	// SearchPaths are the workspace roots walked by the git-repos and env-files scanners.
+	SearchPaths []string `toml:"search_paths"`
+	// DisabledScanners lists scanner names that are never registered.
+	DisabledScanners []string `toml:"disabled_scanners"`
+	// Exclude holds glob patterns for paths the directory-walking scanners skip.
+	// Patterns containing a slash match the full path, others match the base name.
+	Exclude []string `toml:"exclude"`
+	// XDGTools adds tool names to auto-detect under ~/.config/.
+	XDGTools []string `toml:"xdg_tools"`
+	// CustomDefaults are extra macOS defaults captured by the macos-defaults scanner.
+	CustomDefaults []CustomDefault `toml:"custom_defaults"`
+	// Concurrency is the number of scanners run in parallel (0 = built-in default).
+	Concurrency int `toml:"concurrency"`
+	// ScannerTimeout is the per-scanner deadline (0 = built-in default).
+	ScannerTimeout Duration `toml:"scanner_timeout"`
+	// ScannerTimeouts override ScannerTimeout for single scanners by name,
+	// e.g. a slow git-repos walk. A zero duration disables the deadline.
+	ScannerTimeouts map[string]Duration `toml:"scanner_timeouts"`
+	// PluginDirs are searched for machinist-scanner-* executables before $PATH.
+	// When empty, Dir()/plugins is used.
+	PluginDirs []string `toml:"plugin_dirs"`
+	// ProfileDirs hold team profile TOMLs, e.g. a directory checked into a
+	// team repo. Their profiles shadow the built-in ones, and Dir()/profiles
+	// shadows them in turn.
+	ProfileDirs []string `toml:"profile_dirs"`
+}
+
+// CustomDefault is a macOS defaults domain/key pair to capture.
+type CustomDefault struct {
+	Domain    string `toml:"domain"`
+	Key       string `toml:"key"`
+	ValueType string `toml:"value_type"`
+}
+
+// Duration is a time.Duration that decodes from strings such as "90s" or "2m".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText parses a Go duration string.
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalText formats the duration as a Go duration string.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.Duration.String()), nil
+}
+
+// Default returns the configuration used when no config file exists.
+func Default() *Config {
+	return &Config{
+		SearchPaths: append([]string(nil), DefaultSearchPaths...),
+	}
+}
+
+// Dir returns the machinist configuration directory, honouring $XDG_CONFIG_HOME.
+func Dir() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "machinist")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "machinist")
+}
+
+// CacheDir returns the machinist cache directory, honouring $XDG_CACHE_HOME.
+func CacheDir() string {
+	if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "machinist")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache", "machinist")
+}
+
+// DefaultPath returns the location of the user config file.
+func DefaultPath() string {
+	return filepath.Join(Dir(), "config.toml")
+}
+
+// Load reads and validates the config file at path. Unset fields keep
+// their defaults. Unknown keys are rejected so typos do not go unnoticed.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := Default()
+	md, err := toml.Decode(string(data), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, k := range undecoded {
+			keys[i] = k.String()
+		}
+		return nil, fmt.Errorf("%s: unknown key(s): %s", path, strings.Join(keys, ", "))
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// LoadOrDefault loads the config at path. When path is empty the default
+// location is tried, and a missing default file yields Default().
+func LoadOrDefault(path string) (*Config, error) {
+	if path != "" {
+		return Load(path)
+	}
+	cfg, err := Load(DefaultPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		return Default(), nil
+	}
+	return cfg, err
+}
+
+// validate checks values that decode fine but make no sense.
+func (c *Config) validate() error {
+	if c.Concurrency < 0 {
+		return fmt.Errorf("concurrency must not be negative")
+	}
+	if c.ScannerTimeout.Duration < 0 {
+		return fmt.Errorf("scanner_timeout must not be negative")
+	}
+	for name, d := range c.ScannerTimeouts {
+		if d.Duration < 0 {
+			return fmt.Errorf("scanner_timeouts.%s must not be negative", name)
+		}
+	}
+	for _, pattern := range c.Exclude {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+	}
+	for _, d := range c.CustomDefaults {
+		if d.Domain == "" || d.Key == "" {
+			return fmt.Errorf("custom_defaults entries need both domain and key")
+		}
+	}
+	return nil
+}
+
+// IsDisabled reports whether the named scanner is disabled.
+func (c *Config) IsDisabled(name string) bool {
+	for _, n := range c.DisabledScanners {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolvedSearchPaths returns SearchPaths with ~ expanded against homeDir.
+func (c *Config) ResolvedSearchPaths(homeDir string) []string {
+	return expandAll(c.SearchPaths, homeDir)
+}
+
+// ResolvedExcludes returns Exclude with ~ expanded against homeDir.
+func (c *Config) ResolvedExcludes(homeDir string) []string {
+	return expandAll(c.Exclude, homeDir)
+}
+
+// ResolvedPluginDirs returns PluginDirs with ~ expanded against homeDir,
+// falling back to the plugins directory next to the config file.
+func (c *Config) ResolvedPluginDirs(homeDir string) []string {
+	if len(c.PluginDirs) == 0 {
+		return []string{filepath.Join(Dir(), "plugins")}
+	}
+	return expandAll(c.PluginDirs, homeDir)
+}
+
+// ResolvedProfileDirs returns ProfileDirs with ~ expanded against homeDir,
+// followed by the profiles directory next to the config file, lowest
+// precedence first.
+func (c *Config) ResolvedProfileDirs(homeDir string) []string {
+	return append(expandAll(c.ProfileDirs, homeDir), filepath.Join(Dir(), "profiles"))
+}
+
+// expandAll expands a leading ~ in every path against homeDir.
+func expandAll(paths []string, homeDir string) []string {
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		switch {
+		case p == "~":
+			p = homeDir
+		case strings.HasPrefix(p, "~/"):
+			p = filepath.Join(homeDir, p[2:])
+		}
+		out = append(out, p)
+	}
+	return out
+}
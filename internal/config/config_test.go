@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoad_AllFields(t *testing.T) {
+	path := writeConfig(t, `
+search_paths = ["~/src", "/work"]
+disabled_scanners = ["network", "hosts-file"]
+exclude = ["node_modules", "~/src/archive/*"]
+xdg_tools = ["zellij"]
+concurrency = 4
+scanner_timeout = "45s"
+
+[scanner_timeouts]
+git-repos = "5m"
+
+[[custom_defaults]]
+domain = "com.apple.dock"
+key = "autohide-delay"
+value_type = "float"
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"~/src", "/work"}, cfg.SearchPaths)
+	assert.Equal(t, []string{"/home/me/src", "/work"}, cfg.ResolvedSearchPaths("/home/me"))
+	assert.Equal(t, []string{"node_modules", "/home/me/src/archive/*"}, cfg.ResolvedExcludes("/home/me"))
+	assert.True(t, cfg.IsDisabled("network"))
+	assert.False(t, cfg.IsDisabled("homebrew"))
+	assert.Equal(t, []string{"zellij"}, cfg.XDGTools)
+	assert.Equal(t, 4, cfg.Concurrency)
+	assert.Equal(t, 45*time.Second, cfg.ScannerTimeout.Duration)
+	assert.Equal(t, map[string]Duration{"git-repos": {5 * time.Minute}}, cfg.ScannerTimeouts)
+	require.Len(t, cfg.CustomDefaults, 1)
+	assert.Equal(t, CustomDefault{Domain: "com.apple.dock", Key: "autohide-delay", ValueType: "float"}, cfg.CustomDefaults[0])
+}
+
+func TestLoad_KeepsDefaultSearchPaths(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `xdg_tools = ["zellij"]`))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultSearchPaths, cfg.SearchPaths)
+}
+
+func TestLoad_UnknownKey(t *testing.T) {
+	_, err := Load(writeConfig(t, `serach_paths = ["~/src"]`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "serach_paths")
+}
+
+func TestLoad_InvalidValues(t *testing.T) {
+	_, err := Load(writeConfig(t, `scanner_timeout = "soon"`))
+	assert.Error(t, err)
+
+	_, err = Load(writeConfig(t, `exclude = ["[unclosed"]`))
+	assert.Error(t, err)
+
+	_, err = Load(writeConfig(t, "[[custom_defaults]]\ndomain = \"com.apple.dock\"\n"))
+	assert.Error(t, err)
+}
+
+func TestLoadOrDefault(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	cfg, err := LoadOrDefault("")
+	require.NoError(t, err, "a missing default config is not an error")
+	assert.Equal(t, DefaultSearchPaths, cfg.SearchPaths)
+
+	_, err = LoadOrDefault(filepath.Join(t.TempDir(), "missing.toml"))
+	assert.Error(t, err, "an explicit --config path must exist")
+}
+
+func TestDefaultPath_HonoursXDG(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	assert.Equal(t, "/tmp/xdg/machinist/config.toml", DefaultPath())
+}
+
+func TestCacheDir_HonoursXDG(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", "/tmp/cache")
+	assert.Equal(t, "/tmp/cache/machinist", CacheDir())
+}
+
+func TestResolvedPluginDirs(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	assert.Equal(t, []string{"/tmp/xdg/machinist/plugins"}, Default().ResolvedPluginDirs("/home/me"))
+
+	cfg, err := Load(writeConfig(t, `plugin_dirs = ["~/corp/plugins", "/opt/plugins"]`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/home/me/corp/plugins", "/opt/plugins"}, cfg.ResolvedPluginDirs("/home/me"))
+}
+
+func TestResolvedProfileDirs(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	assert.Equal(t, []string{"/tmp/xdg/machinist/profiles"}, Default().ResolvedProfileDirs("/home/me"))
+
+	cfg, err := Load(writeConfig(t, `profile_dirs = ["~/team/profiles"]`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/home/me/team/profiles", "/tmp/xdg/machinist/profiles"}, cfg.ResolvedProfileDirs("/home/me"))
+}
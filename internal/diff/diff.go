@@ -0,0 +1,256 @@
+// Package diff compares two manifests section by section, down to single
+// values: packages and their versions, extensions, repositories, macOS
+// defaults, config file hashes and runtime versions.
+package diff
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+)
+
+// Kind says how a value differs between manifest A and manifest B.
+type Kind string
+
+const (
+	Added   Kind = "added"
+	Removed Kind = "removed"
+	Changed Kind = "changed"
+)
+
+// Change is one value that differs between manifest A and manifest B.
+type Change struct {
+	Kind    Kind   `json:"kind"`
+	Section string `json:"section"`
+	// Key is the dotted TOML key path of the value, with list items named by
+	// their natural key in brackets, e.g. "homebrew.formulae[node].version".
+	Key string `json:"key"`
+	// Item is the natural key of a list item that was added or removed as a
+	// whole, e.g. the package name. It is empty for changed values.
+	Item string `json:"item,omitempty"`
+	// Old and New are the values in A and B, with tables keyed by their TOML
+	// names. A value missing from one side is nil.
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
+
+// Report is the structural difference between two manifests. Changes are
+// in restore order, by section.
+type Report struct {
+	// OnlyA and OnlyB list the sections present in just one manifest.
+	OnlyA   []string `json:"only_a"`
+	OnlyB   []string `json:"only_b"`
+	Changes []Change `json:"changes"`
+}
+
+// Empty reports whether the manifests are the same.
+func (r *Report) Empty() bool { return len(r.Changes) == 0 }
+
+// Manifests compares every section of a and b. List items are matched by
+// the natural key manifests are merged by, so a package whose version
+// changed is one change, not a removal and an addition. [meta] and
+// [profile] are not compared.
+func Manifests(a, b *domain.Snapshot) *Report {
+	r := &Report{OnlyA: []string{}, OnlyB: []string{}, Changes: []Change{}}
+	for _, s := range domain.Sections() {
+		va, vb := s.Value(a), s.Value(b)
+		switch {
+		case va == nil && vb == nil:
+			continue
+		case vb == nil:
+			r.OnlyA = append(r.OnlyA, s.Key)
+		case va == nil:
+			r.OnlyB = append(r.OnlyB, s.Key)
+		}
+		w := walker{section: s.Key, report: r}
+		w.walk(s.Key, reflect.ValueOf(va), reflect.ValueOf(vb))
+	}
+	return r
+}
+
+// walker records the changes in one section.
+type walker struct {
+	section string
+	report  *Report
+}
+
+func (w walker) add(c Change) {
+	c.Section = w.section
+	w.report.Changes = append(w.report.Changes, c)
+}
+
+// walk compares a and b at key. A value missing on one side (invalid) is
+// compared as the zero value of the other side's type.
+func (w walker) walk(key string, a, b reflect.Value) {
+	if !a.IsValid() {
+		a = reflect.Zero(b.Type())
+	}
+	if !b.IsValid() {
+		b = reflect.Zero(a.Type())
+	}
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if a.IsNil() && b.IsNil() {
+			return
+		}
+		var ea, eb reflect.Value
+		if !a.IsNil() {
+			ea = a.Elem()
+		}
+		if !b.IsNil() {
+			eb = b.Elem()
+		}
+		w.walk(key, ea, eb)
+		return
+	case reflect.Struct:
+		if !hasTOMLFields(a.Type()) {
+			break
+		}
+		for i := 0; i < a.NumField(); i++ {
+			f := a.Type().Field(i)
+			if name := tomlName(f); f.IsExported() && name != "-" {
+				w.walk(key+"."+name, a.Field(i), b.Field(i))
+			}
+		}
+		return
+	case reflect.Slice:
+		w.list(key, a, b)
+		return
+	case reflect.Map:
+		for _, k := range mapKeys(a, b) {
+			w.walk(fmt.Sprintf("%s.%v", key, k.Interface()), a.MapIndex(k), b.MapIndex(k))
+		}
+		return
+	}
+	w.scalar(key, a, b)
+}
+
+// list matches the items of a and b by natural key and compares the items
+// found in both.
+func (w walker) list(key string, a, b reflect.Value) {
+	keysA, posA := index(a)
+	keysB, posB := index(b)
+	for _, k := range keysA {
+		itemKey := key + "[" + k + "]"
+		if j, ok := posB[k]; ok {
+			w.walk(itemKey, a.Index(posA[k]), b.Index(j))
+			continue
+		}
+		w.add(Change{Kind: Removed, Key: itemKey, Item: k, Old: plain(a.Index(posA[k]))})
+	}
+	for _, k := range keysB {
+		if _, ok := posA[k]; !ok {
+			w.add(Change{Kind: Added, Key: key + "[" + k + "]", Item: k, New: plain(b.Index(posB[k]))})
+		}
+	}
+}
+
+func (w walker) scalar(key string, a, b reflect.Value) {
+	if reflect.DeepEqual(a.Interface(), b.Interface()) {
+		return
+	}
+	c := Change{Kind: Changed, Key: key, Old: a.Interface(), New: b.Interface()}
+	// An unset value is the TOML default; booleans always read as a change.
+	if a.Kind() != reflect.Bool {
+		switch {
+		case a.IsZero():
+			c.Kind, c.Old = Added, nil
+		case b.IsZero():
+			c.Kind, c.New = Removed, nil
+		}
+	}
+	w.add(c)
+}
+
+// index returns the natural keys of a list's items in order, without
+// repeats, and the position of each key's first item.
+func index(list reflect.Value) ([]string, map[string]int) {
+	var keys []string
+	pos := make(map[string]int, list.Len())
+	for i := 0; i < list.Len(); i++ {
+		k := domain.ItemKey(list.Index(i).Interface())
+		if _, dup := pos[k]; !dup {
+			pos[k] = i
+			keys = append(keys, k)
+		}
+	}
+	return keys, pos
+}
+
+// mapKeys returns the keys of a and b, sorted.
+func mapKeys(a, b reflect.Value) []reflect.Value {
+	seen := make(map[string]bool)
+	var keys []reflect.Value
+	for _, m := range []reflect.Value{a, b} {
+		for _, k := range m.MapKeys() {
+			if s := fmt.Sprint(k.Interface()); !seen[s] {
+				seen[s] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+	})
+	return keys
+}
+
+// plain converts v to the shape it has in TOML: tables become maps keyed by
+// TOML name without their unset fields.
+func plain(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return plain(v.Elem())
+	case reflect.Struct:
+		if !hasTOMLFields(v.Type()) {
+			break
+		}
+		out := make(map[string]any)
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if name := tomlName(f); f.IsExported() && name != "-" && !v.Field(i).IsZero() {
+				out[name] = plain(v.Field(i))
+			}
+		}
+		return out
+	case reflect.Slice:
+		out := make([]any, v.Len())
+		for i := range out {
+			out[i] = plain(v.Index(i))
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = plain(iter.Value())
+		}
+		return out
+	}
+	return v.Interface()
+}
+
+func tomlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// hasTOMLFields reports whether t is a manifest table rather than an opaque
+// value such as time.Time.
+func hasTOMLFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
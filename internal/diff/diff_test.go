@@ -0,0 +1,167 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func oldMachine() *domain.Snapshot {
+	return &domain.Snapshot{
+		Meta: domain.Meta{SourceHostname: "old-mbp"},
+		Homebrew: &domain.HomebrewSection{
+			Formulae: []domain.Package{{Name: "git"}, {Name: "node", Version: "20.1"}, {Name: "wget"}},
+		},
+		Git: &domain.GitSection{
+			ConfigFiles: []domain.ConfigFile{{Source: ".gitconfig", BundlePath: "configs/.gitconfig", ContentHash: "sha256:aaa"}},
+		},
+		VSCode: &domain.VSCodeSection{Extensions: []string{"golang.go", "ms-python.python"}},
+		MacOSDefaults: &domain.MacOSDefaultsSection{
+			Dock:     &domain.DockConfig{AutoHide: true, TileSize: 48},
+			Defaults: []domain.MacDefault{{Domain: "com.apple.finder", Key: "ShowPathbar", Value: "false", ValueType: "bool"}},
+		},
+		Rust: &domain.RustSection{Toolchains: []string{"stable"}},
+	}
+}
+
+func newMachine() *domain.Snapshot {
+	return &domain.Snapshot{
+		Meta: domain.Meta{SourceHostname: "new-mbp"},
+		Homebrew: &domain.HomebrewSection{
+			Formulae: []domain.Package{{Name: "git"}, {Name: "node", Version: "22.3"}, {Name: "httpie"}},
+		},
+		Git: &domain.GitSection{
+			ConfigFiles: []domain.ConfigFile{{Source: ".gitconfig", BundlePath: "configs/.gitconfig", ContentHash: "sha256:bbb"}},
+		},
+		VSCode: &domain.VSCodeSection{Extensions: []string{"golang.go", "rust-lang.rust-analyzer"}},
+		MacOSDefaults: &domain.MacOSDefaultsSection{
+			Dock:     &domain.DockConfig{AutoHide: false, TileSize: 48},
+			Defaults: []domain.MacDefault{{Domain: "com.apple.finder", Key: "ShowPathbar", Value: "true", ValueType: "bool"}},
+		},
+		Python: &domain.PythonSection{Manager: "pyenv"},
+	}
+}
+
+func changeByKey(r *Report, key string) (Change, bool) {
+	for _, c := range r.Changes {
+		if c.Key == key {
+			return c, true
+		}
+	}
+	return Change{}, false
+}
+
+func TestManifests(t *testing.T) {
+	r := Manifests(oldMachine(), newMachine())
+
+	assert.Equal(t, []string{"rust"}, r.OnlyA)
+	assert.Equal(t, []string{"python"}, r.OnlyB)
+
+	tests := []struct {
+		key      string
+		kind     Kind
+		old, new any
+	}{
+		{"homebrew.formulae[node].version", Changed, "20.1", "22.3"},
+		{"homebrew.formulae[wget]", Removed, map[string]any{"name": "wget"}, nil},
+		{"homebrew.formulae[httpie]", Added, nil, map[string]any{"name": "httpie"}},
+		{"git.config_files[.gitconfig].content_hash", Changed, "sha256:aaa", "sha256:bbb"},
+		{"vscode.extensions[ms-python.python]", Removed, "ms-python.python", nil},
+		{"vscode.extensions[rust-lang.rust-analyzer]", Added, nil, "rust-lang.rust-analyzer"},
+		{"macos_defaults.dock.autohide", Changed, true, false},
+		{"macos_defaults.defaults[com.apple.finder/ShowPathbar].value", Changed, "false", "true"},
+		{"rust.toolchains[stable]", Removed, "stable", nil},
+		{"python.manager", Added, nil, "pyenv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			c, ok := changeByKey(r, tt.key)
+			require.True(t, ok, "no change for %s in %+v", tt.key, r.Changes)
+			assert.Equal(t, tt.kind, c.Kind)
+			assert.Equal(t, tt.old, c.Old)
+			assert.Equal(t, tt.new, c.New)
+		})
+	}
+	assert.Len(t, r.Changes, len(tests))
+
+	_, ok := changeByKey(r, "homebrew.formulae[git]")
+	assert.False(t, ok, "unchanged packages must not be reported")
+}
+
+func TestManifests_Same(t *testing.T) {
+	r := Manifests(oldMachine(), oldMachine())
+	assert.True(t, r.Empty())
+	assert.Empty(t, r.OnlyA)
+	assert.Empty(t, r.OnlyB)
+}
+
+func TestManifests_NamedSections(t *testing.T) {
+	a := &domain.Snapshot{Plugins: map[string]*domain.PluginSection{"corp": {Restore: []string{"corp login"}}}}
+	b := &domain.Snapshot{Plugins: map[string]*domain.PluginSection{"corp": {Restore: []string{"corp login --sso"}}}}
+
+	r := Manifests(a, b)
+	_, removed := changeByKey(r, "plugins.corp.restore[corp login]")
+	_, added := changeByKey(r, "plugins.corp.restore[corp login --sso]")
+	assert.True(t, removed && added, "changes: %+v", r.Changes)
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	WriteText(&buf, Manifests(oldMachine(), newMachine()), Options{NameA: "old.toml", NameB: "new.toml"})
+	out := buf.String()
+
+	assert.Contains(t, out, "Only in old.toml: rust")
+	assert.Contains(t, out, "Only in new.toml: python")
+	assert.Contains(t, out, "[homebrew]")
+	assert.Contains(t, out, `~ formulae[node].version: "20.1" → "22.3"`)
+	assert.Contains(t, out, "- formulae[wget]\n")
+	assert.Contains(t, out, "+ formulae[httpie]\n")
+	assert.Contains(t, out, `+ manager = "pyenv"`)
+	assert.Contains(t, out, "3 added, 3 removed, 4 changed")
+
+	buf.Reset()
+	WriteText(&buf, Manifests(oldMachine(), oldMachine()), Options{})
+	assert.Equal(t, "A and B are the same\n", buf.String())
+}
+
+func TestWriteText_Color(t *testing.T) {
+	var buf bytes.Buffer
+	color := func(k Kind, line string) string { return "<" + string(k) + ">" + line }
+	WriteText(&buf, Manifests(oldMachine(), newMachine()), Options{Color: color})
+	assert.Contains(t, buf.String(), "<added>+ formulae[httpie]")
+}
+
+func TestWritePatch(t *testing.T) {
+	var buf bytes.Buffer
+	WritePatch(&buf, Manifests(oldMachine(), newMachine()), Options{NameA: "old.toml", NameB: "new.toml"})
+	out := buf.String()
+
+	assert.Contains(t, out, "--- old.toml\n+++ new.toml\n")
+	assert.Contains(t, out, "@@ homebrew @@\n")
+	assert.Contains(t, out, "-homebrew.formulae[node].version = \"20.1\"\n+homebrew.formulae[node].version = \"22.3\"\n")
+	assert.Contains(t, out, `-homebrew.formulae[wget] = {name = "wget"}`)
+	assert.Contains(t, out, `+homebrew.formulae[httpie] = {name = "httpie"}`)
+}
+
+func TestReport_JSON(t *testing.T) {
+	data, err := json.Marshal(Manifests(oldMachine(), newMachine()))
+	require.NoError(t, err)
+
+	var got struct {
+		OnlyA   []string `json:"only_a"`
+		Changes []struct {
+			Kind string `json:"kind"`
+			Key  string `json:"key"`
+			Old  any    `json:"old"`
+		} `json:"changes"`
+	}
+	require.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, []string{"rust"}, got.OnlyA)
+	assert.Equal(t, "changed", got.Changes[0].Kind)
+	assert.Equal(t, "homebrew.formulae[node].version", got.Changes[0].Key)
+	assert.Equal(t, "20.1", got.Changes[0].Old)
+}
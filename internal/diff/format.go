@@ -0,0 +1,141 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Options configure how a report is written.
+type Options struct {
+	// NameA and NameB label the two manifests, e.g. their file names.
+	// They default to "A" and "B".
+	NameA, NameB string
+	// Color, when set, styles a line for its change kind.
+	Color func(k Kind, line string) string
+}
+
+func (o Options) names() (string, string) {
+	a, b := o.NameA, o.NameB
+	if a == "" {
+		a = "A"
+	}
+	if b == "" {
+		b = "B"
+	}
+	return a, b
+}
+
+func (o Options) color(k Kind, line string) string {
+	if o.Color == nil {
+		return line
+	}
+	return o.Color(k, line)
+}
+
+// marks prefix a change in the text report.
+var marks = map[Kind]string{Added: "+", Removed: "-", Changed: "~"}
+
+// WriteText writes r for people: the sections found in only one manifest,
+// then each section's changes, one per line.
+func WriteText(w io.Writer, r *Report, opts Options) {
+	nameA, nameB := opts.names()
+	if r.Empty() {
+		fmt.Fprintf(w, "%s and %s are the same\n", nameA, nameB)
+		return
+	}
+	if len(r.OnlyA) > 0 {
+		fmt.Fprintf(w, "Only in %s: %s\n", nameA, strings.Join(r.OnlyA, ", "))
+	}
+	if len(r.OnlyB) > 0 {
+		fmt.Fprintf(w, "Only in %s: %s\n", nameB, strings.Join(r.OnlyB, ", "))
+	}
+	section := ""
+	for _, c := range r.Changes {
+		if c.Section != section {
+			section = c.Section
+			fmt.Fprintf(w, "\n[%s]\n", section)
+		}
+		key := strings.TrimPrefix(strings.TrimPrefix(c.Key, c.Section), ".")
+		fmt.Fprintf(w, "  %s\n", opts.color(c.Kind, marks[c.Kind]+" "+c.describe(key)))
+	}
+	fmt.Fprintf(w, "\n%d added, %d removed, %d changed\n", r.count(Added), r.count(Removed), r.count(Changed))
+}
+
+// String describes the change by its key and values, e.g.
+// `go.version: "1.24" → "1.25"`. Whole list items are named by key only.
+func (c Change) String() string { return c.describe(c.Key) }
+
+func (c Change) describe(key string) string {
+	switch {
+	case c.Item != "":
+		return key
+	case c.Kind == Added:
+		return key + " = " + formatValue(c.New)
+	case c.Kind == Removed:
+		return key + " = " + formatValue(c.Old)
+	}
+	return key + ": " + formatValue(c.Old) + " → " + formatValue(c.New)
+}
+
+// WritePatch writes r in the style of a unified diff: a "-" line with the
+// value in A and a "+" line with the value in B for every change, under an
+// "@@ section @@" header per section.
+func WritePatch(w io.Writer, r *Report, opts Options) {
+	nameA, nameB := opts.names()
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", nameA, nameB)
+	section := ""
+	for _, c := range r.Changes {
+		if c.Section != section {
+			section = c.Section
+			fmt.Fprintf(w, "@@ %s @@\n", section)
+		}
+		if c.Kind != Added {
+			fmt.Fprintln(w, opts.color(Removed, "-"+c.Key+" = "+formatValue(c.Old)))
+		}
+		if c.Kind != Removed {
+			fmt.Fprintln(w, opts.color(Added, "+"+c.Key+" = "+formatValue(c.New)))
+		}
+	}
+}
+
+func (r *Report) count(k Kind) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Kind == k {
+			n++
+		}
+	}
+	return n
+}
+
+// formatValue renders a value the way it is written in TOML, with inline
+// tables for list items.
+func formatValue(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return `""`
+	case string:
+		return strconv.Quote(x)
+	case []any:
+		parts := make([]string, len(x))
+		for i, e := range x {
+			parts[i] = formatValue(e)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k + " = " + formatValue(x[k])
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return fmt.Sprint(v)
+}
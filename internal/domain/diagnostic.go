@@ -0,0 +1,44 @@
+package domain
+
+import "strings"
+
+// Severity classifies a Diagnostic.
+type Severity string
+
+const (
+	// SeverityError means a scanner failed and its section is missing.
+	SeverityError Severity = "error"
+	// SeverityWarning means a scanner skipped part of its work; the rest of
+	// its section was still recorded.
+	SeverityWarning Severity = "warning"
+	// SeverityInfo is an observation that needs no action.
+	SeverityInfo Severity = "info"
+)
+
+// Diagnostic describes a problem found while scanning. Diagnostics are
+// recorded under [[meta.diagnostics]] so a manifest explains why a section
+// is missing or incomplete. Diagnostic implements error.
+type Diagnostic struct {
+	Severity Severity `toml:"severity" json:"severity" enum:"error,warning,info"`
+	Scanner  string   `toml:"scanner" json:"scanner"`
+	// Item is what was being scanned, e.g. a path or "taps".
+	Item string `toml:"item,omitempty" json:"item,omitempty"`
+	// Command is the command line that failed, if any.
+	Command string `toml:"command,omitempty" json:"command,omitempty"`
+	Message string `toml:"message" json:"message"`
+	// Fix suggests how to resolve the problem.
+	Fix string `toml:"fix,omitempty" json:"fix,omitempty"`
+}
+
+// Error formats the diagnostic as "scanner <name>: <item>: <message>".
+func (d Diagnostic) Error() string {
+	parts := make([]string, 0, 3)
+	if d.Scanner != "" {
+		parts = append(parts, "scanner "+d.Scanner)
+	}
+	if d.Item != "" {
+		parts = append(parts, d.Item)
+	}
+	parts = append(parts, d.Message)
+	return strings.Join(parts, ": ")
+}
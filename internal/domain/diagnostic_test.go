@@ -0,0 +1,36 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnostic_Error(t *testing.T) {
+	d := Diagnostic{Scanner: "homebrew", Item: "taps", Message: "exit status 1"}
+	assert.Equal(t, "scanner homebrew: taps: exit status 1", d.Error())
+
+	d = Diagnostic{Scanner: "shell", Message: "timed out after 2m0s"}
+	assert.Equal(t, "scanner shell: timed out after 2m0s", d.Error())
+}
+
+func TestDiagnostics_ManifestRoundTrip(t *testing.T) {
+	snap := &Snapshot{Meta: testMeta()}
+	snap.Meta.Diagnostics = []Diagnostic{{
+		Severity: SeverityWarning,
+		Scanner:  "homebrew",
+		Item:     "services",
+		Command:  "brew services list",
+		Message:  "exit status 1",
+		Fix:      "Run 'brew doctor' and rescan",
+	}}
+
+	data, err := MarshalManifest(snap)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "[[meta.diagnostics]]")
+
+	restored, err := UnmarshalManifest(data)
+	require.NoError(t, err)
+	assert.Equal(t, snap.Meta.Diagnostics, restored.Meta.Diagnostics)
+}
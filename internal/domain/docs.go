@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"embed"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"sync"
+)
+
+// The manifest types' sources are embedded so their doc comments can be
+// shipped as schema descriptions without a generate step going stale.
+//
+//go:embed snapshot.go types.go diagnostic.go
+var docSources embed.FS
+
+type typeDocs struct {
+	doc    string
+	fields map[string]string
+}
+
+var loadDocs = sync.OnceValue(func() map[string]typeDocs {
+	docs := make(map[string]typeDocs)
+	entries, _ := docSources.ReadDir(".")
+	fset := token.NewFileSet()
+	for _, e := range entries {
+		src, err := docSources.ReadFile(e.Name())
+		if err != nil {
+			continue
+		}
+		f, err := parser.ParseFile(fset, e.Name(), src, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				td := typeDocs{doc: commentText(ts.Doc), fields: make(map[string]string)}
+				if td.doc == "" && len(gen.Specs) == 1 {
+					td.doc = commentText(gen.Doc)
+				}
+				if st, ok := ts.Type.(*ast.StructType); ok {
+					for _, field := range st.Fields.List {
+						text := commentText(field.Doc)
+						if text == "" {
+							text = commentText(field.Comment)
+						}
+						for _, name := range field.Names {
+							td.fields[name.Name] = text
+						}
+					}
+				}
+				docs[ts.Name.Name] = td
+			}
+		}
+	}
+	return docs
+})
+
+func commentText(g *ast.CommentGroup) string {
+	if g == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(g.Text()), " ")
+}
+
+// TypeDoc returns the doc comment of a manifest type, e.g. "HomebrewSection",
+// as a single line, or "" when it has none.
+func TypeDoc(name string) string {
+	return loadDocs()[name].doc
+}
+
+// FieldDoc returns the doc or line comment of a field of a manifest type, or
+// "" when it has none.
+func FieldDoc(typeName, field string) string {
+	return loadDocs()[typeName].fields[field]
+}
@@ -1,62 +1,52 @@
 package domain
 
-import "reflect"
-
-// RestoreGroup describes a numbered group of restore stages.
+// RestoreGroup describes a numbered group of restore stages. The sections
+// belonging to a group are those registered with its Name in Sections().
 type RestoreGroup struct {
-	ID             string   // e.g. "01-foundation"
-	Name           string   // e.g. "foundation"
-	Label          string   // e.g. "Foundation"
-	ScriptName     string   // e.g. "01-foundation.sh"
-	SnapshotFields []string // Snapshot struct field names
+	ID         string // e.g. "01-homebrew"
+	Name       string // e.g. "homebrew"
+	Label      string // e.g. "Homebrew Packages"
+	ScriptName string // e.g. "01-homebrew.sh"
+}
+
+// Sections returns the sections restored by this group, in restore order.
+func (g RestoreGroup) Sections() []Section {
+	var out []Section
+	for _, s := range sections {
+		if s.Group == g.Name {
+			out = append(out, s)
+		}
+	}
+	return out
 }
 
-// HasData returns true if the snapshot has any non-nil section for this group.
+// Stages returns the stages this group runs for snap, in restore order.
+func (g RestoreGroup) Stages(snap *Snapshot) []Stage {
+	return stages(snap, g.Name)
+}
+
+// HasData returns true if the snapshot has any populated section for this group.
 func (g RestoreGroup) HasData(snap *Snapshot) bool {
-	v := reflect.ValueOf(snap).Elem()
-	for _, fieldName := range g.SnapshotFields {
-		f := v.FieldByName(fieldName)
-		if f.IsValid() && f.Kind() == reflect.Ptr && !f.IsNil() {
+	for _, s := range sections {
+		if s.Group == g.Name && s.present(snap) {
 			return true
 		}
 	}
 	return false
 }
 
-// StageCount returns the number of non-nil stages in this group.
+// StageCount returns the number of stages this group runs for snap.
 func (g RestoreGroup) StageCount(snap *Snapshot) int {
-	count := 0
-	v := reflect.ValueOf(snap).Elem()
-	for _, fieldName := range g.SnapshotFields {
-		f := v.FieldByName(fieldName)
-		if f.IsValid() && f.Kind() == reflect.Ptr && !f.IsNil() {
-			count++
-		}
-	}
-	return count
+	return len(g.Stages(snap))
 }
 
 var restoreGroups = []RestoreGroup{
-	{ID: "01-homebrew", Name: "homebrew", Label: "Homebrew Packages", ScriptName: "01-homebrew.sh",
-		SnapshotFields: []string{"Homebrew"}},
-	{ID: "02-secrets", Name: "secrets", Label: "Secrets & Keys", ScriptName: "02-secrets.sh",
-		SnapshotFields: []string{"SSH", "GPG", "EnvFiles"}},
-	{ID: "03-configs", Name: "configs", Label: "Config Files", ScriptName: "03-configs.sh",
-		SnapshotFields: []string{"Git", "GitHubCLI", "Shell", "Terminal", "Tmux",
-			"VSCode", "Cursor", "Neovim", "JetBrains", "Xcode",
-			"Docker", "AWS", "Kubernetes", "Terraform", "Vercel",
-			"GCP", "Azure", "Flyio", "Firebase", "CloudflareWrangler",
-			"Karabiner", "Rectangle", "BetterTouchTool", "OnePassword",
-			"AITools", "APITools", "XDGConfig", "Databases", "Registries",
-			"Fonts", "Folders", "Browser", "Raycast", "Alfred", "LoginItems"}},
-	{ID: "04-runtimes", Name: "runtimes", Label: "Runtime Installers", ScriptName: "04-runtimes.sh",
-		SnapshotFields: []string{"Node", "Python", "Rust", "Java", "Flutter", "Go",
-			"Ruby", "Deno", "Bun", "Asdf"}},
-	{ID: "05-repos", Name: "repos", Label: "Git Repositories", ScriptName: "05-repos.sh",
-		SnapshotFields: []string{"GitRepos"}},
-	{ID: "06-macos", Name: "macos", Label: "macOS System Settings", ScriptName: "06-macos.sh",
-		SnapshotFields: []string{"MacOSDefaults", "Apps", "Crontab", "LaunchAgents",
-			"Locale", "HostsFile", "Network"}},
+	{ID: "01-homebrew", Name: "homebrew", Label: "Homebrew Packages", ScriptName: "01-homebrew.sh"},
+	{ID: "02-secrets", Name: "secrets", Label: "Secrets & Keys", ScriptName: "02-secrets.sh"},
+	{ID: "03-configs", Name: "configs", Label: "Config Files", ScriptName: "03-configs.sh"},
+	{ID: "04-runtimes", Name: "runtimes", Label: "Runtime Installers", ScriptName: "04-runtimes.sh"},
+	{ID: "05-repos", Name: "repos", Label: "Git Repositories", ScriptName: "05-repos.sh"},
+	{ID: "06-macos", Name: "macos", Label: "macOS System Settings", ScriptName: "06-macos.sh"},
 }
 
 // RestoreGroups returns all restore groups in order.
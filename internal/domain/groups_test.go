@@ -1,7 +1,6 @@
 package domain
 
 import (
-	"reflect"
 	"testing"
 )
 
@@ -19,10 +18,10 @@ func TestRestoreGroupsReturns6Groups(t *testing.T) {
 	}
 }
 
-func TestAllGroupsHaveSnapshotFields(t *testing.T) {
+func TestAllGroupsHaveSections(t *testing.T) {
 	for _, g := range RestoreGroups() {
-		if len(g.SnapshotFields) == 0 {
-			t.Errorf("group %q has no SnapshotFields", g.ID)
+		if len(g.Sections()) == 0 {
+			t.Errorf("group %q has no sections", g.ID)
 		}
 	}
 }
@@ -60,15 +59,25 @@ func TestGroupNames(t *testing.T) {
 	}
 }
 
-func TestRestoreGroups_SnapshotFieldsAreValid(t *testing.T) {
-	snapType := reflect.TypeOf(Snapshot{})
-	for _, g := range RestoreGroups() {
-		for _, fieldName := range g.SnapshotFields {
-			_, ok := snapType.FieldByName(fieldName)
-			if !ok {
-				t.Errorf("group %s references non-existent Snapshot field: %s", g.ID, fieldName)
-			}
+func TestSections_BelongToKnownGroups(t *testing.T) {
+	for _, sec := range Sections() {
+		if _, ok := GroupByName(sec.Group); !ok {
+			t.Errorf("section %s references unknown group %q", sec.Key, sec.Group)
+		}
+	}
+}
+
+func TestSections_ListedInGroupOrder(t *testing.T) {
+	order := make(map[string]int)
+	for i, g := range RestoreGroups() {
+		order[g.Name] = i
+	}
+	last := 0
+	for _, sec := range Sections() {
+		if order[sec.Group] < last {
+			t.Errorf("section %s (group %s) is listed after a later group", sec.Key, sec.Group)
 		}
+		last = order[sec.Group]
 	}
 }
 
@@ -131,3 +140,37 @@ func TestStageCountZeroOnEmpty(t *testing.T) {
 		t.Errorf("StageCount = %d, want 0", got)
 	}
 }
+
+func TestGroupHasData_Plugins(t *testing.T) {
+	g, _ := GroupByName("configs")
+	snap := &Snapshot{Plugins: map[string]*PluginSection{}}
+	if g.HasData(snap) {
+		t.Error("expected HasData=false for an empty plugins map")
+	}
+	snap.Plugins["corp"] = &PluginSection{Restore: []string{"corp login"}}
+	if !g.HasData(snap) {
+		t.Error("expected HasData=true for configs when a plugin is set")
+	}
+	if got := g.StageCount(snap); got != 1 {
+		t.Errorf("StageCount = %d, want 1", got)
+	}
+}
+
+func TestStageCount_SharedStage(t *testing.T) {
+	snap := &Snapshot{
+		Crontab:      &CrontabSection{},
+		LaunchAgents: &LaunchAgentsSection{},
+		Locale:       &LocaleSection{},
+	}
+	g, _ := GroupByName("macos")
+	if got := g.StageCount(snap); got != 2 {
+		t.Errorf("StageCount = %d, want 2 (crontab and launch agents share Scheduled Tasks)", got)
+	}
+	stages := g.Stages(snap)
+	if stages[0].Label != "Scheduled Tasks" || stages[0].Func != "do_scheduled" {
+		t.Errorf("first stage = %+v, want Scheduled Tasks/do_scheduled", stages[0])
+	}
+	if stages[0].Data != snap {
+		t.Error("Scheduled Tasks stage should receive the whole snapshot")
+	}
+}
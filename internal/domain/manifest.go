@@ -7,22 +7,45 @@ import (
 	"github.com/BurntSushi/toml"
 )
 
-// MarshalManifest serializes a Snapshot to TOML bytes.
+// MarshalManifest serializes a Snapshot to TOML bytes, stamped with the
+// current schema version.
 func MarshalManifest(s *Snapshot) ([]byte, error) {
+	stamped := *s
+	stamped.Meta.SchemaVersion = CurrentSchemaVersion
+
 	var buf bytes.Buffer
 	enc := toml.NewEncoder(&buf)
-	if err := enc.Encode(s); err != nil {
+	if err := enc.Encode(&stamped); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
-// UnmarshalManifest deserializes TOML bytes into a Snapshot.
+// UnmarshalManifest deserializes TOML bytes into a Snapshot. Manifests from
+// an older schema are migrated in memory first and record the version they
+// started at in Meta.MigratedFrom; see MigrateManifest.
 func UnmarshalManifest(data []byte) (*Snapshot, error) {
 	var s Snapshot
-	if _, err := toml.Decode(string(data), &s); err != nil {
+	_, decodeErr := toml.Decode(string(data), &s)
+	if decodeErr == nil && s.Meta.SchemaVersion == CurrentSchemaVersion {
+		return &s, nil
+	}
+
+	// Older and newer manifests, and documents whose old shape does not
+	// decode, go through the migration chain, which reports versions it
+	// cannot handle.
+	migrated, from, err := MigrateManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if from == CurrentSchemaVersion {
+		return nil, decodeErr
+	}
+	s = Snapshot{}
+	if _, err := toml.Decode(string(migrated), &s); err != nil {
 		return nil, err
 	}
+	s.Meta.MigratedFrom = from
 	return &s, nil
 }
 
@@ -0,0 +1,250 @@
+package domain
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MergeStrategy says how an overriding manifest layer combines one of its
+// values with the layer below. A manifest picks strategies per key path in
+// [meta.merge], e.g. "vscode.extensions" = "replace"; anything not listed
+// is deep-merged as described on Section.Merge.
+type MergeStrategy string
+
+const (
+	// MergeAppend unions lists by their natural key and merges tables field
+	// by field. It is the default.
+	MergeAppend MergeStrategy = "append"
+	// MergeReplace takes the override's value as is, even when it is empty.
+	MergeReplace MergeStrategy = "replace"
+	// MergeRemove deletes the override's list items (matched by natural
+	// key) or named entries from the base; for any other value it clears
+	// the base value.
+	MergeRemove MergeStrategy = "remove"
+)
+
+// Conflict is a value that an overriding layer changed from one non-empty
+// value to another while merging.
+type Conflict struct {
+	// Path is the dotted TOML key path, e.g. "go.version".
+	Path string
+	// Item is the natural key of the list item holding the value, e.g. the
+	// package name, or empty outside lists.
+	Item     string
+	Base     any
+	Override any
+	// list is the key path of the list holding Item.
+	list string
+}
+
+// Key names the conflicting value, with the list item in brackets when
+// there is one, e.g. "homebrew.formulae[node].version".
+func (c Conflict) Key() string {
+	if c.Item == "" {
+		return c.Path
+	}
+	return c.list + "[" + c.Item + "]" + strings.TrimPrefix(c.Path, c.list)
+}
+
+// merger deep-merges two values of the same type. rules maps dotted TOML
+// key paths to strategies. When conflicts is set, overridden scalars are
+// recorded there; item and list name the list item being merged.
+type merger struct {
+	rules     map[string]MergeStrategy
+	conflicts *[]Conflict
+	item      string
+	list      string
+}
+
+// merge returns base combined with over at path without modifying either.
+func (m merger) merge(path string, base, over reflect.Value) reflect.Value {
+	switch m.rules[path] {
+	case MergeReplace:
+		return over
+	case MergeRemove:
+		return remove(base, over)
+	}
+	return m.deep(path, base, over)
+}
+
+// deep merges by kind: pointers and structs field by field, lists by
+// natural key, maps per entry, and scalars by taking a non-zero override.
+func (m merger) deep(path string, base, over reflect.Value) reflect.Value {
+	switch base.Kind() {
+	case reflect.Pointer:
+		if over.IsNil() {
+			return base
+		}
+		if base.IsNil() {
+			return over
+		}
+		out := reflect.New(base.Type().Elem())
+		out.Elem().Set(m.deep(path, base.Elem(), over.Elem()))
+		return out
+	case reflect.Struct:
+		if !hasTOMLFields(base.Type()) {
+			break
+		}
+		out := reflect.New(base.Type()).Elem()
+		for i := 0; i < base.NumField(); i++ {
+			f := base.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			out.Field(i).Set(m.merge(path+"."+tomlName(f), base.Field(i), over.Field(i)))
+		}
+		return out
+	case reflect.Slice:
+		if over.Len() == 0 {
+			return base
+		}
+		if base.Len() == 0 {
+			return over
+		}
+		return m.union(path, base, over)
+	case reflect.Map:
+		if over.Len() == 0 {
+			return base
+		}
+		if base.Len() == 0 {
+			return over
+		}
+		out := reflect.MakeMapWithSize(base.Type(), base.Len()+over.Len())
+		iter := base.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), iter.Value())
+		}
+		iter = over.MapRange()
+		for iter.Next() {
+			v := iter.Value()
+			if b := base.MapIndex(iter.Key()); b.IsValid() {
+				v = m.merge(fmt.Sprintf("%s.%v", path, iter.Key()), b, v)
+			}
+			out.SetMapIndex(iter.Key(), v)
+		}
+		return out
+	}
+	if over.IsZero() {
+		return base
+	}
+	if m.conflicts != nil && !base.IsZero() && !reflect.DeepEqual(base.Interface(), over.Interface()) {
+		*m.conflicts = append(*m.conflicts, Conflict{Path: path, Item: m.item, Base: base.Interface(), Override: over.Interface(), list: m.list})
+	}
+	return over
+}
+
+// union appends the items of over to base. An item whose natural key is
+// already in base is merged into it in place.
+func (m merger) union(path string, base, over reflect.Value) reflect.Value {
+	out := reflect.MakeSlice(base.Type(), 0, base.Len()+over.Len())
+	index := make(map[string]int, base.Len())
+	for _, list := range []reflect.Value{base, over} {
+		for i := 0; i < list.Len(); i++ {
+			item := list.Index(i)
+			k := naturalKey(item)
+			if pos, ok := index[k]; ok {
+				im := m
+				im.item, im.list = strings.ReplaceAll(k, "\x00", "/"), path
+				out.Index(pos).Set(im.deep(path, out.Index(pos), item))
+				continue
+			}
+			index[k] = out.Len()
+			out = reflect.Append(out, item)
+		}
+	}
+	return out
+}
+
+// remove returns base without the list items or map entries of over, or
+// the zero value for anything else.
+func remove(base, over reflect.Value) reflect.Value {
+	switch base.Kind() {
+	case reflect.Slice:
+		drop := make(map[string]bool, over.Len())
+		for i := 0; i < over.Len(); i++ {
+			drop[naturalKey(over.Index(i))] = true
+		}
+		out := reflect.MakeSlice(base.Type(), 0, base.Len())
+		for i := 0; i < base.Len(); i++ {
+			if !drop[naturalKey(base.Index(i))] {
+				out = reflect.Append(out, base.Index(i))
+			}
+		}
+		return out
+	case reflect.Map:
+		if base.Len() == 0 || over.Len() == 0 {
+			return base
+		}
+		out := reflect.MakeMapWithSize(base.Type(), base.Len())
+		iter := base.MapRange()
+		for iter.Next() {
+			if !over.MapIndex(iter.Key()).IsValid() {
+				out.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+		return out
+	}
+	return reflect.Zero(base.Type())
+}
+
+// mergeKeyer is implemented by list items whose identity is computed, like
+// InstalledApp.
+type mergeKeyer interface {
+	MergeKey() string
+}
+
+// ItemKey returns the natural key list items are matched by when manifests
+// are merged, e.g. a Package's name or a Repository's remote. Keys made of
+// several fields are joined with "/".
+func ItemKey(v any) string {
+	return strings.ReplaceAll(naturalKey(reflect.ValueOf(v)), "\x00", "/")
+}
+
+// naturalKey identifies a list item: the value itself for scalars, the
+// item's MergeKey, or the fields tagged merge:"key" for structs (a Package's
+// name, a ConfigFile's source, a Repository's remote). Structs without key
+// fields are compared whole.
+func naturalKey(v reflect.Value) string {
+	if k, ok := v.Interface().(mergeKeyer); ok {
+		return k.MergeKey()
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Sprint(v.Interface())
+	}
+	var parts []string
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Tag.Get("merge") == "key" {
+			parts = append(parts, fmt.Sprint(v.Field(i).Interface()))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("%+v", v.Interface())
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func tomlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// hasTOMLFields reports whether t is a manifest table rather than an opaque
+// value such as time.Time.
+func hasTOMLFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
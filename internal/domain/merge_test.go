@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNaturalKey(t *testing.T) {
+	assert.Equal(t, "git", naturalKey(reflect.ValueOf(Package{Name: "git", Version: "2"})))
+	assert.Equal(t, naturalKey(reflect.ValueOf(ConfigFile{Source: ".zshrc", BundlePath: "a"})),
+		naturalKey(reflect.ValueOf(ConfigFile{Source: ".zshrc", BundlePath: "b"})))
+	assert.NotEqual(t, naturalKey(reflect.ValueOf(MacDefault{Domain: "com.apple.dock", Key: "autohide"})),
+		naturalKey(reflect.ValueOf(MacDefault{Domain: "com.apple.dock", Key: "tilesize"})))
+	assert.Equal(t, "golang.go", naturalKey(reflect.ValueOf("golang.go")))
+	assert.Equal(t, "com.apple.dock/autohide", ItemKey(MacDefault{Domain: "com.apple.dock", Key: "autohide"}))
+}
+
+func TestMerge_MetaIsNotTouched(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	dst := &Snapshot{Meta: Meta{CreatedAt: created}, Go: &GoSection{Version: "1.22"}}
+	override := &Snapshot{Meta: Meta{CreatedAt: time.Now()}, Go: &GoSection{Version: "1.24"}}
+	for _, s := range Sections() {
+		s.Merge(dst, override)
+	}
+	assert.Equal(t, created, dst.Meta.CreatedAt)
+	assert.Equal(t, "1.24", dst.Go.Version)
+}
+
+func TestMerge_NestedStructs(t *testing.T) {
+	dst := &Snapshot{MacOSDefaults: &MacOSDefaultsSection{
+		Dock: &DockConfig{AutoHide: true, TileSize: 48},
+	}}
+	override := &Snapshot{MacOSDefaults: &MacOSDefaultsSection{
+		Dock: &DockConfig{Orientation: "left"},
+	}}
+	s, _ := SectionByKey("macos_defaults")
+	s.Merge(dst, override)
+
+	require.NotNil(t, dst.MacOSDefaults.Dock)
+	assert.Equal(t, DockConfig{AutoHide: true, TileSize: 48, Orientation: "left"}, *dst.MacOSDefaults.Dock)
+}
+
+func TestMerge_RemoveNamedEntries(t *testing.T) {
+	dst := &Snapshot{Probes: map[string]*ProbeSection{
+		"hammerspoon": {Cask: "hammerspoon"},
+		"bartender":   {Cask: "bartender"},
+	}}
+	override := &Snapshot{
+		Meta:   Meta{Merge: map[string]MergeStrategy{"probes": MergeRemove}},
+		Probes: map[string]*ProbeSection{"bartender": {}},
+	}
+	s, _ := SectionByKey("probes")
+	s.Merge(dst, override)
+
+	assert.Len(t, dst.Probes, 1)
+	assert.Contains(t, dst.Probes, "hammerspoon")
+}
+
+func TestNaturalKey_InstalledAppByID(t *testing.T) {
+	assert.Equal(t, naturalKey(reflect.ValueOf(InstalledApp{Name: "Xcode", ID: 497799835})),
+		naturalKey(reflect.ValueOf(InstalledApp{ID: 497799835})))
+	assert.Equal(t, "Slack", naturalKey(reflect.ValueOf(InstalledApp{Name: "Slack"})))
+}
+
+func TestMerge_Conflicts(t *testing.T) {
+	dst := &Snapshot{
+		Go:       &GoSection{Version: "1.22"},
+		Homebrew: &HomebrewSection{Formulae: []Package{{Name: "node", Version: "20"}, {Name: "git"}}},
+	}
+	override := &Snapshot{
+		Go:       &GoSection{Version: "1.24"},
+		Homebrew: &HomebrewSection{Formulae: []Package{{Name: "node", Version: "22"}, {Name: "git"}, {Name: "jq"}}},
+	}
+
+	var keys []string
+	for _, s := range Sections() {
+		for _, c := range s.Merge(dst, override) {
+			keys = append(keys, c.Key())
+		}
+	}
+	assert.ElementsMatch(t, []string{"go.version", "homebrew.formulae[node].version"}, keys)
+
+	// Filling an empty value or repeating the same one is not a conflict.
+	s, _ := SectionByKey("go")
+	assert.Empty(t, s.Merge(&Snapshot{Go: &GoSection{}}, override))
+	assert.Empty(t, s.Merge(dst, override))
+}
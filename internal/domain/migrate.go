@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// CurrentSchemaVersion is the manifest schema written by this build. Bump it
+// whenever a field changes shape and register a Migration from the previous
+// version. Manifests written before versioning have no schema_version and
+// are treated as version 1.
+const CurrentSchemaVersion = 2
+
+// Migration upgrades a decoded manifest document from schema From to From+1.
+// It works on the generic TOML form because the old shape no longer decodes
+// into the current structs.
+type Migration struct {
+	From        int
+	Description string
+	Apply       func(doc map[string]any) error
+}
+
+// migrations holds one entry per schema bump, ordered by From.
+var migrations = []Migration{
+	{From: 1, Description: "tools scanned by probes move to [probes.<name>]", Apply: migrateToProbes},
+}
+
+// Migrations returns the registered migration chain.
+func Migrations() []Migration {
+	return migrations
+}
+
+// ManifestSchemaVersion returns the schema version recorded in a manifest's
+// [meta] table, or 1 when it has none.
+func ManifestSchemaVersion(data []byte) (int, error) {
+	var doc struct {
+		Meta struct {
+			SchemaVersion int `toml:"schema_version"`
+		} `toml:"meta"`
+	}
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return 0, err
+	}
+	if doc.Meta.SchemaVersion == 0 {
+		return 1, nil
+	}
+	return doc.Meta.SchemaVersion, nil
+}
+
+// MigrateManifest upgrades manifest TOML to CurrentSchemaVersion and returns
+// the migrated document together with the version it started at. Manifests
+// that are already current are returned unchanged; manifests from a newer
+// machinist are rejected rather than silently losing fields.
+func MigrateManifest(data []byte) ([]byte, int, error) {
+	return migrateTo(data, CurrentSchemaVersion, migrations)
+}
+
+// migrateTo applies chain to bring data up to schema version target.
+func migrateTo(data []byte, target int, chain []Migration) ([]byte, int, error) {
+	from, err := ManifestSchemaVersion(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if from > target {
+		return nil, from, fmt.Errorf("manifest schema version %d is newer than this machinist supports (%d); upgrade machinist", from, target)
+	}
+	if from == target {
+		return data, from, nil
+	}
+
+	var doc map[string]any
+	if _, err := toml.Decode(string(data), &doc); err != nil {
+		return nil, from, err
+	}
+	for v := from; v < target; v++ {
+		m, ok := migrationFrom(chain, v)
+		if !ok {
+			return nil, from, fmt.Errorf("no migration from schema version %d", v)
+		}
+		if err := m.Apply(doc); err != nil {
+			return nil, from, fmt.Errorf("migrate schema %d to %d: %w", v, v+1, err)
+		}
+	}
+	meta, _ := doc["meta"].(map[string]any)
+	if meta == nil {
+		meta = make(map[string]any)
+		doc["meta"] = meta
+	}
+	meta["schema_version"] = target
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+		return nil, from, err
+	}
+	return buf.Bytes(), from, nil
+}
+
+func migrationFrom(chain []Migration, v int) (Migration, bool) {
+	for _, m := range chain {
+		if m.From == v {
+			return m, true
+		}
+	}
+	return Migration{}, false
+}
+
+// probedSection describes a schema 1 section whose tool is now covered by a
+// built-in probe: the field that held its path, where the bundle stored it,
+// and the cask and checklist the probe definition adds.
+type probedSection struct {
+	field     string
+	dir       bool
+	bundleDir string
+	// bundleBase stores the path under bundleDir by its base name, as
+	// withFile did; otherwise it is stored as bundleDir itself.
+	bundleBase bool
+	sensitive  bool
+	cask       string
+	checklist  []string
+}
+
+var probedSections = map[string]probedSection{
+	"raycast":         {field: "export_file", dir: true, bundleDir: "configs/raycast", bundleBase: true, cask: "raycast", checklist: []string{"Grant Raycast accessibility permissions"}},
+	"alfred":          {field: "config_dir", dir: true, bundleDir: "configs/alfred", cask: "alfred", checklist: []string{"Point Alfred Preferences > Advanced > Set preferences folder at your synced preferences"}},
+	"karabiner":       {field: "config_dir", dir: true, bundleDir: "configs/karabiner", cask: "karabiner-elements", checklist: []string{"Grant Karabiner-Elements input monitoring permissions"}},
+	"rectangle":       {field: "config_file", bundleDir: "configs/rectangle", bundleBase: true, cask: "rectangle", checklist: []string{"Grant Rectangle accessibility permissions"}},
+	"bettertouchtool": {field: "config_file", dir: true, bundleDir: "configs/bettertouchtool", bundleBase: true, cask: "bettertouchtool", checklist: []string{"Grant BetterTouchTool accessibility permissions"}},
+	"vercel":          {field: "config_dir", dir: true, bundleDir: "configs/vercel", sensitive: true, checklist: []string{"Run `vercel login` to authenticate"}},
+	"flyio":           {field: "config_file", bundleDir: "configs/flyio", bundleBase: true, sensitive: true, checklist: []string{"Run `fly auth login` to re-authenticate"}},
+	"gcp":             {field: "config_dir", dir: true, bundleDir: "configs/gcp", sensitive: true, cask: "google-cloud-sdk", checklist: []string{"Run `gcloud auth login` and `gcloud auth application-default login`"}},
+}
+
+// migrateToProbes moves the sections of tools that became probes into
+// [probes.<name>]. Bundle paths keep pointing where schema 1 bundles stored
+// the files, so restoring from an existing bundle still works.
+func migrateToProbes(doc map[string]any) error {
+	for key, ps := range probedSections {
+		old, ok := doc[key].(map[string]any)
+		if !ok {
+			continue
+		}
+		delete(doc, key)
+		source, _ := old[ps.field].(string)
+		if source == "" {
+			continue
+		}
+		bundlePath := ps.bundleDir
+		if ps.bundleBase {
+			bundlePath = path.Join(ps.bundleDir, path.Base(filepath.ToSlash(source)))
+		}
+		entry := map[string]any{"source": source, "bundle_path": bundlePath}
+		if ps.sensitive {
+			entry["sensitive"] = true
+		}
+		probe := map[string]any{}
+		// Alfred fell back to its preferences plist when it had no folder.
+		if ps.dir && !strings.HasSuffix(source, ".plist") {
+			probe["config_dirs"] = []map[string]any{entry}
+		} else {
+			probe["config_files"] = []map[string]any{entry}
+		}
+		if ps.cask != "" {
+			probe["cask"] = ps.cask
+		}
+		probe["checklist"] = ps.checklist
+
+		probes, _ := doc["probes"].(map[string]any)
+		if probes == nil {
+			probes = make(map[string]any)
+			doc["probes"] = probes
+		}
+		if _, exists := probes[key]; !exists {
+			probes[key] = probe
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testChain reshapes docker.config_file into a list (v1 -> v2) and renames
+// ssh.keys to ssh.key_names (v2 -> v3).
+var testChain = []Migration{
+	{From: 1, Description: "docker config_file becomes a list", Apply: func(doc map[string]any) error {
+		docker, ok := doc["docker"].(map[string]any)
+		if !ok {
+			return nil
+		}
+		if f, ok := docker["config_file"].(string); ok {
+			docker["config_files"] = []any{f}
+			delete(docker, "config_file")
+		}
+		return nil
+	}},
+	{From: 2, Description: "rename ssh keys", Apply: func(doc map[string]any) error {
+		if ssh, ok := doc["ssh"].(map[string]any); ok {
+			ssh["key_names"] = ssh["keys"]
+			delete(ssh, "keys")
+		}
+		return nil
+	}},
+}
+
+const legacyManifest = `
+[meta]
+source_hostname = "old-mac"
+
+[docker]
+config_file = ".docker/config.json"
+`
+
+func TestManifestSchemaVersion(t *testing.T) {
+	v, err := ManifestSchemaVersion([]byte(legacyManifest))
+	require.NoError(t, err)
+	assert.Equal(t, 1, v, "manifests without schema_version are version 1")
+
+	v, err = ManifestSchemaVersion([]byte("[meta]\nschema_version = 4\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 4, v)
+
+	_, err = ManifestSchemaVersion([]byte("[meta"))
+	assert.Error(t, err)
+}
+
+func TestMigrateTo_RunsChain(t *testing.T) {
+	out, from, err := migrateTo([]byte(legacyManifest+"\n[ssh]\nkeys = [\"id_ed25519\"]\n"), 3, testChain)
+	require.NoError(t, err)
+	assert.Equal(t, 1, from)
+
+	v, err := ManifestSchemaVersion(out)
+	require.NoError(t, err)
+	assert.Equal(t, 3, v)
+	assert.Contains(t, string(out), `config_files = [".docker/config.json"]`)
+	assert.Contains(t, string(out), `key_names = ["id_ed25519"]`)
+	assert.Contains(t, string(out), `source_hostname = "old-mac"`)
+}
+
+func TestMigrateTo_StartsMidChain(t *testing.T) {
+	out, from, err := migrateTo([]byte("[meta]\nschema_version = 2\n[docker]\nconfig_file = \"x\"\n"), 3, testChain)
+	require.NoError(t, err)
+	assert.Equal(t, 2, from)
+	assert.Contains(t, string(out), `config_file = "x"`, "the v1 migration must not run again")
+}
+
+func TestMigrateTo_Errors(t *testing.T) {
+	_, _, err := migrateTo([]byte("[meta]\nschema_version = 5\n"), 3, testChain)
+	assert.ErrorContains(t, err, "newer")
+
+	_, _, err = migrateTo([]byte(legacyManifest), 3, testChain[:1])
+	assert.ErrorContains(t, err, "no migration from schema version 2")
+}
+
+func TestMigrateManifest_CurrentIsUnchanged(t *testing.T) {
+	data, err := MarshalManifest(&Snapshot{Meta: testMeta()})
+	require.NoError(t, err)
+
+	out, from, err := MigrateManifest(data)
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion, from)
+	assert.Equal(t, data, out)
+}
+
+func TestUnmarshalManifest_RecordsMigration(t *testing.T) {
+	snap, err := UnmarshalManifest([]byte("[meta]\nsource_hostname = \"old-mac\"\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, snap.Meta.MigratedFrom)
+	assert.Equal(t, CurrentSchemaVersion, snap.Meta.SchemaVersion)
+	assert.Equal(t, "old-mac", snap.Meta.SourceHostname)
+
+	data, err := MarshalManifest(snap)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "migrated")
+	snap, err = UnmarshalManifest(data)
+	require.NoError(t, err)
+	assert.Zero(t, snap.Meta.MigratedFrom)
+
+	_, err = UnmarshalManifest([]byte("[meta]\nschema_version = 999\n"))
+	assert.ErrorContains(t, err, "newer")
+}
+
+func TestMarshalManifest_StampsSchemaVersion(t *testing.T) {
+	data, err := MarshalManifest(&Snapshot{})
+	require.NoError(t, err)
+	v, err := ManifestSchemaVersion(data)
+	require.NoError(t, err)
+	assert.Equal(t, CurrentSchemaVersion, v)
+}
+
+func TestMigrations_FormChain(t *testing.T) {
+	for i, m := range Migrations() {
+		assert.Equal(t, i+1, m.From, "migration %d", i)
+		assert.NotEmpty(t, m.Description)
+		assert.NotNil(t, m.Apply)
+	}
+	assert.Len(t, Migrations(), CurrentSchemaVersion-1)
+}
+
+func TestMigrateManifest_ProbedSections(t *testing.T) {
+	legacy := `
+[meta]
+source_hostname = "old-mac"
+
+[raycast]
+export_file = "Library/Application Support/com.raycast.macos"
+
+[alfred]
+config_dir = "Library/Preferences/com.runningwithcrayons.Alfred-Preferences-3.plist"
+
+[gcp]
+config_dir = ".config/gcloud"
+
+[flyio]
+`
+	snap, err := UnmarshalManifest([]byte(legacy))
+	require.NoError(t, err)
+	require.Len(t, snap.Probes, 3)
+
+	raycast := snap.Probes["raycast"]
+	require.NotNil(t, raycast)
+	assert.Equal(t, []ConfigFile{{Source: "Library/Application Support/com.raycast.macos", BundlePath: "configs/raycast/com.raycast.macos"}}, raycast.ConfigDirs)
+	assert.Equal(t, "raycast", raycast.Cask)
+	assert.Equal(t, []string{"Grant Raycast accessibility permissions"}, raycast.Checklist)
+
+	alfred := snap.Probes["alfred"]
+	require.NotNil(t, alfred)
+	assert.Empty(t, alfred.ConfigDirs)
+	assert.Equal(t, []ConfigFile{{Source: "Library/Preferences/com.runningwithcrayons.Alfred-Preferences-3.plist", BundlePath: "configs/alfred"}}, alfred.ConfigFiles)
+
+	gcp := snap.Probes["gcp"]
+	require.NotNil(t, gcp)
+	assert.Equal(t, []ConfigFile{{Source: ".config/gcloud", BundlePath: "configs/gcp", Sensitive: true}}, gcp.ConfigDirs)
+	assert.Equal(t, "google-cloud-sdk", gcp.Cask)
+}
@@ -0,0 +1,435 @@
+package domain
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+)
+
+// Section describes one manifest section in a single place: its TOML key,
+// the restore group and stage that render it, the files the bundler has to
+// collect for it and how profiles merge it. ApplyResult, StageCount,
+// profile merging, the bundler and the MCP tools all iterate Sections().
+type Section struct {
+	Key      string // TOML key, e.g. "github_cli"
+	Group    string // restore group name, e.g. "configs"
+	Stage    string // run_stage label; sections sharing a label share one stage
+	Template string // stage template name, e.g. "github-cli"
+
+	// scanner is the name of the scanner that produces the section and
+	// category that scanner's category. Named sections are produced by the
+	// scanner each entry is named after, and category is the default for
+	// entries that do not record their own.
+	scanner  string
+	category string
+
+	typ       reflect.Type // *T for the section type
+	present   func(*Snapshot) bool
+	value     func(*Snapshot) any
+	assign    func(snap *Snapshot, name string, v any)
+	merge     func(dst, override *Snapshot) []Conflict
+	stageData func(*Snapshot) any
+	files     func(*Snapshot) []ConfigFile
+	dirs      func(*Snapshot) []ConfigFile
+	// entryCategories returns the categories entries of a named section
+	// record, with "" for those that record none.
+	entryCategories func(*Snapshot) []string
+}
+
+// Present reports whether the section is populated in snap.
+func (s Section) Present(snap *Snapshot) bool { return s.present(snap) }
+
+// Value returns the section stored in snap (a *T, or a map[string]*T for
+// named sections), or nil when it is not populated.
+func (s Section) Value(snap *Snapshot) any {
+	if !s.present(snap) {
+		return nil
+	}
+	return s.value(snap)
+}
+
+// Merge deep-merges override's section into dst: lists are unioned by their
+// natural key, tables merged field by field and non-zero scalars override.
+// override.Meta.Merge can pick another MergeStrategy per key path. It returns
+// the values override changed from one non-empty value to another.
+func (s Section) Merge(dst, override *Snapshot) []Conflict { return s.merge(dst, override) }
+
+// ConfigFiles returns the files the bundler copies for this section.
+func (s Section) ConfigFiles(snap *Snapshot) []ConfigFile {
+	if s.files == nil || !s.present(snap) {
+		return nil
+	}
+	return s.files(snap)
+}
+
+// ConfigDirs returns the directories the bundler copies for this section.
+// Source is the directory relative to home, BundlePath its bundle location.
+func (s Section) ConfigDirs(snap *Snapshot) []ConfigFile {
+	if s.dirs == nil || !s.present(snap) {
+		return nil
+	}
+	return s.dirs(snap)
+}
+
+// Stage is one run_stage call in a restore script.
+type Stage struct {
+	Label    string // e.g. "Git Configuration"
+	Func     string // shell function name, e.g. "do_git_config"
+	Template string // stage template to render
+	Data     any    // value passed to the stage template
+}
+
+// Sections returns every registered section in restore order.
+func Sections() []Section { return sections }
+
+// SectionByKey finds a section by its TOML key.
+func SectionByKey(key string) (Section, bool) {
+	for _, s := range sections {
+		if s.Key == key {
+			return s, true
+		}
+	}
+	return Section{}, false
+}
+
+// AssignSection stores v, a section value produced by a scanner, in snap.
+// name is the scanner name, used as the key for named sections such as
+// plugins. It returns false if v's type is not a registered section.
+func AssignSection(snap *Snapshot, name string, v any) bool {
+	s, ok := sectionsByType[reflect.TypeOf(v)]
+	if !ok || reflect.ValueOf(v).IsNil() {
+		return false
+	}
+	s.assign(snap, name, v)
+	return true
+}
+
+// stages returns the stages for the populated sections of a group, in
+// restore order. Sections that share a stage label produce one stage.
+func stages(snap *Snapshot, group string) []Stage {
+	var out []Stage
+	seen := make(map[string]bool)
+	for _, s := range sections {
+		if s.Group != group || !s.present(snap) || seen[s.Stage] {
+			continue
+		}
+		seen[s.Stage] = true
+		out = append(out, Stage{
+			Label:    s.Stage,
+			Func:     "do_" + shellName(s.Template),
+			Template: s.Template,
+			Data:     s.stageData(snap),
+		})
+	}
+	return out
+}
+
+// shellName turns a template name like "git-config" into "git_config".
+func shellName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c == '-' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// sectionOptions holds the optional behaviour of a section of type T.
+type sectionOptions[T any] struct {
+	files func(*T) []ConfigFile
+	dirs  func(*T) []ConfigFile
+}
+
+type sectionOption[T any] func(*sectionOptions[T])
+
+// withFiles collects config files returned by f.
+func withFiles[T any](f func(*T) []ConfigFile) sectionOption[T] {
+	return func(o *sectionOptions[T]) { o.files = f }
+}
+
+// withFile collects the single config file path returned by f, bundled as
+// configs/<bundleDir>/<base name>.
+func withFile[T any](bundleDir string, f func(*T) string) sectionOption[T] {
+	return withFiles(func(s *T) []ConfigFile {
+		source := f(s)
+		if source == "" {
+			return nil
+		}
+		return []ConfigFile{bundledFile(source, bundleDir)}
+	})
+}
+
+// withDirs collects config directories returned by f.
+func withDirs[T any](f func(*T) []ConfigFile) sectionOption[T] {
+	return func(o *sectionOptions[T]) { o.dirs = f }
+}
+
+// withDir collects the single config directory returned by f, bundled as
+// configs/<bundleDir>.
+func withDir[T any](bundleDir string, f func(*T) string) sectionOption[T] {
+	return withDirs(func(s *T) []ConfigFile {
+		source := f(s)
+		if source == "" {
+			return nil
+		}
+		return []ConfigFile{{Source: source, BundlePath: filepath.Join("configs", bundleDir)}}
+	})
+}
+
+// bundledFile wraps a config file path with its bundle location.
+func bundledFile(source, bundleDir string) ConfigFile {
+	return ConfigFile{
+		Source:     source,
+		BundlePath: filepath.Join("configs", bundleDir, filepath.Base(source)),
+	}
+}
+
+// section registers a Snapshot section stored as a *T field.
+func section[T any](key, group, stage, tmpl string, field func(*Snapshot) **T, opts ...sectionOption[T]) Section {
+	var o sectionOptions[T]
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := Section{
+		Key:      key,
+		Group:    group,
+		Stage:    stage,
+		Template: tmpl,
+		typ:      reflect.TypeFor[*T](),
+		present:  func(snap *Snapshot) bool { return *field(snap) != nil },
+		value:    func(snap *Snapshot) any { return *field(snap) },
+		assign:   func(snap *Snapshot, _ string, v any) { *field(snap) = v.(*T) },
+	}
+	s.stageData = s.value
+	s.merge = func(dst, override *Snapshot) []Conflict {
+		var conflicts []Conflict
+		m := merger{rules: override.Meta.Merge, conflicts: &conflicts}
+		*field(dst) = m.merge(key, reflect.ValueOf(*field(dst)), reflect.ValueOf(*field(override))).Interface().(*T)
+		return conflicts
+	}
+	if o.files != nil {
+		s.files = func(snap *Snapshot) []ConfigFile { return o.files(*field(snap)) }
+	}
+	if o.dirs != nil {
+		s.dirs = func(snap *Snapshot) []ConfigFile { return o.dirs(*field(snap)) }
+	}
+	return s
+}
+
+// namedSection registers a Snapshot section stored as map[string]*T, one
+// entry per scanner name (e.g. [plugins.<name>]). Profiles merge it per name.
+func namedSection[T any](key, group, stage, tmpl string, field func(*Snapshot) *map[string]*T, opts ...sectionOption[T]) Section {
+	var o sectionOptions[T]
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := Section{
+		Key:      key,
+		Group:    group,
+		Stage:    stage,
+		Template: tmpl,
+		typ:      reflect.TypeFor[*T](),
+		present:  func(snap *Snapshot) bool { return len(*field(snap)) > 0 },
+		value:    func(snap *Snapshot) any { return *field(snap) },
+		assign: func(snap *Snapshot, name string, v any) {
+			m := field(snap)
+			if *m == nil {
+				*m = make(map[string]*T)
+			}
+			(*m)[name] = v.(*T)
+		},
+		merge: func(dst, override *Snapshot) []Conflict {
+			var conflicts []Conflict
+			m := merger{rules: override.Meta.Merge, conflicts: &conflicts}
+			*field(dst) = m.merge(key, reflect.ValueOf(*field(dst)), reflect.ValueOf(*field(override))).Interface().(map[string]*T)
+			return conflicts
+		},
+	}
+	s.stageData = s.value
+	s.entryCategories = func(snap *Snapshot) []string {
+		var out []string
+		for _, v := range *field(snap) {
+			if c, ok := any(v).(categorized); ok && v != nil {
+				out = append(out, c.entryCategory())
+			} else {
+				out = append(out, "")
+			}
+		}
+		return out
+	}
+	each := func(snap *Snapshot, f func(*T) []ConfigFile) []ConfigFile {
+		m := *field(snap)
+		names := make([]string, 0, len(m))
+		for name := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var out []ConfigFile
+		for _, name := range names {
+			out = append(out, f(m[name])...)
+		}
+		return out
+	}
+	if o.files != nil {
+		s.files = func(snap *Snapshot) []ConfigFile { return each(snap, o.files) }
+	}
+	if o.dirs != nil {
+		s.dirs = func(snap *Snapshot) []ConfigFile { return each(snap, o.dirs) }
+	}
+	return s
+}
+
+// Scanner returns the name of the scanner that produces the section, e.g.
+// "git-config", or "" for named sections, whose entries are produced by the
+// scanner each is named after.
+func (s Section) Scanner() string { return s.scanner }
+
+// from records the scanner that produces the section and its category.
+func (s Section) from(scanner, category string) Section {
+	s.scanner, s.category = scanner, category
+	return s
+}
+
+// categorized is a named section entry that records the category of the
+// scanner that produced it.
+type categorized interface{ entryCategory() string }
+
+// wholeSnapshot makes a section's stage template receive the whole
+// Snapshot, for stages that render several sections together.
+func wholeSnapshot(s Section) Section {
+	s.stageData = func(snap *Snapshot) any { return snap }
+	return s
+}
+
+// sections lists every manifest section in restore order: by group, then
+// by stage within the group.
+var sections = []Section{
+	// 01 — Homebrew
+	section("homebrew", "homebrew", "Homebrew", "homebrew", func(s *Snapshot) **HomebrewSection { return &s.Homebrew }).from("homebrew", "packages"),
+
+	// 02 — Secrets
+	section("ssh", "secrets", "SSH Keys", "ssh", func(s *Snapshot) **SSHSection { return &s.SSH }).from("ssh", "security"),
+	section("gpg", "secrets", "GPG Keys", "gpg", func(s *Snapshot) **GPGSection { return &s.GPG },
+		withFiles(func(s *GPGSection) []ConfigFile { return s.ConfigFiles })).from("gpg", "security"),
+	section("env_files", "secrets", "Environment Files", "env-files", func(s *Snapshot) **EnvFilesSection { return &s.EnvFiles }).from("env-files", "tools"),
+
+	// 03 — Config files
+	section("git", "configs", "Git Configuration", "git-config", func(s *Snapshot) **GitSection { return &s.Git },
+		withFiles(func(s *GitSection) []ConfigFile { return s.ConfigFiles })).from("git-config", "git"),
+	section("github_cli", "configs", "GitHub CLI", "github-cli", func(s *Snapshot) **GitHubCLISection { return &s.GitHubCLI },
+		withDir("github-cli", func(s *GitHubCLISection) string { return s.ConfigDir })).from("github-cli", "git"),
+	section("shell", "configs", "Shell Configuration", "shell", func(s *Snapshot) **ShellSection { return &s.Shell },
+		withFiles(func(s *ShellSection) []ConfigFile { return s.ConfigFiles })).from("shell", "shell"),
+	section("terminal", "configs", "Terminal Emulator", "terminal", func(s *Snapshot) **TerminalSection { return &s.Terminal },
+		withFiles(func(s *TerminalSection) []ConfigFile { return s.ConfigFiles })).from("terminal", "shell"),
+	section("tmux", "configs", "tmux", "tmux", func(s *Snapshot) **TmuxSection { return &s.Tmux },
+		withFiles(func(s *TmuxSection) []ConfigFile { return s.ConfigFiles })).from("tmux", "shell"),
+	section("vscode", "configs", "Visual Studio Code", "vscode", func(s *Snapshot) **VSCodeSection { return &s.VSCode },
+		withFiles(func(s *VSCodeSection) []ConfigFile { return s.ConfigFiles })).from("vscode", "editors"),
+	section("cursor", "configs", "Cursor", "cursor", func(s *Snapshot) **CursorSection { return &s.Cursor },
+		withFiles(func(s *CursorSection) []ConfigFile { return s.ConfigFiles })).from("cursor", "editors"),
+	section("neovim", "configs", "Neovim", "neovim", func(s *Snapshot) **NeovimSection { return &s.Neovim },
+		withDir("neovim", func(s *NeovimSection) string { return s.ConfigDir })).from("neovim", "editors"),
+	section("jetbrains", "configs", "JetBrains IDEs", "jetbrains", func(s *Snapshot) **JetBrainsSection { return &s.JetBrains }).from("jetbrains", "editors"),
+	section("xcode", "configs", "Xcode", "xcode", func(s *Snapshot) **XcodeSection { return &s.Xcode },
+		withFiles(func(s *XcodeSection) []ConfigFile { return s.ConfigFiles })).from("xcode", "editors"),
+	section("docker", "configs", "Docker", "docker", func(s *Snapshot) **DockerSection { return &s.Docker },
+		withFile("docker", func(s *DockerSection) string { return s.ConfigFile })).from("docker", "cloud"),
+	section("aws", "configs", "AWS CLI", "aws", func(s *Snapshot) **AWSSection { return &s.AWS },
+		withFile("aws", func(s *AWSSection) string { return s.ConfigFile })).from("aws", "cloud"),
+	section("kubernetes", "configs", "Kubernetes", "kubernetes", func(s *Snapshot) **KubernetesSection { return &s.Kubernetes },
+		withFile("kubernetes", func(s *KubernetesSection) string { return s.ConfigFile })).from("kubernetes", "cloud"),
+	section("terraform", "configs", "Terraform", "terraform", func(s *Snapshot) **TerraformSection { return &s.Terraform },
+		withFile("terraform", func(s *TerraformSection) string { return s.ConfigFile })).from("terraform", "cloud"),
+	section("azure", "configs", "Azure", "azure", func(s *Snapshot) **AzureSection { return &s.Azure },
+		withDir("azure", func(s *AzureSection) string { return s.ConfigDir })).from("azure", "cloud"),
+	section("firebase", "configs", "Firebase", "firebase", func(s *Snapshot) **FirebaseSection { return &s.Firebase },
+		withDir("firebase", func(s *FirebaseSection) string { return s.ConfigDir })).from("firebase", "cloud"),
+	section("cloudflare", "configs", "Cloudflare", "cloudflare", func(s *Snapshot) **CloudflareSection { return &s.CloudflareWrangler },
+		withDir("cloudflare", func(s *CloudflareSection) string { return s.ConfigDir })).from("cloudflare", "cloud"),
+	section("onepassword", "configs", "1Password CLI", "onepassword", func(s *Snapshot) **OnePasswordSection { return &s.OnePassword },
+		withDir("onepassword", func(s *OnePasswordSection) string { return s.ConfigDir })).from("1password", "tools"),
+	section("ai_tools", "configs", "AI Tools", "ai-tools", func(s *Snapshot) **AIToolsSection { return &s.AITools },
+		withFile("ai-tools", func(s *AIToolsSection) string { return s.ClaudeCodeConfig })).from("ai-tools", "tools"),
+	section("api_tools", "configs", "API Tools", "api-tools", func(s *Snapshot) **APIToolsSection { return &s.APITools },
+		withFiles(func(s *APIToolsSection) []ConfigFile { return s.ConfigFiles })).from("api-tools", "tools"),
+	section("xdg_config", "configs", "XDG Config", "xdg-config", func(s *Snapshot) **XDGConfigSection { return &s.XDGConfig },
+		withDirs(xdgConfigDirs)).from("xdg-config", "tools"),
+	section("databases", "configs", "Database Clients", "databases", func(s *Snapshot) **DatabasesSection { return &s.Databases },
+		withFiles(func(s *DatabasesSection) []ConfigFile { return s.ConfigFiles })).from("databases", "tools"),
+	section("registries", "configs", "Package Registries", "registries", func(s *Snapshot) **RegistriesSection { return &s.Registries },
+		withFiles(func(s *RegistriesSection) []ConfigFile { return s.ConfigFiles })).from("registries", "tools"),
+	section("fonts", "configs", "Fonts", "fonts", func(s *Snapshot) **FontsSection { return &s.Fonts },
+		withFiles(customFontFiles)).from("fonts", "system"),
+	section("folders", "configs", "Folder Structure", "folders", func(s *Snapshot) **FoldersSection { return &s.Folders }).from("folders", "system"),
+	section("browser", "configs", "Browser", "browser", func(s *Snapshot) **BrowserSection { return &s.Browser }).from("browser", "tools"),
+	section("login_items", "configs", "Login Items", "login-items", func(s *Snapshot) **LoginItemsSection { return &s.LoginItems }).from("login-items", "system"),
+	namedSection("plugins", "configs", "Scanner Plugins", "plugins", func(s *Snapshot) *map[string]*PluginSection { return &s.Plugins },
+		withFiles(func(s *PluginSection) []ConfigFile { return s.ConfigFiles })).from("", "plugins"),
+	namedSection("probes", "configs", "Probed Tools", "probes", func(s *Snapshot) *map[string]*ProbeSection { return &s.Probes },
+		withFiles(func(s *ProbeSection) []ConfigFile { return s.ConfigFiles }),
+		withDirs(func(s *ProbeSection) []ConfigFile { return s.ConfigDirs })).from("", "tools"),
+
+	// 04 — Runtimes
+	section("node", "runtimes", "Node.js", "node", func(s *Snapshot) **NodeSection { return &s.Node }).from("node", "runtimes"),
+	section("python", "runtimes", "Python", "python", func(s *Snapshot) **PythonSection { return &s.Python }).from("python", "runtimes"),
+	section("rust", "runtimes", "Rust", "rust", func(s *Snapshot) **RustSection { return &s.Rust }).from("rust", "runtimes"),
+	section("java", "runtimes", "Java/SDKMAN", "java", func(s *Snapshot) **JavaSection { return &s.Java }).from("java", "runtimes"),
+	section("flutter", "runtimes", "Flutter", "flutter", func(s *Snapshot) **FlutterSection { return &s.Flutter }).from("flutter", "runtimes"),
+	section("go", "runtimes", "Go", "go-runtime", func(s *Snapshot) **GoSection { return &s.Go }).from("go", "runtimes"),
+	section("ruby", "runtimes", "Ruby", "ruby", func(s *Snapshot) **RubySection { return &s.Ruby }).from("ruby", "runtimes"),
+	section("deno", "runtimes", "Deno", "deno", func(s *Snapshot) **DenoSection { return &s.Deno }).from("deno", "runtimes"),
+	section("bun", "runtimes", "Bun", "bun", func(s *Snapshot) **BunSection { return &s.Bun }).from("bun", "runtimes"),
+	section("asdf", "runtimes", "asdf/mise", "asdf", func(s *Snapshot) **AsdfSection { return &s.Asdf }).from("asdf", "runtimes"),
+
+	// 05 — Repositories
+	section("git_repos", "repos", "Git Repositories", "git-repos", func(s *Snapshot) **GitReposSection { return &s.GitRepos }).from("git-repos", "git"),
+
+	// 06 — macOS settings
+	section("macos_defaults", "macos", "macOS Defaults", "macos-defaults", func(s *Snapshot) **MacOSDefaultsSection { return &s.MacOSDefaults }).from("macos-defaults", "system"),
+	section("apps", "macos", "Mac App Store Apps", "apps", func(s *Snapshot) **AppsSection { return &s.Apps }).from("apps", "system"),
+	wholeSnapshot(section("crontab", "macos", "Scheduled Tasks", "scheduled", func(s *Snapshot) **CrontabSection { return &s.Crontab })).from("scheduled", "system"),
+	wholeSnapshot(section("launchagents", "macos", "Scheduled Tasks", "scheduled", func(s *Snapshot) **LaunchAgentsSection { return &s.LaunchAgents },
+		withFiles(func(s *LaunchAgentsSection) []ConfigFile { return s.Plists }))).from("scheduled", "system"),
+	section("locale", "macos", "Locale & Timezone", "locale", func(s *Snapshot) **LocaleSection { return &s.Locale }).from("locale", "system"),
+	section("hosts_file", "macos", "Hosts File", "hosts-file", func(s *Snapshot) **HostsFileSection { return &s.HostsFile }).from("hosts-file", "system"),
+	section("network", "macos", "Network", "network", func(s *Snapshot) **NetworkSection { return &s.Network },
+		withFiles(func(s *NetworkSection) []ConfigFile { return s.VPNConfigs })).from("network", "system"),
+}
+
+// sectionsByType maps each section's *T type to its registration.
+var sectionsByType = func() map[reflect.Type]Section {
+	m := make(map[reflect.Type]Section, len(sections))
+	for _, s := range sections {
+		m[s.typ] = s
+	}
+	return m
+}()
+
+// xdgConfigDirs bundles ~/.config itself when captured, plus every
+// auto-detected tool directory individually.
+func xdgConfigDirs(s *XDGConfigSection) []ConfigFile {
+	var dirs []ConfigFile
+	if s.ConfigDir != "" {
+		dirs = append(dirs, ConfigFile{Source: s.ConfigDir, BundlePath: filepath.Join("configs", "xdg-config")})
+	}
+	for _, name := range s.AutoDetected {
+		dirs = append(dirs, ConfigFile{
+			Source:     filepath.Join(".config", name),
+			BundlePath: filepath.Join("configs", "xdg-config", name),
+		})
+	}
+	return dirs
+}
+
+// customFontFiles bundles the custom fonts that have a bundle path.
+func customFontFiles(s *FontsSection) []ConfigFile {
+	var files []ConfigFile
+	for _, font := range s.CustomFonts {
+		if font.BundlePath != "" {
+			files = append(files, bundledFile(font.BundlePath, "fonts"))
+		}
+	}
+	return files
+}
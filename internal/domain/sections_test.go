@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSections_CoverSnapshot makes sure every Snapshot section field is
+// registered exactly once, so no section is left out of merge or bundling.
+func TestSections_CoverSnapshot(t *testing.T) {
+	registered := make(map[string]int)
+	for _, s := range Sections() {
+		registered[s.Key]++
+	}
+
+	st := reflect.TypeOf(Snapshot{})
+	for i := 0; i < st.NumField(); i++ {
+		f := st.Field(i)
+		if f.Name == "Meta" || f.Name == "Profile" {
+			continue
+		}
+		key := strings.Split(f.Tag.Get("toml"), ",")[0]
+		switch registered[key] {
+		case 1:
+		case 0:
+			t.Errorf("Snapshot.%s (%s) is not registered in Sections()", f.Name, key)
+		default:
+			t.Errorf("Snapshot.%s (%s) is registered %d times", f.Name, key, registered[key])
+		}
+		delete(registered, key)
+	}
+	for key := range registered {
+		t.Errorf("section %s has no Snapshot field", key)
+	}
+}
+
+func TestSections_ValueMatchesSnapshotField(t *testing.T) {
+	snap := &Snapshot{Docker: &DockerSection{Runtime: "colima"}}
+	s, ok := SectionByKey("docker")
+	if !ok {
+		t.Fatal("docker section not registered")
+	}
+	if s.Value(snap) != snap.Docker {
+		t.Error("Value should return the Snapshot's Docker section")
+	}
+	if v := s.Value(&Snapshot{}); v != nil {
+		t.Errorf("Value of an empty section = %v, want nil", v)
+	}
+}
+
+func TestAssignSection(t *testing.T) {
+	snap := &Snapshot{}
+	goSection := &GoSection{Version: "1.22"}
+	if !AssignSection(snap, "go", goSection) {
+		t.Fatal("AssignSection rejected a registered section type")
+	}
+	if snap.Go != goSection {
+		t.Error("expected Snapshot.Go to be set")
+	}
+
+	plugin := &PluginSection{Restore: []string{"corp login"}}
+	AssignSection(snap, "corp", plugin)
+	if snap.Plugins["corp"] != plugin {
+		t.Error("expected plugin to be stored under its scanner name")
+	}
+
+	if AssignSection(snap, "x", &Meta{}) {
+		t.Error("AssignSection should reject unregistered types")
+	}
+	if AssignSection(snap, "x", (*GoSection)(nil)) {
+		t.Error("AssignSection should ignore nil sections")
+	}
+}
+
+func TestSection_MergeDefaultReplaces(t *testing.T) {
+	dst := &Snapshot{Node: &NodeSection{Manager: "nvm"}}
+	override := &Snapshot{Node: &NodeSection{Manager: "fnm"}}
+	s, _ := SectionByKey("node")
+	s.Merge(dst, override)
+	if dst.Node.Manager != "fnm" {
+		t.Errorf("Manager = %q, want fnm", dst.Node.Manager)
+	}
+
+	s.Merge(dst, &Snapshot{})
+	if dst.Node == nil {
+		t.Error("an empty override must not clear the section")
+	}
+}
+
+func TestSection_ConfigFilesAndDirs(t *testing.T) {
+	snap := &Snapshot{
+		Docker:    &DockerSection{ConfigFile: ".docker/config.json"},
+		XDGConfig: &XDGConfigSection{AutoDetected: []string{"bat"}},
+	}
+
+	docker, _ := SectionByKey("docker")
+	files := docker.ConfigFiles(snap)
+	if len(files) != 1 || files[0].BundlePath != "configs/docker/config.json" {
+		t.Errorf("docker config files = %+v", files)
+	}
+
+	xdg, _ := SectionByKey("xdg_config")
+	dirs := xdg.ConfigDirs(snap)
+	if len(dirs) != 1 || dirs[0].Source != ".config/bat" || dirs[0].BundlePath != "configs/xdg-config/bat" {
+		t.Errorf("xdg config dirs = %+v", dirs)
+	}
+
+	if files := docker.ConfigFiles(&Snapshot{}); files != nil {
+		t.Errorf("empty section should have no config files, got %+v", files)
+	}
+}
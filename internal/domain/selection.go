@@ -0,0 +1,144 @@
+package domain
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CategoryPrefix marks a stage selector that picks every stage of a scanner
+// category, e.g. "category:cloud".
+const CategoryPrefix = "category:"
+
+// Category returns the category of the scanner that produces the section,
+// e.g. "cloud". For named sections such as probes it is the category of
+// entries that do not record their own.
+func (s Section) Category() string { return s.category }
+
+// Categories returns the categories the section has in snap: its
+// Category, or for a named section the categories its entries record.
+// With a nil snap it returns Category alone.
+func (s Section) Categories(snap *Snapshot) []string {
+	if snap == nil || s.entryCategories == nil || !s.present(snap) {
+		return []string{s.category}
+	}
+	var out []string
+	for _, c := range s.entryCategories(snap) {
+		if c == "" {
+			c = s.category
+		}
+		if !slices.Contains(out, c) {
+			out = append(out, c)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Categories returns the section categories, and those the named sections
+// of snap record when snap is not nil, sorted.
+func Categories(snap *Snapshot) []string {
+	var out []string
+	for _, s := range sections {
+		for _, c := range s.Categories(snap) {
+			if !slices.Contains(out, c) {
+				out = append(out, c)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// MatchStages returns the names of the stages selector picks, in restore
+// order. Stages are named after their template, e.g. "git-config". A
+// selector is a restore group name, which picks all its stages, a stage
+// name or section key ("git"), a glob over those ("*-tools"), or
+// CategoryPrefix followed by a category. An unknown name matches nothing.
+// Named sections such as probes match the categories their entries in snap
+// record; snap may be nil.
+func MatchStages(snap *Snapshot, selector string) ([]string, error) {
+	var match func(Section) bool
+	if category, ok := strings.CutPrefix(selector, CategoryPrefix); ok {
+		if known := Categories(snap); !slices.Contains(known, category) {
+			return nil, fmt.Errorf("unknown category %q (valid: %s)", category, strings.Join(known, ", "))
+		}
+		match = func(s Section) bool { return slices.Contains(s.Categories(snap), category) }
+	} else {
+		if _, err := path.Match(selector, ""); err != nil {
+			return nil, fmt.Errorf("invalid stage pattern %q: %w", selector, err)
+		}
+		match = func(s Section) bool {
+			if s.Group == selector {
+				return true
+			}
+			for _, name := range []string{s.Template, s.Key} {
+				if ok, _ := path.Match(selector, name); ok {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	var names []string
+	for _, s := range sections {
+		if match(s) && !slices.Contains(names, s.Template) {
+			names = append(names, s.Template)
+		}
+	}
+	return names, nil
+}
+
+// SelectStages returns the names of the stages restore runs for snap, in
+// restore order: those picked by only, or, when only is empty, all but
+// those picked by skip. Selectors are described at MatchStages. A selector
+// that names no stage, or only stages snap has no data for, is an error.
+func SelectStages(snap *Snapshot, only, skip []string) ([]string, error) {
+	var present []string
+	for _, g := range restoreGroups {
+		for _, st := range g.Stages(snap) {
+			present = append(present, st.Template)
+		}
+	}
+
+	selectors := only
+	if len(only) == 0 {
+		selectors = skip
+	}
+	picked := make(map[string]bool)
+	var unknown, absent []string
+	for _, sel := range selectors {
+		names, err := MatchStages(snap, sel)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			unknown = append(unknown, sel)
+			continue
+		}
+		found := false
+		for _, name := range names {
+			picked[name] = true
+			found = found || slices.Contains(present, name)
+		}
+		if !found {
+			absent = append(absent, sel)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown stage(s): %s (see machinist restore --list)", strings.Join(unknown, ", "))
+	}
+	if len(absent) > 0 {
+		return nil, fmt.Errorf("not in the manifest: %s (see machinist restore --list <manifest>)", strings.Join(absent, ", "))
+	}
+
+	var out []string
+	for _, name := range present {
+		if len(selectors) == 0 || picked[name] == (len(only) > 0) {
+			out = append(out, name)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSections_HaveCategories(t *testing.T) {
+	for _, s := range Sections() {
+		if s.Category() == "" {
+			t.Errorf("section %q has no category", s.Key)
+		}
+		if named := s.entryCategories != nil; named != (s.Scanner() == "") {
+			t.Errorf("section %q has scanner %q", s.Key, s.Scanner())
+		}
+	}
+}
+
+func TestMatchStages(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"vscode", []string{"vscode"}},
+		{"git", []string{"git-config"}},
+		{"go", []string{"go-runtime"}},
+		{"launchagents", []string{"scheduled"}},
+		{"secrets", []string{"ssh", "gpg", "env-files"}},
+		{"*-tools", []string{"ai-tools", "api-tools"}},
+		{"category:editors", []string{"vscode", "cursor", "neovim", "jetbrains", "xcode"}},
+		{"pip", nil},
+	}
+	for _, tt := range tests {
+		got, err := MatchStages(nil, tt.selector)
+		if err != nil {
+			t.Fatalf("MatchStages(%q): %v", tt.selector, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MatchStages(%q) = %v, want %v", tt.selector, got, tt.want)
+		}
+	}
+}
+
+func TestMatchStages_Invalid(t *testing.T) {
+	if _, err := MatchStages(nil, "category:games"); err == nil || !strings.Contains(err.Error(), `unknown category "games"`) {
+		t.Errorf("expected an unknown category error, got %v", err)
+	}
+	if _, err := MatchStages(nil, "[vscode"); err == nil {
+		t.Error("expected an invalid pattern error")
+	}
+}
+
+func selectionSnapshot() *Snapshot {
+	return &Snapshot{
+		Homebrew:   &HomebrewSection{},
+		Git:        &GitSection{},
+		VSCode:     &VSCodeSection{},
+		Kubernetes: &KubernetesSection{},
+		Node:       &NodeSection{},
+	}
+}
+
+func TestSelectStages(t *testing.T) {
+	snap := selectionSnapshot()
+	tests := []struct {
+		name       string
+		only, skip []string
+		want       []string
+	}{
+		{"all", nil, nil, []string{"homebrew", "git-config", "vscode", "kubernetes", "node"}},
+		{"only", []string{"git", "category:cloud"}, nil, []string{"git-config", "kubernetes"}},
+		{"skip", nil, []string{"homebrew", "vscode"}, []string{"git-config", "kubernetes", "node"}},
+		{"skip group", nil, []string{"configs"}, []string{"homebrew", "node"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectStages(snap, tt.only, tt.skip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectStages_EntryCategories(t *testing.T) {
+	snap := &Snapshot{
+		Git: &GitSection{},
+		Probes: map[string]*ProbeSection{
+			"warp":   {Category: "tools"},
+			"zed":    {Category: "editors"},
+			"legacy": {},
+		},
+	}
+	got, err := SelectStages(snap, []string{"category:tools"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{"probes"}) {
+		t.Errorf("got %v, want [probes]", got)
+	}
+	if _, err := SelectStages(snap, []string{"category:editors"}, nil); err != nil {
+		t.Errorf("expected a probe category to be selectable, got %v", err)
+	}
+	for _, s := range Sections() {
+		if s.Key == "probes" && !reflect.DeepEqual(s.Categories(snap), []string{"editors", "tools"}) {
+			t.Errorf("probe categories = %v, want [editors tools]", s.Categories(snap))
+		}
+	}
+}
+
+func TestSelectStages_Validates(t *testing.T) {
+	snap := selectionSnapshot()
+	_, err := SelectStages(snap, []string{"vscode", "pip", "npmrc"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown stage(s): pip, npmrc") {
+		t.Errorf("expected unknown stages to be reported, got %v", err)
+	}
+	_, err = SelectStages(snap, nil, []string{"fonts"})
+	if err == nil || !strings.Contains(err.Error(), "not in the manifest: fonts") {
+		t.Errorf("expected a stage without data to be reported, got %v", err)
+	}
+}
@@ -1,5 +1,7 @@
 package domain
 
+import "fmt"
+
 // Sensitivity represents the security classification of data collected by scanners.
 type Sensitivity int
 
@@ -25,3 +27,18 @@ func (s Sensitivity) String() string {
 		return "unknown"
 	}
 }
+
+// ParseSensitivity converts the lowercase name produced by String back to a
+// Sensitivity. An empty string means Public.
+func ParseSensitivity(s string) (Sensitivity, error) {
+	switch s {
+	case "", "public":
+		return Public, nil
+	case "sensitive":
+		return Sensitive, nil
+	case "secret":
+		return Secret, nil
+	default:
+		return Public, fmt.Errorf("unknown sensitivity %q", s)
+	}
+}
@@ -1,21 +1,86 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // Meta contains metadata about the snapshot: when, where, and how it was created.
 type Meta struct {
+	// SchemaVersion is the manifest layout version; see CurrentSchemaVersion.
+	SchemaVersion    int       `toml:"schema_version"`
 	CreatedAt        time.Time `toml:"created_at"`
 	SourceHostname   string    `toml:"source_hostname"`
 	SourceOSVersion  string    `toml:"source_os_version"`
 	SourceArch       string    `toml:"source_arch"`
 	MachinistVersion string    `toml:"machinist_version"`
 	ScanDurationSecs float64   `toml:"scan_duration_secs"`
+	// Extends lists the manifests this one builds on, merged in order
+	// before its own sections: "profile:<name>" for a built-in profile or a
+	// file path relative to this manifest.
+	Extends []string `toml:"extends,omitempty"`
+	// Merge sets how this manifest's values combine with the layers it
+	// extends, per dotted key path, e.g. "vscode.extensions" = "replace".
+	// Unlisted keys are deep-merged.
+	Merge map[string]MergeStrategy `toml:"merge,omitempty" enum:"append,replace,remove"`
+	// Layers records the profiles and files a composed manifest was built
+	// from, in merge order.
+	Layers []Layer `toml:"layers,omitempty"`
+	// Diagnostics lists the problems scanners ran into, so a missing or
+	// partial section can be explained.
+	Diagnostics []Diagnostic `toml:"diagnostics,omitempty"`
+	// MigratedFrom is the schema version an older manifest was migrated
+	// from when it was read, or 0 when it was already current. It is never
+	// written, so callers can tell the user to run `machinist migrate`.
+	MigratedFrom int `toml:"-" json:"-"`
+}
+
+// Layer is the provenance of one layer of a composed manifest, recorded
+// under [[meta.layers]].
+type Layer struct {
+	// Source is "profile:<name>" or the manifest file the layer came from.
+	Source string `toml:"source"`
+	// Sections lists the manifest sections the layer provides.
+	Sections []string `toml:"sections,omitempty"`
+	// Overrides lists the values the layer changed from an earlier layer,
+	// by key path, e.g. "go.version".
+	Overrides []string `toml:"overrides,omitempty"`
+}
+
+// ProfileInfo is the [profile] block of a profile manifest: what the profile
+// sets up and what it needs. It is not restored.
+type ProfileInfo struct {
+	// Description says in one line what the profile sets up.
+	Description string   `toml:"description" json:"description"`
+	Tags        []string `toml:"tags,omitempty" json:"tags,omitempty"`
+	// Arch is the CPU architecture the profile targets; empty means any.
+	Arch       string `toml:"arch,omitempty" json:"arch,omitempty" enum:"arm64,x86_64"`
+	Maintainer string `toml:"maintainer,omitempty" json:"maintainer,omitempty"`
+	// MinMacOS is the oldest macOS version the profile supports, e.g. "14.0".
+	MinMacOS string `toml:"min_macos,omitempty" json:"min_macos,omitempty"`
+	// RequiredGroups lists the restore groups that must run for the profile
+	// to be useful, e.g. "homebrew" and "runtimes".
+	RequiredGroups []string `toml:"required_groups,omitempty" json:"required_groups,omitempty" enum:"homebrew,secrets,configs,runtimes,repos,macos"`
+}
+
+// HasTag reports whether the profile carries tag, ignoring case.
+func (p *ProfileInfo) HasTag(tag string) bool {
+	if p == nil {
+		return false
+	}
+	for _, t := range p.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
 }
 
 // Snapshot is the root aggregate representing a complete picture of a machine's
 // developer environment. Each section is a pointer: nil means "not scanned".
 type Snapshot struct {
 	Meta          Meta                  `toml:"meta"`
+	Profile       *ProfileInfo          `toml:"profile,omitempty"`
 	Homebrew      *HomebrewSection      `toml:"homebrew,omitempty"`
 	Node          *NodeSection          `toml:"node,omitempty"`
 	Python        *PythonSection        `toml:"python,omitempty"`
@@ -39,15 +104,11 @@ type Snapshot struct {
 	AWS           *AWSSection           `toml:"aws,omitempty"`
 	Kubernetes    *KubernetesSection    `toml:"kubernetes,omitempty"`
 	Terraform     *TerraformSection     `toml:"terraform,omitempty"`
-	Vercel        *VercelSection        `toml:"vercel,omitempty"`
 	MacOSDefaults *MacOSDefaultsSection `toml:"macos_defaults,omitempty"`
 	Locale        *LocaleSection        `toml:"locale,omitempty"`
 	LoginItems    *LoginItemsSection    `toml:"login_items,omitempty"`
 	HostsFile     *HostsFileSection     `toml:"hosts_file,omitempty"`
 	Apps          *AppsSection          `toml:"apps,omitempty"`
-	Raycast       *RaycastSection       `toml:"raycast,omitempty"`
-	Karabiner     *KarabinerSection     `toml:"karabiner,omitempty"`
-	Rectangle     *RectangleSection     `toml:"rectangle,omitempty"`
 	SSH           *SSHSection           `toml:"ssh,omitempty"`
 	GPG           *GPGSection           `toml:"gpg,omitempty"`
 	XDGConfig     *XDGConfigSection     `toml:"xdg_config,omitempty"`
@@ -65,20 +126,19 @@ type Snapshot struct {
 	Deno               *DenoSection               `toml:"deno,omitempty"`
 	Bun                *BunSection                `toml:"bun,omitempty"`
 	Ruby               *RubySection               `toml:"ruby,omitempty"`
-	GCP                *GCPSection                `toml:"gcp,omitempty"`
 	Azure              *AzureSection              `toml:"azure,omitempty"`
-	Flyio              *FlyioSection              `toml:"flyio,omitempty"`
 	Firebase           *FirebaseSection           `toml:"firebase,omitempty"`
 	CloudflareWrangler *CloudflareSection         `toml:"cloudflare,omitempty"`
-	Alfred             *AlfredSection             `toml:"alfred,omitempty"`
-	BetterTouchTool    *BetterTouchToolSection    `toml:"bettertouchtool,omitempty"`
 	OnePassword        *OnePasswordSection        `toml:"onepassword,omitempty"`
+	Plugins            map[string]*PluginSection  `toml:"plugins,omitempty"`
+	Probes             map[string]*ProbeSection   `toml:"probes,omitempty"`
 }
 
 // NewSnapshot creates a new Snapshot with populated Meta fields and all sections set to nil.
 func NewSnapshot(hostname, osVersion, arch, machinistVersion string) *Snapshot {
 	return &Snapshot{
 		Meta: Meta{
+			SchemaVersion:    CurrentSchemaVersion,
 			CreatedAt:        time.Now(),
 			SourceHostname:   hostname,
 			SourceOSVersion:  osVersion,
@@ -89,178 +149,19 @@ func NewSnapshot(hostname, osVersion, arch, machinistVersion string) *Snapshot {
 }
 
 // StageCount returns the number of restore stages that will be executed,
-// based on which snapshot sections are non-nil.
+// based on which snapshot sections are populated.
 func (s *Snapshot) StageCount() int {
-	count := 0
-	if s.Homebrew != nil {
-		count++
-	}
-	if s.Shell != nil {
-		count++
-	}
-	if s.GitRepos != nil {
-		count++
-	}
-	if s.Node != nil {
-		count++
-	}
-	if s.Python != nil {
-		count++
-	}
-	if s.Rust != nil {
-		count++
-	}
-	if s.Java != nil {
-		count++
-	}
-	if s.Flutter != nil {
-		count++
-	}
-	if s.Go != nil {
-		count++
-	}
-	if s.Asdf != nil {
-		count++
-	}
-	if s.Deno != nil {
-		count++
-	}
-	if s.Bun != nil {
-		count++
-	}
-	if s.Ruby != nil {
-		count++
-	}
-	if s.Terminal != nil {
-		count++
-	}
-	if s.Tmux != nil {
-		count++
-	}
-	if s.Git != nil {
-		count++
-	}
-	if s.GitHubCLI != nil {
-		count++
-	}
-	if s.VSCode != nil {
-		count++
-	}
-	if s.Cursor != nil {
-		count++
-	}
-	if s.Neovim != nil {
-		count++
-	}
-	if s.JetBrains != nil {
-		count++
-	}
-	if s.Xcode != nil {
-		count++
-	}
-	if s.Docker != nil {
-		count++
-	}
-	if s.AWS != nil {
-		count++
-	}
-	if s.Kubernetes != nil {
-		count++
-	}
-	if s.Terraform != nil {
-		count++
-	}
-	if s.Vercel != nil {
-		count++
-	}
-	if s.GCP != nil {
-		count++
-	}
-	if s.Azure != nil {
-		count++
-	}
-	if s.Flyio != nil {
-		count++
-	}
-	if s.Firebase != nil {
-		count++
-	}
-	if s.CloudflareWrangler != nil {
-		count++
-	}
-	if s.MacOSDefaults != nil {
-		count++
-	}
-	if s.Locale != nil {
-		count++
-	}
-	if s.LoginItems != nil {
-		count++
-	}
-	if s.HostsFile != nil {
-		count++
-	}
-	if s.Apps != nil {
-		count++
-	}
-	if s.Raycast != nil {
-		count++
-	}
-	if s.Alfred != nil {
-		count++
-	}
-	if s.Karabiner != nil {
-		count++
-	}
-	if s.Rectangle != nil {
-		count++
-	}
-	if s.BetterTouchTool != nil {
-		count++
-	}
-	if s.OnePassword != nil {
-		count++
-	}
-	if s.SSH != nil {
-		count++
-	}
-	if s.GPG != nil {
-		count++
-	}
-	if s.XDGConfig != nil {
-		count++
-	}
-	if s.EnvFiles != nil {
-		count++
-	}
-	if s.Folders != nil {
-		count++
-	}
-	if s.Fonts != nil {
-		count++
-	}
-	if s.Crontab != nil || s.LaunchAgents != nil {
-		count++
-	}
-	if s.Network != nil {
-		count++
-	}
-	if s.Browser != nil {
-		count++
-	}
-	if s.AITools != nil {
-		count++
-	}
-	if s.APITools != nil {
-		count++
-	}
-	if s.Databases != nil {
-		count++
-	}
-	if s.Registries != nil {
-		count++
+	return len(s.Stages())
+}
+
+// Stages returns every restore stage for the snapshot, group by group in
+// restore order.
+func (s *Snapshot) Stages() []Stage {
+	var all []Stage
+	for _, g := range restoreGroups {
+		all = append(all, g.Stages(s)...)
 	}
-	return count
+	return all
 }
 
 // ---------------------------------------------------------------------------
@@ -277,7 +178,7 @@ type HomebrewSection struct {
 
 // NodeSection captures Node.js version manager, versions, and global packages.
 type NodeSection struct {
-	Manager        string    `toml:"manager,omitempty"`
+	Manager        string    `toml:"manager,omitempty" enum:"nvm,fnm"`
 	Versions       []string  `toml:"versions,omitempty"`
 	DefaultVersion string    `toml:"default_version,omitempty"`
 	GlobalPackages []Package `toml:"global_packages,omitempty"`
@@ -285,7 +186,7 @@ type NodeSection struct {
 
 // PythonSection captures Python version manager, versions, and global packages.
 type PythonSection struct {
-	Manager        string    `toml:"manager,omitempty"`
+	Manager        string    `toml:"manager,omitempty" enum:"pyenv,uv"`
 	Versions       []string  `toml:"versions,omitempty"`
 	DefaultVersion string    `toml:"default_version,omitempty"`
 	GlobalPackages []Package `toml:"global_packages,omitempty"`
@@ -322,7 +223,7 @@ type GoSection struct {
 
 // AsdfSection captures asdf/mise plugins, versions, and the tool-versions file.
 type AsdfSection struct {
-	Manager          string       `toml:"manager,omitempty"` // "asdf" or "mise"
+	Manager          string       `toml:"manager,omitempty" enum:"asdf,mise"`
 	Plugins          []AsdfPlugin `toml:"plugins,omitempty"`
 	ToolVersionsFile string       `toml:"tool_versions_file,omitempty"`
 }
@@ -351,7 +252,7 @@ type TmuxSection struct {
 // GitSection captures git configuration, signing method, templates, and credential helper.
 type GitSection struct {
 	ConfigFiles      []ConfigFile `toml:"config_files,omitempty"`
-	SigningMethod    string       `toml:"signing_method,omitempty"`
+	SigningMethod    string       `toml:"signing_method,omitempty" enum:"gpg,ssh"`
 	TemplateDir      string       `toml:"template_dir,omitempty"`
 	CredentialHelper string       `toml:"credential_helper,omitempty"`
 }
@@ -384,7 +285,7 @@ type CursorSection struct {
 // NeovimSection captures Neovim configuration directory and plugin manager.
 type NeovimSection struct {
 	ConfigDir     string `toml:"config_dir,omitempty"`
-	PluginManager string `toml:"plugin_manager,omitempty"`
+	PluginManager string `toml:"plugin_manager,omitempty" enum:"lazy.nvim,packer,vim-plug"`
 }
 
 // JetBrainsSection captures JetBrains IDE installations and their settings.
@@ -422,11 +323,6 @@ type TerraformSection struct {
 	ConfigFile string `toml:"config_file,omitempty"`
 }
 
-// VercelSection captures Vercel CLI configuration directory.
-type VercelSection struct {
-	ConfigDir string `toml:"config_dir,omitempty"`
-}
-
 // MacOSDefaultsSection captures macOS system preferences organized by category.
 type MacOSDefaultsSection struct {
 	Dock           *DockConfig           `toml:"dock,omitempty"`
@@ -464,21 +360,6 @@ type AppsSection struct {
 	AppStore []InstalledApp `toml:"app_store,omitempty"`
 }
 
-// RaycastSection captures Raycast configuration export file.
-type RaycastSection struct {
-	ExportFile string `toml:"export_file,omitempty"`
-}
-
-// KarabinerSection captures Karabiner-Elements configuration directory.
-type KarabinerSection struct {
-	ConfigDir string `toml:"config_dir,omitempty"`
-}
-
-// RectangleSection captures Rectangle window manager configuration.
-type RectangleSection struct {
-	ConfigFile string `toml:"config_file,omitempty"`
-}
-
 // SSHSection captures SSH keys and configuration (typically encrypted).
 type SSHSection struct {
 	Encrypted  bool     `toml:"encrypted,omitempty"`
@@ -583,21 +464,11 @@ type RubySection struct {
 	GlobalGems     []Package `toml:"global_gems,omitempty"`
 }
 
-// GCPSection captures Google Cloud CLI configuration directory.
-type GCPSection struct {
-	ConfigDir string `toml:"config_dir,omitempty"`
-}
-
 // AzureSection captures Azure CLI configuration directory.
 type AzureSection struct {
 	ConfigDir string `toml:"config_dir,omitempty"`
 }
 
-// FlyioSection captures Fly.io CLI configuration file.
-type FlyioSection struct {
-	ConfigFile string `toml:"config_file,omitempty"`
-}
-
 // FirebaseSection captures Firebase CLI configuration directory.
 type FirebaseSection struct {
 	ConfigDir string `toml:"config_dir,omitempty"`
@@ -608,17 +479,36 @@ type CloudflareSection struct {
 	ConfigDir string `toml:"config_dir,omitempty"`
 }
 
-// AlfredSection captures Alfred configuration directory.
-type AlfredSection struct {
+// OnePasswordSection captures 1Password configuration directory.
+type OnePasswordSection struct {
 	ConfigDir string `toml:"config_dir,omitempty"`
 }
 
-// BetterTouchToolSection captures BetterTouchTool configuration file.
-type BetterTouchToolSection struct {
-	ConfigFile string `toml:"config_file,omitempty"`
+// PluginSection captures the output of an external scanner plugin, stored
+// under [plugins.<name>]. Packages are Homebrew formulae the plugin's tool
+// needs; Restore holds shell snippets run after config files are copied.
+type PluginSection struct {
+	// Category is the category the plugin reported, used to select its
+	// restore stage.
+	Category    string       `toml:"category,omitempty"`
+	ConfigFiles []ConfigFile `toml:"config_files,omitempty"`
+	Packages    []Package    `toml:"packages,omitempty"`
+	Restore     []string     `toml:"restore,omitempty"`
+}
+
+// ProbeSection captures the files and directories found by a declarative
+// probe scanner, stored under [probes.<name>]. ConfigDirs reuse ConfigFile
+// with a directory as Source. Cask is installed before the files are copied
+// back; Checklist lines are added to the post-restore checklist.
+type ProbeSection struct {
+	// Category is the probe definition's category, used to select its
+	// restore stage.
+	Category    string       `toml:"category,omitempty"`
+	ConfigFiles []ConfigFile `toml:"config_files,omitempty"`
+	ConfigDirs  []ConfigFile `toml:"config_dirs,omitempty"`
+	Cask        string       `toml:"cask,omitempty"`
+	Checklist   []string     `toml:"checklist,omitempty"`
 }
 
-// OnePasswordSection captures 1Password configuration directory.
-type OnePasswordSection struct {
-	ConfigDir string `toml:"config_dir,omitempty"`
-}
+func (p *PluginSection) entryCategory() string { return p.Category }
+func (p *ProbeSection) entryCategory() string  { return p.Category }
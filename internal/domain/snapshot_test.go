@@ -50,15 +50,11 @@ func TestNewSnapshot_AllSectionsNil(t *testing.T) {
 	assert.Nil(t, snap.AWS, "AWS should be nil by default")
 	assert.Nil(t, snap.Kubernetes, "Kubernetes should be nil by default")
 	assert.Nil(t, snap.Terraform, "Terraform should be nil by default")
-	assert.Nil(t, snap.Vercel, "Vercel should be nil by default")
 	assert.Nil(t, snap.MacOSDefaults, "MacOSDefaults should be nil by default")
 	assert.Nil(t, snap.Locale, "Locale should be nil by default")
 	assert.Nil(t, snap.LoginItems, "LoginItems should be nil by default")
 	assert.Nil(t, snap.HostsFile, "HostsFile should be nil by default")
 	assert.Nil(t, snap.Apps, "Apps should be nil by default")
-	assert.Nil(t, snap.Raycast, "Raycast should be nil by default")
-	assert.Nil(t, snap.Karabiner, "Karabiner should be nil by default")
-	assert.Nil(t, snap.Rectangle, "Rectangle should be nil by default")
 	assert.Nil(t, snap.SSH, "SSH should be nil by default")
 	assert.Nil(t, snap.GPG, "GPG should be nil by default")
 	assert.Nil(t, snap.XDGConfig, "XDGConfig should be nil by default")
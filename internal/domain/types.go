@@ -1,20 +1,22 @@
 package domain
 
+import "strconv"
+
 // Package represents a software package with an optional version.
 type Package struct {
-	Name    string `toml:"name"`
+	Name    string `toml:"name" merge:"key"`
 	Version string `toml:"version,omitempty"`
 }
 
 // ServiceEntry represents a background service managed by a package manager.
 type ServiceEntry struct {
-	Name   string `toml:"name"`
-	Status string `toml:"status"`
+	Name   string `toml:"name" merge:"key"`
+	Status string `toml:"status" enum:"started,stopped,scheduled,none,error,unknown,other"`
 }
 
 // ConfigFile represents a configuration file to be captured and bundled.
 type ConfigFile struct {
-	Source      string `toml:"source"`
+	Source      string `toml:"source" merge:"key"`
 	BundlePath  string `toml:"bundle_path"`
 	ContentHash string `toml:"content_hash,omitempty"`
 	Encrypted   bool   `toml:"encrypted,omitempty"`
@@ -24,23 +26,23 @@ type ConfigFile struct {
 // Repository represents a git repository to be cloned during restore.
 type Repository struct {
 	Path    string `toml:"path"`
-	Remote  string `toml:"remote"`
+	Remote  string `toml:"remote" merge:"key"`
 	Branch  string `toml:"branch"`
 	Shallow bool   `toml:"shallow,omitempty"`
 }
 
 // HostEntry represents a custom /etc/hosts entry.
 type HostEntry struct {
-	IP        string   `toml:"ip"`
+	IP        string   `toml:"ip" merge:"key"`
 	Hostnames []string `toml:"hostnames"`
 }
 
 // MacDefault represents a macOS defaults write entry.
 type MacDefault struct {
-	Domain    string `toml:"domain"`
-	Key       string `toml:"key"`
+	Domain    string `toml:"domain" merge:"key"`
+	Key       string `toml:"key" merge:"key"`
 	Value     string `toml:"value"`
-	ValueType string `toml:"value_type"`
+	ValueType string `toml:"value_type" enum:"string,int,integer,float,bool,boolean,date,data"`
 }
 
 // InstalledApp represents an application installed from the App Store or other sources.
@@ -51,33 +53,42 @@ type InstalledApp struct {
 	ID       int    `toml:"id,omitempty"`
 }
 
+// MergeKey identifies the app when manifests are merged: its App Store ID,
+// or its name for apps installed another way.
+func (a InstalledApp) MergeKey() string {
+	if a.ID != 0 {
+		return strconv.Itoa(a.ID)
+	}
+	return a.Name
+}
+
 // Font represents a user-installed font.
 type Font struct {
-	Name       string `toml:"name"`
+	Name       string `toml:"name" merge:"key"`
 	BundlePath string `toml:"bundle_path"`
 }
 
 // EnvFile represents an environment file to be captured (typically encrypted).
 type EnvFile struct {
-	Source     string `toml:"source"`
+	Source     string `toml:"source" merge:"key"`
 	BundlePath string `toml:"bundle_path"`
 }
 
 // JetBrainsIDE represents a JetBrains IDE installation with its settings export.
 type JetBrainsIDE struct {
-	Name           string `toml:"name"`
+	Name           string `toml:"name" merge:"key"`
 	SettingsExport string `toml:"settings_export,omitempty"`
 }
 
 // AsdfPlugin represents an asdf/mise plugin with its installed versions.
 type AsdfPlugin struct {
-	Name     string   `toml:"name"`
+	Name     string   `toml:"name" merge:"key"`
 	Versions []string `toml:"versions"`
 }
 
 // DNSConfig represents DNS server configuration for a network interface.
 type DNSConfig struct {
-	Interface string   `toml:"interface"`
+	Interface string   `toml:"interface" merge:"key"`
 	Servers   []string `toml:"servers"`
 }
 
@@ -85,10 +96,10 @@ type DNSConfig struct {
 type DockConfig struct {
 	AutoHide       bool   `toml:"autohide,omitempty"`
 	TileSize       int    `toml:"tilesize,omitempty"`
-	Orientation    string `toml:"orientation,omitempty"`
+	Orientation    string `toml:"orientation,omitempty" enum:"bottom,left,right"`
 	Magnification  bool   `toml:"magnification,omitempty"`
 	ShowRecents    bool   `toml:"show_recents,omitempty"`
-	MinimizeEffect string `toml:"minimize_effect,omitempty"`
+	MinimizeEffect string `toml:"minimize_effect,omitempty" enum:"genie,scale,suck"`
 }
 
 // FinderConfig represents macOS Finder preferences.
@@ -25,6 +25,21 @@ func TestSensitivity_String(t *testing.T) {
 	}
 }
 
+func TestParseSensitivity(t *testing.T) {
+	for _, level := range []Sensitivity{Public, Sensitive, Secret} {
+		got, err := ParseSensitivity(level.String())
+		assert.NoError(t, err)
+		assert.Equal(t, level, got)
+	}
+
+	got, err := ParseSensitivity("")
+	assert.NoError(t, err)
+	assert.Equal(t, Public, got)
+
+	_, err = ParseSensitivity("top-secret")
+	assert.Error(t, err)
+}
+
 func TestPackage_Fields(t *testing.T) {
 	tests := []struct {
 		name    string
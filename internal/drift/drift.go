@@ -0,0 +1,237 @@
+// Package drift compares the live machine against the manifest it was set
+// up from: what the manifest requires that the machine lacks or has with
+// another value, and what the machine has on top of it.
+package drift
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/moinsen-dev/machinist/internal/diff"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/scanner"
+)
+
+// Report lists how a machine drifted from a manifest. Each entry is a
+// diff.Change from the manifest (A) to the machine (B).
+type Report struct {
+	// Missing are values the manifest requires that the machine lacks:
+	// formulae, casks, extensions, repositories, settings.
+	Missing []diff.Change `json:"missing"`
+	// Changed are values the machine has with another value than the
+	// manifest, such as a pinned runtime version or a config file's
+	// content hash.
+	Changed []diff.Change `json:"changed"`
+	// Extra are list items the machine has on top of the manifest. They
+	// are not drift by themselves.
+	Extra []diff.Change `json:"extra"`
+	// Unchecked are the manifest sections, or named section entries such
+	// as "plugins.corp", that no available scanner could check, including
+	// those of scanners that failed or timed out.
+	Unchecked []string `json:"unchecked"`
+	// Diagnostics are the problems the scan ran into, including why a
+	// failed scanner's sections are unchecked.
+	Diagnostics []domain.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Drifted reports whether the machine lacks or changed something the
+// manifest requires.
+func (r *Report) Drifted() bool {
+	return len(r.Missing) > 0 || len(r.Changed) > 0
+}
+
+// restoreHints are list item fields that tell restore what to do rather
+// than describe the machine, so a scan does not see them: the branch a
+// repository is checked out on is working state, and bundle paths and the
+// encrypted and sensitive flags only exist in bundles.
+var restoreHints = []string{"branch", "shallow", "bundle_path", "encrypted", "sensitive"}
+
+// Options configure Detect.
+type Options struct {
+	// HomeDir is the home directory of the machine, used to compare
+	// absolute paths with ~ and $HOME paths. It defaults to the user's.
+	HomeDir string
+	// Progress, when set, is called as the scanners run.
+	Progress scanner.ProgressFunc
+}
+
+// Detect scans the sections manifest covers with the scanners in reg and
+// compares the result against manifest. The sections of scanners that fail
+// are unchecked rather than missing, since the scan says nothing about them.
+func Detect(ctx context.Context, reg *scanner.Registry, manifest *domain.Snapshot, opts Options) (*Report, error) {
+	names, unchecked := reg.ScannersFor(manifest)
+	machine, errs := reg.ScanSelected(ctx, names, opts.Progress)
+	unchecked = append(unchecked, scanner.SectionsFor(manifest, scanner.FailedScanners(errs))...)
+	if opts.HomeDir == "" {
+		opts.HomeDir, _ = os.UserHomeDir()
+	}
+	report, err := Compare(manifest, machine, opts.HomeDir, unchecked)
+	if err != nil {
+		return nil, err
+	}
+	report.Diagnostics = machine.Meta.Diagnostics
+	return report, nil
+}
+
+// Compare reports how machine drifted from manifest. Only the sections
+// present in manifest are compared, and values the manifest leaves unset,
+// such as a package's version, match anything. Paths in home are compared
+// in ~ form. unchecked sections are left out of the comparison and listed
+// in the report. manifest and machine are not modified.
+func Compare(manifest, machine *domain.Snapshot, home string, unchecked []string) (*Report, error) {
+	want, err := homeRelative(manifest, home)
+	if err != nil {
+		return nil, err
+	}
+	have, err := homeRelative(machine, home)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range domain.Sections() {
+		if !s.Present(want) {
+			dropSection(have, s.Key)
+		}
+	}
+	if want.Homebrew != nil && have.Homebrew != nil {
+		have.Homebrew.Formulae = qualifyBrewNames(want.Homebrew.Formulae, have.Homebrew.Formulae)
+		have.Homebrew.Casks = qualifyBrewNames(want.Homebrew.Casks, have.Homebrew.Casks)
+	}
+
+	report := &Report{Missing: []diff.Change{}, Changed: []diff.Change{}, Extra: []diff.Change{}, Unchecked: []string{}}
+	report.Unchecked = append(report.Unchecked, unchecked...)
+	for _, c := range diff.Manifests(want, have).Changes {
+		if skipped(c.Key, unchecked) || isRestoreHint(c.Key) {
+			continue
+		}
+		switch {
+		case c.Kind == diff.Removed:
+			report.Missing = append(report.Missing, c)
+		case c.Kind == diff.Changed:
+			report.Changed = append(report.Changed, c)
+		case c.Item != "":
+			report.Extra = append(report.Extra, c)
+		}
+	}
+	return report, nil
+}
+
+// qualifyBrewNames gives the packages in have the tap-qualified name of the
+// package in want they are, since `brew list` names a formula or cask from
+// a tap ("hashicorp/tap/terraform") by its last path segment alone.
+func qualifyBrewNames(want, have []domain.Package) []domain.Package {
+	qualified := map[string]string{}
+	for _, p := range want {
+		if i := strings.LastIndex(p.Name, "/"); i >= 0 {
+			qualified[p.Name[i+1:]] = p.Name
+		}
+	}
+	for _, p := range want {
+		delete(qualified, p.Name)
+	}
+	for i, p := range have {
+		if name, ok := qualified[p.Name]; ok {
+			have[i].Name = name
+		}
+	}
+	return have
+}
+
+// dropSection removes the section with key from snap.
+func dropSection(snap *domain.Snapshot, key string) {
+	v := reflect.ValueOf(snap).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if name, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ","); name == key {
+			v.Field(i).SetZero()
+		}
+	}
+}
+
+// skipped reports whether key lies in one of the unchecked sections.
+func skipped(key string, unchecked []string) bool {
+	for _, u := range unchecked {
+		if key == u || strings.HasPrefix(key, u+".") || strings.HasPrefix(key, u+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+func isRestoreHint(key string) bool {
+	i := strings.LastIndex(key, "].")
+	if i < 0 {
+		return false
+	}
+	field := key[i+2:]
+	for _, h := range restoreHints {
+		if field == h {
+			return true
+		}
+	}
+	return false
+}
+
+// homeRelative returns a copy of snap with every path in home, or starting
+// with $HOME, written with a leading ~.
+func homeRelative(snap *domain.Snapshot, home string) (*domain.Snapshot, error) {
+	// A marshal round trip gives a deep copy to rewrite in place.
+	data, err := domain.MarshalManifest(snap)
+	if err != nil {
+		return nil, err
+	}
+	out, err := domain.UnmarshalManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	home = strings.TrimSuffix(home, "/")
+	rewrite := func(s string) string {
+		for _, prefix := range []string{home, "$HOME", "${HOME}"} {
+			if prefix != "" && (s == prefix || strings.HasPrefix(s, prefix+"/")) {
+				return "~" + s[len(prefix):]
+			}
+		}
+		return s
+	}
+	v := reflect.ValueOf(out).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if v.Type().Field(i).Name != "Meta" {
+			rewriteStrings(v.Field(i), rewrite)
+		}
+	}
+	return out, nil
+}
+
+// rewriteStrings applies f to every string in v in place.
+func rewriteStrings(v reflect.Value, f func(string) string) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			rewriteStrings(v.Elem(), f)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				rewriteStrings(v.Field(i), f)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			rewriteStrings(v.Index(i), f)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			val := iter.Value()
+			switch val.Kind() {
+			case reflect.String:
+				v.SetMapIndex(iter.Key(), reflect.ValueOf(f(val.String())).Convert(val.Type()))
+			case reflect.Pointer:
+				rewriteStrings(val, f)
+			}
+		}
+	case reflect.String:
+		v.SetString(f(v.String()))
+	}
+}
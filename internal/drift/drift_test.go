@@ -0,0 +1,146 @@
+package drift
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/diff"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/scanner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func keys(changes []diff.Change) []string {
+	out := make([]string, len(changes))
+	for i, c := range changes {
+		out[i] = c.Key
+	}
+	return out
+}
+
+func teamManifest() *domain.Snapshot {
+	return &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{
+			Formulae: []domain.Package{{Name: "git"}, {Name: "jq"}},
+			Casks:    []domain.Package{{Name: "docker"}},
+		},
+		VSCode: &domain.VSCodeSection{Extensions: []string{"golang.go"}},
+		Go:     &domain.GoSection{Version: "1.25"},
+		GitRepos: &domain.GitReposSection{Repositories: []domain.Repository{
+			{Remote: "git@github.com:team/api.git", Path: "~/Code/api", Branch: "main"},
+		}},
+		Tmux: &domain.TmuxSection{ConfigFiles: []domain.ConfigFile{
+			{Source: ".tmux.conf", BundlePath: "configs/tmux/.tmux.conf", ContentHash: "aaa"},
+		}},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	machine := &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{
+			Formulae: []domain.Package{{Name: "git", Version: "2.47.0"}, {Name: "htop", Version: "3.3"}},
+		},
+		VSCode: &domain.VSCodeSection{Extensions: []string{"golang.go", "vscodevim.vim"}},
+		Go:     &domain.GoSection{Version: "1.24"},
+		GitRepos: &domain.GitReposSection{Repositories: []domain.Repository{
+			{Remote: "git@github.com:team/api.git", Path: "/Users/alice/Code/api", Branch: "feature/x"},
+		}},
+		Tmux: &domain.TmuxSection{ConfigFiles: []domain.ConfigFile{
+			{Source: ".tmux.conf", BundlePath: "configs/tmux/.tmux.conf", ContentHash: "bbb"},
+		}},
+		Rust: &domain.RustSection{Toolchains: []string{"stable"}},
+	}
+
+	manifest := teamManifest()
+	manifest.GitRepos.Repositories[0].Path = "$HOME/Code/api"
+	r, err := Compare(manifest, machine, "/Users/alice", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"homebrew.formulae[jq]", "homebrew.casks[docker]"}, keys(r.Missing))
+	assert.Equal(t, []string{"tmux.config_files[.tmux.conf].content_hash", "go.version"}, keys(r.Changed))
+	assert.Equal(t, []string{"homebrew.formulae[htop]", "vscode.extensions[vscodevim.vim]"}, keys(r.Extra),
+		"sections the manifest does not cover must not show up as extra")
+	assert.True(t, r.Drifted())
+
+	assert.Equal(t, "$HOME/Code/api", manifest.GitRepos.Repositories[0].Path, "the manifest must not be modified")
+	assert.Equal(t, "/Users/alice/Code/api", machine.GitRepos.Repositories[0].Path, "the machine snapshot must not be modified")
+}
+
+func TestCompare_NoDrift(t *testing.T) {
+	machine := teamManifest()
+	machine.Homebrew.Formulae = append(machine.Homebrew.Formulae, domain.Package{Name: "htop"})
+	machine.GitRepos.Repositories[0].Path = "$HOME/Code/api"
+
+	r, err := Compare(teamManifest(), machine, "/Users/alice", nil)
+	require.NoError(t, err)
+	assert.False(t, r.Drifted(), "missing %v, changed %v", r.Missing, r.Changed)
+	assert.Len(t, r.Extra, 1)
+}
+
+func TestCompare_Unchecked(t *testing.T) {
+	r, err := Compare(teamManifest(), &domain.Snapshot{}, "/Users/alice", []string{"homebrew", "vscode", "go", "git_repos"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tmux.config_files[.tmux.conf]"}, keys(r.Missing))
+	assert.Equal(t, []string{"homebrew", "vscode", "go", "git_repos"}, r.Unchecked)
+}
+
+// fakeScanner returns a fixed result or error.
+type fakeScanner struct {
+	name   string
+	result *scanner.ScanResult
+	err    error
+	ran    *[]string
+}
+
+func (f fakeScanner) Name() string        { return f.name }
+func (f fakeScanner) Description() string { return "" }
+func (f fakeScanner) Category() string    { return "" }
+func (f fakeScanner) Scan(context.Context) (*scanner.ScanResult, error) {
+	*f.ran = append(*f.ran, f.name)
+	return f.result, f.err
+}
+
+func TestDetect(t *testing.T) {
+	var ran []string
+	reg := scanner.NewRegistry()
+	reg.SetConcurrency(1)
+	require.NoError(t, reg.Register(fakeScanner{name: "go", ran: &ran,
+		result: &scanner.ScanResult{ScannerName: "go", GoLang: &domain.GoSection{Version: "1.25"}}}))
+	require.NoError(t, reg.Register(fakeScanner{name: "rust", ran: &ran,
+		result: &scanner.ScanResult{ScannerName: "rust", Rust: &domain.RustSection{Toolchains: []string{"stable"}}}}))
+
+	manifest := &domain.Snapshot{
+		Go:   &domain.GoSection{Version: "1.25"},
+		Tmux: &domain.TmuxSection{TPMPlugins: []string{"tmux-plugins/tpm"}},
+	}
+	r, err := Detect(context.Background(), reg, manifest, Options{HomeDir: "/Users/alice"})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"go"}, ran, "only the scanners of the manifest's sections run")
+	assert.False(t, r.Drifted())
+	assert.Equal(t, []string{"tmux"}, r.Unchecked)
+}
+
+func TestDetect_FailedScanner(t *testing.T) {
+	var ran []string
+	reg := scanner.NewRegistry()
+	require.NoError(t, reg.Register(fakeScanner{name: "homebrew", ran: &ran, err: errors.New("brew: command timed out")}))
+	require.NoError(t, reg.Register(fakeScanner{name: "go", ran: &ran,
+		result: &scanner.ScanResult{ScannerName: "go", GoLang: &domain.GoSection{Version: "1.24"}}}))
+
+	manifest := &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{Formulae: []domain.Package{{Name: "git"}}},
+		Go:       &domain.GoSection{Version: "1.25"},
+	}
+	r, err := Detect(context.Background(), reg, manifest, Options{HomeDir: "/Users/alice"})
+	require.NoError(t, err)
+
+	assert.Empty(t, r.Missing, "a failed scanner's section is not missing")
+	assert.Equal(t, []string{"go.version"}, keys(r.Changed))
+	assert.Equal(t, []string{"homebrew"}, r.Unchecked)
+	require.Len(t, r.Diagnostics, 1)
+	assert.Equal(t, "homebrew", r.Diagnostics[0].Scanner)
+	assert.Contains(t, r.Diagnostics[0].Message, "timed out")
+}
@@ -51,7 +51,7 @@ func TestFullPipeline(t *testing.T) {
 
 	// 3. Create Registry and register both scanners.
 	reg := scanner.NewRegistry()
-	require.NoError(t, reg.Register(packages.NewHomebrewScanner(mockCmd)))
+	require.NoError(t, reg.Register(packages.NewHomebrewScanner(homeDir, mockCmd)))
 	require.NoError(t, reg.Register(shell.NewShellConfigScanner(homeDir, mockCmd)))
 
 	// 4. Run ScanAll.
@@ -149,7 +149,7 @@ func TestFullPipeline_NoBrewInstalled(t *testing.T) {
 	require.NoError(t, os.MkdirAll(filepath.Join(homeDir, ".oh-my-zsh"), 0755))
 
 	reg := scanner.NewRegistry()
-	require.NoError(t, reg.Register(packages.NewHomebrewScanner(mockCmd)))
+	require.NoError(t, reg.Register(packages.NewHomebrewScanner(homeDir, mockCmd)))
 	require.NoError(t, reg.Register(shell.NewShellConfigScanner(homeDir, mockCmd)))
 
 	snap, errs := reg.ScanAll(context.Background())
@@ -184,7 +184,7 @@ func TestFullPipeline_EmptySystem(t *testing.T) {
 	homeDir := t.TempDir()
 
 	reg := scanner.NewRegistry()
-	require.NoError(t, reg.Register(packages.NewHomebrewScanner(mockCmd)))
+	require.NoError(t, reg.Register(packages.NewHomebrewScanner(homeDir, mockCmd)))
 	require.NoError(t, reg.Register(shell.NewShellConfigScanner(homeDir, mockCmd)))
 
 	snap, errs := reg.ScanAll(context.Background())
@@ -4,18 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"reflect"
 	"runtime"
-	"sort"
 	"strings"
 
 	gomcp "github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 
 	"github.com/moinsen-dev/machinist/internal/bundler"
+	"github.com/moinsen-dev/machinist/internal/diff"
 	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/drift"
 	"github.com/moinsen-dev/machinist/internal/scanner"
+	"github.com/moinsen-dev/machinist/internal/schema"
 	"github.com/moinsen-dev/machinist/internal/util"
+	"github.com/moinsen-dev/machinist/internal/validate"
 	"github.com/moinsen-dev/machinist/profiles"
 )
 
@@ -65,14 +67,17 @@ func (s *MachinistServer) registerTools() {
 
 	s.addTool("scan_all",
 		gomcp.NewTool("scan_all",
-			gomcp.WithDescription("Run all scanners and return the full TOML manifest"),
+			gomcp.WithDescription("Run all scanners and return the full TOML manifest. Problems that left a section missing or partial are listed under [[meta.diagnostics]] with the scanner, item, failing command and a suggested fix"),
 		),
 		s.handleScanAll,
 	)
 
 	s.addTool("list_profiles",
 		gomcp.NewTool("list_profiles",
-			gomcp.WithDescription("List all available profile names"),
+			gomcp.WithDescription("List the available profiles with their metadata: description, tags, target arch, maintainer, minimum macOS and required restore groups"),
+			gomcp.WithString("tag",
+				gomcp.Description("Only list profiles with this tag, e.g. mobile or backend"),
+			),
 		),
 		s.handleListProfiles,
 	)
@@ -90,13 +95,20 @@ func (s *MachinistServer) registerTools() {
 
 	s.addTool("compose_manifest",
 		gomcp.NewTool("compose_manifest",
-			gomcp.WithDescription("Compose a TOML manifest from a base profile with optional additional packages"),
+			gomcp.WithDescription("Compose a TOML manifest from a base profile with optional items to add or remove. The manifest format is described by the machinist://schema/manifest resource"),
 			gomcp.WithString("base_profile",
 				gomcp.Required(),
-				gomcp.Description("Base profile name to start from"),
+				gomcp.Description("Base profile name to start from; join several with + (e.g. go-dev+devops) to layer them in order"),
+			),
+			gomcp.WithString("overlay",
+				gomcp.Description("TOML manifest deep-merged onto the profile; lists are combined and [meta.merge] can replace or remove values"),
 			),
 			gomcp.WithArray("add_packages",
-				gomcp.Description("Additional Homebrew formulae to add"),
+				gomcp.Description("Items to add, written <type>:<value> (formula, cask, tap, vscode, cursor, npm, cargo, go, repo, mas), e.g. cask:docker or npm:pnpm; a bare name is a Homebrew formula"),
+				gomcp.WithStringItems(),
+			),
+			gomcp.WithArray("remove_packages",
+				gomcp.Description("Items to remove, in the same <type>:<value> form as add_packages"),
 				gomcp.WithStringItems(),
 			),
 		),
@@ -105,7 +117,7 @@ func (s *MachinistServer) registerTools() {
 
 	s.addTool("validate_manifest",
 		gomcp.NewTool("validate_manifest",
-			gomcp.WithDescription("Validate a TOML manifest by attempting to unmarshal it into a Snapshot"),
+			gomcp.WithDescription("Validate a TOML manifest: syntax, schema version, unknown keys, duplicate or invalid package names, and unsafe config paths. Returns a JSON report of issues with keys and line numbers"),
 			gomcp.WithString("manifest",
 				gomcp.Required(),
 				gomcp.Description("Raw TOML content to validate"),
@@ -136,7 +148,7 @@ func (s *MachinistServer) registerTools() {
 
 	s.addTool("diff_manifests",
 		gomcp.NewTool("diff_manifests",
-			gomcp.WithDescription("Compare two TOML manifests and return a human-readable diff summary"),
+			gomcp.WithDescription("Compare two TOML manifests section by section: packages and versions, extensions, repositories, macOS defaults, config file hashes and runtime versions"),
 			gomcp.WithString("manifest_a",
 				gomcp.Required(),
 				gomcp.Description("First TOML manifest"),
@@ -145,9 +157,24 @@ func (s *MachinistServer) registerTools() {
 				gomcp.Required(),
 				gomcp.Description("Second TOML manifest"),
 			),
+			gomcp.WithString("format",
+				gomcp.Description("Output format: text (default), json or patch"),
+				gomcp.Enum("text", "json", "patch"),
+			),
 		),
 		s.handleDiffManifests,
 	)
+
+	s.addTool("check_drift",
+		gomcp.NewTool("check_drift",
+			gomcp.WithDescription("Scan this machine for the sections a manifest covers and report what the manifest requires that the machine lacks (missing), values that differ such as pinned versions or config file hashes (changed), and what the machine has on top of it (extra)"),
+			gomcp.WithString("manifest",
+				gomcp.Required(),
+				gomcp.Description("TOML manifest to check the machine against"),
+			),
+		),
+		s.handleCheckDrift,
+	)
 }
 
 func (s *MachinistServer) addTool(name string, tool gomcp.Tool, handler server.ToolHandlerFunc) {
@@ -205,23 +232,9 @@ func (s *MachinistServer) handleScan(ctx context.Context, req gomcp.CallToolRequ
 }
 
 // handleScanAll runs all scanners and returns the full TOML manifest.
+// Scan problems are part of the manifest as [[meta.diagnostics]].
 func (s *MachinistServer) handleScanAll(ctx context.Context, _ gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
-	snap, errs := s.registry.ScanAll(ctx)
-	if len(errs) > 0 {
-		msgs := make([]string, len(errs))
-		for i, e := range errs {
-			msgs[i] = e.Error()
-		}
-		// Still return the partial result but note errors
-		data, marshalErr := domain.MarshalManifest(snap)
-		if marshalErr != nil {
-			return gomcp.NewToolResultError(fmt.Sprintf("scan errors: %s; marshal error: %v",
-				strings.Join(msgs, "; "), marshalErr)), nil
-		}
-		return gomcp.NewToolResultText(fmt.Sprintf("# Warnings: %s\n\n%s",
-			strings.Join(msgs, "; "), string(data))), nil
-	}
-
+	snap, _ := s.registry.ScanAll(ctx)
 	data, err := domain.MarshalManifest(snap)
 	if err != nil {
 		return gomcp.NewToolResultError(fmt.Sprintf("failed to marshal manifest: %v", err)), nil
@@ -229,16 +242,17 @@ func (s *MachinistServer) handleScanAll(ctx context.Context, _ gomcp.CallToolReq
 	return gomcp.NewToolResultText(string(data)), nil
 }
 
-// handleListProfiles returns a JSON array of profile names.
-func (s *MachinistServer) handleListProfiles(_ context.Context, _ gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
-	names, err := profiles.List()
+// handleListProfiles returns a JSON array of the profiles and their
+// metadata, optionally filtered by tag.
+func (s *MachinistServer) handleListProfiles(_ context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+	infos, err := profiles.Search(req.GetString("tag", ""))
 	if err != nil {
 		return gomcp.NewToolResultError(fmt.Sprintf("failed to list profiles: %v", err)), nil
 	}
 
-	data, err := json.Marshal(names)
+	data, err := json.Marshal(infos)
 	if err != nil {
-		return gomcp.NewToolResultError(fmt.Sprintf("failed to marshal profile names: %v", err)), nil
+		return gomcp.NewToolResultError(fmt.Sprintf("failed to marshal profiles: %v", err)), nil
 	}
 	return gomcp.NewToolResultText(string(data)), nil
 }
@@ -261,25 +275,42 @@ func (s *MachinistServer) handleGetProfile(_ context.Context, req gomcp.CallTool
 	return gomcp.NewToolResultText(string(toml)), nil
 }
 
-// handleComposeManifest loads a base profile and appends optional packages.
+// handleComposeManifest composes the base profiles and deep-merges an optional
+// overlay manifest and extra items onto it, the same way extends layers
+// are merged, then drops the items to remove.
 func (s *MachinistServer) handleComposeManifest(_ context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
 	baseProfile, err := req.RequireString("base_profile")
 	if err != nil {
 		return gomcp.NewToolResultError(err.Error()), nil
 	}
 
-	snap, err := profiles.Get(baseProfile)
+	var refs []string
+	for _, name := range strings.Split(baseProfile, "+") {
+		refs = append(refs, profiles.ProfilePrefix+strings.TrimSpace(name))
+	}
+	snap, _, err := profiles.Compose(refs, "")
 	if err != nil {
 		return gomcp.NewToolResultError(fmt.Sprintf("failed to load profile %q: %v", baseProfile, err)), nil
 	}
 
-	addPkgs := req.GetStringSlice("add_packages", nil)
-	if len(addPkgs) > 0 {
-		if snap.Homebrew == nil {
-			snap.Homebrew = &domain.HomebrewSection{}
+	if overlay := req.GetString("overlay", ""); overlay != "" {
+		over, err := parseManifest(overlay)
+		if err != nil {
+			return gomcp.NewToolResultError(fmt.Sprintf("failed to parse overlay: %v", err)), nil
 		}
-		for _, pkg := range addPkgs {
-			snap.Homebrew.Formulae = append(snap.Homebrew.Formulae, domain.Package{Name: pkg})
+		snap = profiles.Merge(snap, over)
+	}
+
+	if items := req.GetStringSlice("add_packages", nil); len(items) > 0 {
+		snap, err = profiles.AddItems(snap, items)
+		if err != nil {
+			return gomcp.NewToolResultError(fmt.Sprintf("add_packages: %v", err)), nil
+		}
+	}
+	if items := req.GetStringSlice("remove_packages", nil); len(items) > 0 {
+		snap, err = profiles.RemoveItems(snap, items)
+		if err != nil {
+			return gomcp.NewToolResultError(fmt.Sprintf("remove_packages: %v", err)), nil
 		}
 	}
 
@@ -290,27 +321,32 @@ func (s *MachinistServer) handleComposeManifest(_ context.Context, req gomcp.Cal
 	return gomcp.NewToolResultText(string(data)), nil
 }
 
-// handleValidateManifest validates a TOML manifest by attempting to unmarshal it.
+// handleValidateManifest runs the strict manifest checks and returns the
+// report. "valid" is false when any issue has error severity; "error"
+// summarises the first one for callers that only look at that.
 func (s *MachinistServer) handleValidateManifest(_ context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
 	manifest, err := req.RequireString("manifest")
 	if err != nil {
 		return gomcp.NewToolResultError(err.Error()), nil
 	}
 
-	snap, err := domain.UnmarshalManifest([]byte(manifest))
-	if err != nil {
-		result := map[string]interface{}{
-			"valid": false,
-			"error": fmt.Sprintf("parse error: %v", err),
-		}
-		data, _ := json.Marshal(result)
-		return gomcp.NewToolResultText(string(data)), nil
-	}
-
-	sections := populatedSections(snap)
+	report := validate.Manifest([]byte(manifest), validate.Options{})
 	result := map[string]interface{}{
-		"valid":    true,
-		"sections": sections,
+		"valid":          report.Valid,
+		"schema_version": report.SchemaVersion,
+		"sections":       report.Sections,
+		"issues":         report.Issues,
+	}
+	for _, issue := range report.Issues {
+		if issue.Severity != domain.SeverityError {
+			continue
+		}
+		if issue.Code == validate.CodeParse {
+			result["error"] = fmt.Sprintf("parse error: %s", issue.Message)
+		} else {
+			result["error"] = issue.String()
+		}
+		break
 	}
 	data, err := json.Marshal(result)
 	if err != nil {
@@ -319,6 +355,16 @@ func (s *MachinistServer) handleValidateManifest(_ context.Context, req gomcp.Ca
 	return gomcp.NewToolResultText(string(data)), nil
 }
 
+// parseManifest decodes manifest TOML and resolves its meta.extends chain.
+// Relative file layers are looked up from the server's working directory.
+func parseManifest(manifest string) (*domain.Snapshot, error) {
+	snap, err := domain.UnmarshalManifest([]byte(manifest))
+	if err != nil {
+		return nil, err
+	}
+	return profiles.Resolve(snap, "")
+}
+
 // handleBuildDMG builds a DMG disk image from a TOML manifest.
 func (s *MachinistServer) handleBuildDMG(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
 	manifest, err := req.RequireString("manifest")
@@ -326,7 +372,7 @@ func (s *MachinistServer) handleBuildDMG(ctx context.Context, req gomcp.CallTool
 		return gomcp.NewToolResultError(err.Error()), nil
 	}
 
-	snap, err := domain.UnmarshalManifest([]byte(manifest))
+	snap, err := parseManifest(manifest)
 	if err != nil {
 		result := map[string]interface{}{
 			"success": false,
@@ -366,7 +412,7 @@ func (s *MachinistServer) handleBuildDMG(ctx context.Context, req gomcp.CallTool
 	return gomcp.NewToolResultText(string(data)), nil
 }
 
-// handleDiffManifests compares two TOML manifests and returns a human-readable diff.
+// handleDiffManifests compares two TOML manifests section by section.
 func (s *MachinistServer) handleDiffManifests(_ context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
 	manifestA, err := req.RequireString("manifest_a")
 	if err != nil {
@@ -376,119 +422,64 @@ func (s *MachinistServer) handleDiffManifests(_ context.Context, req gomcp.CallT
 	if err != nil {
 		return gomcp.NewToolResultError(err.Error()), nil
 	}
+	format := req.GetString("format", "text")
 
-	snapA, err := domain.UnmarshalManifest([]byte(manifestA))
+	snapA, err := parseManifest(manifestA)
 	if err != nil {
 		return gomcp.NewToolResultError(fmt.Sprintf("failed to parse manifest_a: %v", err)), nil
 	}
-	snapB, err := domain.UnmarshalManifest([]byte(manifestB))
+	snapB, err := parseManifest(manifestB)
 	if err != nil {
 		return gomcp.NewToolResultError(fmt.Sprintf("failed to parse manifest_b: %v", err)), nil
 	}
 
-	sectionsA := populatedSections(snapA)
-	sectionsB := populatedSections(snapB)
-
-	setA := make(map[string]bool, len(sectionsA))
-	for _, s := range sectionsA {
-		setA[s] = true
-	}
-	setB := make(map[string]bool, len(sectionsB))
-	for _, s := range sectionsB {
-		setB[s] = true
-	}
-
-	var onlyA, onlyB, both []string
-	for _, s := range sectionsA {
-		if setB[s] {
-			both = append(both, s)
-		} else {
-			onlyA = append(onlyA, s)
-		}
-	}
-	for _, s := range sectionsB {
-		if !setA[s] {
-			onlyB = append(onlyB, s)
-		}
-	}
-
-	sort.Strings(onlyA)
-	sort.Strings(onlyB)
-	sort.Strings(both)
-
+	report := diff.Manifests(snapA, snapB)
 	var sb strings.Builder
-	sb.WriteString(fmt.Sprintf("Only in A: %v\n", onlyA))
-	sb.WriteString(fmt.Sprintf("Only in B: %v\n", onlyB))
-	sb.WriteString(fmt.Sprintf("In both: %v\n", both))
-
-	// Homebrew package-level diff
-	if snapA.Homebrew != nil && snapB.Homebrew != nil {
-		pkgsA := make(map[string]bool)
-		for _, p := range snapA.Homebrew.Formulae {
-			pkgsA[p.Name] = true
-		}
-		for _, p := range snapA.Homebrew.Casks {
-			pkgsA[p.Name] = true
-		}
-		pkgsB := make(map[string]bool)
-		for _, p := range snapB.Homebrew.Formulae {
-			pkgsB[p.Name] = true
-		}
-		for _, p := range snapB.Homebrew.Casks {
-			pkgsB[p.Name] = true
-		}
-
-		var hOnlyA, hOnlyB []string
-		for name := range pkgsA {
-			if !pkgsB[name] {
-				hOnlyA = append(hOnlyA, name)
-			}
-		}
-		for name := range pkgsB {
-			if !pkgsA[name] {
-				hOnlyB = append(hOnlyB, name)
-			}
-		}
-		sort.Strings(hOnlyA)
-		sort.Strings(hOnlyB)
-
-		if len(hOnlyA) > 0 || len(hOnlyB) > 0 {
-			sb.WriteString("\nHomebrew diff:\n")
-			if len(hOnlyA) > 0 {
-				sb.WriteString(fmt.Sprintf("  Only in A: %v\n", hOnlyA))
-			}
-			if len(hOnlyB) > 0 {
-				sb.WriteString(fmt.Sprintf("  Only in B: %v\n", hOnlyB))
-			}
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return gomcp.NewToolResultError(fmt.Sprintf("failed to marshal diff: %v", err)), nil
 		}
+		return gomcp.NewToolResultText(string(data)), nil
+	case "patch":
+		diff.WritePatch(&sb, report, diff.Options{})
+	case "text":
+		diff.WriteText(&sb, report, diff.Options{})
+	default:
+		return gomcp.NewToolResultError(fmt.Sprintf("unknown format %q (supported: text, json, patch)", format)), nil
 	}
-
 	return gomcp.NewToolResultText(sb.String()), nil
 }
 
-// populatedSections returns the TOML tag names of non-nil pointer sections in a Snapshot.
-func populatedSections(snap *domain.Snapshot) []string {
-	var sections []string
-	v := reflect.ValueOf(*snap)
-	t := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		if field.Kind() == reflect.Ptr && !field.IsNil() {
-			tag := t.Field(i).Tag.Get("toml")
-			if tag == "" {
-				continue
-			}
-			// Strip ",omitempty" or similar suffixes
-			if idx := strings.Index(tag, ","); idx != -1 {
-				tag = tag[:idx]
-			}
-			sections = append(sections, tag)
-		}
+// handleCheckDrift compares the live machine against a manifest and returns
+// the drift report as JSON.
+func (s *MachinistServer) handleCheckDrift(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+	manifest, err := req.RequireString("manifest")
+	if err != nil {
+		return gomcp.NewToolResultError(err.Error()), nil
+	}
+	snap, err := parseManifest(manifest)
+	if err != nil {
+		return gomcp.NewToolResultError(fmt.Sprintf("invalid manifest: %v", err)), nil
 	}
-	return sections
+
+	report, err := drift.Detect(ctx, s.registry, snap, drift.Options{})
+	if err != nil {
+		return gomcp.NewToolResultError(fmt.Sprintf("drift check failed: %v", err)), nil
+	}
+	data, err := json.MarshalIndent(struct {
+		Drifted bool `json:"drifted"`
+		*drift.Report
+	}{report.Drifted(), report}, "", "  ")
+	if err != nil {
+		return gomcp.NewToolResultError(fmt.Sprintf("failed to marshal report: %v", err)), nil
+	}
+	return gomcp.NewToolResultText(string(data)), nil
 }
 
-// registerResources registers MCP resources for system snapshot and profiles.
+// registerResources registers MCP resources for system snapshot, the
+// manifest schema and profiles.
 func (s *MachinistServer) registerResources() {
 	// Static resource: system snapshot
 	s.server.AddResource(
@@ -501,6 +492,17 @@ func (s *MachinistServer) registerResources() {
 		s.handleSnapshotResource,
 	)
 
+	// Static resource: manifest JSON Schema
+	s.server.AddResource(
+		gomcp.NewResource(
+			"machinist://schema/manifest",
+			"Manifest Schema",
+			gomcp.WithResourceDescription("JSON Schema of the TOML manifest format, with descriptions and allowed values for every section"),
+			gomcp.WithMIMEType("application/schema+json"),
+		),
+		s.handleSchemaResource,
+	)
+
 	// Template resource: profiles by name
 	s.server.AddResourceTemplate(
 		gomcp.NewResourceTemplate(
@@ -534,6 +536,22 @@ func (s *MachinistServer) handleSnapshotResource(ctx context.Context, req gomcp.
 	}, nil
 }
 
+// handleSchemaResource returns the manifest JSON Schema.
+func (s *MachinistServer) handleSchemaResource(_ context.Context, _ gomcp.ReadResourceRequest) ([]gomcp.ResourceContents, error) {
+	data, err := schema.JSONSchema()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate schema: %w", err)
+	}
+
+	return []gomcp.ResourceContents{
+		gomcp.TextResourceContents{
+			URI:      "machinist://schema/manifest",
+			MIMEType: "application/schema+json",
+			Text:     string(data),
+		},
+	}, nil
+}
+
 // handleProfileResource returns a specific profile TOML by name.
 func (s *MachinistServer) handleProfileResource(_ context.Context, req gomcp.ReadResourceRequest) ([]gomcp.ResourceContents, error) {
 	uri := req.Params.URI
@@ -3,14 +3,19 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	gomcp "github.com/mark3labs/mcp-go/mcp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/moinsen-dev/machinist/internal/diff"
 	"github.com/moinsen-dev/machinist/internal/domain"
 	"github.com/moinsen-dev/machinist/internal/scanner"
+	"github.com/moinsen-dev/machinist/profiles"
 )
 
 // mockScanner implements scanner.Scanner for testing.
@@ -160,6 +165,26 @@ func TestScanAll(t *testing.T) {
 	assert.Contains(t, text, "[shell]")
 }
 
+func TestScanAll_ReportsDiagnostics(t *testing.T) {
+	reg := newTestRegistry(
+		&mockScanner{name: "homebrew", err: fmt.Errorf("brew not installed")},
+		&mockScanner{
+			name:   "shell",
+			result: &scanner.ScanResult{ScannerName: "shell", Shell: &domain.ShellSection{DefaultShell: "/bin/zsh"}},
+		},
+	)
+	srv := NewMachinistServer(reg)
+
+	result, err := callTool(srv, "scan_all", nil)
+	require.NoError(t, err)
+	assert.False(t, result.IsError, "a failed scanner does not fail the whole scan")
+
+	text := getTextContent(t, result)
+	assert.Contains(t, text, "[shell]")
+	assert.Contains(t, text, "[[meta.diagnostics]]")
+	assert.Contains(t, text, "brew not installed")
+}
+
 func TestListProfiles(t *testing.T) {
 	srv := NewMachinistServer(scanner.NewRegistry())
 
@@ -170,11 +195,31 @@ func TestListProfiles(t *testing.T) {
 
 	text := getTextContent(t, result)
 
-	var profiles []string
-	err = json.Unmarshal([]byte(text), &profiles)
+	var infos []profiles.Info
+	err = json.Unmarshal([]byte(text), &infos)
 	require.NoError(t, err)
-	assert.Greater(t, len(profiles), 0, "should find at least one profile")
-	assert.Contains(t, profiles, "minimal")
+	assert.Greater(t, len(infos), 0, "should find at least one profile")
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+		assert.NotEmpty(t, info.Description, "profile %s has no description", info.Name)
+	}
+	assert.Contains(t, names, "minimal")
+}
+
+func TestListProfiles_Tag(t *testing.T) {
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	result, err := callTool(srv, "list_profiles", map[string]interface{}{"tag": "mobile"})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+
+	var infos []profiles.Info
+	require.NoError(t, json.Unmarshal([]byte(getTextContent(t, result)), &infos))
+	require.Len(t, infos, 1)
+	assert.Equal(t, "flutter-ios", infos[0].Name)
+	assert.Equal(t, "embedded", infos[0].Source)
+	assert.Contains(t, infos[0].RequiredGroups, "runtimes")
 }
 
 func TestGetProfile(t *testing.T) {
@@ -190,6 +235,22 @@ func TestGetProfile(t *testing.T) {
 	assert.Contains(t, text, "git")
 }
 
+func TestGetProfile_SearchPath(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team.toml"), []byte("[go]\nversion = \"1.25\"\n"), 0o644))
+	t.Setenv(profiles.PathEnv, dir)
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	result, err := callTool(srv, "list_profiles", nil)
+	require.NoError(t, err)
+	assert.Contains(t, getTextContent(t, result), `"team"`)
+
+	result, err = callTool(srv, "get_profile", map[string]interface{}{"name": "team"})
+	require.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Contains(t, getTextContent(t, result), `version = "1.25"`)
+}
+
 func TestGetProfile_NotFound(t *testing.T) {
 	srv := NewMachinistServer(scanner.NewRegistry())
 
@@ -233,6 +294,100 @@ func TestComposeManifest_NoAddPackages(t *testing.T) {
 	assert.Contains(t, text, "[homebrew]")
 }
 
+func TestComposeManifest_Overlay(t *testing.T) {
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	overlay := `
+[meta.merge]
+"homebrew.casks" = "remove"
+
+[[homebrew.casks]]
+name = "iterm2"
+
+[vscode]
+extensions = ["golang.go"]
+`
+	result, err := callTool(srv, "compose_manifest", map[string]interface{}{
+		"base_profile": "minimal",
+		"overlay":      overlay,
+		"add_packages": []interface{}{"git"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.False(t, result.IsError)
+
+	snap, err := domain.UnmarshalManifest([]byte(getTextContent(t, result)))
+	require.NoError(t, err)
+	for _, c := range snap.Homebrew.Casks {
+		assert.NotEqual(t, "iterm2", c.Name, "the overlay removes iterm2")
+	}
+	git := 0
+	for _, f := range snap.Homebrew.Formulae {
+		if f.Name == "git" {
+			git++
+		}
+	}
+	assert.Equal(t, 1, git, "packages already in the profile are not duplicated")
+	require.NotNil(t, snap.VSCode)
+	assert.Contains(t, snap.VSCode.Extensions, "golang.go")
+}
+
+func TestComposeManifest_TypedItems(t *testing.T) {
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	result, err := callTool(srv, "compose_manifest", map[string]interface{}{
+		"base_profile":    "minimal",
+		"add_packages":    []interface{}{"cask:docker", "cargo:ripgrep", "mas:497799835=Xcode"},
+		"remove_packages": []interface{}{"cask:iterm2"},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.False(t, result.IsError)
+
+	snap, err := domain.UnmarshalManifest([]byte(getTextContent(t, result)))
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Package{{Name: "visual-studio-code"}, {Name: "docker"}}, snap.Homebrew.Casks)
+	require.NotNil(t, snap.Rust)
+	assert.Equal(t, []domain.Package{{Name: "ripgrep"}}, snap.Rust.CargoPackages)
+	require.NotNil(t, snap.Apps)
+	assert.Equal(t, 497799835, snap.Apps.AppStore[0].ID)
+}
+
+func TestComposeManifest_UnknownItemType(t *testing.T) {
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	result, err := callTool(srv, "compose_manifest", map[string]interface{}{
+		"base_profile": "minimal",
+		"add_packages": []interface{}{"pip:requests"},
+	})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
+	assert.Contains(t, getTextContent(t, result), "unknown item type")
+}
+
+func TestComposeManifest_MultipleProfiles(t *testing.T) {
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	result, err := callTool(srv, "compose_manifest", map[string]interface{}{
+		"base_profile": "go-dev+devops",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.False(t, result.IsError)
+
+	snap, err := domain.UnmarshalManifest([]byte(getTextContent(t, result)))
+	require.NoError(t, err)
+	require.NotNil(t, snap.Go)
+	var formulae []string
+	for _, f := range snap.Homebrew.Formulae {
+		formulae = append(formulae, f.Name)
+	}
+	assert.Contains(t, formulae, "go")
+	assert.Contains(t, formulae, "kubectl")
+	require.Len(t, snap.Meta.Layers, 2)
+	assert.Equal(t, "profile:devops", snap.Meta.Layers[1].Source)
+}
+
 func TestValidateManifest_Valid(t *testing.T) {
 	srv := NewMachinistServer(scanner.NewRegistry())
 
@@ -292,6 +447,45 @@ func TestValidateManifest_Invalid(t *testing.T) {
 	assert.Contains(t, resp["error"].(string), "parse error")
 }
 
+func TestValidateManifest_Issues(t *testing.T) {
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	manifest := `[meta]
+schema_version = 2
+
+[homebrew]
+taps = ["homebrew/core"]
+tapz = ["x/y"]
+
+[git]
+[[git.config_files]]
+source = ".gitconfig"
+bundle_path = "../outside"
+`
+	result, err := callTool(srv, "validate_manifest", map[string]interface{}{
+		"manifest": manifest,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+
+	var resp struct {
+		Valid  bool `json:"valid"`
+		Issues []struct {
+			Code string `json:"code"`
+			Key  string `json:"key"`
+			Line int    `json:"line"`
+		} `json:"issues"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(getTextContent(t, result)), &resp))
+	assert.False(t, resp.Valid)
+	require.Len(t, resp.Issues, 2)
+	assert.Equal(t, "unknown-key", resp.Issues[0].Code)
+	assert.Equal(t, "homebrew.tapz", resp.Issues[0].Key)
+	assert.Equal(t, 6, resp.Issues[0].Line)
+	assert.Equal(t, "bundle-path-escape", resp.Issues[1].Code)
+	assert.Equal(t, 10, resp.Issues[1].Line)
+}
+
 func TestDiffManifests(t *testing.T) {
 	srv := NewMachinistServer(scanner.NewRegistry())
 
@@ -324,12 +518,28 @@ manager = "pyenv"
 	assert.False(t, result.IsError)
 
 	text := getTextContent(t, result)
-	assert.Contains(t, text, "rust")
-	assert.Contains(t, text, "python")
-	assert.Contains(t, text, "homebrew")
+	assert.Contains(t, text, "Only in A: rust")
+	assert.Contains(t, text, "Only in B: python")
+	assert.Contains(t, text, `- default_toolchain = "stable"`)
+	assert.Contains(t, text, `+ manager = "pyenv"`)
+	assert.NotContains(t, text, "[homebrew]", "identical sections must not be reported")
+}
+
+func TestDiffManifests_ResolvesExtends(t *testing.T) {
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	result, err := callTool(srv, "diff_manifests", map[string]interface{}{
+		"manifest_a": "[meta]\nextends = [\"profile:go-dev\"]\n",
+		"manifest_b": "[go]\nversion = \"1.24\"\n",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.False(t, result.IsError)
+
+	text := getTextContent(t, result)
 	assert.Contains(t, text, "Only in A:")
-	assert.Contains(t, text, "Only in B:")
-	assert.Contains(t, text, "In both:")
+	assert.Contains(t, text, "homebrew")
+	assert.Contains(t, text, "[go]")
 }
 
 func TestDiffManifests_HomebrewDiff(t *testing.T) {
@@ -367,10 +577,69 @@ name = "httpie"
 	assert.False(t, result.IsError)
 
 	text := getTextContent(t, result)
-	assert.Contains(t, text, "Homebrew diff:")
-	assert.Contains(t, text, "wget")
-	assert.Contains(t, text, "tree")
-	assert.Contains(t, text, "httpie")
+	assert.Contains(t, text, "[homebrew]")
+	assert.Contains(t, text, "- formulae[wget]")
+	assert.Contains(t, text, "- formulae[tree]")
+	assert.Contains(t, text, "+ formulae[httpie]")
+}
+
+func TestDiffManifests_VersionsJSON(t *testing.T) {
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	result, err := callTool(srv, "diff_manifests", map[string]interface{}{
+		"manifest_a": "[[homebrew.formulae]]\nname = \"node\"\nversion = \"20\"\n",
+		"manifest_b": "[[homebrew.formulae]]\nname = \"node\"\nversion = \"22\"\n",
+		"format":     "json",
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	var report diff.Report
+	require.NoError(t, json.Unmarshal([]byte(getTextContent(t, result)), &report))
+	require.Len(t, report.Changes, 1)
+	assert.Equal(t, diff.Changed, report.Changes[0].Kind)
+	assert.Equal(t, "homebrew.formulae[node].version", report.Changes[0].Key)
+	assert.Equal(t, "20", report.Changes[0].Old)
+	assert.Equal(t, "22", report.Changes[0].New)
+}
+
+func TestCheckDrift(t *testing.T) {
+	reg := newTestRegistry(&mockScanner{
+		name: "homebrew",
+		result: &scanner.ScanResult{
+			ScannerName: "homebrew",
+			Homebrew:    &domain.HomebrewSection{Formulae: []domain.Package{{Name: "git"}, {Name: "htop"}}},
+		},
+	})
+	srv := NewMachinistServer(reg)
+
+	result, err := callTool(srv, "check_drift", map[string]interface{}{
+		"manifest": "[[homebrew.formulae]]\nname = \"git\"\n\n[[homebrew.formulae]]\nname = \"jq\"\n\n[tmux]\ntpm_plugins = [\"tmux-plugins/tpm\"]\n",
+	})
+	require.NoError(t, err)
+	require.False(t, result.IsError)
+
+	var resp struct {
+		Drifted   bool          `json:"drifted"`
+		Missing   []diff.Change `json:"missing"`
+		Extra     []diff.Change `json:"extra"`
+		Unchecked []string      `json:"unchecked"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(getTextContent(t, result)), &resp))
+	assert.True(t, resp.Drifted)
+	require.Len(t, resp.Missing, 1)
+	assert.Equal(t, "homebrew.formulae[jq]", resp.Missing[0].Key)
+	require.Len(t, resp.Extra, 1)
+	assert.Equal(t, "homebrew.formulae[htop]", resp.Extra[0].Key)
+	assert.Equal(t, []string{"tmux"}, resp.Unchecked)
+}
+
+func TestCheckDrift_InvalidManifest(t *testing.T) {
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	result, err := callTool(srv, "check_drift", map[string]interface{}{"manifest": "not valid toml [[["})
+	require.NoError(t, err)
+	assert.True(t, result.IsError)
 }
 
 func TestBuildDMG_InvalidManifest(t *testing.T) {
@@ -406,3 +675,22 @@ func getTextContent(t *testing.T, result *gomcp.CallToolResult) string {
 	require.True(t, ok, "expected TextContent, got %T", result.Content[0])
 	return tc.Text
 }
+
+func TestSchemaResource(t *testing.T) {
+	srv := NewMachinistServer(scanner.NewRegistry())
+
+	var req gomcp.ReadResourceRequest
+	req.Params.URI = "machinist://schema/manifest"
+	contents, err := srv.handleSchemaResource(context.Background(), req)
+	require.NoError(t, err)
+	require.Len(t, contents, 1)
+
+	text, ok := contents[0].(gomcp.TextResourceContents)
+	require.True(t, ok)
+	assert.Equal(t, "application/schema+json", text.MIMEType)
+
+	var s map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(text.Text), &s))
+	assert.Contains(t, s["properties"], "homebrew")
+	assert.Contains(t, s["definitions"], "AsdfSection")
+}
@@ -0,0 +1,420 @@
+// Package plan lists what a restore would do, item by item: every tap,
+// package, config file copy, macOS default and repository clone, each with
+// whether the machine already has it.
+package plan
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/moinsen-dev/machinist/internal/diff"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/drift"
+	"github.com/moinsen-dev/machinist/internal/scanner"
+	"github.com/moinsen-dev/machinist/internal/util"
+)
+
+// Status says what restoring an item would change on this machine.
+type Status string
+
+const (
+	// Satisfied items are already on the machine as the manifest has them.
+	Satisfied Status = "satisfied"
+	// Install items are missing from the machine.
+	Install Status = "install"
+	// Overwrite items exist with another value or content.
+	Overwrite Status = "overwrite"
+	// Unknown items belong to sections no available scanner could check,
+	// because their scanner is disabled, failed or timed out.
+	Unknown Status = "unknown"
+)
+
+// Action is one thing a restore does.
+type Action struct {
+	Group   string `json:"group"`
+	Section string `json:"section"`
+	// Key is the manifest value the action restores, named like a
+	// diff.Change key, e.g. "homebrew.formulae[jq]".
+	Key string `json:"key"`
+	// Description says what restore does, e.g. "brew install jq" or
+	// "copy configs/git/.gitconfig → ~/.gitconfig".
+	Description string `json:"description"`
+	Status      Status `json:"status"`
+}
+
+// Plan is every action of a restore, in restore order.
+type Plan struct {
+	Groups  []string `json:"groups"`
+	Stages  []string `json:"stages"`
+	Actions []Action `json:"actions"`
+	// Unchecked are the sections, or named section entries, whose actions
+	// could not be probed.
+	Unchecked   []string            `json:"unchecked"`
+	Diagnostics []domain.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// Count returns the number of actions with status s.
+func (p *Plan) Count(s Status) int {
+	n := 0
+	for _, a := range p.Actions {
+		if a.Status == s {
+			n++
+		}
+	}
+	return n
+}
+
+// Options configure a plan.
+type Options struct {
+	// Stages are the names of the restore stages to plan, as returned by
+	// domain.SelectStages.
+	Stages []string
+	// HomeDir is the home directory files are restored to. It defaults to
+	// the user's.
+	HomeDir string
+	// BundleDir is the directory bundle paths are relative to, usually the
+	// manifest's.
+	BundleDir string
+	// Progress, when set, is called as the scanners run.
+	Progress scanner.ProgressFunc
+}
+
+// Build plans restoring the stages in opts from manifest, probing this
+// machine with the scanners in reg. Config files and repositories are
+// probed on disk instead. Actions in the sections of scanners that fail are
+// Unknown, and the failures are in the plan's Diagnostics.
+func Build(ctx context.Context, reg *scanner.Registry, manifest *domain.Snapshot, opts Options) (*Plan, error) {
+	if opts.HomeDir == "" {
+		opts.HomeDir, _ = os.UserHomeDir()
+	}
+	scoped := selectStages(manifest, opts.Stages)
+	probe := *scoped
+	probe.GitRepos = nil
+	report, err := drift.Detect(ctx, reg, &probe, drift.Options{HomeDir: opts.HomeDir, Progress: opts.Progress})
+	if err != nil {
+		return nil, err
+	}
+	return New(scoped, report, opts), nil
+}
+
+// New plans restoring manifest on a machine that drifted from it as report
+// says. Every value of manifest is one action.
+func New(manifest *domain.Snapshot, report *drift.Report, opts Options) *Plan {
+	p := &Plan{
+		Groups:      []string{},
+		Stages:      append([]string{}, opts.Stages...),
+		Actions:     []Action{},
+		Unchecked:   append([]string{}, report.Unchecked...),
+		Diagnostics: report.Diagnostics,
+	}
+	for _, c := range diff.Manifests(manifest, &domain.Snapshot{}).Changes {
+		s, _ := domain.SectionByKey(c.Section)
+		if !slices.Contains(opts.Stages, s.Template) {
+			continue
+		}
+		if !slices.Contains(p.Groups, s.Group) {
+			p.Groups = append(p.Groups, s.Group)
+		}
+		a := Action{Group: s.Group, Section: c.Section, Key: c.Key}
+		item, _ := c.Old.(map[string]any)
+		switch {
+		case item["bundle_path"] != nil && item["source"] != nil:
+			a.Description, a.Status = opts.copyFile(item)
+		case item["remote"] != nil && item["path"] != nil:
+			a.Description, a.Status = opts.cloneRepo(item)
+		default:
+			a.Description = describe(manifest, c)
+			a.Status = status(c.Key, report)
+		}
+		p.Actions = append(p.Actions, a)
+	}
+	return p
+}
+
+// selectStages returns a copy of manifest with just the sections of the
+// named stages.
+func selectStages(manifest *domain.Snapshot, stages []string) *domain.Snapshot {
+	out := *manifest
+	v := reflect.ValueOf(&out).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("toml"), ",")
+		if s, ok := domain.SectionByKey(key); ok && !slices.Contains(stages, s.Template) {
+			v.Field(i).SetZero()
+		}
+	}
+	return &out
+}
+
+// status classifies the manifest value at key by the drift report.
+func status(key string, r *drift.Report) Status {
+	within := func(k string) bool {
+		return strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[")
+	}
+	for _, u := range r.Unchecked {
+		if key == u || strings.HasPrefix(key, u+".") || strings.HasPrefix(key, u+"[") {
+			return Unknown
+		}
+	}
+	for _, c := range r.Missing {
+		if c.Key == key {
+			return Install
+		}
+		if within(c.Key) {
+			return Overwrite
+		}
+	}
+	for _, c := range r.Changed {
+		if c.Key == key || within(c.Key) {
+			return Overwrite
+		}
+	}
+	return Satisfied
+}
+
+// commands describe the list items restore installs with a command, like
+// the stage templates run them. A describer returns "" when restore does
+// not install the item, such as a Node version without a version manager.
+var commands = map[string]func(m *domain.Snapshot, item any) string{
+	"homebrew.taps": func(_ *domain.Snapshot, item any) string { return fmt.Sprintf("brew tap %v", item) },
+	"homebrew.formulae": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("brew install %v", name(item))
+	},
+	"homebrew.casks": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("brew install --cask %v", name(item))
+	},
+	"homebrew.services": func(_ *domain.Snapshot, item any) string {
+		if item.(map[string]any)["status"] != "started" {
+			return ""
+		}
+		return fmt.Sprintf("brew services start %v", name(item))
+	},
+	"node.versions": func(m *domain.Snapshot, item any) string {
+		switch m.Node.Manager {
+		case "nvm":
+			return fmt.Sprintf("nvm install %v", item)
+		case "fnm":
+			return fmt.Sprintf("fnm install %v", item)
+		}
+		return ""
+	},
+	"node.global_packages": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("npm install -g %v", name(item))
+	},
+	"python.versions": func(m *domain.Snapshot, item any) string {
+		switch m.Python.Manager {
+		case "pyenv":
+			return fmt.Sprintf("pyenv install -s %v", item)
+		case "uv":
+			return fmt.Sprintf("uv python install %v", item)
+		}
+		return ""
+	},
+	"python.global_packages": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("pip install %v", name(item))
+	},
+	"rust.toolchains": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("rustup toolchain install %v", item)
+	},
+	"rust.components": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("rustup component add %v", item)
+	},
+	"rust.cargo_packages": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("cargo install %v", name(item))
+	},
+	"java.versions": func(m *domain.Snapshot, item any) string {
+		if m.Java.Manager != "sdkman" {
+			return ""
+		}
+		return fmt.Sprintf("sdk install java %v", item)
+	},
+	"flutter.dart_global_packages": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("dart pub global activate %v", item)
+	},
+	"go.global_packages": func(_ *domain.Snapshot, item any) string {
+		version, _ := item.(map[string]any)["version"].(string)
+		if version == "" {
+			version = "latest"
+		}
+		return fmt.Sprintf("go install %v@%s", name(item), version)
+	},
+	"asdf.plugins": func(m *domain.Snapshot, item any) string {
+		p := item.(map[string]any)
+		versions, _ := p["versions"].([]any)
+		if m.Asdf.Manager == "mise" {
+			cmds := make([]string, len(versions))
+			for i, v := range versions {
+				cmds[i] = fmt.Sprintf("mise install %v@%v", p["name"], v)
+			}
+			return strings.Join(cmds, " && ")
+		}
+		cmds := []string{fmt.Sprintf("asdf plugin add %v", p["name"])}
+		for _, v := range versions {
+			cmds = append(cmds, fmt.Sprintf("asdf install %v %v", p["name"], v))
+		}
+		return strings.Join(cmds, " && ")
+	},
+	"deno.global_packages": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("deno install -g %v", name(item))
+	},
+	"bun.global_packages": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("bun install -g %v", name(item))
+	},
+	"ruby.global_gems": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("gem install %v", name(item))
+	},
+	"github_cli.extensions": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("gh extension install %v", item)
+	},
+	"vscode.extensions": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("code --install-extension %v", item)
+	},
+	"cursor.extensions": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("cursor --install-extension %v", item)
+	},
+	"docker.frequently_used_images": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("docker pull %v", item)
+	},
+	"ai_tools.ollama_models": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("ollama pull %v", item)
+	},
+	"apps.app_store": func(_ *domain.Snapshot, item any) string {
+		id, ok := item.(map[string]any)["id"]
+		if !ok {
+			return ""
+		}
+		return fmt.Sprintf("mas install %v", id)
+	},
+	"fonts.homebrew_fonts": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("brew install --cask %v", item)
+	},
+	"fonts.custom_fonts": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("copy configs/fonts/%v → ~/Library/Fonts/", name(item))
+	},
+	"folders.structure": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("mkdir -p ~/%v", item)
+	},
+	"xdg_config.auto_detected": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("copy configs/xdg-config/%v → ~/.config/%v", item, item)
+	},
+	"ssh.keys": func(m *domain.Snapshot, item any) string {
+		if m.SSH.Encrypted {
+			return fmt.Sprintf("decrypt configs/ssh/%v.age → ~/.ssh/%v", item, item)
+		}
+		return fmt.Sprintf("copy configs/ssh/%v → ~/.ssh/%v", item, item)
+	},
+	"gpg.keys": func(m *domain.Snapshot, item any) string {
+		if m.GPG.Encrypted {
+			return fmt.Sprintf("gpg --import configs/gpg/%v.asc.age", item)
+		}
+		return fmt.Sprintf("gpg --import configs/gpg/%v.asc", item)
+	},
+	"crontab.entries": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("crontab += %v", item)
+	},
+	"hosts_file.custom_entries": func(_ *domain.Snapshot, item any) string {
+		e := item.(map[string]any)
+		line := fmt.Sprint(e["ip"])
+		hostnames, _ := e["hostnames"].([]any)
+		for _, h := range hostnames {
+			line += fmt.Sprintf(" %v", h)
+		}
+		return fmt.Sprintf("append %q to /etc/hosts", line)
+	},
+	"plugins.*.packages": func(_ *domain.Snapshot, item any) string {
+		return fmt.Sprintf("brew install %v", name(item))
+	},
+	"macos_defaults.defaults": func(_ *domain.Snapshot, item any) string {
+		d := item.(map[string]any)
+		return fmt.Sprintf("defaults write %v %v -%v %v", d["domain"], d["key"], d["value_type"], d["value"])
+	},
+}
+
+// name returns the name of a list item decoded as a table.
+func name(item any) any { return item.(map[string]any)["name"] }
+
+// describe says what restoring the value of c, from manifest, does.
+func describe(manifest *domain.Snapshot, c diff.Change) string {
+	if i := strings.Index(c.Key, "["); c.Item != "" && i > 0 {
+		if f, ok := commands[listKey(c.Key[:i])]; ok {
+			if desc := f(manifest, c.Old); desc != "" {
+				return desc
+			}
+		}
+	}
+	c.Kind = diff.Removed
+	return c.String()
+}
+
+// listKey names the list at key as commands does, with the plugin name of
+// a plugins.<name>.packages list replaced by *.
+func listKey(key string) string {
+	if rest, ok := strings.CutPrefix(key, "plugins."); ok {
+		if _, list, ok := strings.Cut(rest, "."); ok {
+			return "plugins.*." + list
+		}
+	}
+	return key
+}
+
+// copyFile describes copying a config file from the bundle and probes its
+// destination: a file with the manifest's content hash, or the bundled
+// file's when the manifest has none, is satisfied.
+func (o Options) copyFile(item map[string]any) (string, Status) {
+	src, _ := item["bundle_path"].(string)
+	dst := o.homePath(fmt.Sprint(item["source"]))
+	desc := fmt.Sprintf("copy %s → %s", src, tildePath(dst, o.HomeDir))
+
+	info, err := os.Stat(dst)
+	switch {
+	case err != nil:
+		return desc, Install
+	case info.IsDir():
+		return desc, Overwrite
+	}
+	want, _ := item["content_hash"].(string)
+	if want == "" {
+		want, _ = util.ContentHash(filepath.Join(o.BundleDir, src))
+	}
+	if have, err := util.ContentHash(dst); err == nil && want != "" && have == want {
+		return desc, Satisfied
+	}
+	return desc, Overwrite
+}
+
+// cloneRepo describes cloning a repository; like restore, it skips a
+// target directory that already exists.
+func (o Options) cloneRepo(item map[string]any) (string, Status) {
+	path := fmt.Sprint(item["path"])
+	desc := fmt.Sprintf("git clone %v → %s", item["remote"], path)
+	if util.DirExists(o.homePath(path)) {
+		return desc, Satisfied
+	}
+	return desc, Install
+}
+
+// homePath resolves a path written relative to the home directory, with
+// ~ or $HOME, to an absolute path.
+func (o Options) homePath(p string) string {
+	for _, prefix := range []string{"~/", "$HOME/", "${HOME}/"} {
+		if rest, ok := strings.CutPrefix(p, prefix); ok {
+			return filepath.Join(o.HomeDir, rest)
+		}
+	}
+	if filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(o.HomeDir, p)
+}
+
+// tildePath writes p in home with a leading ~.
+func tildePath(p, home string) string {
+	if rel, err := filepath.Rel(home, p); err == nil && !strings.HasPrefix(rel, "..") {
+		return "~/" + rel
+	}
+	return p
+}
@@ -0,0 +1,280 @@
+package plan
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/bundler"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/drift"
+	"github.com/moinsen-dev/machinist/internal/scanner"
+	"github.com/moinsen-dev/machinist/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func hash(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "f")
+	write(t, path, content)
+	h, err := util.ContentHash(path)
+	require.NoError(t, err)
+	return h
+}
+
+func newHireManifest(t *testing.T) *domain.Snapshot {
+	return &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{
+			Taps:     []string{"homebrew/cask-fonts"},
+			Formulae: []domain.Package{{Name: "git"}, {Name: "jq"}},
+			Casks:    []domain.Package{{Name: "docker"}},
+		},
+		Git: &domain.GitSection{ConfigFiles: []domain.ConfigFile{
+			{Source: ".gitconfig", BundlePath: "configs/git/.gitconfig", ContentHash: hash(t, "[user]\n\tname = Team\n")},
+		}},
+		Tmux: &domain.TmuxSection{ConfigFiles: []domain.ConfigFile{
+			{Source: ".tmux.conf", BundlePath: "configs/tmux/.tmux.conf"},
+		}},
+		Go: &domain.GoSection{Version: "1.25"},
+		GitRepos: &domain.GitReposSection{Repositories: []domain.Repository{
+			{Remote: "git@github.com:team/api.git", Path: "~/Code/api"},
+			{Remote: "git@github.com:team/web.git", Path: "~/Code/web"},
+		}},
+		MacOSDefaults: &domain.MacOSDefaultsSection{Defaults: []domain.MacDefault{
+			{Domain: "com.apple.finder", Key: "ShowPathbar", Value: "true", ValueType: "bool"},
+		}},
+	}
+}
+
+func statuses(p *Plan) map[string]string {
+	out := make(map[string]string, len(p.Actions))
+	for _, a := range p.Actions {
+		out[a.Description] = string(a.Status)
+	}
+	return out
+}
+
+func TestNew(t *testing.T) {
+	home := t.TempDir()
+	bundle := t.TempDir()
+	write(t, filepath.Join(home, ".gitconfig"), "[user]\n\tname = Me\n")
+	write(t, filepath.Join(bundle, "configs/tmux/.tmux.conf"), "set -g mouse on\n")
+	write(t, filepath.Join(home, ".tmux.conf"), "set -g mouse on\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "Code/api/.git"), 0o755))
+
+	manifest := newHireManifest(t)
+	machine := &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{
+			Taps:     []string{"homebrew/cask-fonts"},
+			Formulae: []domain.Package{{Name: "git", Version: "2.47.0"}},
+		},
+		Go: &domain.GoSection{Version: "1.24"},
+	}
+	report, err := drift.Compare(manifest, machine, home, []string{"macos_defaults"})
+	require.NoError(t, err)
+
+	stages, err := domain.SelectStages(manifest, nil, nil)
+	require.NoError(t, err)
+	p := New(manifest, report, Options{Stages: stages, HomeDir: home, BundleDir: bundle})
+	assert.Equal(t, map[string]string{
+		"brew tap homebrew/cask-fonts":                           "satisfied",
+		"brew install git":                                       "satisfied",
+		"brew install jq":                                        "install",
+		"brew install --cask docker":                             "install",
+		"copy configs/git/.gitconfig → ~/.gitconfig":             "overwrite",
+		"copy configs/tmux/.tmux.conf → ~/.tmux.conf":            "satisfied",
+		`go.version = "1.25"`:                                    "overwrite",
+		"git clone git@github.com:team/api.git → ~/Code/api":     "satisfied",
+		"git clone git@github.com:team/web.git → ~/Code/web":     "install",
+		"defaults write com.apple.finder ShowPathbar -bool true": "unknown",
+	}, statuses(p))
+
+	assert.Equal(t, 3, p.Count(Install))
+	assert.Equal(t, 2, p.Count(Overwrite))
+	assert.Equal(t, 4, p.Count(Satisfied))
+	assert.Equal(t, 1, p.Count(Unknown))
+	assert.Equal(t, "homebrew", p.Actions[0].Group)
+	assert.Equal(t, "homebrew.taps[homebrew/cask-fonts]", p.Actions[0].Key)
+}
+
+func TestNew_MissingFile(t *testing.T) {
+	manifest := &domain.Snapshot{Git: &domain.GitSection{ConfigFiles: []domain.ConfigFile{
+		{Source: ".gitconfig", BundlePath: "configs/git/.gitconfig"},
+	}}}
+	p := New(manifest, &drift.Report{}, Options{Stages: []string{"git-config"}, HomeDir: t.TempDir()})
+	require.Len(t, p.Actions, 1)
+	assert.Equal(t, Install, p.Actions[0].Status)
+}
+
+// fakeScanner returns a fixed result or error.
+type fakeScanner struct {
+	name   string
+	result *scanner.ScanResult
+	err    error
+}
+
+func (f fakeScanner) Name() string        { return f.name }
+func (f fakeScanner) Description() string { return "" }
+func (f fakeScanner) Category() string    { return "" }
+func (f fakeScanner) Scan(context.Context) (*scanner.ScanResult, error) {
+	return f.result, f.err
+}
+
+func TestBuild_Stages(t *testing.T) {
+	reg := scanner.NewRegistry()
+	require.NoError(t, reg.Register(fakeScanner{name: "homebrew", result: &scanner.ScanResult{
+		ScannerName: "homebrew",
+		Homebrew:    &domain.HomebrewSection{Formulae: []domain.Package{{Name: "git"}}},
+	}}))
+
+	p, err := Build(context.Background(), reg, newHireManifest(t), Options{Stages: []string{"homebrew", "tmux"}, HomeDir: t.TempDir()})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"homebrew", "configs"}, p.Groups)
+	assert.Equal(t, 5, len(p.Actions))
+	assert.Equal(t, "copy configs/tmux/.tmux.conf → ~/.tmux.conf", p.Actions[4].Description)
+	assert.Equal(t, 1, p.Count(Satisfied))
+	assert.Equal(t, []string{"tmux"}, p.Unchecked)
+}
+
+func TestBuild_FailedScanner(t *testing.T) {
+	reg := scanner.NewRegistry()
+	require.NoError(t, reg.Register(fakeScanner{name: "homebrew", err: errors.New("every brew command failed")}))
+
+	p, err := Build(context.Background(), reg, newHireManifest(t), Options{Stages: []string{"homebrew"}, HomeDir: t.TempDir()})
+	require.NoError(t, err)
+	require.Len(t, p.Actions, 4)
+	assert.Equal(t, 4, p.Count(Unknown), "a failed scan says nothing about what is installed")
+	assert.Equal(t, []string{"homebrew"}, p.Unchecked)
+	require.Len(t, p.Diagnostics, 1)
+	assert.Equal(t, "homebrew", p.Diagnostics[0].Scanner)
+}
+
+// listManifest has an item in every list restore installs with a command.
+func listManifest() *domain.Snapshot {
+	return &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{
+			Services: []domain.ServiceEntry{{Name: "postgresql@16", Status: "started"}},
+		},
+		Node:    &domain.NodeSection{Manager: "nvm", Versions: []string{"22.3.0"}, GlobalPackages: []domain.Package{{Name: "pnpm"}}},
+		Python:  &domain.PythonSection{Manager: "uv", Versions: []string{"3.12"}, GlobalPackages: []domain.Package{{Name: "ruff"}}},
+		Rust:    &domain.RustSection{Toolchains: []string{"stable"}, Components: []string{"clippy"}, CargoPackages: []domain.Package{{Name: "ripgrep"}}},
+		Java:    &domain.JavaSection{Manager: "sdkman", Versions: []string{"21.0.2-tem"}},
+		Flutter: &domain.FlutterSection{DartGlobalPackages: []string{"melos"}},
+		Go:      &domain.GoSection{GlobalPackages: []domain.Package{{Name: "golang.org/x/tools/gopls", Version: "v0.16.0"}}},
+		Asdf: &domain.AsdfSection{Manager: "mise", Plugins: []domain.AsdfPlugin{
+			{Name: "erlang", Versions: []string{"26.2", "27.0"}},
+		}},
+		Deno:      &domain.DenoSection{GlobalPackages: []domain.Package{{Name: "jsr:@std/http/file-server"}}},
+		Bun:       &domain.BunSection{GlobalPackages: []domain.Package{{Name: "vercel"}}},
+		Ruby:      &domain.RubySection{GlobalGems: []domain.Package{{Name: "rails"}}},
+		GitHubCLI: &domain.GitHubCLISection{Extensions: []string{"dlvhdr/gh-dash"}},
+		VSCode:    &domain.VSCodeSection{Extensions: []string{"golang.go"}},
+		Cursor:    &domain.CursorSection{Extensions: []string{"esbenp.prettier-vscode"}},
+		Docker:    &domain.DockerSection{FrequentlyUsedImages: []string{"postgres:16"}},
+		AITools:   &domain.AIToolsSection{OllamaModels: []string{"llama3"}},
+		Apps:      &domain.AppsSection{AppStore: []domain.InstalledApp{{Name: "Xcode", ID: 497799835}}},
+		Fonts:     &domain.FontsSection{HomebrewFonts: []string{"font-fira-code"}, CustomFonts: []domain.Font{{Name: "Corp.otf"}}},
+		Folders:   &domain.FoldersSection{Structure: []string{"Code"}},
+		XDGConfig: &domain.XDGConfigSection{AutoDetected: []string{"starship"}},
+		SSH:       &domain.SSHSection{Encrypted: true, Keys: []string{"id_ed25519"}},
+		GPG:       &domain.GPGSection{Keys: []string{"ABCD1234"}},
+		Crontab:   &domain.CrontabSection{Entries: []string{"0 9 * * * ~/bin/sync"}},
+		HostsFile: &domain.HostsFileSection{CustomEntries: []domain.HostEntry{{IP: "127.0.0.1", Hostnames: []string{"api.local", "web.local"}}}},
+		Plugins:   map[string]*domain.PluginSection{"corp": {Packages: []domain.Package{{Name: "corp-cli"}}}},
+	}
+}
+
+func TestNew_ListCommands(t *testing.T) {
+	manifest := listManifest()
+	stages, err := domain.SelectStages(manifest, nil, nil)
+	require.NoError(t, err)
+	p := New(manifest, &drift.Report{}, Options{Stages: stages, HomeDir: "/Users/lab"})
+
+	got := make(map[string]string, len(p.Actions))
+	for _, a := range p.Actions {
+		got[a.Key] = a.Description
+	}
+	for key, want := range map[string]string{
+		"homebrew.services[postgresql@16]":                "brew services start postgresql@16",
+		"node.versions[22.3.0]":                           "nvm install 22.3.0",
+		"node.global_packages[pnpm]":                      "npm install -g pnpm",
+		"python.versions[3.12]":                           "uv python install 3.12",
+		"python.global_packages[ruff]":                    "pip install ruff",
+		"rust.toolchains[stable]":                         "rustup toolchain install stable",
+		"rust.components[clippy]":                         "rustup component add clippy",
+		"rust.cargo_packages[ripgrep]":                    "cargo install ripgrep",
+		"java.versions[21.0.2-tem]":                       "sdk install java 21.0.2-tem",
+		"flutter.dart_global_packages[melos]":             "dart pub global activate melos",
+		"go.global_packages[golang.org/x/tools/gopls]":    "go install golang.org/x/tools/gopls@v0.16.0",
+		"asdf.plugins[erlang]":                            "mise install erlang@26.2 && mise install erlang@27.0",
+		"deno.global_packages[jsr:@std/http/file-server]": "deno install -g jsr:@std/http/file-server",
+		"bun.global_packages[vercel]":                     "bun install -g vercel",
+		"ruby.global_gems[rails]":                         "gem install rails",
+		"github_cli.extensions[dlvhdr/gh-dash]":           "gh extension install dlvhdr/gh-dash",
+		"vscode.extensions[golang.go]":                    "code --install-extension golang.go",
+		"cursor.extensions[esbenp.prettier-vscode]":       "cursor --install-extension esbenp.prettier-vscode",
+		"docker.frequently_used_images[postgres:16]":      "docker pull postgres:16",
+		"ai_tools.ollama_models[llama3]":                  "ollama pull llama3",
+		"apps.app_store[497799835]":                       "mas install 497799835",
+		"fonts.homebrew_fonts[font-fira-code]":            "brew install --cask font-fira-code",
+		"fonts.custom_fonts[Corp.otf]":                    "copy configs/fonts/Corp.otf → ~/Library/Fonts/",
+		"folders.structure[Code]":                         "mkdir -p ~/Code",
+		"xdg_config.auto_detected[starship]":              "copy configs/xdg-config/starship → ~/.config/starship",
+		"ssh.keys[id_ed25519]":                            "decrypt configs/ssh/id_ed25519.age → ~/.ssh/id_ed25519",
+		"gpg.keys[ABCD1234]":                              "gpg --import configs/gpg/ABCD1234.asc",
+		"crontab.entries[0 9 * * * ~/bin/sync]":           "crontab += 0 9 * * * ~/bin/sync",
+		"hosts_file.custom_entries[127.0.0.1]":            `append "127.0.0.1 api.local web.local" to /etc/hosts`,
+		"plugins.corp.packages[corp-cli]":                 "brew install corp-cli",
+	} {
+		assert.Equal(t, want, got[key], key)
+	}
+}
+
+// TestNew_ListCommandsMatchScripts keeps the commands plans describe in line
+// with those the stage templates run. Copies, crontab and hosts file edits
+// are described rather than spelled as commands.
+func TestNew_ListCommandsMatchScripts(t *testing.T) {
+	for _, managers := range [][3]string{{"nvm", "uv", "mise"}, {"fnm", "pyenv", "asdf"}} {
+		manifest := listManifest()
+		manifest.Homebrew.Taps = []string{"hashicorp/tap"}
+		manifest.Homebrew.Formulae = []domain.Package{{Name: "jq"}}
+		manifest.Homebrew.Casks = []domain.Package{{Name: "iterm2"}}
+		manifest.Node.Manager, manifest.Python.Manager, manifest.Asdf.Manager = managers[0], managers[1], managers[2]
+		manifest.MacOSDefaults = &domain.MacOSDefaultsSection{Defaults: []domain.MacDefault{
+			{Domain: "com.apple.dock", Key: "autohide", ValueType: "bool", Value: "true"},
+		}}
+		scripts, err := bundler.GenerateRestoreScripts(manifest)
+		require.NoError(t, err)
+		var all strings.Builder
+		for _, s := range scripts {
+			all.WriteString(strings.NewReplacer(`"`, "", "$HOME/", "~/").Replace(s))
+		}
+		script := all.String()
+
+		stages, err := domain.SelectStages(manifest, nil, nil)
+		require.NoError(t, err)
+		p := New(manifest, &drift.Report{}, Options{Stages: stages, HomeDir: "/Users/lab"})
+		for _, a := range p.Actions {
+			desc := a.Description
+			if !strings.Contains(a.Key, "[") || strings.Contains(desc, " → ") ||
+				strings.HasPrefix(desc, "crontab += ") || strings.HasPrefix(desc, "append ") {
+				continue
+			}
+			for _, cmd := range strings.Split(desc, " && ") {
+				if !strings.Contains(script, cmd) {
+					t.Errorf("%v: %s: %q is not in the restore scripts", managers, a.Key, cmd)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package restore
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	machinist "github.com/moinsen-dev/machinist"
+)
+
+// ReportFile is the file name of the last restore's report in ~/.machinist.
+const ReportFile = "restore-report.json"
+
+// maxLogLines caps the log excerpt kept for a stage.
+const maxLogLines = 20
+
+// FailedCommand is a command that failed inside a stage.
+type FailedCommand struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+}
+
+// StageResult is the outcome of one stage in a restore run.
+type StageResult struct {
+	Group    string `json:"group"`
+	Stage    string `json:"stage"`
+	Label    string `json:"label"`
+	Status   Status `json:"status"`
+	Duration int    `json:"duration_seconds"`
+	// Error explains a failure the stage could not report itself, e.g.
+	// because its group script stopped first.
+	Error          string          `json:"error,omitempty"`
+	FailedCommands []FailedCommand `json:"failed_commands,omitempty"`
+	// Log is the tail of what the stage wrote to its group's log.
+	Log []string `json:"log,omitempty"`
+
+	logFirst, logLast int
+}
+
+// Report describes a restore run, stage by stage, for people and for the
+// automation that has to know whether it succeeded.
+type Report struct {
+	Manifest     string        `json:"manifest"`
+	ManifestHash string        `json:"manifest_hash"`
+	Host         string        `json:"host"`
+	StartedAt    time.Time     `json:"started_at"`
+	FinishedAt   time.Time     `json:"finished_at"`
+	Status       Status        `json:"status"`
+	Stages       []StageResult `json:"stages"`
+}
+
+// ReportPath returns the restore report's location under home.
+func ReportPath(home string) string {
+	return filepath.Join(home, ".machinist", ReportFile)
+}
+
+// Count returns the number of stages with status s.
+func (r *Report) Count(s Status) int {
+	n := 0
+	for _, st := range r.Stages {
+		if st.Status == s {
+			n++
+		}
+	}
+	return n
+}
+
+// Finish stamps the report's end and sets its status: failed if any stage
+// failed, completed otherwise.
+func (r *Report) Finish(at time.Time) {
+	r.FinishedAt = at.UTC().Truncate(time.Second)
+	r.Status = Completed
+	if r.Count(Failed) > 0 {
+		r.Status = Failed
+	}
+}
+
+// ReadResults parses the results file the group scripts append to while
+// MACHINIST_RESULTS names it, keyed by stage name. The log ranges are
+// resolved by ReadLog.
+func ReadResults(path string) (map[string]*StageResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results := make(map[string]*StageResult)
+	result := func(stage string) *StageResult {
+		if results[stage] == nil {
+			results[stage] = &StageResult{Stage: stage}
+		}
+		return results[stage]
+	}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		fields := strings.SplitN(sc.Text(), "\t", 6)
+		switch {
+		case fields[0] == "command" && len(fields) >= 4:
+			code, _ := strconv.Atoi(fields[2])
+			r := result(fields[1])
+			r.FailedCommands = append(r.FailedCommands, FailedCommand{
+				Command:  strings.Join(fields[3:], "\t"),
+				ExitCode: code,
+			})
+		case fields[0] == "stage" && len(fields) == 6:
+			r := result(fields[1])
+			r.Status = Status(fields[2])
+			r.Duration, _ = strconv.Atoi(fields[3])
+			r.logFirst, _ = strconv.Atoi(fields[4])
+			r.logLast, _ = strconv.Atoi(fields[5])
+		}
+	}
+	return results, sc.Err()
+}
+
+// ReadLog fills in r.Log with the last lines of the range of the group log
+// at path that the stage wrote.
+func (r *StageResult) ReadLog(path string) error {
+	if r.logFirst <= 0 || r.logLast < r.logFirst {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var lines []string
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; sc.Scan() && n <= r.logLast; n++ {
+		if n >= r.logFirst {
+			lines = append(lines, sc.Text())
+		}
+	}
+	if len(lines) > maxLogLines {
+		lines = lines[len(lines)-maxLogLines:]
+	}
+	r.Log = lines
+	return sc.Err()
+}
+
+// WriteJSON stores the report at path.
+func (r *Report) WriteJSON(path string) error {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(r); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b.Bytes(), 0o644)
+}
+
+// WriteMarkdown renders the report as Markdown.
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	tmpl, err := template.ParseFS(machinist.TemplateFS, "templates/report/restore-report.md.tmpl")
+	if err != nil {
+		return fmt.Errorf("parse report template: %w", err)
+	}
+	return tmpl.Execute(w, r)
+}
+
+// WriteHTML renders the report as a standalone HTML page.
+func (r *Report) WriteHTML(w io.Writer) error {
+	tmpl, err := htmltemplate.ParseFS(machinist.TemplateFS, "templates/report/restore-report.html.tmpl")
+	if err != nil {
+		return fmt.Errorf("parse report template: %w", err)
+	}
+	return tmpl.Execute(w, r)
+}
+
+// CheckReportFile reports an error if WriteFile cannot render to path.
+func CheckReportFile(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".html", ".htm":
+		return nil
+	}
+	return fmt.Errorf("report %s: use a .md or .html file", path)
+}
+
+// WriteFile renders the report to path as Markdown (.md) or HTML (.html,
+// .htm), by its extension.
+func (r *Report) WriteFile(path string) error {
+	if err := CheckReportFile(path); err != nil {
+		return err
+	}
+	render := r.WriteMarkdown
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".html" || ext == ".htm" {
+		render = r.WriteHTML
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := render(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,123 @@
+package restore
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadResults(t *testing.T) {
+	dir := t.TempDir()
+	results := filepath.Join(dir, "results.tsv")
+	require.NoError(t, os.WriteFile(results, []byte(
+		"stage\thomebrew\tcompleted\t42\t2\t3\n"+
+			"command\tnode\t1\tnpm install -g \"typescript\"\n"+
+			"stage\tnode\tfailed\t7\t4\t6\n"+
+			"command\tvscode\t127\tcode --install-extension golang.go\n"), 0o644))
+	log := filepath.Join(dir, "restore.log")
+	require.NoError(t, os.WriteFile(log, []byte("started\n=== Homebrew ===\n-> Homebrew completed\n=== Node.js ===\n!! exit 1: npm install -g \"typescript\"\n!! Node.js failed\nsummary\n"), 0o644))
+
+	got, err := ReadResults(results)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+
+	assert.Equal(t, Completed, got["homebrew"].Status)
+	assert.Equal(t, 42, got["homebrew"].Duration)
+
+	node := got["node"]
+	assert.Equal(t, Failed, node.Status)
+	assert.Equal(t, []FailedCommand{{Command: `npm install -g "typescript"`, ExitCode: 1}}, node.FailedCommands)
+	require.NoError(t, node.ReadLog(log))
+	assert.Equal(t, []string{"=== Node.js ===", `!! exit 1: npm install -g "typescript"`, "!! Node.js failed"}, node.Log)
+
+	// A stage whose script stopped has failed commands but no status.
+	assert.Equal(t, Status(""), got["vscode"].Status)
+	require.NoError(t, got["vscode"].ReadLog(log))
+	assert.Empty(t, got["vscode"].Log)
+}
+
+func TestReadLog_KeepsTail(t *testing.T) {
+	log := filepath.Join(t.TempDir(), "restore.log")
+	var b strings.Builder
+	for i := 1; i <= 30; i++ {
+		b.WriteString(strings.Repeat("x", i) + "\n")
+	}
+	require.NoError(t, os.WriteFile(log, []byte(b.String()), 0o644))
+
+	r := &StageResult{logFirst: 1, logLast: 30}
+	require.NoError(t, r.ReadLog(log))
+	require.Len(t, r.Log, maxLogLines)
+	assert.Equal(t, strings.Repeat("x", 30), r.Log[maxLogLines-1])
+}
+
+func newReport() *Report {
+	at := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	r := &Report{
+		Manifest:  "manifest.toml",
+		Host:      "new-mac",
+		StartedAt: at,
+		Stages: []StageResult{
+			{Group: "homebrew", Stage: "homebrew", Label: "Homebrew", Status: Completed, Duration: 42},
+			{Group: "runtimes", Stage: "node", Label: "Node.js", Status: Failed, Duration: 7,
+				FailedCommands: []FailedCommand{{Command: "npm install -g <pkg>", ExitCode: 1}},
+				Log:            []string{"!! exit 1: npm install -g <pkg>"}},
+			{Group: "macos", Stage: "locale", Label: "Locale & Timezone", Status: Skipped},
+		},
+	}
+	r.Finish(at.Add(time.Minute))
+	return r
+}
+
+func TestReport_Finish(t *testing.T) {
+	r := newReport()
+	assert.Equal(t, Failed, r.Status)
+	assert.Equal(t, 1, r.Count(Completed))
+	assert.Equal(t, 1, r.Count(Skipped))
+
+	r.Stages = r.Stages[:1]
+	r.Finish(time.Now())
+	assert.Equal(t, Completed, r.Status)
+}
+
+func TestReport_Render(t *testing.T) {
+	r := newReport()
+
+	var md bytes.Buffer
+	require.NoError(t, r.WriteMarkdown(&md))
+	assert.Contains(t, md.String(), "**Status:** failed (1 completed, 1 failed, 1 skipped)")
+	assert.Contains(t, md.String(), "| runtimes | Node.js (`node`) | failed | 7s |")
+	assert.Contains(t, md.String(), "## Node.js failed")
+	assert.Contains(t, md.String(), "- `npm install -g <pkg>` (exit 1)")
+
+	var html bytes.Buffer
+	require.NoError(t, r.WriteHTML(&html))
+	assert.Contains(t, html.String(), `<td class="failed">failed</td>`)
+	assert.Contains(t, html.String(), "<code>npm install -g &lt;pkg&gt;</code> (exit 1)")
+	assert.Contains(t, html.String(), "Locale &amp; Timezone")
+}
+
+func TestReport_WriteFile(t *testing.T) {
+	dir := t.TempDir()
+	r := newReport()
+	require.NoError(t, r.WriteFile(filepath.Join(dir, "report.html")))
+	data, err := os.ReadFile(filepath.Join(dir, "report.html"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "<!DOCTYPE html>"))
+
+	assert.Error(t, r.WriteFile(filepath.Join(dir, "report.pdf")))
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	path := ReportPath(t.TempDir())
+	require.NoError(t, newReport().WriteJSON(path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"status": "failed"`)
+	assert.Contains(t, string(data), `"command": "npm install -g <pkg>"`)
+}
@@ -0,0 +1,161 @@
+// Package restore keeps track of restore runs across the generated group
+// scripts and the restore command: which stages of a manifest completed or
+// failed, so an interrupted restore can resume.
+package restore
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+)
+
+// StateFile is the restore state's file name in ~/.machinist. The group
+// scripts' record_stage writes the same file.
+const StateFile = "restore-state.json"
+
+// Status is the recorded outcome of a stage.
+type Status string
+
+const (
+	// Completed stages ran successfully.
+	Completed Status = "completed"
+	// Failed stages returned an error or ran a command that failed, or were
+	// due to run when their group script stopped.
+	Failed Status = "failed"
+	// Skipped stages were left out of a run by a stage selection, --resume
+	// or --retry-failed. Only reports use it.
+	Skipped Status = "skipped"
+)
+
+// StageState is the last recorded outcome of a stage.
+type StageState struct {
+	Status     Status    `json:"status"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// State records the outcome of each stage restored from one manifest,
+// keyed by stage name (see domain.MatchStages).
+type State struct {
+	// ManifestHash is the ManifestHash of the manifest the stages restored.
+	ManifestHash string                `json:"manifest_hash"`
+	Stages       map[string]StageState `json:"stages"`
+}
+
+// StatePath returns the restore state's location under home.
+func StatePath(home string) string {
+	return filepath.Join(home, ".machinist", StateFile)
+}
+
+// NewState returns an empty state for the manifest with hash.
+func NewState(hash string) *State {
+	return &State{ManifestHash: hash, Stages: make(map[string]StageState)}
+}
+
+// ReadState reads the state at path. A missing file reads as an empty state
+// without a manifest hash.
+func ReadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewState(""), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := NewState("")
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if s.Stages == nil {
+		s.Stages = make(map[string]StageState)
+	}
+	return s, nil
+}
+
+// ManifestHash returns the sha256 of snap, a manifest with its profiles
+// resolved, as a bundle's manifest.toml holds it. A change to a profile the
+// manifest extends changes the hash, and a bundled group script run on its
+// own records the same hash from its manifest.toml.
+func ManifestHash(snap *domain.Snapshot) (string, error) {
+	data, err := domain.MarshalManifest(snap)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(data)), nil
+}
+
+// For reports whether s was recorded for the manifest with hash; a state
+// of another manifest no longer applies.
+func (s *State) For(hash string) bool {
+	return s.ManifestHash != "" && s.ManifestHash == hash
+}
+
+// Record stores the outcome of stage.
+func (s *State) Record(stage string, status Status, at time.Time) {
+	s.Stages[stage] = StageState{Status: status, FinishedAt: at.UTC().Truncate(time.Second)}
+}
+
+// Select returns the names among stages whose recorded status is status,
+// in the order given.
+func (s *State) Select(stages []string, status Status) []string {
+	var out []string
+	for _, name := range stages {
+		if st, ok := s.Stages[name]; ok && st.Status == status {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// Write stores s at path, replacing the file atomically. Each stage is
+// written on a line of its own, the layout record_stage edits.
+func (s *State) Write(path string) error {
+	names := make([]string, 0, len(s.Stages))
+	for name := range s.Stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	hash, _ := json.Marshal(s.ManifestHash)
+	fmt.Fprintf(&b, "{\n  \"manifest_hash\": %s,\n  \"stages\": {\n", hash)
+	for i, name := range names {
+		key, _ := json.Marshal(name)
+		entry, err := json.Marshal(s.Stages[name])
+		if err != nil {
+			return err
+		}
+		sep := ","
+		if i == len(names)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(&b, "    %s: %s%s\n", key, entry, sep)
+	}
+	b.WriteString("  }\n}\n")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".restore-state-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b.Bytes()); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
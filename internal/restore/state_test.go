@@ -0,0 +1,81 @@
+package restore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadState_Missing(t *testing.T) {
+	s, err := ReadState(filepath.Join(t.TempDir(), StateFile))
+	require.NoError(t, err)
+	assert.False(t, s.For("abc"))
+	assert.Empty(t, s.Stages)
+}
+
+func TestState_WriteRead(t *testing.T) {
+	path := StatePath(t.TempDir())
+	at := time.Date(2026, 10, 17, 9, 30, 0, 0, time.UTC)
+	s := NewState("abc")
+	s.Record("homebrew", Completed, at)
+	s.Record("git-config", Failed, at)
+	require.NoError(t, s.Write(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{
+  "manifest_hash": "abc",
+  "stages": {
+    "git-config": {"status":"failed","finished_at":"2026-10-17T09:30:00Z"},
+    "homebrew": {"status":"completed","finished_at":"2026-10-17T09:30:00Z"}
+  }
+}
+`, string(data))
+
+	got, err := ReadState(path)
+	require.NoError(t, err)
+	assert.True(t, got.For("abc"))
+	assert.False(t, got.For("def"))
+	assert.Equal(t, s, got)
+}
+
+func TestState_Select(t *testing.T) {
+	s := NewState("abc")
+	s.Record("homebrew", Completed, time.Now())
+	s.Record("vscode", Failed, time.Now())
+	s.Record("tmux", Completed, time.Now())
+
+	stages := []string{"homebrew", "git-config", "vscode", "tmux"}
+	assert.Equal(t, []string{"homebrew", "tmux"}, s.Select(stages, Completed))
+	assert.Equal(t, []string{"vscode"}, s.Select(stages, Failed))
+}
+
+func TestReadState_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), StateFile)
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o644))
+	_, err := ReadState(path)
+	assert.Error(t, err)
+}
+
+func TestManifestHash(t *testing.T) {
+	snap := &domain.Snapshot{Tmux: &domain.TmuxSection{}}
+	hash, err := ManifestHash(snap)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "manifest.toml")
+	require.NoError(t, domain.WriteManifest(snap, path))
+	file, err := util.ContentHash(path)
+	require.NoError(t, err)
+	assert.Equal(t, file, hash, "a bundled manifest.toml hashes the same")
+
+	snap.Folders = &domain.FoldersSection{Structure: []string{"Code"}}
+	changed, err := ManifestHash(snap)
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, changed)
+}
@@ -0,0 +1,165 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/moinsen-dev/machinist/internal/util"
+)
+
+// cacheFormat is bumped whenever the cache entry layout changes so stale
+// entries from older builds are ignored.
+const cacheFormat = 1
+
+// Inputs describes what a scanner's result depends on. A cached result is
+// reused as long as every watched path has the same modification time and
+// size and every command prints the same output.
+type Inputs struct {
+	// Paths are absolute files or directories. A directory's own mtime
+	// changes when entries are added or removed, not when files inside change.
+	Paths []string
+	// Commands are run through the scanner's CommandRunner, typically cheap
+	// version queries such as {"brew", "--version"}.
+	Commands [][]string
+	// Values are settings the result depends on, such as a probe definition.
+	Values []string
+}
+
+// Glob adds the paths matching each pattern, such as the global package
+// directory of every installed version. A new match is only noticed through
+// a watched parent, so patterns are usually paired with their base directory.
+func (in *Inputs) Glob(patterns ...string) {
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		in.Paths = append(in.Paths, matches...)
+	}
+}
+
+// Cacheable is implemented by scanners whose results can be reused from the
+// scan cache while their inputs are unchanged. Scanners without it always run.
+type Cacheable interface {
+	CacheInputs() Inputs
+}
+
+// Cache stores scanner results on disk, one JSON file per scanner.
+type Cache struct {
+	dir     string
+	cmd     util.CommandRunner
+	version string
+	refresh bool
+}
+
+// NewCache creates a Cache in dir. cmd runs the input commands and version
+// is mixed into every key, so results are not reused across releases.
+func NewCache(dir string, cmd util.CommandRunner, version string) *Cache {
+	return &Cache{dir: dir, cmd: cmd, version: version}
+}
+
+// SetRefresh makes Get always miss, so every scanner runs and its fresh
+// result replaces the cached one.
+func (c *Cache) SetRefresh(refresh bool) {
+	c.refresh = refresh
+}
+
+// cacheEntry is the on-disk form of a cached result.
+type cacheEntry struct {
+	Key    string      `json:"key"`
+	Result *ScanResult `json:"result"`
+}
+
+// Key fingerprints the scanner's inputs. ok is false for scanners that are
+// not Cacheable or declare no inputs.
+func (c *Cache) Key(ctx context.Context, s Scanner) (key string, ok bool) {
+	cs, isCacheable := s.(Cacheable)
+	if !isCacheable {
+		return "", false
+	}
+	in := cs.CacheInputs()
+	if len(in.Paths) == 0 && len(in.Commands) == 0 {
+		return "", false
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "format %d\nversion %s\nscanner %s\n", cacheFormat, c.version, s.Name())
+	for _, p := range in.Paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			fmt.Fprintf(h, "path %s missing\n", p)
+			continue
+		}
+		fmt.Fprintf(h, "path %s %d %d\n", p, info.ModTime().UnixNano(), info.Size())
+	}
+	for _, v := range in.Values {
+		fmt.Fprintf(h, "value %q\n", v)
+	}
+	for _, args := range in.Commands {
+		if len(args) == 0 {
+			continue
+		}
+		out, err := c.cmd.Run(ctx, args[0], args[1:]...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", false
+			}
+			out = "error: " + err.Error()
+		}
+		fmt.Fprintf(h, "command %s\n%s\n", strings.Join(args, " "), out)
+	}
+	return hex.EncodeToString(h.Sum(nil)), true
+}
+
+func (c *Cache) path(name string) string {
+	return filepath.Join(c.dir, "scans", url.PathEscape(name)+".json")
+}
+
+// Get returns the cached result for the named scanner if it was stored
+// under key. Unreadable entries are treated as misses.
+func (c *Cache) Get(name, key string) (*ScanResult, bool) {
+	if c.refresh {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.path(name))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key || entry.Result == nil {
+		return nil, false
+	}
+	return entry.Result, true
+}
+
+// Put stores result for the named scanner under key.
+func (c *Cache) Put(name, key string, result *ScanResult) error {
+	data, err := json.Marshal(cacheEntry{Key: key, Result: result})
+	if err != nil {
+		return err
+	}
+	path := c.path(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	// Write to a temporary file first so concurrent runs never read a
+	// partially written entry.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
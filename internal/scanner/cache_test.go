@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingScanner is a Cacheable scanner that counts how often it runs.
+type countingScanner struct {
+	inputs Inputs
+	runs   int
+}
+
+func (c *countingScanner) Name() string        { return "counting" }
+func (c *countingScanner) Description() string { return "Counts scans" }
+func (c *countingScanner) Category() string    { return "test" }
+func (c *countingScanner) CacheInputs() Inputs { return c.inputs }
+func (c *countingScanner) Scan(ctx context.Context) (*ScanResult, error) {
+	c.runs++
+	return &ScanResult{
+		ScannerName: c.Name(),
+		Homebrew:    &domain.HomebrewSection{Taps: []string{"homebrew/core"}},
+	}, nil
+}
+
+func TestRegistry_CacheReusesResult(t *testing.T) {
+	watched := filepath.Join(t.TempDir(), "watched")
+	require.NoError(t, os.WriteFile(watched, []byte("a"), 0o644))
+
+	s := &countingScanner{inputs: Inputs{Paths: []string{watched}}}
+	reg := NewRegistry()
+	require.NoError(t, reg.Register(s))
+	reg.SetCache(NewCache(t.TempDir(), &util.MockCommandRunner{}, "test"))
+
+	var cached []bool
+	progress := func(e ProgressEvent) {
+		if e.Done {
+			cached = append(cached, e.Cached)
+		}
+	}
+
+	snap, errs := reg.ScanAllWithProgress(context.Background(), progress)
+	require.Empty(t, errs)
+	require.NotNil(t, snap.Homebrew)
+
+	snap, errs = reg.ScanAllWithProgress(context.Background(), progress)
+	require.Empty(t, errs)
+	require.NotNil(t, snap.Homebrew)
+	assert.Equal(t, []string{"homebrew/core"}, snap.Homebrew.Taps)
+	assert.Equal(t, 1, s.runs, "second scan should come from the cache")
+	assert.Equal(t, []bool{false, true}, cached)
+
+	// Changing a watched path invalidates the entry.
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(watched, later, later))
+	_, errs = reg.ScanAll(context.Background())
+	require.Empty(t, errs)
+	assert.Equal(t, 2, s.runs)
+}
+
+func TestRegistry_CacheRefresh(t *testing.T) {
+	s := &countingScanner{inputs: Inputs{Values: []string{"v"}, Paths: []string{"/nonexistent"}}}
+	reg := NewRegistry()
+	require.NoError(t, reg.Register(s))
+	cache := NewCache(t.TempDir(), &util.MockCommandRunner{}, "test")
+	cache.SetRefresh(true)
+	reg.SetCache(cache)
+
+	_, err := reg.ScanOne(context.Background(), "counting")
+	require.NoError(t, err)
+	_, err = reg.ScanOne(context.Background(), "counting")
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.runs)
+}
+
+func TestCache_KeyTracksInputs(t *testing.T) {
+	ctx := context.Background()
+	mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		"brew --version": {Output: "Homebrew 4.4.0"},
+	}}
+	cache := NewCache(t.TempDir(), mock, "test")
+
+	s := &countingScanner{inputs: Inputs{Commands: [][]string{{"brew", "--version"}}}}
+	key1, ok := cache.Key(ctx, s)
+	require.True(t, ok)
+
+	mock.Responses["brew --version"] = util.MockResponse{Output: "Homebrew 4.5.0"}
+	key2, ok := cache.Key(ctx, s)
+	require.True(t, ok)
+	assert.NotEqual(t, key1, key2)
+
+	s.inputs.Values = []string{"probe definition"}
+	key3, _ := cache.Key(ctx, s)
+	assert.NotEqual(t, key2, key3)
+
+	other := NewCache(t.TempDir(), mock, "other-version")
+	key4, _ := other.Key(ctx, s)
+	assert.NotEqual(t, key3, key4)
+}
+
+func TestInputs_Glob(t *testing.T) {
+	dir := t.TempDir()
+	for _, v := range []string{"v18", "v20"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, v, "lib"), 0o755))
+	}
+
+	var in Inputs
+	in.Glob(filepath.Join(dir, "*", "lib"), filepath.Join(dir, "[")) // a bad pattern matches nothing
+	assert.Equal(t, []string{filepath.Join(dir, "v18", "lib"), filepath.Join(dir, "v20", "lib")}, in.Paths)
+}
+
+func TestCache_NotCacheable(t *testing.T) {
+	cache := NewCache(t.TempDir(), &util.MockCommandRunner{}, "test")
+
+	_, ok := cache.Key(context.Background(), &mockScanner{name: "plain"})
+	assert.False(t, ok)
+	_, ok = cache.Key(context.Background(), &countingScanner{})
+	assert.False(t, ok, "scanners without inputs are not cached")
+}
+
+func TestCache_GetMissesOnCorruptEntry(t *testing.T) {
+	dir := t.TempDir()
+	cache := NewCache(dir, &util.MockCommandRunner{}, "test")
+	require.NoError(t, cache.Put("counting", "k", &ScanResult{ScannerName: "counting"}))
+
+	result, ok := cache.Get("counting", "k")
+	require.True(t, ok)
+	assert.Equal(t, "counting", result.ScannerName)
+
+	_, ok = cache.Get("counting", "other")
+	assert.False(t, ok)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "scans", "counting.json"), []byte("{"), 0o600))
+	_, ok = cache.Get("counting", "k")
+	assert.False(t, ok)
+}
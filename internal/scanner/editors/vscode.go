@@ -31,6 +31,21 @@ func (s *VSCodeScanner) Name() string        { return "vscode" }
 func (s *VSCodeScanner) Description() string { return "Scans VS Code extensions, settings, and snippets" }
 func (s *VSCodeScanner) Category() string    { return "editors" }
 
+// CacheInputs watches the extensions registry and the user config files,
+// keyed on the code CLI version.
+func (s *VSCodeScanner) CacheInputs() scanner.Inputs {
+	configDir := filepath.Join(s.homeDir, "Library", "Application Support", "Code", "User")
+	return scanner.Inputs{
+		Paths: []string{
+			filepath.Join(s.homeDir, ".vscode", "extensions", "extensions.json"),
+			filepath.Join(configDir, "settings.json"),
+			filepath.Join(configDir, "keybindings.json"),
+			filepath.Join(configDir, "snippets"),
+		},
+		Commands: [][]string{{"code", "--version"}},
+	}
+}
+
 // Scan inspects VS Code extensions, config files, and snippets directory.
 func (s *VSCodeScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
 	section := &domain.VSCodeSection{}
@@ -103,6 +118,20 @@ func (s *CursorScanner) Name() string        { return "cursor" }
 func (s *CursorScanner) Description() string { return "Scans Cursor editor extensions and settings" }
 func (s *CursorScanner) Category() string    { return "editors" }
 
+// CacheInputs watches the extensions registry and the user config files,
+// keyed on the cursor CLI version.
+func (s *CursorScanner) CacheInputs() scanner.Inputs {
+	configDir := filepath.Join(s.homeDir, "Library", "Application Support", "Cursor", "User")
+	return scanner.Inputs{
+		Paths: []string{
+			filepath.Join(s.homeDir, ".cursor", "extensions", "extensions.json"),
+			filepath.Join(configDir, "settings.json"),
+			filepath.Join(configDir, "keybindings.json"),
+		},
+		Commands: [][]string{{"cursor", "--version"}},
+	}
+}
+
 // Scan inspects Cursor extensions and config files.
 func (s *CursorScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
 	section := &domain.CursorSection{}
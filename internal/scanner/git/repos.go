@@ -2,6 +2,8 @@ package git
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"os"
 	"path/filepath"
 
@@ -13,6 +15,7 @@ import (
 // GitReposScanner scans for Git repositories in specified paths.
 type GitReposScanner struct {
 	searchPaths []string
+	excludes    []string
 	cmd         util.CommandRunner
 }
 
@@ -24,6 +27,11 @@ func NewGitReposScanner(searchPaths []string, cmd util.CommandRunner) *GitReposS
 	}
 }
 
+// SetExcludes sets glob patterns for directories that are not walked.
+func (g *GitReposScanner) SetExcludes(patterns []string) {
+	g.excludes = patterns
+}
+
 func (g *GitReposScanner) Name() string        { return "git-repos" }
 func (g *GitReposScanner) Description() string  { return "Scans for Git repositories in specified paths" }
 func (g *GitReposScanner) Category() string     { return "git" }
@@ -47,11 +55,7 @@ func (g *GitReposScanner) Scan(ctx context.Context) (*scanner.ScanResult, error)
 	}
 
 	for _, searchPath := range g.searchPaths {
-		repos, err := g.findRepos(ctx, searchPath)
-		if err != nil {
-			continue
-		}
-		section.Repositories = append(section.Repositories, repos...)
+		section.Repositories = append(section.Repositories, g.findRepos(ctx, searchPath, result)...)
 	}
 
 	if section.Repositories == nil {
@@ -63,12 +67,24 @@ func (g *GitReposScanner) Scan(ctx context.Context) (*scanner.ScanResult, error)
 }
 
 // findRepos walks the given path looking for .git directories and returns Repository structs.
-func (g *GitReposScanner) findRepos(ctx context.Context, root string) ([]domain.Repository, error) {
+// Directories that cannot be read are skipped and reported as warnings on result;
+// a search path that does not exist is skipped silently.
+func (g *GitReposScanner) findRepos(ctx context.Context, root string, result *scanner.ScanResult) []domain.Repository {
 	var repos []domain.Repository
 
-	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
-			return nil // skip inaccessible directories
+			if !errors.Is(err, fs.ErrNotExist) {
+				result.Warn(domain.Diagnostic{
+					Item:    path,
+					Message: "skipped unreadable directory: " + err.Error(),
+					Fix:     "Grant your terminal Full Disk Access or add the path to exclude in config.toml",
+				})
+			}
+			return nil
+		}
+		if d.IsDir() && path != root && util.MatchesAny(path, g.excludes) {
+			return filepath.SkipDir
 		}
 		if d.IsDir() && d.Name() == ".git" {
 			repoPath := filepath.Dir(path)
@@ -79,7 +95,7 @@ func (g *GitReposScanner) findRepos(ctx context.Context, root string) ([]domain.
 		return nil
 	})
 
-	return repos, err
+	return repos
 }
 
 // buildRepo creates a Repository struct by querying git for remote URL and current branch.
@@ -200,3 +200,53 @@ func TestGitReposScanner_Scan_GitNotInstalled(t *testing.T) {
 	assert.Nil(t, result.GitRepos)
 	assert.Empty(t, result.GitRepos)
 }
+
+func TestGitReposScanner_Scan_Excludes(t *testing.T) {
+	tmpDir := t.TempDir()
+	keep := filepath.Join(tmpDir, "keep")
+	skipped := filepath.Join(tmpDir, "archive", "old")
+	require.NoError(t, os.MkdirAll(filepath.Join(keep, ".git"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(skipped, ".git"), 0o755))
+
+	mock := &util.MockCommandRunner{
+		Responses: map[string]util.MockResponse{
+			"git": {Output: "", Err: nil},
+			fmt.Sprintf("git -C %s remote get-url origin", keep): {Output: "git@github.com:user/keep.git"},
+			fmt.Sprintf("git -C %s branch --show-current", keep): {Output: "main"},
+		},
+	}
+
+	s := NewGitReposScanner([]string{tmpDir}, mock)
+	s.SetExcludes([]string{"archive"})
+	result, err := s.Scan(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, result.GitRepos)
+	require.Len(t, result.GitRepos.Repositories, 1)
+	assert.Equal(t, keep, result.GitRepos.Repositories[0].Path)
+}
+
+func TestGitReposScanner_Scan_UnreadableDirWarns(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	tmpDir := t.TempDir()
+	locked := filepath.Join(tmpDir, "locked")
+	require.NoError(t, os.MkdirAll(locked, 0o755))
+	require.NoError(t, os.Chmod(locked, 0o000))
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	mock := &util.MockCommandRunner{
+		Responses: map[string]util.MockResponse{
+			"git": {Output: "", Err: nil},
+		},
+	}
+
+	s := NewGitReposScanner([]string{tmpDir, filepath.Join(tmpDir, "missing")}, mock)
+	result, err := s.Scan(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, result.GitRepos)
+	require.Len(t, result.Diagnostics, 1, "missing search paths are not reported")
+	assert.Equal(t, locked, result.Diagnostics[0].Item)
+}
@@ -2,6 +2,8 @@ package packages
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/moinsen-dev/machinist/internal/domain"
@@ -11,18 +13,40 @@ import (
 
 // HomebrewScanner scans Homebrew packages, casks, taps, and services.
 type HomebrewScanner struct {
-	cmd util.CommandRunner
+	homeDir string
+	cmd     util.CommandRunner
 }
 
-// NewHomebrewScanner creates a new HomebrewScanner with the given CommandRunner.
-func NewHomebrewScanner(cmd util.CommandRunner) *HomebrewScanner {
-	return &HomebrewScanner{cmd: cmd}
+// NewHomebrewScanner creates a new HomebrewScanner with the given home directory and CommandRunner.
+func NewHomebrewScanner(homeDir string, cmd util.CommandRunner) *HomebrewScanner {
+	return &HomebrewScanner{homeDir: homeDir, cmd: cmd}
 }
 
 func (h *HomebrewScanner) Name() string        { return "homebrew" }
 func (h *HomebrewScanner) Description() string  { return "Scans Homebrew packages, casks, taps, and services" }
 func (h *HomebrewScanner) Category() string     { return "packages" }
 
+// homebrewPrefixes are the default Homebrew prefixes on Apple Silicon and Intel Macs.
+var homebrewPrefixes = []string{"/opt/homebrew", "/usr/local"}
+
+// CacheInputs watches the Cellar, Caskroom, taps and opt links, which change
+// on every install, upgrade and tap, plus the LaunchAgents of brew services.
+// A cask upgrade only adds a version directory under Caskroom/<cask>, so
+// those are watched as well.
+func (h *HomebrewScanner) CacheInputs() scanner.Inputs {
+	in := scanner.Inputs{
+		Paths:    []string{filepath.Join(h.homeDir, "Library", "LaunchAgents")},
+		Commands: [][]string{{"brew", "--version"}},
+	}
+	for _, prefix := range homebrewPrefixes {
+		for _, dir := range []string{"Cellar", "Caskroom", "opt", "Library/Taps"} {
+			in.Paths = append(in.Paths, filepath.Join(prefix, dir))
+		}
+		in.Glob(filepath.Join(prefix, "Caskroom", "*"), filepath.Join(prefix, "Caskroom", "*", "*"))
+	}
+	return in
+}
+
 // Scan runs brew commands and returns a ScanResult with the Homebrew field populated.
 func (h *HomebrewScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
 	result := &scanner.ScanResult{
@@ -34,12 +58,23 @@ func (h *HomebrewScanner) Scan(ctx context.Context) (*scanner.ScanResult, error)
 	}
 
 	section := &domain.HomebrewSection{}
+	const fix = "Run 'brew doctor' and rescan"
 
-	// Formulae
-	formulae, err := h.cmd.RunLines(ctx, "brew", "list", "--formula", "--versions")
-	if err != nil {
-		return nil, err
+	// Each listing is independent: a failing command is reported and the
+	// rest of the section is still recorded.
+	var ran, failed int
+	brew := func(item, fix string, args ...string) []string {
+		ran++
+		lines, err := h.cmd.RunLines(ctx, "brew", args...)
+		if err != nil {
+			failed++
+			result.WarnCommand(item, err, fix, "brew", args...)
+		}
+		return lines
 	}
+
+	// Formulae
+	formulae := brew("formulae", fix, "list", "--formula", "--versions")
 	for _, line := range formulae {
 		parts := strings.SplitN(line, " ", 2)
 		pkg := domain.Package{Name: parts[0]}
@@ -50,26 +85,16 @@ func (h *HomebrewScanner) Scan(ctx context.Context) (*scanner.ScanResult, error)
 	}
 
 	// Casks
-	casks, err := h.cmd.RunLines(ctx, "brew", "list", "--cask")
-	if err != nil {
-		return nil, err
-	}
+	casks := brew("casks", fix, "list", "--cask")
 	for _, line := range casks {
 		section.Casks = append(section.Casks, domain.Package{Name: line})
 	}
 
 	// Taps
-	taps, err := h.cmd.RunLines(ctx, "brew", "tap")
-	if err != nil {
-		return nil, err
-	}
-	section.Taps = taps
+	section.Taps = brew("taps", fix, "tap")
 
 	// Services
-	lines, err := h.cmd.RunLines(ctx, "brew", "services", "list")
-	if err != nil {
-		return nil, err
-	}
+	lines := brew("services", "Install brew services with 'brew tap homebrew/services'", "services", "list")
 	for i, line := range lines {
 		// Skip header line
 		if i == 0 && strings.HasPrefix(line, "Name") {
@@ -86,6 +111,11 @@ func (h *HomebrewScanner) Scan(ctx context.Context) (*scanner.ScanResult, error)
 		section.Services = append(section.Services, entry)
 	}
 
+	// An empty section would claim nothing is installed.
+	if failed == ran {
+		return nil, fmt.Errorf("every brew command failed, e.g. %s", result.Diagnostics[0].Message)
+	}
+
 	result.Homebrew = section
 	return result, nil
 }
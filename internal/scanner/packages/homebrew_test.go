@@ -3,8 +3,12 @@ package packages
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/scanner"
 	"github.com/moinsen-dev/machinist/internal/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -15,20 +19,46 @@ func newMock(responses map[string]util.MockResponse) *util.MockCommandRunner {
 }
 
 func TestHomebrewScanner_Name(t *testing.T) {
-	s := NewHomebrewScanner(&util.MockCommandRunner{})
+	s := NewHomebrewScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "homebrew", s.Name())
 }
 
 func TestHomebrewScanner_Description(t *testing.T) {
-	s := NewHomebrewScanner(&util.MockCommandRunner{})
+	s := NewHomebrewScanner("/home/user", &util.MockCommandRunner{})
 	assert.NotEmpty(t, s.Description())
 }
 
 func TestHomebrewScanner_Category(t *testing.T) {
-	s := NewHomebrewScanner(&util.MockCommandRunner{})
+	s := NewHomebrewScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "packages", s.Category())
 }
 
+func TestHomebrewScanner_CacheInputs(t *testing.T) {
+	in := NewHomebrewScanner("/Users/other", &util.MockCommandRunner{}).CacheInputs()
+	assert.Contains(t, in.Paths, "/Users/other/Library/LaunchAgents")
+	assert.Contains(t, in.Paths, "/opt/homebrew/Cellar")
+	assert.Equal(t, [][]string{{"brew", "--version"}}, in.Commands)
+}
+
+func TestHomebrewScanner_CacheInputs_CaskUpgrade(t *testing.T) {
+	prefix := t.TempDir()
+	old := homebrewPrefixes
+	homebrewPrefixes = []string{prefix}
+	t.Cleanup(func() { homebrewPrefixes = old })
+	cask := filepath.Join(prefix, "Caskroom", "firefox")
+	require.NoError(t, os.MkdirAll(filepath.Join(cask, "120.0"), 0o755))
+
+	s := NewHomebrewScanner(t.TempDir(), &util.MockCommandRunner{})
+	cache := scanner.NewCache(t.TempDir(), &util.MockCommandRunner{}, "test")
+	before, ok := cache.Key(context.Background(), s)
+	require.True(t, ok)
+
+	require.NoError(t, os.Rename(filepath.Join(cask, "120.0"), filepath.Join(cask, "121.0")))
+	after, ok := cache.Key(context.Background(), s)
+	require.True(t, ok)
+	assert.NotEqual(t, before, after, "a cask upgrade must invalidate the cached scan")
+}
+
 func TestHomebrewScanner_Scan_Formulae(t *testing.T) {
 	mock := newMock(map[string]util.MockResponse{
 		"brew": {Output: "", Err: nil}, // IsInstalled check
@@ -40,7 +70,7 @@ func TestHomebrewScanner_Scan_Formulae(t *testing.T) {
 		"brew services list":   {Output: ""},
 	})
 
-	s := NewHomebrewScanner(mock)
+	s := NewHomebrewScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -66,7 +96,7 @@ func TestHomebrewScanner_Scan_Casks(t *testing.T) {
 		"brew services list": {Output: ""},
 	})
 
-	s := NewHomebrewScanner(mock)
+	s := NewHomebrewScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -90,7 +120,7 @@ func TestHomebrewScanner_Scan_Taps(t *testing.T) {
 		"brew services list": {Output: ""},
 	})
 
-	s := NewHomebrewScanner(mock)
+	s := NewHomebrewScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -113,7 +143,7 @@ func TestHomebrewScanner_Scan_Services(t *testing.T) {
 		},
 	})
 
-	s := NewHomebrewScanner(mock)
+	s := NewHomebrewScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -145,7 +175,7 @@ func TestHomebrewScanner_Scan_Combined(t *testing.T) {
 		},
 	})
 
-	s := NewHomebrewScanner(mock)
+	s := NewHomebrewScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -163,7 +193,7 @@ func TestHomebrewScanner_Scan_BrewNotInstalled(t *testing.T) {
 	// No "brew" key in Responses → IsInstalled returns false
 	mock := newMock(map[string]util.MockResponse{})
 
-	s := NewHomebrewScanner(mock)
+	s := NewHomebrewScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -188,13 +218,35 @@ func TestHomebrewScanner_Scan_FormulaCommandError(t *testing.T) {
 		"brew services list": {Output: ""},
 	})
 
-	s := NewHomebrewScanner(mock)
+	s := NewHomebrewScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
-	// The implementation returns nil + error when any brew sub-command fails.
+	// A failing sub-command is reported; the rest of the section is kept.
+	require.NoError(t, err)
+	require.NotNil(t, result.Homebrew)
+	assert.Empty(t, result.Homebrew.Formulae)
+	assert.Len(t, result.Homebrew.Casks, 2)
+	assert.Equal(t, []string{"homebrew/core"}, result.Homebrew.Taps)
+
+	require.Len(t, result.Diagnostics, 1)
+	d := result.Diagnostics[0]
+	assert.Equal(t, domain.SeverityWarning, d.Severity)
+	assert.Equal(t, "homebrew", d.Scanner)
+	assert.Equal(t, "formulae", d.Item)
+	assert.Equal(t, "brew list --formula --versions", d.Command)
+	assert.Contains(t, d.Message, "brew formula list failed")
+	assert.NotEmpty(t, d.Fix)
+}
+
+func TestHomebrewScanner_Scan_AllCommandsFail(t *testing.T) {
+	mock := newMock(map[string]util.MockResponse{
+		"brew": {Output: "", Err: nil},
+	})
+
+	s := NewHomebrewScanner("/home/user", mock)
+	result, err := s.Scan(context.Background())
 	require.Error(t, err)
 	assert.Nil(t, result)
-	assert.Contains(t, err.Error(), "brew formula list failed")
 }
 
 func TestHomebrewScanner_Scan_SingleFormula(t *testing.T) {
@@ -208,7 +260,7 @@ func TestHomebrewScanner_Scan_SingleFormula(t *testing.T) {
 		"brew services list": {Output: ""},
 	})
 
-	s := NewHomebrewScanner(mock)
+	s := NewHomebrewScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -227,7 +279,7 @@ func TestHomebrewScanner_Scan_EmptyOutput(t *testing.T) {
 		"brew services list":            {Output: ""},
 	})
 
-	s := NewHomebrewScanner(mock)
+	s := NewHomebrewScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -0,0 +1,247 @@
+// Package plugin runs external scanner executables named machinist-scanner-*.
+//
+// A plugin speaks a small JSON protocol on stdout:
+//
+//	machinist-scanner-foo info  -> {"name": "foo", "description": "...", "category": "..."}
+//	machinist-scanner-foo scan  -> {"config_files": [{"source": "...", "bundle_path": "..."}],
+//	                                "packages": [{"name": "...", "version": "..."}],
+//	                                "restore": ["shell snippet", ...]}
+//
+// The scan output is stored under [plugins.<name>] in the snapshot.
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/scanner"
+	"github.com/moinsen-dev/machinist/internal/util"
+)
+
+// Prefix is the executable name prefix that marks a scanner plugin.
+const Prefix = "machinist-scanner-"
+
+// DefaultCategory is used when a plugin does not report a category.
+const DefaultCategory = "plugins"
+
+// infoTimeout bounds the "info" call made while discovering plugins.
+const infoTimeout = 5 * time.Second
+
+// info is the wire format of the "info" subcommand.
+type info struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+}
+
+// scanOutput is the wire format of the "scan" subcommand.
+type scanOutput struct {
+	ConfigFiles []struct {
+		Source     string `json:"source"`
+		BundlePath string `json:"bundle_path"`
+		Sensitive  bool   `json:"sensitive"`
+	} `json:"config_files"`
+	Packages []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"packages"`
+	Restore []string `json:"restore"`
+}
+
+// Scanner adapts an external plugin executable to scanner.Scanner.
+type Scanner struct {
+	path        string
+	name        string
+	description string
+	category    string
+	homeDir     string
+	cmd         util.CommandRunner
+}
+
+// Load asks the executable at path for its metadata and returns a Scanner for it.
+func Load(ctx context.Context, path, homeDir string, cmd util.CommandRunner) (*Scanner, error) {
+	ctx, cancel := context.WithTimeout(ctx, infoTimeout)
+	defer cancel()
+
+	out, err := cmd.Run(ctx, path, "info")
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s: info: %w", path, err)
+	}
+	var meta info
+	if err := json.Unmarshal([]byte(out), &meta); err != nil {
+		return nil, fmt.Errorf("plugin %s: decode info: %w", path, err)
+	}
+
+	s := &Scanner{
+		path:        path,
+		name:        meta.Name,
+		description: meta.Description,
+		category:    meta.Category,
+		homeDir:     homeDir,
+		cmd:         cmd,
+	}
+	if s.name == "" {
+		s.name = strings.TrimPrefix(filepath.Base(path), Prefix)
+	}
+	if s.name == "." || s.name == ".." || strings.ContainsAny(s.name, `/\`) {
+		return nil, fmt.Errorf("plugin %s: name %q is not a single path segment", path, s.name)
+	}
+	if s.description == "" {
+		s.description = "External scanner plugin " + filepath.Base(path)
+	}
+	if s.category == "" {
+		s.category = DefaultCategory
+	}
+	return s, nil
+}
+
+func (s *Scanner) Name() string        { return s.name }
+func (s *Scanner) Description() string { return s.description }
+func (s *Scanner) Category() string    { return s.category }
+
+// Path returns the plugin executable's location.
+func (s *Scanner) Path() string { return s.path }
+
+// Scan runs the plugin's "scan" subcommand and converts its output to a PluginSection.
+func (s *Scanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
+	result := &scanner.ScanResult{ScannerName: s.Name()}
+
+	out, err := s.cmd.Run(ctx, s.path, "scan")
+	if err != nil {
+		return nil, fmt.Errorf("run %s scan: %w", s.path, err)
+	}
+	var raw scanOutput
+	if err := json.Unmarshal([]byte(out), &raw); err != nil {
+		return nil, fmt.Errorf("decode %s scan output: %w", s.path, err)
+	}
+
+	section := &domain.PluginSection{Restore: raw.Restore}
+	for _, f := range raw.ConfigFiles {
+		source, err := s.relativeSource(f.Source)
+		if err != nil {
+			return nil, err
+		}
+		bundlePath := f.BundlePath
+		if bundlePath == "" {
+			bundlePath = path.Join("configs", "plugins", s.name, filepath.Base(source))
+		} else if !insideBundle(bundlePath) {
+			return nil, fmt.Errorf("plugin %s: bundle path %s is outside the bundle", s.name, bundlePath)
+		}
+		section.ConfigFiles = append(section.ConfigFiles, domain.ConfigFile{
+			Source:     source,
+			BundlePath: bundlePath,
+			Sensitive:  f.Sensitive,
+		})
+	}
+	for _, p := range raw.Packages {
+		if p.Name == "" {
+			continue
+		}
+		section.Packages = append(section.Packages, domain.Package{Name: p.Name, Version: p.Version})
+	}
+
+	if len(section.ConfigFiles) == 0 && len(section.Packages) == 0 && len(section.Restore) == 0 {
+		return result, nil
+	}
+	section.Category = s.category
+	result.Plugin = section
+	return result, nil
+}
+
+// relativeSource turns a plugin-reported path ("~/.corp/cli.toml",
+// "/Users/me/.corp/cli.toml" or ".corp/cli.toml") into a clean path
+// relative to the home directory, which is how every other section records
+// sources. A path that leaves the home directory is an error.
+func (s *Scanner) relativeSource(source string) (string, error) {
+	rel := source
+	switch {
+	case source == "":
+		return "", fmt.Errorf("plugin %s: config file without source", s.name)
+	case strings.HasPrefix(source, "~/"):
+		rel = source[2:]
+	case filepath.IsAbs(source):
+		var err error
+		if rel, err = filepath.Rel(s.homeDir, source); err != nil {
+			rel = source
+		}
+	}
+	if !insideBundle(rel) {
+		return "", fmt.Errorf("plugin %s: config file %s is outside the home directory", s.name, source)
+	}
+	return path.Clean(filepath.ToSlash(rel)), nil
+}
+
+// insideBundle reports whether bundlePath is relative and stays inside the
+// bundle directory once cleaned. Sources are held to the same rule against
+// the home directory.
+func insideBundle(bundlePath string) bool {
+	clean := path.Clean(filepath.ToSlash(bundlePath))
+	return !path.IsAbs(clean) && !filepath.IsAbs(bundlePath) && clean != "." && clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// SearchDirs returns pluginDirs followed by every directory on $PATH.
+func SearchDirs(pluginDirs []string) []string {
+	dirs := append([]string(nil), pluginDirs...)
+	return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// Discover finds machinist-scanner-* executables in dirs and loads each one.
+// Directories are searched in order and the first executable with a given
+// file name wins, so plugin directories can shadow $PATH. Plugins that fail
+// to load are skipped and reported in the returned errors.
+func Discover(ctx context.Context, dirs []string, homeDir string, cmd util.CommandRunner) ([]*Scanner, []error) {
+	var plugins []*Scanner
+	var errs []error
+	seen := make(map[string]bool)
+
+	for _, dir := range dirs {
+		for _, exe := range findExecutables(dir) {
+			base := filepath.Base(exe)
+			if seen[base] {
+				continue
+			}
+			seen[base] = true
+
+			s, err := Load(ctx, exe, homeDir, cmd)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			plugins = append(plugins, s)
+		}
+	}
+	return plugins, errs
+}
+
+// findExecutables lists the plugin executables directly inside dir, sorted by name.
+func findExecutables(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var found []string
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), Prefix) || len(e.Name()) == len(Prefix) {
+			continue
+		}
+		full := filepath.Join(dir, e.Name())
+		fi, err := os.Stat(full)
+		if err != nil || fi.IsDir() || fi.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		found = append(found, full)
+	}
+	sort.Strings(found)
+	return found
+}
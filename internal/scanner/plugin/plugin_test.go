@@ -0,0 +1,216 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeExecutable(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o755))
+	return path
+}
+
+func TestLoad_Info(t *testing.T) {
+	mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		"/bin/machinist-scanner-corp info": {Output: `{"name":"corp-cli","description":"Corp CLI settings","category":"tools"}`},
+	}}
+
+	s, err := Load(context.Background(), "/bin/machinist-scanner-corp", "/home/me", mock)
+	require.NoError(t, err)
+	assert.Equal(t, "corp-cli", s.Name())
+	assert.Equal(t, "Corp CLI settings", s.Description())
+	assert.Equal(t, "tools", s.Category())
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		"/bin/machinist-scanner-proxy info": {Output: `{}`},
+	}}
+
+	s, err := Load(context.Background(), "/bin/machinist-scanner-proxy", "/home/me", mock)
+	require.NoError(t, err)
+	assert.Equal(t, "proxy", s.Name())
+	assert.NotEmpty(t, s.Description())
+	assert.Equal(t, DefaultCategory, s.Category())
+}
+
+func TestLoad_Errors(t *testing.T) {
+	mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		"/bin/machinist-scanner-broken info":  {Output: `not json`},
+		"/bin/machinist-scanner-failing info": {Err: errors.New("exit status 1")},
+	}}
+
+	_, err := Load(context.Background(), "/bin/machinist-scanner-broken", "/home/me", mock)
+	assert.Error(t, err)
+	_, err = Load(context.Background(), "/bin/machinist-scanner-failing", "/home/me", mock)
+	assert.Error(t, err)
+}
+
+func TestScanner_Scan(t *testing.T) {
+	mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		"/bin/machinist-scanner-corp info": {Output: `{"name":"corp"}`},
+		"/bin/machinist-scanner-corp scan": {Output: `{
+			"config_files": [
+				{"source": "~/.corp/cli.toml"},
+				{"source": "/home/me/.corp/token", "bundle_path": "configs/corp/token", "sensitive": true}
+			],
+			"packages": [{"name": "corp-cli", "version": "2.1.0"}, {"name": ""}],
+			"restore": ["corp-cli login --sso"]
+		}`},
+	}}
+
+	s, err := Load(context.Background(), "/bin/machinist-scanner-corp", "/home/me", mock)
+	require.NoError(t, err)
+	result, err := s.Scan(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "corp", result.ScannerName)
+	require.NotNil(t, result.Plugin)
+
+	require.Len(t, result.Plugin.ConfigFiles, 2)
+	assert.Equal(t, ".corp/cli.toml", result.Plugin.ConfigFiles[0].Source)
+	assert.Equal(t, "configs/plugins/corp/cli.toml", result.Plugin.ConfigFiles[0].BundlePath)
+	assert.Equal(t, ".corp/token", result.Plugin.ConfigFiles[1].Source)
+	assert.Equal(t, "configs/corp/token", result.Plugin.ConfigFiles[1].BundlePath)
+	assert.True(t, result.Plugin.ConfigFiles[1].Sensitive)
+
+	require.Len(t, result.Plugin.Packages, 1)
+	assert.Equal(t, "corp-cli", result.Plugin.Packages[0].Name)
+	assert.Equal(t, []string{"corp-cli login --sso"}, result.Plugin.Restore)
+}
+
+func TestScanner_Scan_Empty(t *testing.T) {
+	mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		"/bin/machinist-scanner-corp info": {Output: `{}`},
+		"/bin/machinist-scanner-corp scan": {Output: `{}`},
+	}}
+
+	s, err := Load(context.Background(), "/bin/machinist-scanner-corp", "/home/me", mock)
+	require.NoError(t, err)
+	result, err := s.Scan(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, result.Plugin)
+}
+
+func TestScanner_Scan_SourceOutsideHome(t *testing.T) {
+	for _, source := range []string{"/etc/corp.conf", "/home/me/../x", "~/../x", "~/.corp/../../x", "../x", ".corp/../..", "~/"} {
+		mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+			"/bin/machinist-scanner-corp info": {Output: `{}`},
+			"/bin/machinist-scanner-corp scan": {Output: `{"config_files": [{"source": "` + source + `"}]}`},
+		}}
+
+		s, err := Load(context.Background(), "/bin/machinist-scanner-corp", "/home/me", mock)
+		require.NoError(t, err)
+		_, err = s.Scan(context.Background())
+		assert.ErrorContains(t, err, "outside the home directory", source)
+	}
+}
+
+func TestScanner_Scan_CleansSources(t *testing.T) {
+	mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		"/bin/machinist-scanner-corp info": {Output: `{}`},
+		"/bin/machinist-scanner-corp scan": {Output: `{"config_files": [{"source": "~/.corp//x/../cli.toml"}, {"source": "./.corp/token"}]}`},
+	}}
+
+	s, err := Load(context.Background(), "/bin/machinist-scanner-corp", "/home/me", mock)
+	require.NoError(t, err)
+	result, err := s.Scan(context.Background())
+	require.NoError(t, err)
+	require.Len(t, result.Plugin.ConfigFiles, 2)
+	assert.Equal(t, ".corp/cli.toml", result.Plugin.ConfigFiles[0].Source)
+	assert.Equal(t, ".corp/token", result.Plugin.ConfigFiles[1].Source)
+}
+
+func TestScanner_Scan_BundlePathOutsideBundle(t *testing.T) {
+	for _, bundlePath := range []string{"../../x", "configs/../../x", "/etc/corp.conf", ".."} {
+		mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+			"/bin/machinist-scanner-corp info": {Output: `{}`},
+			"/bin/machinist-scanner-corp scan": {Output: `{"config_files": [{"source": "~/.corp/cli.toml", "bundle_path": "` + bundlePath + `"}]}`},
+		}}
+
+		s, err := Load(context.Background(), "/bin/machinist-scanner-corp", "/home/me", mock)
+		require.NoError(t, err)
+		_, err = s.Scan(context.Background())
+		assert.ErrorContains(t, err, "outside the bundle", bundlePath)
+	}
+}
+
+func TestLoad_NameNotASegment(t *testing.T) {
+	for _, name := range []string{"..", "../evil", "a/b"} {
+		mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+			"/bin/machinist-scanner-corp info": {Output: `{"name":"` + name + `"}`},
+		}}
+
+		_, err := Load(context.Background(), "/bin/machinist-scanner-corp", "/home/me", mock)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestDiscover_FirstDirWins(t *testing.T) {
+	pluginDir := t.TempDir()
+	pathDir := t.TempDir()
+
+	first := writeExecutable(t, pluginDir, "machinist-scanner-corp", "")
+	writeExecutable(t, pathDir, "machinist-scanner-corp", "")
+	other := writeExecutable(t, pathDir, "machinist-scanner-proxy", "")
+	// Not executable, not a plugin, or a bare prefix: all ignored.
+	require.NoError(t, os.WriteFile(filepath.Join(pathDir, "machinist-scanner-notes"), nil, 0o644))
+	writeExecutable(t, pathDir, "machinist", "")
+	writeExecutable(t, pathDir, "machinist-scanner-", "")
+
+	mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		first + " info": {Output: `{"name":"corp"}`},
+		other + " info": {Output: `{"name":"proxy"}`},
+	}}
+
+	plugins, errs := Discover(context.Background(), []string{pluginDir, pathDir, ""}, "/home/me", mock)
+	assert.Empty(t, errs)
+	require.Len(t, plugins, 2)
+	assert.Equal(t, first, plugins[0].Path())
+	assert.Equal(t, "proxy", plugins[1].Name())
+}
+
+func TestDiscover_ReportsBrokenPlugins(t *testing.T) {
+	dir := t.TempDir()
+	broken := writeExecutable(t, dir, "machinist-scanner-broken", "")
+
+	mock := &util.MockCommandRunner{Responses: map[string]util.MockResponse{
+		broken + " info": {Output: "oops"},
+	}}
+
+	plugins, errs := Discover(context.Background(), []string{dir}, "/home/me", mock)
+	assert.Empty(t, plugins)
+	assert.Len(t, errs, 1)
+}
+
+func TestSearchDirs(t *testing.T) {
+	t.Setenv("PATH", "/usr/local/bin"+string(os.PathListSeparator)+"/usr/bin")
+	assert.Equal(t, []string{"/plugins", "/usr/local/bin", "/usr/bin"}, SearchDirs([]string{"/plugins"}))
+}
+
+func TestPlugin_RealExecutable(t *testing.T) {
+	dir := t.TempDir()
+	writeExecutable(t, dir, "machinist-scanner-hello", `#!/bin/sh
+case "$1" in
+  info) echo '{"name":"hello","description":"Says hello"}' ;;
+  scan) echo '{"restore":["echo hello"]}' ;;
+esac
+`)
+
+	plugins, errs := Discover(context.Background(), []string{dir}, "/home/me", &util.RealCommandRunner{})
+	require.Empty(t, errs)
+	require.Len(t, plugins, 1)
+
+	result, err := plugins[0].Scan(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, result.Plugin)
+	assert.Equal(t, []string{"echo hello"}, result.Plugin.Restore)
+}
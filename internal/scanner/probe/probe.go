@@ -0,0 +1,239 @@
+// Package probe provides declarative scanners that look for known files and
+// directories under the home directory. Each probe is a small TOML file;
+// built-in probes are embedded from definitions/ and users can add their own
+// in ~/.config/machinist/probes/. Results are stored under [probes.<name>].
+package probe
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/scanner"
+	"github.com/moinsen-dev/machinist/internal/util"
+)
+
+//go:embed definitions/*.toml
+var definitionFS embed.FS
+
+// DefaultCategory is used when a definition does not set a category.
+const DefaultCategory = "tools"
+
+// Definition describes a probe scanner. The file name (without .toml) is
+// used as the name when Name is empty.
+type Definition struct {
+	Name        string `toml:"name"`
+	Description string `toml:"description"`
+	Category    string `toml:"category"`
+	// Paths are checked relative to the home directory ("~/" prefix optional).
+	// Existing files become config files, existing directories config dirs.
+	Paths []string `toml:"paths"`
+	// Sensitivity is "public" (default) or "sensitive". Sensitive files are
+	// flagged so bundling warns about them.
+	Sensitivity string `toml:"sensitivity"`
+	// BundlePrefix is where found paths are stored in the bundle
+	// (default "configs/<name>").
+	BundlePrefix string `toml:"bundle_prefix"`
+	// Cask is a Homebrew cask installed before the files are restored.
+	Cask string `toml:"cask"`
+	// Checklist lines are added to the post-restore checklist.
+	Checklist []string `toml:"checklist"`
+}
+
+// validate fills in defaults and checks the definition for mistakes.
+func (d *Definition) validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("probe has no name")
+	}
+	if !relative(d.Name) {
+		return fmt.Errorf("probe %q: name must be a relative path without ..", d.Name)
+	}
+	if len(d.Paths) == 0 {
+		return fmt.Errorf("probe %s: no paths", d.Name)
+	}
+	for i, p := range d.Paths {
+		rel := strings.TrimPrefix(p, "~/")
+		if !relative(rel) {
+			return fmt.Errorf("probe %s: path %q must be inside the home directory", d.Name, p)
+		}
+		d.Paths[i] = filepath.Clean(rel)
+	}
+	if d.BundlePrefix != "" && !relative(d.BundlePrefix) {
+		return fmt.Errorf("probe %s: bundle prefix %q must be inside the bundle", d.Name, d.BundlePrefix)
+	}
+	level, err := domain.ParseSensitivity(d.Sensitivity)
+	if err != nil {
+		return fmt.Errorf("probe %s: %w", d.Name, err)
+	}
+	if level == domain.Secret {
+		return fmt.Errorf("probe %s: secret paths need encryption and cannot be probed", d.Name)
+	}
+	if d.Description == "" {
+		d.Description = "Scans " + d.Name + " configuration"
+	}
+	if d.Category == "" {
+		d.Category = DefaultCategory
+	}
+	if d.BundlePrefix == "" {
+		d.BundlePrefix = path.Join("configs", d.Name)
+	}
+	return nil
+}
+
+// relative reports whether p is a relative path that stays below its base
+// directory once cleaned.
+func relative(p string) bool {
+	clean := path.Clean(filepath.ToSlash(p))
+	return p != "" && clean != "." && !path.IsAbs(clean) && !filepath.IsAbs(p) &&
+		clean != ".." && !strings.HasPrefix(clean, "../")
+}
+
+// Builtin returns the embedded probe definitions, sorted by name.
+func Builtin() ([]Definition, error) {
+	return loadFS(definitionFS, "definitions")
+}
+
+// LoadDir reads every *.toml definition in dir. A missing directory yields
+// no definitions. Invalid files are skipped and reported in the returned
+// error alongside the valid definitions.
+func LoadDir(dir string) ([]Definition, error) {
+	defs, err := loadFS(os.DirFS(dir), ".")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return defs, err
+}
+
+// Merge combines definition lists; later lists replace earlier entries with
+// the same name, so user probes can override built-ins.
+func Merge(lists ...[]Definition) []Definition {
+	byName := make(map[string]Definition)
+	for _, defs := range lists {
+		for _, d := range defs {
+			byName[d.Name] = d
+		}
+	}
+	merged := make([]Definition, 0, len(byName))
+	for _, d := range byName {
+		merged = append(merged, d)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name < merged[j].Name })
+	return merged
+}
+
+func loadFS(fsys fs.FS, dir string) ([]Definition, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var defs []Definition
+	var errs []error
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".toml") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, e.Name()))
+		if err == nil {
+			var def Definition
+			def, err = Parse(strings.TrimSuffix(e.Name(), ".toml"), data)
+			if err == nil {
+				defs = append(defs, def)
+				continue
+			}
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, errors.Join(errs...)
+}
+
+// Parse decodes and validates one definition. defaultName is used when the
+// definition does not set a name.
+func Parse(defaultName string, data []byte) (Definition, error) {
+	var def Definition
+	md, err := toml.Decode(string(data), &def)
+	if err != nil {
+		return Definition{}, err
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return Definition{}, fmt.Errorf("unknown key %s", undecoded[0])
+	}
+	if def.Name == "" {
+		def.Name = defaultName
+	}
+	if err := def.validate(); err != nil {
+		return Definition{}, err
+	}
+	return def, nil
+}
+
+// Scanner checks a Definition's paths and reports the ones that exist.
+type Scanner struct {
+	def     Definition
+	homeDir string
+}
+
+// New creates a Scanner for def. The definition must come from Parse,
+// Builtin or LoadDir so its defaults are filled in.
+func New(def Definition, homeDir string) *Scanner {
+	return &Scanner{def: def, homeDir: homeDir}
+}
+
+func (s *Scanner) Name() string        { return s.def.Name }
+func (s *Scanner) Description() string { return s.def.Description }
+func (s *Scanner) Category() string    { return s.def.Category }
+
+// CacheInputs watches the definition's paths. The definition itself is part
+// of the key, so editing a probe invalidates its cached result.
+func (s *Scanner) CacheInputs() scanner.Inputs {
+	in := scanner.Inputs{
+		Paths:  make([]string, len(s.def.Paths)),
+		Values: []string{fmt.Sprintf("%+v", s.def)},
+	}
+	for i, rel := range s.def.Paths {
+		in.Paths[i] = filepath.Join(s.homeDir, rel)
+	}
+	return in
+}
+
+// Scan records every definition path that exists under the home directory.
+// Each is bundled at its path relative to home below the bundle prefix, so
+// paths that share a base name do not overwrite each other.
+func (s *Scanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
+	result := &scanner.ScanResult{ScannerName: s.Name()}
+	sensitive := s.def.Sensitivity == domain.Sensitive.String()
+
+	section := &domain.ProbeSection{}
+	for _, rel := range s.def.Paths {
+		full := filepath.Join(s.homeDir, rel)
+		entry := domain.ConfigFile{
+			Source:     rel,
+			BundlePath: path.Join(s.def.BundlePrefix, filepath.ToSlash(rel)),
+			Sensitive:  sensitive,
+		}
+		switch {
+		case util.DirExists(full):
+			section.ConfigDirs = append(section.ConfigDirs, entry)
+		case util.FileExists(full):
+			section.ConfigFiles = append(section.ConfigFiles, entry)
+		}
+	}
+
+	if len(section.ConfigFiles) == 0 && len(section.ConfigDirs) == 0 {
+		return result, nil
+	}
+	section.Category = s.def.Category
+	section.Cask = s.def.Cask
+	section.Checklist = s.def.Checklist
+	result.Probe = section
+	return result, nil
+}
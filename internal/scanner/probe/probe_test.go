@@ -0,0 +1,164 @@
+package probe
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltin(t *testing.T) {
+	defs, err := Builtin()
+	require.NoError(t, err)
+	require.NotEmpty(t, defs)
+
+	names := make([]string, len(defs))
+	for i, d := range defs {
+		names[i] = d.Name
+		assert.NotEmpty(t, d.Description, d.Name)
+		assert.NotEmpty(t, d.Paths, d.Name)
+	}
+	assert.Contains(t, names, "hammerspoon")
+	assert.IsIncreasing(t, names)
+}
+
+func TestParse_Defaults(t *testing.T) {
+	def, err := Parse("zed", []byte(`paths = ["~/.config/zed/settings.json"]`))
+	require.NoError(t, err)
+	assert.Equal(t, "zed", def.Name)
+	assert.Equal(t, DefaultCategory, def.Category)
+	assert.Equal(t, "configs/zed", def.BundlePrefix)
+	assert.Equal(t, []string{".config/zed/settings.json"}, def.Paths)
+	assert.NotEmpty(t, def.Description)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"no paths":      `description = "x"`,
+		"outside home":  `paths = ["/etc/hosts"]`,
+		"parent escape": `paths = ["~/../other"]`,
+		"nested escape": `paths = ["~/.config/../../other"]`,
+		"prefix escape": "paths = [\"~/.x\"]\nbundle_prefix = \"../..\"",
+		"prefix nested": "paths = [\"~/.x\"]\nbundle_prefix = \"configs/../../x\"",
+		"prefix abs":    "paths = [\"~/.x\"]\nbundle_prefix = \"/tmp/x\"",
+		"name escape":   "paths = [\"~/.x\"]\nname = \"../..\"",
+		"name abs":      "paths = [\"~/.x\"]\nname = \"/tmp\"",
+		"secret":        "paths = [\"~/.token\"]\nsensitivity = \"secret\"",
+		"bad level":     "paths = [\"~/.token\"]\nsensitivity = \"classified\"",
+		"unknown key":   "paths = [\"~/.x\"]\npath = \"~/.y\"",
+		"not toml":      `paths = [`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse("x", []byte(content))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "corp.toml"), []byte(`paths = ["~/.corp"]`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.toml"), []byte(`paths = []`), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`ignored`), 0o644))
+
+	defs, err := LoadDir(dir)
+	assert.Error(t, err, "broken.toml should be reported")
+	require.Len(t, defs, 1)
+	assert.Equal(t, "corp", defs[0].Name)
+
+	defs, err = LoadDir(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Empty(t, defs)
+}
+
+func TestMerge_LaterWins(t *testing.T) {
+	builtin := []Definition{{Name: "a", Cask: "a"}, {Name: "b", Cask: "b"}}
+	user := []Definition{{Name: "b", Cask: "custom-b"}, {Name: "c"}}
+
+	merged := Merge(builtin, user)
+	require.Len(t, merged, 3)
+	assert.Equal(t, "custom-b", merged[1].Cask)
+	assert.Equal(t, "c", merged[2].Name)
+}
+
+func TestScanner_Scan(t *testing.T) {
+	home := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".hammerspoon"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".hammerspoon", "init.lua"), []byte("--"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".hammerspoon.token"), []byte("x"), 0o600))
+
+	def, err := Parse("hammerspoon", []byte(`
+paths = ["~/.hammerspoon", "~/.hammerspoon.token", "~/.missing"]
+sensitivity = "sensitive"
+cask = "hammerspoon"
+checklist = ["Grant accessibility permissions"]
+`))
+	require.NoError(t, err)
+
+	s := New(def, home)
+	assert.Equal(t, "hammerspoon", s.Name())
+	assert.Equal(t, "tools", s.Category())
+
+	result, err := s.Scan(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, result.Probe)
+
+	assert.Equal(t, []domain.ConfigFile{
+		{Source: ".hammerspoon", BundlePath: "configs/hammerspoon/.hammerspoon", Sensitive: true},
+	}, result.Probe.ConfigDirs)
+	assert.Equal(t, []domain.ConfigFile{
+		{Source: ".hammerspoon.token", BundlePath: "configs/hammerspoon/.hammerspoon.token", Sensitive: true},
+	}, result.Probe.ConfigFiles)
+	assert.Equal(t, "hammerspoon", result.Probe.Cask)
+	assert.Equal(t, []string{"Grant accessibility permissions"}, result.Probe.Checklist)
+}
+
+func TestScanner_Scan_SameBaseName(t *testing.T) {
+	home := t.TempDir()
+	for _, dir := range []string{".config/zed", "Library/Application Support/Zed"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(home, dir), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(home, dir, "settings.json"), []byte("{}"), 0o644))
+	}
+	def, err := Parse("zed", []byte(`paths = ["~/.config/zed/settings.json", "~/Library/Application Support/Zed/settings.json"]`))
+	require.NoError(t, err)
+
+	result, err := New(def, home).Scan(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, result.Probe)
+	assert.Equal(t, []domain.ConfigFile{
+		{Source: ".config/zed/settings.json", BundlePath: "configs/zed/.config/zed/settings.json"},
+		{Source: "Library/Application Support/Zed/settings.json", BundlePath: "configs/zed/Library/Application Support/Zed/settings.json"},
+	}, result.Probe.ConfigFiles)
+}
+
+func TestBuiltin_ReplacesScanners(t *testing.T) {
+	defs, err := Builtin()
+	require.NoError(t, err)
+	byName := make(map[string]Definition)
+	for _, d := range defs {
+		byName[d.Name] = d
+	}
+	for _, name := range []string{"raycast", "rectangle", "bettertouchtool", "alfred", "karabiner"} {
+		require.Contains(t, byName, name)
+		assert.Equal(t, "tools", byName[name].Category, name)
+	}
+	for _, name := range []string{"vercel", "flyio", "gcp"} {
+		require.Contains(t, byName, name)
+		assert.Equal(t, "cloud", byName[name].Category, name)
+		assert.Equal(t, "sensitive", byName[name].Sensitivity, name)
+	}
+}
+
+func TestScanner_Scan_NothingFound(t *testing.T) {
+	def, err := Parse("hammerspoon", []byte(`paths = ["~/.hammerspoon"]`))
+	require.NoError(t, err)
+
+	result, err := New(def, t.TempDir()).Scan(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, result.Probe)
+}
@@ -26,6 +26,21 @@ func (a *AsdfScanner) Name() string        { return "asdf" }
 func (a *AsdfScanner) Description() string { return "Scans asdf/mise plugins and installed versions" }
 func (a *AsdfScanner) Category() string    { return "runtimes" }
 
+// CacheInputs watches the asdf plugins and installs, the mise installs and
+// the global tool versions, keyed on the asdf and mise versions.
+func (a *AsdfScanner) CacheInputs() scanner.Inputs {
+	return scanner.Inputs{
+		Paths: []string{
+			filepath.Join(a.homeDir, ".tool-versions"),
+			filepath.Join(a.homeDir, ".asdf", "plugins"),
+			filepath.Join(a.homeDir, ".asdf", "installs"),
+			filepath.Join(a.homeDir, ".local", "share", "mise", "installs"),
+			filepath.Join(a.homeDir, ".config", "mise", "config.toml"),
+		},
+		Commands: [][]string{{"asdf", "--version"}, {"mise", "--version"}},
+	}
+}
+
 // Scan checks for asdf or mise and returns a ScanResult with the Asdf field populated.
 // asdf is preferred; mise is used as a fallback.
 func (a *AsdfScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
@@ -2,6 +2,7 @@ package runtimes
 
 import (
 	"context"
+	"path/filepath"
 	"strings"
 
 	"github.com/moinsen-dev/machinist/internal/domain"
@@ -11,18 +12,31 @@ import (
 
 // BunScanner scans Bun version and globally installed packages.
 type BunScanner struct {
-	cmd util.CommandRunner
+	homeDir string
+	cmd     util.CommandRunner
 }
 
-// NewBunScanner creates a new BunScanner with the given CommandRunner.
-func NewBunScanner(cmd util.CommandRunner) *BunScanner {
-	return &BunScanner{cmd: cmd}
+// NewBunScanner creates a new BunScanner with the given home directory and CommandRunner.
+func NewBunScanner(homeDir string, cmd util.CommandRunner) *BunScanner {
+	return &BunScanner{homeDir: homeDir, cmd: cmd}
 }
 
 func (b *BunScanner) Name() string        { return "bun" }
 func (b *BunScanner) Description() string  { return "Scans Bun version and globally installed packages" }
 func (b *BunScanner) Category() string     { return "runtimes" }
 
+// CacheInputs watches the global package manifest and binaries, keyed on
+// the bun version.
+func (b *BunScanner) CacheInputs() scanner.Inputs {
+	return scanner.Inputs{
+		Paths: []string{
+			filepath.Join(b.homeDir, ".bun", "install", "global", "package.json"),
+			filepath.Join(b.homeDir, ".bun", "bin"),
+		},
+		Commands: [][]string{{"bun", "--version"}},
+	}
+}
+
 // Scan detects the Bun version and lists globally installed packages.
 func (b *BunScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
 	result := &scanner.ScanResult{
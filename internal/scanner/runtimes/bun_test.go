@@ -11,17 +11,17 @@ import (
 )
 
 func TestBunScanner_Name(t *testing.T) {
-	s := NewBunScanner(&util.MockCommandRunner{})
+	s := NewBunScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "bun", s.Name())
 }
 
 func TestBunScanner_Description(t *testing.T) {
-	s := NewBunScanner(&util.MockCommandRunner{})
+	s := NewBunScanner("/home/user", &util.MockCommandRunner{})
 	assert.NotEmpty(t, s.Description())
 }
 
 func TestBunScanner_Category(t *testing.T) {
-	s := NewBunScanner(&util.MockCommandRunner{})
+	s := NewBunScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "runtimes", s.Category())
 }
 
@@ -36,7 +36,7 @@ func TestBunScanner_Scan_HappyPath(t *testing.T) {
 		},
 	}
 
-	s := NewBunScanner(mock)
+	s := NewBunScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Bun)
@@ -55,7 +55,7 @@ func TestBunScanner_Scan_NotInstalled(t *testing.T) {
 		},
 	}
 
-	s := NewBunScanner(mock)
+	s := NewBunScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	assert.Nil(t, result.Bun)
@@ -70,7 +70,7 @@ func TestBunScanner_Scan_EmptyGlobalList(t *testing.T) {
 		},
 	}
 
-	s := NewBunScanner(mock)
+	s := NewBunScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Bun)
@@ -90,7 +90,7 @@ func TestBunScanner_Scan_ScopedPackages(t *testing.T) {
 		},
 	}
 
-	s := NewBunScanner(mock)
+	s := NewBunScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Bun)
@@ -113,7 +113,7 @@ func TestBunScanner_Scan_NoVersionSuffix(t *testing.T) {
 		},
 	}
 
-	s := NewBunScanner(mock)
+	s := NewBunScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Bun)
@@ -26,6 +26,14 @@ func (d *DenoScanner) Name() string        { return "deno" }
 func (d *DenoScanner) Description() string  { return "Scans Deno version and globally installed scripts" }
 func (d *DenoScanner) Category() string     { return "runtimes" }
 
+// CacheInputs watches the installed scripts, keyed on the deno version.
+func (d *DenoScanner) CacheInputs() scanner.Inputs {
+	return scanner.Inputs{
+		Paths:    []string{filepath.Join(d.homeDir, ".deno", "bin")},
+		Commands: [][]string{{"deno", "--version"}},
+	}
+}
+
 // Scan detects the Deno version and lists globally installed scripts from ~/.deno/bin/.
 func (d *DenoScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
 	result := &scanner.ScanResult{
@@ -2,6 +2,7 @@ package runtimes
 
 import (
 	"context"
+	"path/filepath"
 	"strings"
 
 	"github.com/moinsen-dev/machinist/internal/domain"
@@ -11,18 +12,28 @@ import (
 
 // FlutterScanner scans Flutter SDK channel, version, and Dart global packages.
 type FlutterScanner struct {
-	cmd util.CommandRunner
+	homeDir string
+	cmd     util.CommandRunner
 }
 
-// NewFlutterScanner creates a new FlutterScanner with the given CommandRunner.
-func NewFlutterScanner(cmd util.CommandRunner) *FlutterScanner {
-	return &FlutterScanner{cmd: cmd}
+// NewFlutterScanner creates a new FlutterScanner with the given home directory and CommandRunner.
+func NewFlutterScanner(homeDir string, cmd util.CommandRunner) *FlutterScanner {
+	return &FlutterScanner{homeDir: homeDir, cmd: cmd}
 }
 
 func (f *FlutterScanner) Name() string        { return "flutter" }
 func (f *FlutterScanner) Description() string { return "Scans Flutter SDK and Dart global packages" }
 func (f *FlutterScanner) Category() string    { return "runtimes" }
 
+// CacheInputs watches the globally activated pub packages, keyed on the dart
+// version, which changes with every Flutter upgrade.
+func (f *FlutterScanner) CacheInputs() scanner.Inputs {
+	return scanner.Inputs{
+		Paths:    []string{filepath.Join(f.homeDir, ".pub-cache", "global_packages")},
+		Commands: [][]string{{"dart", "--version"}},
+	}
+}
+
 // Scan runs flutter --version and dart pub global list to populate FlutterSection.
 func (f *FlutterScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
 	result := &scanner.ScanResult{
@@ -10,17 +10,17 @@ import (
 )
 
 func TestFlutterScanner_Name(t *testing.T) {
-	s := NewFlutterScanner(&util.MockCommandRunner{})
+	s := NewFlutterScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "flutter", s.Name())
 }
 
 func TestFlutterScanner_Description(t *testing.T) {
-	s := NewFlutterScanner(&util.MockCommandRunner{})
+	s := NewFlutterScanner("/home/user", &util.MockCommandRunner{})
 	assert.NotEmpty(t, s.Description())
 }
 
 func TestFlutterScanner_Category(t *testing.T) {
-	s := NewFlutterScanner(&util.MockCommandRunner{})
+	s := NewFlutterScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "runtimes", s.Category())
 }
 
@@ -40,7 +40,7 @@ func TestFlutterScanner_Scan_Full(t *testing.T) {
 		},
 	}
 
-	s := NewFlutterScanner(mock)
+	s := NewFlutterScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Flutter)
@@ -62,7 +62,7 @@ func TestFlutterScanner_Scan_NotInstalled(t *testing.T) {
 		},
 	}
 
-	s := NewFlutterScanner(mock)
+	s := NewFlutterScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	assert.Nil(t, result.Flutter)
@@ -81,7 +81,7 @@ func TestFlutterScanner_Scan_NoDartGlobalPackages(t *testing.T) {
 		},
 	}
 
-	s := NewFlutterScanner(mock)
+	s := NewFlutterScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Flutter)
@@ -103,7 +103,7 @@ func TestFlutterScanner_Scan_DartPkgListError(t *testing.T) {
 		},
 	}
 
-	s := NewFlutterScanner(mock)
+	s := NewFlutterScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Flutter)
@@ -13,18 +13,28 @@ import (
 
 // GoScanner scans Go version and globally installed tools.
 type GoScanner struct {
-	cmd util.CommandRunner
+	homeDir string
+	cmd     util.CommandRunner
 }
 
-// NewGoScanner creates a new GoScanner with the given CommandRunner.
-func NewGoScanner(cmd util.CommandRunner) *GoScanner {
-	return &GoScanner{cmd: cmd}
+// NewGoScanner creates a new GoScanner with the given home directory and CommandRunner.
+func NewGoScanner(homeDir string, cmd util.CommandRunner) *GoScanner {
+	return &GoScanner{homeDir: homeDir, cmd: cmd}
 }
 
 func (g *GoScanner) Name() string        { return "go" }
 func (g *GoScanner) Description() string { return "Scans Go version and globally installed tools" }
 func (g *GoScanner) Category() string    { return "runtimes" }
 
+// CacheInputs watches the default GOPATH bin directory, keyed on the go
+// version and the configured GOPATH.
+func (g *GoScanner) CacheInputs() scanner.Inputs {
+	return scanner.Inputs{
+		Paths:    []string{filepath.Join(g.homeDir, "go", "bin")},
+		Commands: [][]string{{"go", "version"}, {"go", "env", "GOPATH"}},
+	}
+}
+
 // Scan runs go version and go env GOPATH, then lists $GOPATH/bin/ for global tools.
 func (g *GoScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
 	result := &scanner.ScanResult{
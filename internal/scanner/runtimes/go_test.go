@@ -13,17 +13,17 @@ import (
 )
 
 func TestGoScanner_Name(t *testing.T) {
-	s := NewGoScanner(&util.MockCommandRunner{})
+	s := NewGoScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "go", s.Name())
 }
 
 func TestGoScanner_Description(t *testing.T) {
-	s := NewGoScanner(&util.MockCommandRunner{})
+	s := NewGoScanner("/home/user", &util.MockCommandRunner{})
 	assert.NotEmpty(t, s.Description())
 }
 
 func TestGoScanner_Category(t *testing.T) {
-	s := NewGoScanner(&util.MockCommandRunner{})
+	s := NewGoScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "runtimes", s.Category())
 }
 
@@ -49,7 +49,7 @@ func TestGoScanner_Scan_Full(t *testing.T) {
 		},
 	}
 
-	s := NewGoScanner(mock)
+	s := NewGoScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.GoLang)
@@ -80,7 +80,7 @@ func TestGoScanner_Scan_NotInstalled(t *testing.T) {
 		},
 	}
 
-	s := NewGoScanner(mock)
+	s := NewGoScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	assert.Nil(t, result.GoLang)
@@ -98,7 +98,7 @@ func TestGoScanner_Scan_EmptyGOPATHBin(t *testing.T) {
 		},
 	}
 
-	s := NewGoScanner(mock)
+	s := NewGoScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.GoLang)
@@ -26,6 +26,20 @@ func (j *JavaScanner) Name() string        { return "java" }
 func (j *JavaScanner) Description() string { return "Scans Java versions via SDKMAN or system java" }
 func (j *JavaScanner) Category() string    { return "runtimes" }
 
+// CacheInputs watches the SDKMAN candidates and the system JVMs. `java
+// -version` writes to stderr, so JAVA_HOME stands in for a version command.
+func (j *JavaScanner) CacheInputs() scanner.Inputs {
+	candidates := filepath.Join(j.homeDir, ".sdkman", "candidates", "java")
+	return scanner.Inputs{
+		Paths: []string{
+			candidates,
+			filepath.Join(candidates, "current"),
+			"/Library/Java/JavaVirtualMachines",
+		},
+		Values: []string{os.Getenv("JAVA_HOME")},
+	}
+}
+
 // Scan detects Java installations via SDKMAN (preferred) or falls back to system java.
 func (j *JavaScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
 	result := &scanner.ScanResult{
@@ -28,6 +28,25 @@ func (n *NodeScanner) Name() string        { return "node" }
 func (n *NodeScanner) Description() string  { return "Scans Node.js versions and global packages" }
 func (n *NodeScanner) Category() string     { return "runtimes" }
 
+// CacheInputs watches the nvm and fnm installs, the nvm default alias and the
+// global node_modules of every version, keyed on the active node and npm.
+func (n *NodeScanner) CacheInputs() scanner.Inputs {
+	nvmVersions := filepath.Join(n.homeDir, ".nvm", "versions", "node")
+	in := scanner.Inputs{
+		Paths: []string{
+			nvmVersions,
+			filepath.Join(n.homeDir, ".nvm", "alias", "default"),
+			filepath.Join(n.homeDir, ".local", "share", "fnm", "node-versions"),
+			filepath.Join(n.homeDir, "Library", "Application Support", "fnm", "node-versions"),
+			"/opt/homebrew/lib/node_modules",
+			"/usr/local/lib/node_modules",
+		},
+		Commands: [][]string{{"node", "--version"}, {"npm", "--version"}},
+	}
+	in.Glob(filepath.Join(nvmVersions, "*", "lib", "node_modules"))
+	return in
+}
+
 // Scan detects the Node.js version manager, installed versions, default version,
 // and globally installed npm packages.
 func (n *NodeScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
@@ -161,6 +180,10 @@ type npmListOutput struct {
 	} `json:"dependencies"`
 }
 
+// bundledPackages are the global packages Node installs itself. They are
+// not the user's to restore or remove.
+var bundledPackages = map[string]bool{"npm": true, "corepack": true}
+
 // scanGlobalPackages parses `npm list -g --depth=0 --json` output.
 func (n *NodeScanner) scanGlobalPackages(ctx context.Context) []domain.Package {
 	output, err := n.cmd.Run(ctx, "npm", "list", "-g", "--depth=0", "--json")
@@ -176,8 +199,7 @@ func (n *NodeScanner) scanGlobalPackages(ctx context.Context) []domain.Package {
 	// Collect and sort package names for deterministic output.
 	names := make([]string, 0, len(parsed.Dependencies))
 	for name := range parsed.Dependencies {
-		// Skip npm itself — it comes bundled with node.
-		if name == "npm" {
+		if bundledPackages[name] {
 			continue
 		}
 		names = append(names, name)
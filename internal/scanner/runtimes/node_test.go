@@ -27,6 +27,17 @@ func TestNodeScanner_Category(t *testing.T) {
 	assert.Equal(t, "runtimes", s.Category())
 }
 
+func TestNodeScanner_CacheInputs(t *testing.T) {
+	homeDir := t.TempDir()
+	globals := filepath.Join(homeDir, ".nvm", "versions", "node", "v20.11.0", "lib", "node_modules")
+	require.NoError(t, os.MkdirAll(globals, 0o755))
+
+	in := NewNodeScanner(homeDir, &util.MockCommandRunner{}).CacheInputs()
+	assert.Contains(t, in.Paths, filepath.Join(homeDir, ".nvm", "alias", "default"))
+	assert.Contains(t, in.Paths, globals)
+	assert.Equal(t, [][]string{{"node", "--version"}, {"npm", "--version"}}, in.Commands)
+}
+
 func TestNodeScanner_Scan_WithNvm(t *testing.T) {
 	homeDir := t.TempDir()
 	require.NoError(t, os.MkdirAll(filepath.Join(homeDir, ".nvm"), 0o755))
@@ -37,7 +48,7 @@ func TestNodeScanner_Scan_WithNvm(t *testing.T) {
 				Output: "->     v20.10.0\n       v18.19.0\n       v16.20.2\ndefault -> v20.10.0",
 			},
 			"npm list -g --depth=0 --json": {
-				Output: `{"dependencies":{"npm":{"version":"10.2.3"},"typescript":{"version":"5.3.3"},"eslint":{"version":"8.56.0"}}}`,
+				Output: `{"dependencies":{"npm":{"version":"10.2.3"},"corepack":{"version":"0.24.0"},"typescript":{"version":"5.3.3"},"eslint":{"version":"8.56.0"}}}`,
 			},
 		},
 	}
@@ -53,7 +64,7 @@ func TestNodeScanner_Scan_WithNvm(t *testing.T) {
 	assert.Equal(t, "v20.10.0", node.DefaultVersion)
 	assert.ElementsMatch(t, []string{"v20.10.0", "v18.19.0", "v16.20.2"}, node.Versions)
 
-	// npm itself should be excluded from global packages
+	// npm and corepack ship with node and are excluded from global packages
 	assert.Len(t, node.GlobalPackages, 2)
 	assertHasPackage(t, node.GlobalPackages, "typescript", "5.3.3")
 	assertHasPackage(t, node.GlobalPackages, "eslint", "8.56.0")
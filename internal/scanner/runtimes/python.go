@@ -3,6 +3,7 @@ package runtimes
 import (
 	"context"
 	"encoding/json"
+	"path/filepath"
 	"strings"
 
 	"github.com/moinsen-dev/machinist/internal/domain"
@@ -12,18 +13,40 @@ import (
 
 // PythonScanner scans Python versions and globally installed packages.
 type PythonScanner struct {
-	cmd util.CommandRunner
+	homeDir string
+	cmd     util.CommandRunner
 }
 
-// NewPythonScanner creates a new PythonScanner with the given CommandRunner.
-func NewPythonScanner(cmd util.CommandRunner) *PythonScanner {
-	return &PythonScanner{cmd: cmd}
+// NewPythonScanner creates a new PythonScanner with the given home directory and CommandRunner.
+func NewPythonScanner(homeDir string, cmd util.CommandRunner) *PythonScanner {
+	return &PythonScanner{homeDir: homeDir, cmd: cmd}
 }
 
 func (p *PythonScanner) Name() string        { return "python" }
 func (p *PythonScanner) Description() string  { return "Scans Python versions and global packages" }
 func (p *PythonScanner) Category() string     { return "runtimes" }
 
+// CacheInputs watches the pyenv and uv installs and the site-packages of
+// every interpreter, keyed on the active python3 and pip.
+func (p *PythonScanner) CacheInputs() scanner.Inputs {
+	pyenvVersions := filepath.Join(p.homeDir, ".pyenv", "versions")
+	in := scanner.Inputs{
+		Paths: []string{
+			pyenvVersions,
+			filepath.Join(p.homeDir, ".pyenv", "version"),
+			filepath.Join(p.homeDir, ".local", "share", "uv", "python"),
+		},
+		Commands: [][]string{{"python3", "--version"}, {"pip", "--version"}},
+	}
+	in.Glob(
+		filepath.Join(pyenvVersions, "*", "lib", "python*", "site-packages"),
+		filepath.Join(p.homeDir, "Library", "Python", "*", "lib", "python", "site-packages"),
+		"/opt/homebrew/lib/python*/site-packages",
+		"/usr/local/lib/python*/site-packages",
+	)
+	return in
+}
+
 // pipPackage is used for JSON deserialization of pip list output.
 type pipPackage struct {
 	Name    string `json:"name"`
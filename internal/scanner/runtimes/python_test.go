@@ -11,17 +11,17 @@ import (
 )
 
 func TestPythonScanner_Name(t *testing.T) {
-	s := NewPythonScanner(&util.MockCommandRunner{})
+	s := NewPythonScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "python", s.Name())
 }
 
 func TestPythonScanner_Description(t *testing.T) {
-	s := NewPythonScanner(&util.MockCommandRunner{})
+	s := NewPythonScanner("/home/user", &util.MockCommandRunner{})
 	assert.NotEmpty(t, s.Description())
 }
 
 func TestPythonScanner_Category(t *testing.T) {
-	s := NewPythonScanner(&util.MockCommandRunner{})
+	s := NewPythonScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "runtimes", s.Category())
 }
 
@@ -41,7 +41,7 @@ func TestPythonScanner_Scan_WithPyenv(t *testing.T) {
 		},
 	}
 
-	s := NewPythonScanner(mock)
+	s := NewPythonScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -75,7 +75,7 @@ func TestPythonScanner_Scan_WithUv(t *testing.T) {
 		},
 	}
 
-	s := NewPythonScanner(mock)
+	s := NewPythonScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -101,7 +101,7 @@ func TestPythonScanner_Scan_SystemPython(t *testing.T) {
 		},
 	}
 
-	s := NewPythonScanner(mock)
+	s := NewPythonScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -119,7 +119,7 @@ func TestPythonScanner_Scan_NoPython(t *testing.T) {
 		},
 	}
 
-	s := NewPythonScanner(mock)
+	s := NewPythonScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
@@ -148,7 +148,7 @@ func TestPythonScanner_Scan_PipListParsing(t *testing.T) {
 		},
 	}
 
-	s := NewPythonScanner(mock)
+	s := NewPythonScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 
 	require.NoError(t, err)
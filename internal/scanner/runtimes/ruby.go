@@ -2,6 +2,7 @@ package runtimes
 
 import (
 	"context"
+	"path/filepath"
 	"strings"
 
 	"github.com/moinsen-dev/machinist/internal/domain"
@@ -11,18 +12,40 @@ import (
 
 // RubyScanner scans Ruby version manager, installed versions, and globally installed gems.
 type RubyScanner struct {
-	cmd util.CommandRunner
+	homeDir string
+	cmd     util.CommandRunner
 }
 
-// NewRubyScanner creates a new RubyScanner with the given CommandRunner.
-func NewRubyScanner(cmd util.CommandRunner) *RubyScanner {
-	return &RubyScanner{cmd: cmd}
+// NewRubyScanner creates a new RubyScanner with the given home directory and CommandRunner.
+func NewRubyScanner(homeDir string, cmd util.CommandRunner) *RubyScanner {
+	return &RubyScanner{homeDir: homeDir, cmd: cmd}
 }
 
 func (r *RubyScanner) Name() string        { return "ruby" }
 func (r *RubyScanner) Description() string  { return "Scans Ruby version manager, versions, and global gems" }
 func (r *RubyScanner) Category() string     { return "runtimes" }
 
+// CacheInputs watches the rbenv and rvm installs and the gem directories of
+// every version, keyed on the active ruby and gem.
+func (r *RubyScanner) CacheInputs() scanner.Inputs {
+	rbenvVersions := filepath.Join(r.homeDir, ".rbenv", "versions")
+	in := scanner.Inputs{
+		Paths: []string{
+			rbenvVersions,
+			filepath.Join(r.homeDir, ".rbenv", "version"),
+			filepath.Join(r.homeDir, ".rvm", "rubies"),
+			filepath.Join(r.homeDir, ".rvm", "gems"),
+		},
+		Commands: [][]string{{"ruby", "--version"}, {"gem", "--version"}},
+	}
+	in.Glob(
+		filepath.Join(rbenvVersions, "*", "lib", "ruby", "gems", "*", "gems"),
+		filepath.Join(r.homeDir, ".rvm", "gems", "*", "gems"),
+		filepath.Join(r.homeDir, ".gem", "ruby", "*", "gems"),
+	)
+	return in
+}
+
 // Scan detects the Ruby version manager (rbenv, rvm, or system), installed
 // versions, the default/current version, and globally installed gems.
 func (r *RubyScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
@@ -11,17 +11,17 @@ import (
 )
 
 func TestRubyScanner_Name(t *testing.T) {
-	s := NewRubyScanner(&util.MockCommandRunner{})
+	s := NewRubyScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "ruby", s.Name())
 }
 
 func TestRubyScanner_Description(t *testing.T) {
-	s := NewRubyScanner(&util.MockCommandRunner{})
+	s := NewRubyScanner("/home/user", &util.MockCommandRunner{})
 	assert.NotEmpty(t, s.Description())
 }
 
 func TestRubyScanner_Category(t *testing.T) {
-	s := NewRubyScanner(&util.MockCommandRunner{})
+	s := NewRubyScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "runtimes", s.Category())
 }
 
@@ -39,7 +39,7 @@ func TestRubyScanner_Scan_WithRbenv(t *testing.T) {
 		},
 	}
 
-	s := NewRubyScanner(mock)
+	s := NewRubyScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Ruby)
@@ -71,7 +71,7 @@ func TestRubyScanner_Scan_WithRvm(t *testing.T) {
 		},
 	}
 
-	s := NewRubyScanner(mock)
+	s := NewRubyScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Ruby)
@@ -99,7 +99,7 @@ func TestRubyScanner_Scan_SystemRuby(t *testing.T) {
 		},
 	}
 
-	s := NewRubyScanner(mock)
+	s := NewRubyScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Ruby)
@@ -118,7 +118,7 @@ func TestRubyScanner_Scan_NoRuby(t *testing.T) {
 		},
 	}
 
-	s := NewRubyScanner(mock)
+	s := NewRubyScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	assert.Nil(t, result.Ruby)
@@ -135,7 +135,7 @@ func TestRubyScanner_Scan_RbenvNoGems(t *testing.T) {
 		},
 	}
 
-	s := NewRubyScanner(mock)
+	s := NewRubyScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Ruby)
@@ -158,7 +158,7 @@ func TestRubyScanner_Scan_GemListHeaderSkipped(t *testing.T) {
 		},
 	}
 
-	s := NewRubyScanner(mock)
+	s := NewRubyScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Ruby)
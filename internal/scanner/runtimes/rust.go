@@ -2,6 +2,7 @@ package runtimes
 
 import (
 	"context"
+	"path/filepath"
 	"strings"
 
 	"github.com/moinsen-dev/machinist/internal/domain"
@@ -11,18 +12,32 @@ import (
 
 // RustScanner scans Rust toolchains, components, and cargo-installed packages.
 type RustScanner struct {
-	cmd util.CommandRunner
+	homeDir string
+	cmd     util.CommandRunner
 }
 
-// NewRustScanner creates a new RustScanner with the given CommandRunner.
-func NewRustScanner(cmd util.CommandRunner) *RustScanner {
-	return &RustScanner{cmd: cmd}
+// NewRustScanner creates a new RustScanner with the given home directory and CommandRunner.
+func NewRustScanner(homeDir string, cmd util.CommandRunner) *RustScanner {
+	return &RustScanner{homeDir: homeDir, cmd: cmd}
 }
 
 func (r *RustScanner) Name() string        { return "rust" }
 func (r *RustScanner) Description() string  { return "Scans Rust toolchains and cargo packages" }
 func (r *RustScanner) Category() string     { return "runtimes" }
 
+// CacheInputs watches the rustup toolchains and default, and the cargo
+// install registry, keyed on the active rustc and cargo.
+func (r *RustScanner) CacheInputs() scanner.Inputs {
+	return scanner.Inputs{
+		Paths: []string{
+			filepath.Join(r.homeDir, ".rustup", "toolchains"),
+			filepath.Join(r.homeDir, ".rustup", "settings.toml"),
+			filepath.Join(r.homeDir, ".cargo", ".crates.toml"),
+		},
+		Commands: [][]string{{"rustc", "--version"}, {"cargo", "--version"}},
+	}
+}
+
 // Scan runs rustup and cargo commands and returns a ScanResult with the Rust field populated.
 func (r *RustScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
 	result := &scanner.ScanResult{
@@ -11,17 +11,17 @@ import (
 )
 
 func TestRustScanner_Name(t *testing.T) {
-	s := NewRustScanner(&util.MockCommandRunner{})
+	s := NewRustScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "rust", s.Name())
 }
 
 func TestRustScanner_Description(t *testing.T) {
-	s := NewRustScanner(&util.MockCommandRunner{})
+	s := NewRustScanner("/home/user", &util.MockCommandRunner{})
 	assert.NotEmpty(t, s.Description())
 }
 
 func TestRustScanner_Category(t *testing.T) {
-	s := NewRustScanner(&util.MockCommandRunner{})
+	s := NewRustScanner("/home/user", &util.MockCommandRunner{})
 	assert.Equal(t, "runtimes", s.Category())
 }
 
@@ -41,7 +41,7 @@ func TestRustScanner_Scan_Full(t *testing.T) {
 		},
 	}
 
-	s := NewRustScanner(mock)
+	s := NewRustScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Rust)
@@ -69,7 +69,7 @@ func TestRustScanner_Scan_NoRust(t *testing.T) {
 		},
 	}
 
-	s := NewRustScanner(mock)
+	s := NewRustScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	assert.Nil(t, result.Rust)
@@ -91,7 +91,7 @@ func TestRustScanner_Scan_NoCargoPackages(t *testing.T) {
 		},
 	}
 
-	s := NewRustScanner(mock)
+	s := NewRustScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Rust)
@@ -119,7 +119,7 @@ func TestRustScanner_Scan_DefaultToolchainParsing(t *testing.T) {
 		},
 	}
 
-	s := NewRustScanner(mock)
+	s := NewRustScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Rust)
@@ -151,7 +151,7 @@ func TestRustScanner_Scan_CargoInstallParsing(t *testing.T) {
 		},
 	}
 
-	s := NewRustScanner(mock)
+	s := NewRustScanner("/home/user", mock)
 	result, err := s.Scan(context.Background())
 	require.NoError(t, err)
 	require.NotNil(t, result.Rust)
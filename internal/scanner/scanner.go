@@ -2,13 +2,20 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"reflect"
 	"runtime"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/util"
 )
 
 // ScanResult holds the output of a single scanner.
@@ -16,7 +23,9 @@ import (
 type ScanResult struct {
 	ScannerName string
 	Duration    time.Duration
-	Err         error
+	// Diagnostics are problems that did not stop the scanner; the populated
+	// section is partial but still used.
+	Diagnostics []domain.Diagnostic
 
 	// Each scanner populates one of these (the rest remain nil):
 	Homebrew           *domain.HomebrewSection
@@ -51,20 +60,12 @@ type ScanResult struct {
 	AWS                *domain.AWSSection
 	Kubernetes         *domain.KubernetesSection
 	Terraform          *domain.TerraformSection
-	Vercel             *domain.VercelSection
-	GCP                *domain.GCPSection
 	Azure              *domain.AzureSection
-	Flyio              *domain.FlyioSection
 	Firebase           *domain.FirebaseSection
 	CloudflareWrangler *domain.CloudflareSection
 	Locale             *domain.LocaleSection
 	LoginItems         *domain.LoginItemsSection
 	HostsFile          *domain.HostsFileSection
-	Raycast            *domain.RaycastSection
-	Alfred             *domain.AlfredSection
-	Karabiner          *domain.KarabinerSection
-	Rectangle          *domain.RectangleSection
-	BetterTouchTool    *domain.BetterTouchToolSection
 	OnePassword        *domain.OnePasswordSection
 	SSH                *domain.SSHSection
 	GPG                *domain.GPGSection
@@ -76,6 +77,55 @@ type ScanResult struct {
 	APITools           *domain.APIToolsSection
 	Databases          *domain.DatabasesSection
 	Registries         *domain.RegistriesSection
+
+	// Plugin is populated by external scanner plugins and stored under
+	// [plugins.<ScannerName>] in the snapshot.
+	Plugin *domain.PluginSection
+	// Probe is populated by declarative probe scanners and stored under
+	// [probes.<ScannerName>] in the snapshot.
+	Probe *domain.ProbeSection
+}
+
+// Warn records a problem that did not stop the scanner. Scanner and
+// Severity default to the result's scanner and SeverityWarning.
+func (r *ScanResult) Warn(d domain.Diagnostic) {
+	if d.Scanner == "" {
+		d.Scanner = r.ScannerName
+	}
+	if d.Severity == "" {
+		d.Severity = domain.SeverityWarning
+	}
+	r.Diagnostics = append(r.Diagnostics, d)
+}
+
+// WarnCommand records a failed command as a warning about item. fix is an
+// optional suggestion shown to the user.
+func (r *ScanResult) WarnCommand(item string, err error, fix string, name string, args ...string) {
+	r.Warn(domain.Diagnostic{
+		Item:    item,
+		Command: strings.Join(append([]string{name}, args...), " "),
+		Message: CommandError(err),
+		Fix:     fix,
+	})
+}
+
+// CommandError describes a command failure, including the first line the
+// command wrote to stderr when it is available.
+func CommandError(err error) string {
+	var stderr []byte
+	var exitErr *exec.ExitError
+	var replayErr *util.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		stderr = exitErr.Stderr
+	case errors.As(err, &replayErr):
+		stderr = []byte(replayErr.Stderr)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(stderr)), "\n")
+	if line == "" {
+		return err.Error()
+	}
+	return fmt.Sprintf("%v: %s", err, line)
 }
 
 // Scanner is the interface that all scanners must implement.
@@ -86,16 +136,64 @@ type Scanner interface {
 	Scan(ctx context.Context) (*ScanResult, error)
 }
 
+// Default scheduling parameters for Registry scans.
+const (
+	// DefaultConcurrency is the number of scanners run at the same time.
+	DefaultConcurrency = 8
+	// DefaultTimeout is the deadline applied to each scanner's context.
+	DefaultTimeout = 2 * time.Minute
+)
+
 // Registry manages all registered scanners.
 type Registry struct {
-	scanners map[string]Scanner
+	scanners    map[string]Scanner
+	concurrency int
+	timeout     time.Duration
+	timeouts    map[string]time.Duration
+	cache       *Cache
 }
 
 // NewRegistry creates a new empty Registry.
 func NewRegistry() *Registry {
 	return &Registry{
-		scanners: make(map[string]Scanner),
+		scanners:    make(map[string]Scanner),
+		concurrency: DefaultConcurrency,
+		timeout:     DefaultTimeout,
+		timeouts:    make(map[string]time.Duration),
+	}
+}
+
+// SetConcurrency sets how many scanners may run at the same time.
+// Values below 1 run scanners one after another.
+func (r *Registry) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
+	}
+	r.concurrency = n
+}
+
+// SetTimeout sets the default per-scanner deadline. Zero disables the deadline.
+func (r *Registry) SetTimeout(d time.Duration) {
+	r.timeout = d
+}
+
+// SetScannerTimeout overrides the deadline for a single scanner by name.
+func (r *Registry) SetScannerTimeout(name string, d time.Duration) {
+	r.timeouts[name] = d
+}
+
+// SetCache enables reuse of results from c for Cacheable scanners.
+// A nil cache runs every scanner.
+func (r *Registry) SetCache(c *Cache) {
+	r.cache = c
+}
+
+// timeoutFor returns the deadline that applies to the named scanner.
+func (r *Registry) timeoutFor(name string) time.Duration {
+	if d, ok := r.timeouts[name]; ok {
+		return d
 	}
+	return r.timeout
 }
 
 // Register adds a scanner. Returns error if name already registered.
@@ -130,25 +228,141 @@ func (r *Registry) List() []Scanner {
 }
 
 // ProgressEvent describes what happened during a scan step.
+// Scanners run concurrently, so events for different scanners interleave:
+// Index is the scanner's position in the sorted scan list, while Completed
+// counts how many scanners have finished so far (including this one when Done).
 type ProgressEvent struct {
-	Name     string
-	Index    int
-	Total    int
-	Done     bool
-	Duration time.Duration
-	Err      error
+	Name      string
+	Index     int
+	Total     int
+	Completed int
+	Done      bool
+	Duration  time.Duration
+	Err       error
+	// Cached is set when the result was reused from the scan cache.
+	Cached bool
+	// Warnings counts the diagnostics a successful scanner reported.
+	Warnings int
 }
 
 // ProgressFunc is called before (Done=false) and after (Done=true) each scanner.
+// Calls are serialized, so implementations need not be safe for concurrent use.
 type ProgressFunc func(event ProgressEvent)
 
-// ScanAll runs all registered scanners sequentially and merges results into a Snapshot.
+// ScanAll runs all registered scanners and merges results into a Snapshot.
+// Every problem is recorded in the snapshot's Meta.Diagnostics; the returned
+// errors are the domain.Diagnostic values of scanners that failed outright.
 func (r *Registry) ScanAll(ctx context.Context) (*domain.Snapshot, []error) {
 	return r.ScanAllWithProgress(ctx, nil)
 }
 
 // ScanAllWithProgress runs all scanners with an optional progress callback.
 func (r *Registry) ScanAllWithProgress(ctx context.Context, onProgress ProgressFunc) (*domain.Snapshot, []error) {
+	return r.scan(ctx, r.List(), nil, onProgress)
+}
+
+// ScanSelected runs the named scanners and merges their results into a Snapshot.
+// Unknown names are reported as errors without aborting the run.
+func (r *Registry) ScanSelected(ctx context.Context, names []string, onProgress ProgressFunc) (*domain.Snapshot, []error) {
+	var scanners []Scanner
+	var diags []domain.Diagnostic
+	for _, name := range names {
+		s, err := r.Get(name)
+		if err != nil {
+			diags = append(diags, domain.Diagnostic{
+				Severity: domain.SeverityError,
+				Scanner:  name,
+				Message:  "not registered",
+				Fix:      "Run 'machinist list scanners' to see available scanners",
+			})
+			continue
+		}
+		scanners = append(scanners, s)
+	}
+	return r.scan(ctx, scanners, diags, onProgress)
+}
+
+// ScannersFor returns the registered scanners that produce the sections
+// present in snap, in restore order and without repeats, for re-scanning
+// just what a manifest covers. Entries of named sections such as plugins
+// are produced by the scanner they are named after. missing lists the
+// sections, or "plugins.<name>" entries, that no registered scanner
+// produces, e.g. because the scanner is disabled.
+func (r *Registry) ScannersFor(snap *domain.Snapshot) (names, missing []string) {
+	seen := make(map[string]bool)
+	eachSection(snap, func(key, name string) {
+		if _, ok := r.scanners[name]; !ok {
+			missing = append(missing, key)
+			return
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	})
+	return names, missing
+}
+
+// SectionsFor returns the sections, or "plugins.<name>" entries, present in
+// snap that the named scanners produce, in restore order. It is the reverse
+// of ScannersFor, e.g. to tell which sections failed scanners left unchecked.
+func SectionsFor(snap *domain.Snapshot, names []string) []string {
+	var keys []string
+	eachSection(snap, func(key, name string) {
+		if slices.Contains(names, name) {
+			keys = append(keys, key)
+		}
+	})
+	return keys
+}
+
+// FailedScanners returns the names of the scanners whose errors, as
+// returned by ScanAll and ScanSelected, are attributed to a scanner.
+func FailedScanners(errs []error) []string {
+	var names []string
+	for _, err := range errs {
+		var d domain.Diagnostic
+		if errors.As(err, &d) && d.Scanner != "" && !slices.Contains(names, d.Scanner) {
+			names = append(names, d.Scanner)
+		}
+	}
+	return names
+}
+
+// eachSection calls f with the key of every section present in snap, or
+// "<section>.<name>" for the entries of named sections, and the name of the
+// scanner that produces it.
+func eachSection(snap *domain.Snapshot, f func(key, scanner string)) {
+	for _, s := range domain.Sections() {
+		v := s.Value(snap)
+		if v == nil {
+			continue
+		}
+		if m := reflect.ValueOf(v); m.Kind() == reflect.Map {
+			entries := make([]string, 0, m.Len())
+			for _, k := range m.MapKeys() {
+				entries = append(entries, k.String())
+			}
+			sort.Strings(entries)
+			for _, name := range entries {
+				f(s.Key+"."+name, name)
+			}
+			continue
+		}
+		f(s.Key, s.Scanner())
+	}
+}
+
+// scanOutcome is the result of running one scanner inside the worker pool.
+type scanOutcome struct {
+	result *ScanResult
+	err    error
+}
+
+// scan runs the given scanners through a bounded worker pool and applies their
+// results to a new Snapshot in list order, so the output does not depend on
+// which scanner finished first.
+func (r *Registry) scan(ctx context.Context, scanners []Scanner, diags []domain.Diagnostic, onProgress ProgressFunc) (*domain.Snapshot, []error) {
 	start := time.Now()
 
 	hostname, _ := os.Hostname()
@@ -157,30 +371,70 @@ func (r *Registry) ScanAllWithProgress(ctx context.Context, onProgress ProgressF
 
 	snap := domain.NewSnapshot(hostname, osVersion, arch, "")
 
-	scanners := r.List()
 	total := len(scanners)
-	var errs []error
+	outcomes := make([]scanOutcome, total)
 
-	for i, s := range scanners {
+	var (
+		mu        sync.Mutex
+		completed int
+		wg        sync.WaitGroup
+	)
+	report := func(e ProgressEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		if e.Done {
+			completed++
+		}
+		e.Completed = completed
 		if onProgress != nil {
-			onProgress(ProgressEvent{Name: s.Name(), Index: i, Total: total})
+			onProgress(e)
 		}
+	}
 
-		scanStart := time.Now()
-		result, err := s.Scan(ctx)
-		elapsed := time.Since(scanStart)
+	concurrency := r.concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	sem := make(chan struct{}, concurrency)
 
-		if err != nil {
-			errs = append(errs, fmt.Errorf("scanner %s: %w", s.Name(), err))
-			if onProgress != nil {
-				onProgress(ProgressEvent{Name: s.Name(), Index: i, Total: total, Done: true, Duration: elapsed, Err: err})
+	for i, s := range scanners {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, s Scanner) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			report(ProgressEvent{Name: s.Name(), Index: i, Total: total})
+
+			scanStart := time.Now()
+			result, cached, err := r.runCached(ctx, s)
+			elapsed := time.Since(scanStart)
+
+			outcomes[i] = scanOutcome{result: result, err: err}
+			e := ProgressEvent{Name: s.Name(), Index: i, Total: total, Done: true, Duration: elapsed, Err: err, Cached: cached}
+			if result != nil {
+				e.Warnings = len(result.Diagnostics)
 			}
+			report(e)
+		}(i, s)
+	}
+	wg.Wait()
+
+	snap.Meta.Diagnostics = diags
+	for i, o := range outcomes {
+		if o.err != nil {
+			snap.Meta.Diagnostics = append(snap.Meta.Diagnostics, failure(scanners[i].Name(), o.err))
 			continue
 		}
-		ApplyResult(snap, result)
+		if o.result != nil {
+			ApplyResult(snap, o.result)
+		}
+	}
 
-		if onProgress != nil {
-			onProgress(ProgressEvent{Name: s.Name(), Index: i, Total: total, Done: true, Duration: elapsed})
+	var errs []error
+	for _, d := range snap.Meta.Diagnostics {
+		if d.Severity == domain.SeverityError {
+			errs = append(errs, d)
 		}
 	}
 
@@ -188,190 +442,131 @@ func (r *Registry) ScanAllWithProgress(ctx context.Context, onProgress ProgressF
 	return snap, errs
 }
 
+// failure converts the error of a failed scanner into an error Diagnostic.
+// Scanners may return a domain.Diagnostic themselves to add an item or fix.
+func failure(name string, err error) domain.Diagnostic {
+	var d domain.Diagnostic
+	if !errors.As(err, &d) {
+		d = domain.Diagnostic{Message: err.Error()}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			d.Message = CommandError(err)
+		}
+	}
+	d.Severity = domain.SeverityError
+	d.Scanner = name
+	return d
+}
+
+// runCached returns the scanner's cached result when its inputs are
+// unchanged, and otherwise runs it and stores a successful result.
+func (r *Registry) runCached(ctx context.Context, s Scanner) (*ScanResult, bool, error) {
+	if r.cache == nil {
+		result, err := r.runScanner(ctx, s)
+		return result, false, err
+	}
+	key, ok := r.cache.Key(ctx, s)
+	if ok {
+		if result, hit := r.cache.Get(s.Name(), key); hit {
+			return result, true, nil
+		}
+	}
+	result, err := r.runScanner(ctx, s)
+	// Results with diagnostics are not cached: the problem may be transient.
+	if err == nil && ok && result != nil && len(result.Diagnostics) == 0 {
+		// A failed write only costs a rescan next time.
+		_ = r.cache.Put(s.Name(), key, result)
+	}
+	return result, false, err
+}
+
+// runScanner runs a single scanner under its configured deadline. A scanner
+// that ignores its context is abandoned once the deadline passes, and a
+// panicking scanner is reported as an error instead of crashing the run.
+func (r *Registry) runScanner(ctx context.Context, s Scanner) (*ScanResult, error) {
+	start := time.Now()
+	timeout := r.timeoutFor(s.Name())
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan scanOutcome, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- scanOutcome{err: fmt.Errorf("panic: %v", p)}
+			}
+		}()
+		result, err := s.Scan(ctx)
+		done <- scanOutcome{result: result, err: err}
+	}()
+
+	select {
+	case o := <-done:
+		if o.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, timedOut(ctx, start, timeout)
+		}
+		return o.result, o.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, timedOut(ctx, start, timeout)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// timedOut reports a scanner started at start whose ctx ran past its
+// deadline: the scanner's own timeout, or an earlier one of the context the
+// run was given, which the scanner timeout settings do not lift.
+func timedOut(ctx context.Context, start time.Time, timeout time.Duration) domain.Diagnostic {
+	deadline, _ := ctx.Deadline()
+	after := deadline.Sub(start)
+	if timeout > 0 && after >= timeout {
+		return domain.Diagnostic{
+			Message: fmt.Sprintf("timed out after %s", timeout),
+			Fix:     "Raise --scanner-timeout, or scanner_timeout or [scanner_timeouts] in config.toml",
+		}
+	}
+	return domain.Diagnostic{
+		Message: fmt.Sprintf("stopped after %s, when the run's deadline passed", after.Round(time.Millisecond)),
+	}
+}
+
 // ScanOne runs a single scanner by name and returns its result.
 func (r *Registry) ScanOne(ctx context.Context, name string) (*ScanResult, error) {
 	s, err := r.Get(name)
 	if err != nil {
 		return nil, err
 	}
-	result, err := s.Scan(ctx)
+	result, _, err := r.runCached(ctx, s)
 	if err != nil {
-		return nil, fmt.Errorf("scanner %s: %w", name, err)
+		return nil, failure(name, err)
 	}
 	return result, nil
 }
 
 // ApplyResult maps a ScanResult's populated fields onto the Snapshot.
+// Each non-nil section field is stored through the domain section registry,
+// so a new section only needs a ScanResult field and a registry entry.
+// The result's diagnostics are appended to the snapshot's.
 func ApplyResult(snap *domain.Snapshot, result *ScanResult) {
-	if result.Homebrew != nil {
-		snap.Homebrew = result.Homebrew
-	}
-	if result.Shell != nil {
-		snap.Shell = result.Shell
-	}
-	if result.Node != nil {
-		snap.Node = result.Node
-	}
-	if result.Python != nil {
-		snap.Python = result.Python
-	}
-	if result.Rust != nil {
-		snap.Rust = result.Rust
-	}
-	if result.Git != nil {
-		snap.Git = result.Git
-	}
-	if result.GitRepos != nil {
-		snap.GitRepos = result.GitRepos
-	}
-	if result.VSCode != nil {
-		snap.VSCode = result.VSCode
-	}
-	if result.Cursor != nil {
-		snap.Cursor = result.Cursor
-	}
-	if result.Docker != nil {
-		snap.Docker = result.Docker
-	}
-	if result.MacOSDefaults != nil {
-		snap.MacOSDefaults = result.MacOSDefaults
-	}
-	if result.Folders != nil {
-		snap.Folders = result.Folders
-	}
-	if result.Fonts != nil {
-		snap.Fonts = result.Fonts
-	}
-	if result.Crontab != nil {
-		snap.Crontab = result.Crontab
-	}
-	if result.LaunchAgents != nil {
-		snap.LaunchAgents = result.LaunchAgents
-	}
-	if result.Apps != nil {
-		snap.Apps = result.Apps
-	}
-	if result.Java != nil {
-		snap.Java = result.Java
-	}
-	if result.Flutter != nil {
-		snap.Flutter = result.Flutter
-	}
-	if result.GoLang != nil {
-		snap.Go = result.GoLang
-	}
-	if result.Asdf != nil {
-		snap.Asdf = result.Asdf
-	}
-	if result.Deno != nil {
-		snap.Deno = result.Deno
-	}
-	if result.Bun != nil {
-		snap.Bun = result.Bun
-	}
-	if result.Ruby != nil {
-		snap.Ruby = result.Ruby
-	}
-	if result.Terminal != nil {
-		snap.Terminal = result.Terminal
-	}
-	if result.Tmux != nil {
-		snap.Tmux = result.Tmux
-	}
-	if result.GitHubCLI != nil {
-		snap.GitHubCLI = result.GitHubCLI
-	}
-	if result.Neovim != nil {
-		snap.Neovim = result.Neovim
-	}
-	if result.JetBrains != nil {
-		snap.JetBrains = result.JetBrains
-	}
-	if result.Xcode != nil {
-		snap.Xcode = result.Xcode
-	}
-	if result.AWS != nil {
-		snap.AWS = result.AWS
-	}
-	if result.Kubernetes != nil {
-		snap.Kubernetes = result.Kubernetes
-	}
-	if result.Terraform != nil {
-		snap.Terraform = result.Terraform
-	}
-	if result.Vercel != nil {
-		snap.Vercel = result.Vercel
-	}
-	if result.GCP != nil {
-		snap.GCP = result.GCP
-	}
-	if result.Azure != nil {
-		snap.Azure = result.Azure
-	}
-	if result.Flyio != nil {
-		snap.Flyio = result.Flyio
-	}
-	if result.Firebase != nil {
-		snap.Firebase = result.Firebase
-	}
-	if result.CloudflareWrangler != nil {
-		snap.CloudflareWrangler = result.CloudflareWrangler
-	}
-	if result.Locale != nil {
-		snap.Locale = result.Locale
-	}
-	if result.LoginItems != nil {
-		snap.LoginItems = result.LoginItems
-	}
-	if result.HostsFile != nil {
-		snap.HostsFile = result.HostsFile
-	}
-	if result.Raycast != nil {
-		snap.Raycast = result.Raycast
-	}
-	if result.Alfred != nil {
-		snap.Alfred = result.Alfred
-	}
-	if result.Karabiner != nil {
-		snap.Karabiner = result.Karabiner
-	}
-	if result.Rectangle != nil {
-		snap.Rectangle = result.Rectangle
-	}
-	if result.BetterTouchTool != nil {
-		snap.BetterTouchTool = result.BetterTouchTool
-	}
-	if result.OnePassword != nil {
-		snap.OnePassword = result.OnePassword
-	}
-	if result.SSH != nil {
-		snap.SSH = result.SSH
-	}
-	if result.GPG != nil {
-		snap.GPG = result.GPG
-	}
-	if result.XDGConfig != nil {
-		snap.XDGConfig = result.XDGConfig
-	}
-	if result.EnvFiles != nil {
-		snap.EnvFiles = result.EnvFiles
-	}
-	if result.Network != nil {
-		snap.Network = result.Network
-	}
-	if result.Browser != nil {
-		snap.Browser = result.Browser
-	}
-	if result.AITools != nil {
-		snap.AITools = result.AITools
-	}
-	if result.APITools != nil {
-		snap.APITools = result.APITools
-	}
-	if result.Databases != nil {
-		snap.Databases = result.Databases
+	for _, d := range result.Diagnostics {
+		if d.Scanner == "" {
+			d.Scanner = result.ScannerName
+		}
+		if d.Severity == "" {
+			d.Severity = domain.SeverityWarning
+		}
+		snap.Meta.Diagnostics = append(snap.Meta.Diagnostics, d)
 	}
-	if result.Registries != nil {
-		snap.Registries = result.Registries
+	v := reflect.ValueOf(result).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() != reflect.Ptr || f.IsNil() {
+			continue
+		}
+		domain.AssignSection(snap, result.ScannerName, f.Interface())
 	}
 }
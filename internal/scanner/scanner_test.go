@@ -3,10 +3,12 @@ package scanner
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -342,3 +344,286 @@ func TestRegistry_ScanAll_AppliesMoreSections(t *testing.T) {
 	assert.Equal(t, "stable", snap.Rust.DefaultToolchain)
 	assert.Equal(t, []string{"rustfmt", "clippy"}, snap.Rust.Components)
 }
+
+// slowScanner blocks for delay (or until its context is cancelled, if
+// honorCtx is set) before returning its result.
+type slowScanner struct {
+	mockScanner
+	delay    time.Duration
+	honorCtx bool
+}
+
+func (s *slowScanner) Scan(ctx context.Context) (*ScanResult, error) {
+	if s.honorCtx {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	} else {
+		time.Sleep(s.delay)
+	}
+	return s.mockScanner.Scan(ctx)
+}
+
+func TestRegistry_ScanAll_RunsConcurrently(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetConcurrency(4)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, reg.Register(&slowScanner{
+			mockScanner: mockScanner{name: name, result: &ScanResult{ScannerName: name}},
+			delay:       200 * time.Millisecond,
+		}))
+	}
+
+	start := time.Now()
+	_, errs := reg.ScanAll(context.Background())
+	elapsed := time.Since(start)
+
+	assert.Empty(t, errs)
+	assert.Less(t, elapsed, 600*time.Millisecond, "four 200ms scanners with concurrency 4 should not run back to back")
+}
+
+func TestRegistry_ScanAll_TimeoutBecomesError(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetTimeout(50 * time.Millisecond)
+
+	shellSection := &domain.ShellSection{DefaultShell: "/bin/zsh"}
+	require.NoError(t, reg.Register(&mockScanner{
+		name:   "shell",
+		result: &ScanResult{ScannerName: "shell", Shell: shellSection},
+	}))
+	require.NoError(t, reg.Register(&slowScanner{
+		mockScanner: mockScanner{name: "cooperative", result: &ScanResult{ScannerName: "cooperative"}},
+		delay:       time.Second,
+		honorCtx:    true,
+	}))
+	require.NoError(t, reg.Register(&slowScanner{
+		mockScanner: mockScanner{name: "stubborn", result: &ScanResult{ScannerName: "stubborn"}},
+		delay:       time.Second,
+	}))
+
+	start := time.Now()
+	snap, errs := reg.ScanAll(context.Background())
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "timed out scanners must not hold up the run")
+
+	require.NotNil(t, snap.Shell, "results from healthy scanners are still applied")
+	require.Len(t, errs, 2)
+	for _, err := range errs {
+		assert.Contains(t, err.Error(), "timed out")
+	}
+}
+
+func TestRegistry_SetScannerTimeout(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetTimeout(10 * time.Millisecond)
+	reg.SetScannerTimeout("slow", time.Second)
+
+	require.NoError(t, reg.Register(&slowScanner{
+		mockScanner: mockScanner{name: "slow", result: &ScanResult{ScannerName: "slow"}},
+		delay:       50 * time.Millisecond,
+		honorCtx:    true,
+	}))
+
+	_, errs := reg.ScanAll(context.Background())
+	assert.Empty(t, errs, "per-scanner timeout should override the default")
+}
+
+func TestRegistry_ScanAll_RunDeadline(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetTimeout(time.Minute)
+	require.NoError(t, reg.Register(&slowScanner{
+		mockScanner: mockScanner{name: "slow", result: &ScanResult{ScannerName: "slow"}},
+		delay:       time.Second,
+		honorCtx:    true,
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, errs := reg.ScanAll(ctx)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "run's deadline", "the scanner's own timeout did not expire")
+	assert.NotContains(t, errs[0].Error(), "1m0s")
+}
+
+func TestRegistry_ScanAll_RecoversPanic(t *testing.T) {
+	reg := NewRegistry()
+	require.NoError(t, reg.Register(&panicScanner{mockScanner{name: "boom"}}))
+
+	snap, errs := reg.ScanAll(context.Background())
+	require.NotNil(t, snap)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "panic")
+}
+
+type panicScanner struct{ mockScanner }
+
+func (p *panicScanner) Scan(ctx context.Context) (*ScanResult, error) { panic("kaboom") }
+
+func TestRegistry_ScanAllWithProgress_OutOfOrder(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetConcurrency(3)
+	delays := map[string]time.Duration{"a": 150 * time.Millisecond, "b": 75 * time.Millisecond, "c": 0}
+	for name, d := range delays {
+		require.NoError(t, reg.Register(&slowScanner{
+			mockScanner: mockScanner{name: name, result: &ScanResult{ScannerName: name}},
+			delay:       d,
+		}))
+	}
+
+	var done []ProgressEvent
+	var started int
+	_, errs := reg.ScanAllWithProgress(context.Background(), func(e ProgressEvent) {
+		if !e.Done {
+			started++
+			return
+		}
+		done = append(done, e)
+	})
+	require.Empty(t, errs)
+	assert.Equal(t, 3, started)
+	require.Len(t, done, 3)
+
+	// Completion order follows scanner speed, not name order.
+	assert.Equal(t, "c", done[0].Name)
+	assert.Equal(t, 2, done[0].Index)
+	assert.Equal(t, "a", done[2].Name)
+	for i, e := range done {
+		assert.Equal(t, i+1, e.Completed, "Completed counts finished scanners")
+		assert.Equal(t, 3, e.Total)
+	}
+}
+
+func TestRegistry_ScanSelected(t *testing.T) {
+	reg := NewRegistry()
+	require.NoError(t, reg.Register(&mockScanner{
+		name:   "shell",
+		result: &ScanResult{ScannerName: "shell", Shell: &domain.ShellSection{DefaultShell: "/bin/zsh"}},
+	}))
+	require.NoError(t, reg.Register(&mockScanner{
+		name:   "brew",
+		result: &ScanResult{ScannerName: "brew", Homebrew: &domain.HomebrewSection{}},
+	}))
+
+	snap, errs := reg.ScanSelected(context.Background(), []string{"shell", "missing"}, nil)
+	require.NotNil(t, snap.Shell)
+	assert.Nil(t, snap.Homebrew, "unselected scanners must not run")
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "missing")
+}
+
+func TestRegistry_ScannersFor(t *testing.T) {
+	reg := NewRegistry()
+	for _, name := range []string{"homebrew", "git-config", "git-repos", "scheduled", "corp"} {
+		require.NoError(t, reg.Register(&mockScanner{name: name}))
+	}
+	snap := &domain.Snapshot{
+		Homebrew:     &domain.HomebrewSection{},
+		Git:          &domain.GitSection{},
+		GitRepos:     &domain.GitReposSection{},
+		Crontab:      &domain.CrontabSection{},
+		LaunchAgents: &domain.LaunchAgentsSection{},
+		Tmux:         &domain.TmuxSection{},
+		Plugins:      map[string]*domain.PluginSection{"corp": {}, "vpn": {}},
+	}
+
+	names, missing := reg.ScannersFor(snap)
+	assert.ElementsMatch(t, []string{"homebrew", "git-config", "git-repos", "scheduled", "corp"}, names)
+	assert.ElementsMatch(t, []string{"tmux", "plugins.vpn"}, missing)
+}
+
+func TestSectionsFor(t *testing.T) {
+	snap := &domain.Snapshot{
+		Homebrew:     &domain.HomebrewSection{},
+		Crontab:      &domain.CrontabSection{},
+		LaunchAgents: &domain.LaunchAgentsSection{},
+		Tmux:         &domain.TmuxSection{},
+		Plugins:      map[string]*domain.PluginSection{"corp": {}, "vpn": {}},
+	}
+	assert.Equal(t, []string{"homebrew", "plugins.corp", "crontab", "launchagents"},
+		SectionsFor(snap, []string{"homebrew", "scheduled", "corp", "docker"}))
+	assert.Empty(t, SectionsFor(snap, nil))
+}
+
+func TestFailedScanners(t *testing.T) {
+	reg := NewRegistry()
+	require.NoError(t, reg.Register(&mockScanner{name: "broken", err: fmt.Errorf("boom")}))
+	require.NoError(t, reg.Register(&mockScanner{name: "fine", result: &ScanResult{ScannerName: "fine"}}))
+
+	_, errs := reg.ScanSelected(context.Background(), []string{"broken", "fine", "unknown"}, nil)
+	assert.Equal(t, []string{"unknown", "broken"}, FailedScanners(errs))
+}
+
+func TestRegistry_ScanAll_AppliesPlugins(t *testing.T) {
+	reg := NewRegistry()
+	require.NoError(t, reg.Register(&mockScanner{
+		name:   "corp",
+		result: &ScanResult{ScannerName: "corp", Plugin: &domain.PluginSection{Restore: []string{"corp login"}}},
+	}))
+	require.NoError(t, reg.Register(&mockScanner{
+		name:   "proxy",
+		result: &ScanResult{ScannerName: "proxy", Plugin: &domain.PluginSection{Packages: []domain.Package{{Name: "proxy-certs"}}}},
+	}))
+
+	snap, errs := reg.ScanAll(context.Background())
+	require.Empty(t, errs)
+	require.Len(t, snap.Plugins, 2)
+	assert.Equal(t, []string{"corp login"}, snap.Plugins["corp"].Restore)
+	assert.Equal(t, "proxy-certs", snap.Plugins["proxy"].Packages[0].Name)
+}
+
+// TestScanResult_SectionFieldsRegistered ensures ApplyResult can store every
+// section a scanner may return.
+func TestScanResult_SectionFieldsRegistered(t *testing.T) {
+	rt := reflect.TypeOf(ScanResult{})
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.Type.Kind() != reflect.Ptr {
+			continue
+		}
+		v := reflect.New(f.Type.Elem()).Interface()
+		assert.True(t, domain.AssignSection(&domain.Snapshot{}, "test", v),
+			"ScanResult.%s (%s) is not a registered domain section", f.Name, f.Type)
+	}
+}
+
+func TestRegistry_ScanAll_RecordsDiagnostics(t *testing.T) {
+	reg := NewRegistry()
+	partial := &ScanResult{ScannerName: "shell", Shell: &domain.ShellSection{DefaultShell: "/bin/zsh"}}
+	partial.Warn(domain.Diagnostic{Item: ".zshrc", Message: "unreadable"})
+	require.NoError(t, reg.Register(&mockScanner{name: "shell", result: partial}))
+	require.NoError(t, reg.Register(&mockScanner{name: "brew", err: fmt.Errorf("brew not installed")}))
+
+	var warnings int
+	snap, errs := reg.ScanAllWithProgress(context.Background(), func(e ProgressEvent) {
+		warnings += e.Warnings
+	})
+	require.NotNil(t, snap.Shell, "partial results are still applied")
+	assert.Equal(t, 1, warnings)
+
+	require.Len(t, snap.Meta.Diagnostics, 2)
+	assert.Equal(t, domain.Diagnostic{
+		Severity: domain.SeverityError, Scanner: "brew", Message: "brew not installed",
+	}, snap.Meta.Diagnostics[0])
+	assert.Equal(t, domain.Diagnostic{
+		Severity: domain.SeverityWarning, Scanner: "shell", Item: ".zshrc", Message: "unreadable",
+	}, snap.Meta.Diagnostics[1])
+
+	require.Len(t, errs, 1, "only failed scanners are returned as errors")
+	assert.Equal(t, snap.Meta.Diagnostics[0], errs[0])
+}
+
+func TestScanResult_WarnCommand(t *testing.T) {
+	result := &ScanResult{ScannerName: "homebrew"}
+	result.WarnCommand("taps", &util.ExitError{Code: 1, Stderr: "Error: no network\nmore\n"}, "retry", "brew", "tap")
+
+	require.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, domain.Diagnostic{
+		Severity: domain.SeverityWarning,
+		Scanner:  "homebrew",
+		Item:     "taps",
+		Command:  "brew tap",
+		Message:  "exit status 1: Error: no network",
+		Fix:      "retry",
+	}, result.Diagnostics[0])
+}
@@ -2,6 +2,7 @@ package system
 
 import (
 	"context"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -24,6 +25,21 @@ func (s *AppsScanner) Name() string        { return "apps" }
 func (s *AppsScanner) Description() string { return "Scans Mac App Store apps via mas" }
 func (s *AppsScanner) Category() string    { return "system" }
 
+// applicationsDir is where App Store apps are installed.
+var applicationsDir = "/Applications"
+
+// CacheInputs watches /Applications, which changes when App Store apps
+// are installed or removed, and the Info.plist of every app, which an
+// update replaces, keyed on the mas version.
+func (s *AppsScanner) CacheInputs() scanner.Inputs {
+	in := scanner.Inputs{
+		Paths:    []string{applicationsDir},
+		Commands: [][]string{{"mas", "version"}},
+	}
+	in.Glob(filepath.Join(applicationsDir, "*.app", "Contents", "Info.plist"))
+	return in
+}
+
 // Scan checks for mas CLI and lists installed App Store apps.
 func (s *AppsScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
 	if !s.cmd.IsInstalled(ctx, "mas") {
@@ -2,8 +2,11 @@ package system
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/moinsen-dev/machinist/internal/scanner"
 	"github.com/moinsen-dev/machinist/internal/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -19,6 +22,26 @@ func TestAppsScanner_Category(t *testing.T) {
 	assert.Equal(t, "system", s.Category())
 }
 
+func TestAppsScanner_CacheInputs_AppUpdate(t *testing.T) {
+	dir := t.TempDir()
+	old := applicationsDir
+	applicationsDir = dir
+	t.Cleanup(func() { applicationsDir = old })
+	plist := filepath.Join(dir, "Keynote.app", "Contents", "Info.plist")
+	require.NoError(t, os.MkdirAll(filepath.Dir(plist), 0o755))
+	require.NoError(t, os.WriteFile(plist, []byte("<string>14.0</string>"), 0o644))
+
+	s := NewAppsScanner(&util.MockCommandRunner{})
+	cache := scanner.NewCache(t.TempDir(), &util.MockCommandRunner{}, "test")
+	before, ok := cache.Key(context.Background(), s)
+	require.True(t, ok)
+
+	require.NoError(t, os.WriteFile(plist, []byte("<string>14.1.2</string>"), 0o644))
+	after, ok := cache.Key(context.Background(), s)
+	require.True(t, ok)
+	assert.NotEqual(t, before, after, "an app update must invalidate the cached scan")
+}
+
 func TestAppsScanner_Scan_MasApps(t *testing.T) {
 	cmd := &util.MockCommandRunner{
 		Responses: map[string]util.MockResponse{
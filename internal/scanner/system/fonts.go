@@ -35,6 +35,19 @@ func (f *FontsScanner) Name() string        { return "fonts" }
 func (f *FontsScanner) Description() string  { return "Scans user-installed fonts and Homebrew font casks" }
 func (f *FontsScanner) Category() string     { return "system" }
 
+// CacheInputs watches ~/Library/Fonts and the Homebrew Caskroom, keyed on
+// the brew version.
+func (f *FontsScanner) CacheInputs() scanner.Inputs {
+	return scanner.Inputs{
+		Paths: []string{
+			filepath.Join(f.homeDir, "Library", "Fonts"),
+			"/opt/homebrew/Caskroom",
+			"/usr/local/Caskroom",
+		},
+		Commands: [][]string{{"brew", "--version"}},
+	}
+}
+
 // Scan walks ~/Library/Fonts for user-installed font files and queries Homebrew
 // for font-* casks. It returns a ScanResult with the Fonts field populated.
 func (f *FontsScanner) Scan(ctx context.Context) (*scanner.ScanResult, error) {
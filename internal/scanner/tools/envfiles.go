@@ -18,8 +18,9 @@ var envFileNames = map[string]bool{
 	".env.production":  true,
 }
 
-// workspaceDirs is the list of common workspace directories to search.
-var workspaceDirs = []string{
+// defaultWorkspaceDirs is the list of common workspace directories to search,
+// relative to the home directory.
+var defaultWorkspaceDirs = []string{
 	"Code",
 	"Projects",
 	"Developer",
@@ -28,12 +29,25 @@ var workspaceDirs = []string{
 
 // EnvFilesScanner scans workspace directories for .env files.
 type EnvFilesScanner struct {
-	homeDir string
+	homeDir       string
+	workspaceDirs []string
+	excludes      []string
 }
 
 // NewEnvFilesScanner creates a new EnvFilesScanner with the given home directory.
 func NewEnvFilesScanner(homeDir string) *EnvFilesScanner {
-	return &EnvFilesScanner{homeDir: homeDir}
+	return &EnvFilesScanner{homeDir: homeDir, workspaceDirs: defaultWorkspaceDirs}
+}
+
+// SetWorkspaceDirs replaces the directories searched for .env files.
+// Relative entries are resolved against the home directory.
+func (s *EnvFilesScanner) SetWorkspaceDirs(dirs []string) {
+	s.workspaceDirs = dirs
+}
+
+// SetExcludes sets glob patterns for directories that are not walked.
+func (s *EnvFilesScanner) SetExcludes(patterns []string) {
+	s.excludes = patterns
 }
 
 func (s *EnvFilesScanner) Name() string        { return "env-files" }
@@ -46,8 +60,11 @@ func (s *EnvFilesScanner) Scan(ctx context.Context) (*scanner.ScanResult, error)
 
 	var files []domain.EnvFile
 
-	for _, dir := range workspaceDirs {
-		wsDir := filepath.Join(s.homeDir, dir)
+	for _, dir := range s.workspaceDirs {
+		wsDir := dir
+		if !filepath.IsAbs(wsDir) {
+			wsDir = filepath.Join(s.homeDir, dir)
+		}
 		if !util.DirExists(wsDir) {
 			continue
 		}
@@ -88,6 +105,9 @@ func (s *EnvFilesScanner) walkForEnvFiles(dir string, currentDepth, maxDepth int
 			if strings.HasPrefix(name, ".") {
 				continue
 			}
+			if util.MatchesAny(filepath.Join(dir, name), s.excludes) {
+				continue
+			}
 			if currentDepth < maxDepth {
 				subFiles := s.walkForEnvFiles(filepath.Join(dir, name), currentDepth+1, maxDepth)
 				files = append(files, subFiles...)
@@ -98,6 +118,9 @@ func (s *EnvFilesScanner) walkForEnvFiles(dir string, currentDepth, maxDepth int
 		// Check if this is an env file we care about
 		if envFileNames[name] {
 			fullPath := filepath.Join(dir, name)
+			if util.MatchesAny(fullPath, s.excludes) {
+				continue
+			}
 			relPath := strings.TrimPrefix(fullPath, s.homeDir+"/")
 			files = append(files, domain.EnvFile{
 				Source:     relPath,
@@ -138,3 +138,23 @@ func TestEnvFilesScanner_Scan_EnvProduction(t *testing.T) {
 	assert.Contains(t, result.EnvFiles.Files[0].Source, ".env.production")
 	assert.False(t, filepath.IsAbs(result.EnvFiles.Files[0].Source))
 }
+
+func TestEnvFilesScanner_Scan_CustomWorkspaceDirsAndExcludes(t *testing.T) {
+	homeDir := t.TempDir()
+	srcDir := filepath.Join(homeDir, "src")
+	for _, project := range []string{"app", "archive"} {
+		dir := filepath.Join(srcDir, project)
+		require.NoError(t, os.MkdirAll(dir, 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, ".env"), []byte("KEY=val"), 0o644))
+	}
+
+	s := NewEnvFilesScanner(homeDir)
+	s.SetWorkspaceDirs([]string{srcDir})
+	s.SetExcludes([]string{"archive"})
+	result, err := s.Scan(context.Background())
+
+	require.NoError(t, err)
+	require.NotNil(t, result.EnvFiles)
+	require.Len(t, result.EnvFiles.Files, 1)
+	assert.Equal(t, filepath.Join("src", "app", ".env"), result.EnvFiles.Files[0].Source)
+}
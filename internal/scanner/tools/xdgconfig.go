@@ -26,12 +26,18 @@ var knownXDGTools = map[string]bool{
 
 // XDGConfigScanner scans the ~/.config/ directory for known tool configurations.
 type XDGConfigScanner struct {
-	homeDir string
+	homeDir    string
+	extraTools map[string]bool
 }
 
 // NewXDGConfigScanner creates a new XDGConfigScanner with the given home directory.
 func NewXDGConfigScanner(homeDir string) *XDGConfigScanner {
-	return &XDGConfigScanner{homeDir: homeDir}
+	return &XDGConfigScanner{homeDir: homeDir, extraTools: make(map[string]bool)}
+}
+
+// AddKnownTool registers an additional tool directory name to auto-detect.
+func (s *XDGConfigScanner) AddKnownTool(name string) {
+	s.extraTools[name] = true
 }
 
 func (s *XDGConfigScanner) Name() string        { return "xdg-config" }
@@ -58,7 +64,7 @@ func (s *XDGConfigScanner) Scan(ctx context.Context) (*scanner.ScanResult, error
 			continue
 		}
 		name := entry.Name()
-		if knownXDGTools[name] {
+		if knownXDGTools[name] || s.extraTools[name] {
 			autoDetected = append(autoDetected, name)
 		}
 	}
@@ -87,3 +87,20 @@ func TestXDGConfigScanner_Scan_AllKnownTools(t *testing.T) {
 	require.NotNil(t, result.XDGConfig)
 	assert.Len(t, result.XDGConfig.AutoDetected, 10)
 }
+
+func TestXDGConfigScanner_Scan_ExtraTools(t *testing.T) {
+	homeDir := t.TempDir()
+	configDir := filepath.Join(homeDir, ".config")
+	require.NoError(t, os.MkdirAll(filepath.Join(configDir, "zellij"), 0o755))
+
+	s := NewXDGConfigScanner(homeDir)
+	result, err := s.Scan(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, result.XDGConfig, "zellij is not a built-in tool")
+
+	s.AddKnownTool("zellij")
+	result, err = s.Scan(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, result.XDGConfig)
+	assert.Equal(t, []string{"zellij"}, result.XDGConfig.AutoDetected)
+}
@@ -0,0 +1,115 @@
+// Package schema describes the manifest format for tools: a JSON Schema
+// generated from the domain structs, their toml tags and doc comments, so
+// editors such as Taplo can complete and check setup.toml files and AI
+// clients know exactly what compose_manifest accepts.
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+)
+
+// Draft is the JSON Schema dialect of the generated schema. Draft-07 is the
+// newest one Taplo fully supports.
+const Draft = "http://json-schema.org/draft-07/schema#"
+
+// JSONSchema returns the manifest JSON Schema, indented.
+func JSONSchema() ([]byte, error) {
+	data, err := json.MarshalIndent(Generate(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Generate builds the manifest JSON Schema as a JSON-ready value. Every
+// struct becomes an entry in "definitions"; unknown keys are rejected, in
+// line with machinist validate.
+func Generate() map[string]any {
+	g := &generator{defs: make(map[string]any)}
+	root := g.object(reflect.TypeOf(domain.Snapshot{}))
+	root["$schema"] = Draft
+	root["title"] = "machinist manifest"
+	root["definitions"] = g.defs
+	return root
+}
+
+type generator struct {
+	defs map[string]any
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaFor returns the schema of a value of type t.
+func (g *generator) schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]any{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]any{"type": "number"}
+	case t.Kind() == reflect.Slice:
+		return map[string]any{"type": "array", "items": g.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // reserve the name for recursive types
+			g.defs[t.Name()] = g.object(t)
+		}
+		return map[string]any{"$ref": "#/definitions/" + t.Name()}
+	}
+	return map[string]any{}
+}
+
+// object returns the schema of a struct's TOML table.
+func (g *generator) object(t reflect.Type) map[string]any {
+	props := make(map[string]any)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+		prop := g.schemaFor(f.Type)
+		if enum := f.Tag.Get("enum"); enum != "" {
+			// On a list or map the allowed values apply to its elements.
+			target := prop
+			if items, ok := prop["items"].(map[string]any); ok {
+				target = items
+			} else if values, ok := prop["additionalProperties"].(map[string]any); ok {
+				target = values
+			}
+			target["enum"] = strings.Split(enum, ",")
+		}
+		if doc := domain.FieldDoc(t.Name(), f.Name); doc != "" {
+			if _, ok := prop["$ref"]; ok {
+				// Draft-07 ignores keywords next to $ref.
+				prop = map[string]any{"allOf": []any{prop}}
+			}
+			prop["description"] = doc
+		}
+		props[name] = prop
+	}
+	obj := map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"additionalProperties": false,
+	}
+	if doc := domain.TypeDoc(t.Name()); doc != "" {
+		obj["description"] = doc
+	}
+	return obj
+}
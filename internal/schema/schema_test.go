@@ -0,0 +1,135 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/profiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func definition(t *testing.T, s map[string]any, name string) map[string]any {
+	t.Helper()
+	def, ok := s["definitions"].(map[string]any)[name].(map[string]any)
+	require.True(t, ok, "missing definition %s", name)
+	return def
+}
+
+func property(t *testing.T, obj map[string]any, name string) map[string]any {
+	t.Helper()
+	prop, ok := obj["properties"].(map[string]any)[name].(map[string]any)
+	require.True(t, ok, "missing property %s", name)
+	return prop
+}
+
+func TestGenerate_CoversEverySection(t *testing.T) {
+	s := Generate()
+	assert.Equal(t, Draft, s["$schema"])
+	assert.Equal(t, false, s["additionalProperties"])
+	for _, sec := range domain.Sections() {
+		property(t, s, sec.Key)
+	}
+	assert.Equal(t, map[string]any{"$ref": "#/definitions/Meta"}, property(t, s, "meta"))
+}
+
+func TestGenerate_Descriptions(t *testing.T) {
+	s := Generate()
+	brew := definition(t, s, "HomebrewSection")
+	assert.Equal(t, "HomebrewSection captures Homebrew taps, formulae, casks, and services.", brew["description"])
+
+	meta := definition(t, s, "Meta")
+	assert.Contains(t, property(t, meta, "schema_version")["description"], "manifest layout version")
+	assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, property(t, meta, "created_at"))
+
+	diags := property(t, meta, "diagnostics")
+	assert.Contains(t, diags["description"], "problems scanners ran into")
+	assert.Equal(t, map[string]any{"$ref": "#/definitions/Diagnostic"}, diags["items"])
+}
+
+func TestGenerate_Enums(t *testing.T) {
+	s := Generate()
+	assert.Equal(t, []string{"asdf", "mise"}, property(t, definition(t, s, "AsdfSection"), "manager")["enum"])
+	assert.Equal(t, []string{"bottom", "left", "right"}, property(t, definition(t, s, "DockConfig"), "orientation")["enum"])
+	assert.Contains(t, property(t, definition(t, s, "ServiceEntry"), "status")["enum"], "started")
+	assert.Equal(t, []string{"error", "warning", "info"}, property(t, definition(t, s, "Diagnostic"), "severity")["enum"])
+
+	merge := property(t, definition(t, s, "Meta"), "merge")
+	assert.Equal(t, map[string]any{"type": "string", "enum": []string{"append", "replace", "remove"}}, merge["additionalProperties"])
+}
+
+func TestGenerate_NamedSections(t *testing.T) {
+	s := Generate()
+	plugins := property(t, s, "plugins")
+	assert.Equal(t, "object", plugins["type"])
+	assert.Equal(t, map[string]any{"$ref": "#/definitions/PluginSection"}, plugins["additionalProperties"])
+}
+
+// TestGenerate_AcceptsProfiles walks every built-in profile and checks that
+// each key it writes is described by the schema with a matching type.
+func TestGenerate_AcceptsProfiles(t *testing.T) {
+	data, err := JSONSchema()
+	require.NoError(t, err)
+	var s map[string]any
+	require.NoError(t, json.Unmarshal(data, &s))
+	defs := s["definitions"].(map[string]any)
+
+	names, err := profiles.List()
+	require.NoError(t, err)
+	require.NotEmpty(t, names)
+	for _, name := range names {
+		snap, err := profiles.Get(name)
+		require.NoError(t, err)
+		out, err := domain.MarshalManifest(snap)
+		require.NoError(t, err)
+		var doc map[string]any
+		_, err = toml.Decode(string(out), &doc)
+		require.NoError(t, err)
+		checkValue(t, name, defs, s, doc)
+	}
+}
+
+func checkValue(t *testing.T, path string, defs, schema map[string]any, v any) {
+	t.Helper()
+	if all, ok := schema["allOf"].([]any); ok {
+		schema = all[0].(map[string]any)
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = defs[ref[len("#/definitions/"):]].(map[string]any)
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		require.Equal(t, "object", schema["type"], path)
+		for k, child := range v {
+			var sub map[string]any
+			if props, ok := schema["properties"].(map[string]any); ok {
+				sub, _ = props[k].(map[string]any)
+			}
+			if sub == nil {
+				sub, _ = schema["additionalProperties"].(map[string]any)
+			}
+			require.NotNil(t, sub, "%s.%s is not in the schema", path, k)
+			checkValue(t, path+"."+k, defs, sub, child)
+		}
+	case []map[string]any:
+		require.Equal(t, "array", schema["type"], path)
+		for _, item := range v {
+			checkValue(t, path, defs, schema["items"].(map[string]any), item)
+		}
+	case []any:
+		require.Equal(t, "array", schema["type"], path)
+		for _, item := range v {
+			checkValue(t, path, defs, schema["items"].(map[string]any), item)
+		}
+	case string:
+		assert.Equal(t, "string", schema["type"], path)
+	case bool:
+		assert.Equal(t, "boolean", schema["type"], path)
+	case int64:
+		assert.Contains(t, []any{"integer", "number"}, schema["type"], path)
+	case float64:
+		assert.Equal(t, "number", schema["type"], path)
+	}
+}
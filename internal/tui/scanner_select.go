@@ -23,6 +23,7 @@ type ScannerSelectModel struct {
 	cursor  int
 	done    bool
 	quitted bool
+	title   string
 }
 
 // NewScannerSelectModel creates a new model with the given items.
@@ -37,6 +38,13 @@ func NewScannerSelectModel(items []ScannerItem) ScannerSelectModel {
 	}
 }
 
+// WithTitle returns the model with another heading, for picking items other
+// than scanners.
+func (m ScannerSelectModel) WithTitle(title string) ScannerSelectModel {
+	m.title = title
+	return m
+}
+
 // Selected returns the names of the scanners the user chose.
 func (m ScannerSelectModel) Selected() []string {
 	var names []string
@@ -117,7 +125,11 @@ func (m ScannerSelectModel) View() string {
 	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241")).MarginTop(1)
 
 	var b strings.Builder
-	b.WriteString(titleStyle.Render("Select scanners to run:"))
+	title := m.title
+	if title == "" {
+		title = "Select scanners to run:"
+	}
+	b.WriteString(titleStyle.Render(title))
 	b.WriteString("\n")
 
 	for i, item := range m.items {
@@ -129,6 +129,14 @@ func TestScannerSelectModel_View(t *testing.T) {
 	assert.Contains(t, view, "packages")
 }
 
+func TestScannerSelectModel_WithTitle(t *testing.T) {
+	m := NewScannerSelectModel(sampleItems()).WithTitle("Select sections to share:")
+
+	view := m.View()
+	assert.Contains(t, view, "Select sections to share:")
+	assert.NotContains(t, view, "Select scanners to run:")
+}
+
 func TestScannerSelectModel_ViewDone(t *testing.T) {
 	m := NewScannerSelectModel(sampleItems())
 	model, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
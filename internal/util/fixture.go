@@ -0,0 +1,254 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FixtureEntry is one recorded command execution or PATH lookup. A fixture
+// file holds one JSON-encoded entry per line.
+type FixtureEntry struct {
+	// Kind is "run" for a command execution or "lookpath" for IsInstalled.
+	Kind     string   `json:"kind"`
+	Name     string   `json:"name"`
+	Args     []string `json:"args,omitempty"`
+	Stdout   string   `json:"stdout,omitempty"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exit_code,omitempty"`
+	// Error is set when the command could not be started or did not exit
+	// normally (e.g. not found, killed by a timeout).
+	Error string `json:"error,omitempty"`
+	// Found is the IsInstalled answer for "lookpath" entries.
+	Found bool `json:"found,omitempty"`
+}
+
+const (
+	fixtureRun      = "run"
+	fixtureLookPath = "lookpath"
+)
+
+// FixtureHome stands in for the home directory in recorded arguments and
+// output, so a fixture replays on a machine with another home.
+const FixtureHome = "{{home}}"
+
+// replaceHome replaces home in s with to wherever it is a whole path: at the
+// end of s or followed by a character that cannot continue the directory
+// name, so /Users/al does not match inside /Users/alice.
+func replaceHome(s, home, to string) string {
+	if home == "" || home == "/" {
+		return s
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, home)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(home)
+		if end == len(s) || !isNameByte(s[end]) {
+			b.WriteString(s[:i])
+			b.WriteString(to)
+		} else {
+			b.WriteString(s[:end])
+		}
+		s = s[end:]
+	}
+}
+
+func isNameByte(c byte) bool {
+	return c == '.' || c == '-' || c == '_' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= 0x80
+}
+
+// RecordingRunner executes commands like RealCommandRunner and appends every
+// execution and PATH lookup to a fixture. It is safe for concurrent use.
+type RecordingRunner struct {
+	mu   sync.Mutex
+	enc  *json.Encoder
+	home string
+	err  error
+}
+
+// NewRecordingRunner creates a RecordingRunner writing fixture entries to w.
+// Entries are written as they happen, so w needs no final flush. homeDir is
+// recorded as FixtureHome wherever it appears in arguments and output.
+func NewRecordingRunner(w io.Writer, homeDir string) *RecordingRunner {
+	return &RecordingRunner{enc: json.NewEncoder(w), home: filepath.Clean(homeDir)}
+}
+
+// Err returns the first error encountered while writing the fixture.
+func (r *RecordingRunner) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *RecordingRunner) record(e FixtureEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil {
+		r.err = r.enc.Encode(e)
+	}
+}
+
+func (r *RecordingRunner) Run(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	entry := FixtureEntry{
+		Kind:   fixtureRun,
+		Name:   name,
+		Args:   make([]string, len(args)),
+		Stdout: replaceHome(stdout.String(), r.home, FixtureHome),
+		Stderr: replaceHome(stderr.String(), r.home, FixtureHome),
+	}
+	for i, a := range args {
+		entry.Args[i] = replaceHome(a, r.home, FixtureHome)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// Like cmd.Output in RealCommandRunner, hand the stderr to callers
+		// that report why the command failed.
+		exitErr.Stderr = stderr.Bytes()
+	}
+	switch {
+	case exitErr != nil && exitErr.Exited():
+		entry.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		entry.Error = err.Error()
+	}
+	r.record(entry)
+
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func (r *RecordingRunner) RunLines(ctx context.Context, name string, args ...string) ([]string, error) {
+	output, err := r.Run(ctx, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+func (r *RecordingRunner) IsInstalled(ctx context.Context, name string) bool {
+	_, err := exec.LookPath(name)
+	r.record(FixtureEntry{Kind: fixtureLookPath, Name: name, Found: err == nil})
+	return err == nil
+}
+
+// ExitError is returned by ReplayRunner for commands that were recorded with
+// a non-zero exit code.
+type ExitError struct {
+	Code   int
+	Stderr string
+}
+
+func (e *ExitError) Error() string { return fmt.Sprintf("exit status %d", e.Code) }
+
+// ReplayRunner answers commands from a recorded fixture instead of running
+// them. Repeated calls to the same command return the recorded executions
+// in order, repeating the last one once they run out. Commands that were
+// never recorded fail as if they were not installed. FixtureHome in the
+// fixture is read as the replaying home directory.
+type ReplayRunner struct {
+	mu      sync.Mutex
+	runs    map[string][]FixtureEntry
+	lookups map[string]bool
+}
+
+// LoadFixture reads a fixture written by RecordingRunner, replaying it for
+// homeDir.
+func LoadFixture(r io.Reader, homeDir string) (*ReplayRunner, error) {
+	home := filepath.Clean(homeDir)
+	rr := &ReplayRunner{
+		runs:    make(map[string][]FixtureEntry),
+		lookups: make(map[string]bool),
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	line := 0
+	for sc.Scan() {
+		line++
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var e FixtureEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("fixture line %d: %w", line, err)
+		}
+		switch e.Kind {
+		case fixtureRun:
+			for i, a := range e.Args {
+				e.Args[i] = strings.ReplaceAll(a, FixtureHome, home)
+			}
+			e.Stdout = strings.ReplaceAll(e.Stdout, FixtureHome, home)
+			e.Stderr = strings.ReplaceAll(e.Stderr, FixtureHome, home)
+			k := fixtureKey(e.Name, e.Args...)
+			rr.runs[k] = append(rr.runs[k], e)
+		case fixtureLookPath:
+			rr.lookups[e.Name] = e.Found
+		default:
+			return nil, fmt.Errorf("fixture line %d: unknown kind %q", line, e.Kind)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read fixture: %w", err)
+	}
+	return rr, nil
+}
+
+func fixtureKey(name string, args ...string) string {
+	return strings.Join(append([]string{name}, args...), " ")
+}
+
+func (r *ReplayRunner) Run(ctx context.Context, name string, args ...string) (string, error) {
+	k := fixtureKey(name, args...)
+	r.mu.Lock()
+	entries := r.runs[k]
+	if len(entries) == 0 {
+		r.mu.Unlock()
+		return "", fmt.Errorf("replay: %q was not recorded: %w", k, exec.ErrNotFound)
+	}
+	e := entries[0]
+	if len(entries) > 1 {
+		r.runs[k] = entries[1:]
+	}
+	r.mu.Unlock()
+
+	switch {
+	case e.Error != "":
+		return "", errors.New(e.Error)
+	case e.ExitCode != 0:
+		return "", &ExitError{Code: e.ExitCode, Stderr: e.Stderr}
+	}
+	return strings.TrimSpace(e.Stdout), nil
+}
+
+func (r *ReplayRunner) RunLines(ctx context.Context, name string, args ...string) ([]string, error) {
+	output, err := r.Run(ctx, name, args...)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(output), nil
+}
+
+func (r *ReplayRunner) IsInstalled(ctx context.Context, name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lookups[name]
+}
@@ -0,0 +1,136 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordingRunner_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	rec := NewRecordingRunner(&buf, t.TempDir())
+
+	out, err := rec.Run(ctx, "echo", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out)
+
+	_, err = rec.Run(ctx, "sh", "-c", "echo oops >&2; exit 3")
+	var exitErr, realErr *exec.ExitError
+	require.ErrorAs(t, err, &exitErr)
+	_, err = (&RealCommandRunner{}).Run(ctx, "sh", "-c", "echo oops >&2; exit 3")
+	require.ErrorAs(t, err, &realErr)
+	assert.Equal(t, realErr.Stderr, exitErr.Stderr, "recorded runs fail like real ones")
+	assert.Equal(t, realErr.Error(), exitErr.Error())
+
+	_, err = rec.Run(ctx, "machinist-no-such-command")
+	require.Error(t, err)
+
+	assert.True(t, rec.IsInstalled(ctx, "sh"))
+	assert.False(t, rec.IsInstalled(ctx, "machinist-no-such-command"))
+	require.NoError(t, rec.Err())
+
+	replay, err := LoadFixture(&buf, t.TempDir())
+	require.NoError(t, err)
+
+	out, err = replay.Run(ctx, "echo", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", out)
+
+	_, err = replay.Run(ctx, "sh", "-c", "echo oops >&2; exit 3")
+	var replayErr *ExitError
+	require.ErrorAs(t, err, &replayErr)
+	assert.Equal(t, 3, replayErr.Code)
+	assert.Equal(t, "oops\n", replayErr.Stderr)
+	assert.Equal(t, "exit status 3", err.Error())
+
+	_, err = replay.Run(ctx, "machinist-no-such-command")
+	assert.Error(t, err)
+
+	assert.True(t, replay.IsInstalled(ctx, "sh"))
+	assert.False(t, replay.IsInstalled(ctx, "machinist-no-such-command"))
+}
+
+func TestReplayRunner_SequentialResponses(t *testing.T) {
+	fixture := `{"kind":"run","name":"defaults","args":["read","x"],"stdout":"first\n"}
+{"kind":"run","name":"defaults","args":["read","x"],"stdout":"second\n"}
+`
+	replay, err := LoadFixture(strings.NewReader(fixture), "/Users/test")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, want := range []string{"first", "second", "second"} {
+		out, err := replay.Run(ctx, "defaults", "read", "x")
+		require.NoError(t, err)
+		assert.Equal(t, want, out)
+	}
+}
+
+func TestReplayRunner_Unrecorded(t *testing.T) {
+	replay, err := LoadFixture(strings.NewReader(""), "/Users/test")
+	require.NoError(t, err)
+
+	_, err = replay.Run(context.Background(), "brew", "list")
+	assert.True(t, errors.Is(err, exec.ErrNotFound))
+	lines, err := replay.RunLines(context.Background(), "brew", "list")
+	assert.Error(t, err)
+	assert.Nil(t, lines)
+	assert.False(t, replay.IsInstalled(context.Background(), "brew"))
+}
+
+func TestReplayRunner_RunLines(t *testing.T) {
+	replay, err := LoadFixture(strings.NewReader(`{"kind":"run","name":"brew","args":["leaves"],"stdout":"go\nnode\n\n"}`), "/Users/test")
+	require.NoError(t, err)
+
+	lines, err := replay.RunLines(context.Background(), "brew", "leaves")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go", "node"}, lines)
+}
+
+func TestLoadFixture_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"not json":     "{",
+		"unknown kind": `{"kind":"exec","name":"brew"}`,
+	}
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := LoadFixture(strings.NewReader(content), "/Users/test")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFixture_ReplaysUnderAnotherHome(t *testing.T) {
+	ctx := context.Background()
+	recordHome := "/Users/alice"
+	var buf bytes.Buffer
+	rec := NewRecordingRunner(&buf, recordHome)
+	_, err := rec.Run(ctx, "echo", recordHome+"/Code/api", "/Users/alicia/x")
+	require.NoError(t, err)
+	require.NoError(t, rec.Err())
+
+	assert.Contains(t, buf.String(), `"args":["{{home}}/Code/api","/Users/alicia/x"]`)
+	assert.Contains(t, buf.String(), `"stdout":"{{home}}/Code/api /Users/alicia/x\n"`)
+
+	replay, err := LoadFixture(&buf, "/home/bob")
+	require.NoError(t, err)
+	out, err := replay.Run(ctx, "echo", "/home/bob/Code/api", "/Users/alicia/x")
+	require.NoError(t, err)
+	assert.Equal(t, "/home/bob/Code/api /Users/alicia/x", out)
+
+	_, err = replay.Run(ctx, "echo", recordHome+"/Code/api", "/Users/alicia/x")
+	assert.Error(t, err, "the recording home is not replayed")
+}
+
+func TestReplaceHome(t *testing.T) {
+	assert.Equal(t, "{{home}}", replaceHome("/Users/al", "/Users/al", FixtureHome))
+	assert.Equal(t, "{{home}}/.zshrc:{{home}}", replaceHome("/Users/al/.zshrc:/Users/al", "/Users/al", FixtureHome))
+	assert.Equal(t, "/Users/alice /Users/al.d", replaceHome("/Users/alice /Users/al.d", "/Users/al", FixtureHome))
+	assert.Equal(t, "/", replaceHome("/", "/", FixtureHome))
+}
@@ -57,3 +57,19 @@ func ContentHash(path string) (string, error) {
 	hash := sha256.Sum256(data)
 	return fmt.Sprintf("%x", hash), nil
 }
+
+// MatchesAny reports whether path matches one of the glob patterns.
+// Patterns containing a path separator are matched against the full path,
+// other patterns against the base name.
+func MatchesAny(path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		target := filepath.Base(path)
+		if strings.Contains(pattern, string(filepath.Separator)) {
+			target = path
+		}
+		if ok, _ := filepath.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
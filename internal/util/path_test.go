@@ -199,3 +199,13 @@ func TestFileExists_OnDir(t *testing.T) {
 
 	assert.False(t, FileExists(tmpDir))
 }
+
+func TestMatchesAny(t *testing.T) {
+	patterns := []string{"node_modules", "/home/me/Code/archive/*"}
+
+	assert.True(t, MatchesAny("/home/me/Code/app/node_modules", patterns), "base-name pattern")
+	assert.True(t, MatchesAny("/home/me/Code/archive/old", patterns), "full-path pattern")
+	assert.False(t, MatchesAny("/home/me/Code/app", patterns))
+	assert.False(t, MatchesAny("/home/me/Code/archive", patterns))
+	assert.False(t, MatchesAny("/anything", nil))
+}
@@ -0,0 +1,118 @@
+package validate
+
+import (
+	"strconv"
+	"strings"
+)
+
+// locator maps keys and values back to manifest lines. The TOML decoder
+// only reports positions for syntax errors, so this does a light line scan
+// that tracks the current [table] header; it is good enough for the
+// manifests machinist writes and for hand-edited ones in the same layout.
+type locator struct {
+	lines []locLine
+}
+
+type locLine struct {
+	table  string // dotted table the line belongs to
+	header bool   // the line is a [table] or [[table]] header
+	key    string // bare key assigned on this line, if any
+	text   string
+}
+
+func newLocator(data string) *locator {
+	l := &locator{}
+	table := ""
+	for _, raw := range strings.Split(data, "\n") {
+		text := strings.TrimSpace(raw)
+		ll := locLine{text: text}
+		switch {
+		case strings.HasPrefix(text, "["):
+			name := strings.Trim(text, "[] \t")
+			if i := strings.Index(name, "#"); i >= 0 {
+				name = strings.Trim(name[:i], "[] \t")
+			}
+			table = unquoteKey(name)
+			ll.header = true
+		case text != "" && !strings.HasPrefix(text, "#"):
+			if k, _, ok := strings.Cut(text, "="); ok {
+				ll.key = unquoteKey(strings.TrimSpace(k))
+			}
+		}
+		ll.table = table
+		l.lines = append(l.lines, ll)
+	}
+	return l
+}
+
+// unquoteKey strips quotes from each part of a dotted key.
+func unquoteKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if u, err := strconv.Unquote(p); err == nil {
+			p = u
+		} else {
+			p = strings.Trim(p, "'")
+		}
+		parts[i] = p
+	}
+	return strings.Join(parts, ".")
+}
+
+// keyLine returns the line that defines the key path, falling back to the
+// closest enclosing table header. It returns 0 when nothing matches.
+func (l *locator) keyLine(parts ...string) int {
+	full := strings.Join(parts, ".")
+	for i, ll := range l.lines {
+		if ll.header && ll.table == full {
+			return i + 1
+		}
+	}
+	for n := len(parts) - 1; n >= 0; n-- {
+		table := strings.Join(parts[:n], ".")
+		key := strings.Join(parts[n:], ".")
+		for i, ll := range l.lines {
+			if !ll.header && ll.table == table && ll.key == key {
+				return i + 1
+			}
+		}
+	}
+	for n := len(parts) - 1; n > 0; n-- {
+		table := strings.Join(parts[:n], ".")
+		for i, ll := range l.lines {
+			if ll.header && ll.table == table {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// valueLine returns the line of the first quoted occurrence of value inside
+// the given top-level section, or 0.
+func (l *locator) valueLine(section, value string) int {
+	return l.valueLineNth(section, value, 1)
+}
+
+// valueLineNth returns the line of the nth quoted occurrence of value inside
+// the given top-level section, or 0.
+func (l *locator) valueLineNth(section, value string, nth int) int {
+	if value == "" {
+		return 0
+	}
+	needles := []string{strconv.Quote(value), "'" + value + "'"}
+	seen := 0
+	for i, ll := range l.lines {
+		if ll.table != section && !strings.HasPrefix(ll.table, section+".") {
+			continue
+		}
+		for _, needle := range needles {
+			seen += strings.Count(ll.text, needle)
+		}
+		if seen >= nth {
+			return i + 1
+		}
+	}
+	return 0
+}
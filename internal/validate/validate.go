@@ -0,0 +1,347 @@
+// Package validate checks manifests beyond what decoding catches: unknown
+// keys, duplicate or malformed package names, config file paths that would
+// escape the bundle or the home directory, layers in meta.extends that cannot
+// be resolved, and sections that cannot restore anything. Every issue
+// carries the TOML key and line it refers to.
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/profiles"
+)
+
+// Issue codes.
+const (
+	CodeParse          = "parse-error"
+	CodeSchema         = "schema-version"
+	CodeUnknownKey     = "unknown-key"
+	CodeDuplicate      = "duplicate"
+	CodeInvalidName    = "invalid-name"
+	CodeBundleEscape   = "bundle-path-escape"
+	CodeAbsoluteSource = "absolute-source"
+	CodeSourceEscape   = "source-escape"
+	CodeMissingFile    = "missing-file"
+	CodeEmptySection   = "empty-section"
+	CodeWrongGroup     = "wrong-group"
+	CodeExtends        = "extends"
+	CodeProfile        = "profile"
+)
+
+// Issue is one problem found in a manifest.
+type Issue struct {
+	Severity domain.Severity `json:"severity"`
+	Code     string          `json:"code"`
+	// Key is the dotted TOML key the issue refers to, e.g. "homebrew.taps".
+	Key string `json:"key,omitempty"`
+	// Line is the 1-based line of Key in the manifest, or 0 if unknown.
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	loc := i.Key
+	if i.Line > 0 {
+		loc = fmt.Sprintf("line %d: %s", i.Line, i.Key)
+	}
+	if loc == "" {
+		return fmt.Sprintf("%s: %s", i.Severity, i.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", i.Severity, loc, i.Message)
+}
+
+// Report is the result of validating one manifest.
+type Report struct {
+	// Valid is false when any issue has error severity.
+	Valid         bool     `json:"valid"`
+	SchemaVersion int      `json:"schema_version,omitempty"`
+	Sections      []string `json:"sections,omitempty"`
+	Issues        []Issue  `json:"issues"`
+}
+
+// Count returns the number of issues with the given severity.
+func (r *Report) Count(sev domain.Severity) int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Severity == sev {
+			n++
+		}
+	}
+	return n
+}
+
+// Options controls the optional checks.
+type Options struct {
+	// HomeDir, when set, enables the missing-file check: every config file
+	// and directory the manifest references must exist under it.
+	HomeDir string
+	// Path is the manifest's file. Relative meta.extends paths resolve
+	// against its directory and cycles are reported starting at it, as
+	// `machinist resolve` does. Empty means the working directory.
+	Path string
+}
+
+// checker accumulates issues for one manifest.
+type checker struct {
+	loc    *locator
+	issues []Issue
+}
+
+func (c *checker) add(sev domain.Severity, code, key string, line int, format string, args ...any) {
+	c.issues = append(c.issues, Issue{
+		Severity: sev,
+		Code:     code,
+		Key:      key,
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Manifest validates manifest TOML.
+func Manifest(data []byte, opts Options) *Report {
+	report := &Report{Issues: []Issue{}}
+	c := &checker{loc: newLocator(string(data))}
+	defer func() {
+		report.Issues = append(report.Issues, c.issues...)
+		report.Valid = report.Count(domain.SeverityError) == 0
+	}()
+
+	version, err := domain.ManifestSchemaVersion(data)
+	if err != nil {
+		c.parseError(err)
+		return report
+	}
+	report.SchemaVersion = version
+	migrated, _, err := domain.MigrateManifest(data)
+	if err != nil {
+		c.add(domain.SeverityError, CodeSchema, "meta.schema_version", c.loc.keyLine("meta", "schema_version"), "%v", err)
+		return report
+	}
+	if version < domain.CurrentSchemaVersion {
+		c.add(domain.SeverityWarning, CodeSchema, "meta.schema_version", c.loc.keyLine("meta", "schema_version"),
+			"schema version %d is older than %d; run 'machinist migrate'", version, domain.CurrentSchemaVersion)
+	}
+
+	var snap domain.Snapshot
+	md, err := toml.Decode(string(migrated), &snap)
+	if err != nil {
+		c.parseError(err)
+		return report
+	}
+	for _, key := range md.Undecoded() {
+		c.add(domain.SeverityError, CodeUnknownKey, key.String(), c.loc.keyLine(key...), "unknown key %q", key[len(key)-1])
+	}
+
+	for _, s := range domain.Sections() {
+		if !s.Present(&snap) {
+			continue
+		}
+		report.Sections = append(report.Sections, s.Key)
+		c.checkSection(s, &snap, opts)
+	}
+	c.checkHomebrew(snap.Homebrew)
+	c.checkProfile(snap.Profile)
+	if len(snap.Meta.Extends) > 0 {
+		var err error
+		if opts.Path != "" {
+			_, err = profiles.ResolveFile(&snap, opts.Path)
+		} else {
+			_, err = profiles.Resolve(&snap, "")
+		}
+		if err != nil {
+			c.add(domain.SeverityError, CodeExtends, "meta.extends", c.loc.keyLine("meta", "extends"), "%v", err)
+		}
+	}
+	return report
+}
+
+func (c *checker) parseError(err error) {
+	line := 0
+	var perr toml.ParseError
+	if errors.As(err, &perr) {
+		line = perr.Position.Line
+	}
+	c.add(domain.SeverityError, CodeParse, "", line, "%v", err)
+}
+
+// checkSection runs the checks that apply to every section.
+func (c *checker) checkSection(s domain.Section, snap *domain.Snapshot, opts Options) {
+	if isEmpty(s.Value(snap)) {
+		c.add(domain.SeverityWarning, CodeEmptySection, s.Key, c.loc.keyLine(s.Key),
+			"section is present but empty; its %q stage has nothing to restore", s.Stage)
+	}
+	c.checkDuplicates(s.Key, reflect.ValueOf(s.Value(snap)))
+
+	files := s.ConfigFiles(snap)
+	dirs := s.ConfigDirs(snap)
+	for i, f := range append(files, dirs...) {
+		isDir := i >= len(files)
+		line := c.loc.valueLine(s.Key, f.Source)
+		if line == 0 {
+			line = c.loc.valueLine(s.Key, f.BundlePath)
+		}
+		c.checkConfigFile(s, f, isDir, line, opts)
+	}
+}
+
+func (c *checker) checkConfigFile(s domain.Section, f domain.ConfigFile, isDir bool, line int, opts Options) {
+	if f.BundlePath != "" {
+		if path.IsAbs(filepath.ToSlash(f.BundlePath)) || escapes(f.BundlePath) {
+			c.add(domain.SeverityError, CodeBundleEscape, s.Key, line,
+				"bundle path %q escapes the bundle", f.BundlePath)
+		}
+	}
+	switch {
+	case f.Source == "":
+	case strings.HasPrefix(f.Source, "~"):
+		c.add(domain.SeverityError, CodeAbsoluteSource, s.Key, line,
+			"source %q must be relative to the home directory without ~", f.Source)
+	case filepath.IsAbs(f.Source):
+		c.add(domain.SeverityError, CodeAbsoluteSource, s.Key, line,
+			"source %q is absolute; sources are restored relative to the home directory", f.Source)
+	case escapes(f.Source):
+		c.add(domain.SeverityError, CodeSourceEscape, s.Key, line,
+			"source %q escapes the home directory", f.Source)
+	case opts.HomeDir != "":
+		info, err := os.Stat(filepath.Join(opts.HomeDir, f.Source))
+		switch {
+		case err != nil:
+			c.add(domain.SeverityWarning, CodeMissingFile, s.Key, line, "%s does not exist under %s", f.Source, opts.HomeDir)
+		case isDir && !info.IsDir():
+			c.add(domain.SeverityWarning, CodeMissingFile, s.Key, line, "%s is not a directory", f.Source)
+		}
+	}
+	if f.Encrypted && s.Group != "secrets" {
+		c.add(domain.SeverityWarning, CodeWrongGroup, s.Key, line,
+			"%s is marked encrypted but [%s] is restored by the %q group, which does not decrypt files", f.Source, s.Key, s.Group)
+	}
+}
+
+// escapes reports whether the relative path p leaves the directory it is
+// relative to once cleaned.
+func escapes(p string) bool {
+	clean := path.Clean(filepath.ToSlash(p))
+	return clean == ".." || strings.HasPrefix(clean, "../")
+}
+
+// checkDuplicates reports repeated entries in any list of packages or
+// strings inside a section.
+func (c *checker) checkDuplicates(key string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			c.checkDuplicates(key, v.Elem())
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			c.checkDuplicates(key+"."+fmt.Sprint(k.Interface()), v.MapIndex(k))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			c.checkDuplicates(key+"."+name, v.Field(i))
+		}
+	case reflect.Slice:
+		seen := make(map[string]bool)
+		for i := 0; i < v.Len(); i++ {
+			name, ok := entryName(v.Index(i))
+			if !ok {
+				c.checkDuplicates(fmt.Sprintf("%s[%d]", key, i), v.Index(i))
+				continue
+			}
+			if name != "" && seen[name] {
+				c.add(domain.SeverityWarning, CodeDuplicate, key, c.loc.valueLineNth(topKey(key), name, 2),
+					"%q is listed more than once", name)
+			}
+			seen[name] = true
+		}
+	}
+}
+
+// entryName returns the identity of a list entry: the string itself or a
+// package's name.
+func entryName(v reflect.Value) (string, bool) {
+	switch x := v.Interface().(type) {
+	case string:
+		return x, true
+	case domain.Package:
+		return x.Name, true
+	}
+	return "", false
+}
+
+var (
+	// brewName matches formula and cask names, optionally qualified with
+	// their tap ("user/repo/name").
+	brewName = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_-]*/[A-Za-z0-9][A-Za-z0-9_.-]*/)?[a-z0-9][a-z0-9+_.@-]*$`)
+	brewTap  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*/[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+)
+
+func (c *checker) checkHomebrew(h *domain.HomebrewSection) {
+	if h == nil {
+		return
+	}
+	check := func(key, name string, re *regexp.Regexp, what string) {
+		if !re.MatchString(name) {
+			c.add(domain.SeverityError, CodeInvalidName, key, c.loc.valueLine("homebrew", name),
+				"%q is not a valid Homebrew %s name", name, what)
+		}
+	}
+	for _, p := range h.Formulae {
+		check("homebrew.formulae", p.Name, brewName, "formula")
+	}
+	for _, p := range h.Casks {
+		check("homebrew.casks", p.Name, brewName, "cask")
+	}
+	for _, t := range h.Taps {
+		check("homebrew.taps", t, brewTap, "tap")
+	}
+}
+
+// checkProfile checks the values of a [profile] block.
+func (c *checker) checkProfile(p *domain.ProfileInfo) {
+	if p == nil {
+		return
+	}
+	if p.Arch != "" && p.Arch != "arm64" && p.Arch != "x86_64" {
+		c.add(domain.SeverityError, CodeProfile, "profile.arch", c.loc.keyLine("profile", "arch"),
+			"arch %q must be arm64 or x86_64", p.Arch)
+	}
+	for _, g := range p.RequiredGroups {
+		if _, ok := domain.GroupByName(g); !ok {
+			c.add(domain.SeverityError, CodeProfile, "profile.required_groups", c.loc.valueLine("profile", g),
+				"unknown restore group %q", g)
+		}
+	}
+}
+
+// isEmpty reports whether a section value carries no data. Named sections
+// are empty when they have no entries.
+func isEmpty(v any) bool {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		return rv.Len() == 0
+	case reflect.Pointer:
+		return rv.IsNil() || rv.Elem().IsZero()
+	}
+	return v == nil
+}
+
+func topKey(key string) string {
+	top, _, _ := strings.Cut(key, ".")
+	return top
+}
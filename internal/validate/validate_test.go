@@ -0,0 +1,264 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validManifest = `[meta]
+schema_version = 2
+source_hostname = "mac"
+
+[homebrew]
+taps = ["homebrew/core", "hashicorp/tap"]
+
+[[homebrew.formulae]]
+name = "git"
+
+[[homebrew.formulae]]
+name = "hashicorp/tap/terraform"
+
+[git]
+[[git.config_files]]
+source = ".gitconfig"
+bundle_path = "configs/git/.gitconfig"
+`
+
+func findIssue(t *testing.T, r *Report, code string) Issue {
+	t.Helper()
+	for _, i := range r.Issues {
+		if i.Code == code {
+			return i
+		}
+	}
+	t.Fatalf("no %s issue in %+v", code, r.Issues)
+	return Issue{}
+}
+
+func TestManifest_Valid(t *testing.T) {
+	r := Manifest([]byte(validManifest), Options{})
+	assert.True(t, r.Valid)
+	assert.Empty(t, r.Issues)
+	assert.Equal(t, 2, r.SchemaVersion)
+	assert.Equal(t, []string{"homebrew", "git"}, r.Sections)
+}
+
+func TestManifest_ParseError(t *testing.T) {
+	r := Manifest([]byte("[meta]\nsource_hostname = \"mac\"\nbroken =\n[homebrew]\n"), Options{})
+	assert.False(t, r.Valid)
+	i := findIssue(t, r, CodeParse)
+	assert.Equal(t, 3, i.Line)
+}
+
+func TestManifest_NewerSchema(t *testing.T) {
+	r := Manifest([]byte("[meta]\nschema_version = 99\n"), Options{})
+	assert.False(t, r.Valid)
+	i := findIssue(t, r, CodeSchema)
+	assert.Equal(t, domain.SeverityError, i.Severity)
+	assert.Equal(t, 2, i.Line)
+}
+
+func TestManifest_UnknownKeys(t *testing.T) {
+	data := `[meta]
+source_hostname = "mac"
+
+[homebrew]
+tapz = ["x/y"]
+
+[docker]
+compose = true
+`
+	r := Manifest([]byte(data), Options{})
+	assert.False(t, r.Valid)
+
+	var keys []string
+	for _, i := range r.Issues {
+		if i.Code == CodeUnknownKey {
+			keys = append(keys, i.Key)
+			switch i.Key {
+			case "homebrew.tapz":
+				assert.Equal(t, 5, i.Line)
+			case "docker.compose":
+				assert.Equal(t, 8, i.Line)
+			}
+		}
+	}
+	assert.ElementsMatch(t, []string{"homebrew.tapz", "docker.compose"}, keys)
+}
+
+func TestManifest_DuplicatesAndInvalidNames(t *testing.T) {
+	data := `[homebrew]
+taps = ["homebrew/core", "not a tap", "homebrew/core"]
+
+[[homebrew.formulae]]
+name = "git"
+
+[[homebrew.formulae]]
+name = "Git Tools"
+
+[[homebrew.formulae]]
+name = "git"
+`
+	r := Manifest([]byte(data), Options{})
+	assert.False(t, r.Valid)
+
+	var dups, invalid []Issue
+	for _, i := range r.Issues {
+		switch i.Code {
+		case CodeDuplicate:
+			dups = append(dups, i)
+		case CodeInvalidName:
+			invalid = append(invalid, i)
+		}
+	}
+	require.Len(t, dups, 2)
+	assert.Equal(t, domain.SeverityWarning, dups[0].Severity)
+	assert.Equal(t, "homebrew.taps", dups[0].Key)
+	assert.Equal(t, 2, dups[0].Line)
+	assert.Equal(t, "homebrew.formulae", dups[1].Key)
+	assert.Equal(t, 11, dups[1].Line)
+
+	require.Len(t, invalid, 2)
+	assert.Equal(t, "homebrew.formulae", invalid[0].Key)
+	assert.Contains(t, invalid[0].Message, "Git Tools")
+	assert.Equal(t, 8, invalid[0].Line)
+	assert.Equal(t, "homebrew.taps", invalid[1].Key)
+	assert.Contains(t, invalid[1].Message, "not a tap")
+}
+
+func TestManifest_ConfigPaths(t *testing.T) {
+	data := `[git]
+[[git.config_files]]
+source = ".gitconfig"
+bundle_path = "../../etc/passwd"
+
+[[git.config_files]]
+source = "/etc/gitconfig"
+bundle_path = "configs/git/gitconfig"
+
+[[git.config_files]]
+source = "~/.gitignore_global"
+bundle_path = "configs/git/.gitignore_global"
+
+[[git.config_files]]
+source = "code/../../shared/.gitconfig"
+bundle_path = "configs/git/shared"
+`
+	r := Manifest([]byte(data), Options{})
+	assert.False(t, r.Valid)
+
+	escape := findIssue(t, r, CodeBundleEscape)
+	assert.Equal(t, "git", escape.Key)
+	assert.Equal(t, 3, escape.Line)
+
+	var sources []int
+	for _, i := range r.Issues {
+		if i.Code == CodeAbsoluteSource {
+			sources = append(sources, i.Line)
+		}
+	}
+	assert.Equal(t, []int{7, 11}, sources)
+
+	source := findIssue(t, r, CodeSourceEscape)
+	assert.Equal(t, "git", source.Key)
+	assert.Equal(t, 15, source.Line)
+}
+
+func TestManifest_MissingFiles(t *testing.T) {
+	home := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".gitconfig"), nil, 0o644))
+
+	r := Manifest([]byte(validManifest), Options{HomeDir: home})
+	assert.True(t, r.Valid)
+	assert.Empty(t, r.Issues)
+
+	require.NoError(t, os.Remove(filepath.Join(home, ".gitconfig")))
+	r = Manifest([]byte(validManifest), Options{HomeDir: home})
+	assert.True(t, r.Valid, "missing files are warnings")
+	i := findIssue(t, r, CodeMissingFile)
+	assert.Equal(t, domain.SeverityWarning, i.Severity)
+	assert.Equal(t, 16, i.Line)
+}
+
+func TestManifest_GroupInconsistencies(t *testing.T) {
+	data := `[docker]
+
+[git]
+[[git.config_files]]
+source = ".gitconfig"
+bundle_path = "configs/git/.gitconfig"
+encrypted = true
+`
+	r := Manifest([]byte(data), Options{})
+	assert.True(t, r.Valid)
+
+	empty := findIssue(t, r, CodeEmptySection)
+	assert.Equal(t, "docker", empty.Key)
+	assert.Equal(t, 1, empty.Line)
+
+	group := findIssue(t, r, CodeWrongGroup)
+	assert.Equal(t, "git", group.Key)
+	assert.Contains(t, group.Message, `"configs"`)
+}
+
+func TestIssue_String(t *testing.T) {
+	i := Issue{Severity: domain.SeverityError, Key: "homebrew.taps", Line: 4, Message: "bad"}
+	assert.Equal(t, "error: line 4: homebrew.taps: bad", i.String())
+	i.Line = 0
+	assert.Equal(t, "error: homebrew.taps: bad", i.String())
+	i.Key = ""
+	assert.Equal(t, "error: bad", i.String())
+}
+
+func TestManifest_Extends(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base.toml"), []byte("[go]\nversion = \"1.24\"\n"), 0o644))
+
+	data := "[meta]\nextends = [\"profile:go-dev\", \"base.toml\"]\n"
+	r := Manifest([]byte(data), Options{Path: filepath.Join(dir, "manifest.toml")})
+	assert.True(t, r.Valid, "%+v", r.Issues)
+
+	r = Manifest([]byte(data), Options{Path: filepath.Join(t.TempDir(), "manifest.toml")})
+	assert.False(t, r.Valid)
+	i := findIssue(t, r, CodeExtends)
+	assert.Equal(t, "meta.extends", i.Key)
+	assert.Equal(t, 2, i.Line)
+	assert.Contains(t, i.Message, "base.toml")
+}
+
+func TestManifest_ExtendsCycleStartsAtManifest(t *testing.T) {
+	dir := t.TempDir()
+	c1 := filepath.Join(dir, "c1.toml")
+	c2 := filepath.Join(dir, "c2.toml")
+	data := "[meta]\nschema_version = 2\nextends = [\"c2.toml\"]\n"
+	require.NoError(t, os.WriteFile(c1, []byte(data), 0o644))
+	require.NoError(t, os.WriteFile(c2, []byte("[meta]\nschema_version = 2\nextends = [\"c1.toml\"]\n"), 0o644))
+
+	r := Manifest([]byte(data), Options{Path: c1})
+	i := findIssue(t, r, CodeExtends)
+	assert.Contains(t, i.Message, "extends cycle: "+c1+" -> "+c2+" -> "+c1)
+}
+
+func TestManifest_Profile(t *testing.T) {
+	manifest := `[meta]
+schema_version = 2
+
+[profile]
+description = "Team setup"
+arch = "ppc"
+required_groups = ["homebrew", "dotfiles"]
+`
+	report := Manifest([]byte(manifest), Options{})
+	assert.False(t, report.Valid)
+	assert.Equal(t, 2, report.Count(domain.SeverityError))
+	arch := findIssue(t, report, CodeProfile)
+	assert.Equal(t, "profile.arch", arch.Key)
+	assert.Equal(t, 6, arch.Line)
+	assert.Equal(t, 7, report.Issues[1].Line)
+	assert.Contains(t, report.Issues[1].Message, `"dotfiles"`)
+}
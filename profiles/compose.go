@@ -0,0 +1,110 @@
+package profiles
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+)
+
+// Conflict is a value that a compose layer changed from the one an earlier
+// layer set.
+type Conflict struct {
+	domain.Conflict
+	// Layer is the layer whose value won; Previous set the value it replaced.
+	Layer    string
+	Previous string
+}
+
+func (c Conflict) String() string {
+	return fmt.Sprintf("%s: %v (%s) overridden by %v (%s)", c.Key(), c.Base, c.Previous, c.Override, c.Layer)
+}
+
+// Compose loads the given layers and merges them in order, each over the
+// ones before it, with the same deep merge as Merge. A layer is
+// "profile:<name>" or a manifest file path relative to dir (the working
+// directory when empty); each has its own extends chain resolved first.
+//
+// The result keeps the first layer's [meta] and records every layer, with
+// the sections it provides and the values it overrode, in Meta.Layers. It
+// is a setup rather than a profile, so it has no [profile] block.
+// The returned conflicts list those overrides in merge order.
+func Compose(refs []string, dir string) (*domain.Snapshot, []Conflict, error) {
+	if len(refs) == 0 {
+		return nil, nil, fmt.Errorf("nothing to compose")
+	}
+	if dir == "" {
+		dir = "."
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	layers := make([]*domain.Snapshot, len(refs))
+	for i, ref := range refs {
+		key, layerDir := layerKey(ref, abs)
+		snap, err := readLayer(key)
+		if err != nil {
+			return nil, nil, fmt.Errorf("layer %q: %w", ref, err)
+		}
+		if layers[i], err = resolve(snap, layerDir, []string{key}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var (
+		merged    *domain.Snapshot
+		conflicts []Conflict
+		info      = make([]domain.Layer, len(refs))
+		// winner maps a conflict key to the layer that last overrode it.
+		winner = make(map[string]string)
+	)
+	for i, layer := range layers {
+		info[i] = domain.Layer{Source: refs[i], Sections: sectionKeys(layer)}
+		if i == 0 {
+			merged = layer
+			continue
+		}
+		var changed []domain.Conflict
+		merged, changed = merge(merged, layer)
+		for _, c := range changed {
+			prev, ok := winner[c.Key()]
+			if !ok {
+				prev = setBy(c, refs[:i], layers[:i], layer)
+			}
+			winner[c.Key()] = refs[i]
+			conflicts = append(conflicts, Conflict{Conflict: c, Layer: refs[i], Previous: prev})
+			info[i].Overrides = append(info[i].Overrides, c.Key())
+		}
+	}
+	merged.Meta = layers[0].Meta
+	merged.Meta.Layers = info
+	merged.Profile = nil
+	return merged, conflicts, nil
+}
+
+// setBy finds the latest of the earlier layers that sets the value c
+// replaced, by merging layer over each of them alone.
+func setBy(c domain.Conflict, refs []string, earlier []*domain.Snapshot, layer *domain.Snapshot) string {
+	for j := len(earlier) - 1; j >= 0; j-- {
+		_, changed := merge(earlier[j], layer)
+		for _, o := range changed {
+			if o.Key() == c.Key() && fmt.Sprint(o.Base) == fmt.Sprint(c.Base) {
+				return refs[j]
+			}
+		}
+	}
+	return refs[0]
+}
+
+// sectionKeys returns the keys of the sections snap provides.
+func sectionKeys(snap *domain.Snapshot) []string {
+	var keys []string
+	for _, s := range domain.Sections() {
+		if s.Present(snap) {
+			keys = append(keys, s.Key)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,70 @@
+package profiles_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/profiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompose_Layers(t *testing.T) {
+	snap, conflicts, err := profiles.Compose([]string{"profile:go-dev", "profile:devops"}, "")
+	require.NoError(t, err)
+
+	assert.Contains(t, formulaNames(snap), "go")
+	assert.Contains(t, formulaNames(snap), "kubectl")
+	assert.Equal(t, "profile:go-dev", snap.Meta.SourceHostname, "the first layer's meta is kept")
+	require.Len(t, snap.Meta.Layers, 2)
+	assert.Equal(t, "profile:go-dev", snap.Meta.Layers[0].Source)
+	assert.Contains(t, snap.Meta.Layers[0].Sections, "go")
+	assert.Equal(t, "profile:devops", snap.Meta.Layers[1].Source)
+	assert.NotContains(t, snap.Meta.Layers[1].Sections, "go")
+	assert.Empty(t, conflicts)
+}
+
+func TestCompose_ReportsConflicts(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.toml"), "[go]\nversion = \"1.25\"\n")
+	writeFile(t, filepath.Join(dir, "b.toml"), "[shell]\ndefault_shell = \"/bin/bash\"\n")
+	writeFile(t, filepath.Join(dir, "c.toml"), "[go]\nversion = \"1.26\"\n")
+
+	snap, conflicts, err := profiles.Compose([]string{"profile:go-dev", "a.toml", "b.toml", "c.toml"}, dir)
+	require.NoError(t, err)
+	assert.Equal(t, "1.26", snap.Go.Version)
+
+	require.Len(t, conflicts, 3)
+	assert.Equal(t, "go.version", conflicts[0].Key())
+	assert.Equal(t, "profile:go-dev", conflicts[0].Previous)
+	assert.Equal(t, "a.toml", conflicts[0].Layer)
+	assert.Equal(t, "shell.default_shell", conflicts[1].Key())
+	assert.Equal(t, "profile:go-dev", conflicts[1].Previous)
+	assert.Equal(t, "go.version", conflicts[2].Key())
+	assert.Equal(t, "a.toml", conflicts[2].Previous, "the latest layer that set the value is named")
+	assert.Equal(t, `go.version: 1.25 (a.toml) overridden by 1.26 (c.toml)`, conflicts[2].String())
+
+	assert.Equal(t, []string{"go.version"}, snap.Meta.Layers[3].Overrides)
+}
+
+func TestCompose_FileLayerResolvesExtends(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "base.toml"), "[rust]\ndefault_toolchain = \"stable\"\n")
+	writeFile(t, filepath.Join(dir, "personal.toml"), "[meta]\nextends = [\"base.toml\"]\n")
+
+	snap, _, err := profiles.Compose([]string{"profile:minimal", "personal.toml"}, dir)
+	require.NoError(t, err)
+	require.NotNil(t, snap.Rust)
+	assert.Equal(t, "stable", snap.Rust.DefaultToolchain)
+	assert.Equal(t, []string{"rust"}, snap.Meta.Layers[1].Sections)
+}
+
+func TestCompose_MissingLayer(t *testing.T) {
+	_, _, err := profiles.Compose([]string{"profile:minimal", filepath.Join(t.TempDir(), "nope.toml")}, "")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	_, _, err = profiles.Compose(nil, "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,187 @@
+package profiles
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+)
+
+// MachineSections are the sections Extract always drops: keys and secrets,
+// and settings that only make sense on one machine or network.
+var MachineSections = []string{"ssh", "gpg", "env_files", "network", "hosts_file"}
+
+// ExtractOptions configures Extract.
+type ExtractOptions struct {
+	// Name is the profile name, recorded as source_hostname "profile:<name>".
+	Name string
+	// Info becomes the profile's [profile] block.
+	Info domain.ProfileInfo
+	// Sections keeps only these section keys. When empty every section
+	// except MachineSections is kept.
+	Sections []string
+	// KeepVersions keeps package versions and the lists of installed runtime
+	// versions, which are per-machine by default. A runtime's default
+	// version is always kept as the profile's target.
+	KeepVersions bool
+	// HomeDir is the home directory of the machine the snapshot was taken
+	// on. Paths below it, or below any /Users/<name>, become ~-relative.
+	HomeDir string
+}
+
+// usersHome matches a macOS home directory at the start of a path.
+var usersHome = regexp.MustCompile(`^/Users/[^/]+`)
+
+// Extract turns a snapshot of a real machine into a shareable profile. It
+// drops MachineSections, the computer name and host name, content hashes,
+// config files marked sensitive or encrypted, scan metadata and, unless
+// opts.KeepVersions is set, package versions, and makes absolute home paths
+// ~-relative. Without a description in opts.Info, the profile gets one
+// listing its sections. snap is not modified.
+func Extract(snap *domain.Snapshot, opts ExtractOptions) (*domain.Snapshot, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("a profile needs a name")
+	}
+	for _, key := range opts.Sections {
+		if _, ok := domain.SectionByKey(key); !ok {
+			return nil, fmt.Errorf("unknown section %q", key)
+		}
+		if slices.Contains(MachineSections, key) {
+			return nil, fmt.Errorf("section %q is machine-specific and cannot be shared", key)
+		}
+	}
+
+	// A marshal round trip gives a deep copy to scrub in place.
+	data, err := domain.MarshalManifest(snap)
+	if err != nil {
+		return nil, err
+	}
+	out, err := domain.UnmarshalManifest(data)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := func(key string) bool {
+		if slices.Contains(MachineSections, key) {
+			return false
+		}
+		return len(opts.Sections) == 0 || slices.Contains(opts.Sections, key)
+	}
+	v := reflect.ValueOf(out).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if f.Name == "Meta" || f.Name == "Profile" {
+			continue
+		}
+		if !keep(tomlKey(f)) {
+			v.Field(i).SetZero()
+			continue
+		}
+		opts.scrub(v.Field(i), false)
+	}
+
+	out.Meta = domain.Meta{
+		SchemaVersion:    domain.CurrentSchemaVersion,
+		CreatedAt:        time.Now(),
+		SourceHostname:   ProfilePrefix + opts.Name,
+		MachinistVersion: "profile",
+	}
+	info := opts.Info
+	if info.Description == "" {
+		info.Description = describeSections(out)
+	}
+	out.Profile = &info
+	return out, nil
+}
+
+// describeSections is the description of an extracted profile that was
+// given none: the sections it sets up.
+func describeSections(snap *domain.Snapshot) string {
+	var keys []string
+	for _, s := range domain.Sections() {
+		if s.Present(snap) {
+			keys = append(keys, s.Key)
+		}
+	}
+	if len(keys) == 0 {
+		return "Extracted from a machine snapshot"
+	}
+	return "Extracted from a machine snapshot: " + strings.Join(keys, ", ")
+}
+
+// scrub removes per-machine values from v in place. inList is set for list
+// items, whose version fields are package versions.
+func (opts ExtractOptions) scrub(v reflect.Value, inList bool) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			opts.scrub(v.Elem(), inList)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			switch tomlKey(f) {
+			case "content_hash", "computer_name", "local_hostname":
+				v.Field(i).SetZero()
+				continue
+			case "version":
+				if inList && !opts.KeepVersions {
+					v.Field(i).SetZero()
+					continue
+				}
+			case "versions":
+				if !opts.KeepVersions {
+					v.Field(i).SetZero()
+					continue
+				}
+			}
+			opts.scrub(v.Field(i), false)
+		}
+	case reflect.Slice:
+		if files, ok := v.Interface().([]domain.ConfigFile); ok {
+			v.Set(reflect.ValueOf(slices.DeleteFunc(files, func(f domain.ConfigFile) bool {
+				return f.Sensitive || f.Encrypted
+			})))
+		}
+		for i := 0; i < v.Len(); i++ {
+			opts.scrub(v.Index(i), true)
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			val := iter.Value()
+			switch val.Kind() {
+			case reflect.String:
+				v.SetMapIndex(iter.Key(), reflect.ValueOf(opts.homeRelative(val.String())).Convert(val.Type()))
+			case reflect.Pointer:
+				opts.scrub(val, false)
+			}
+		}
+	case reflect.String:
+		v.SetString(opts.homeRelative(v.String()))
+	}
+}
+
+// homeRelative rewrites a path in the home directory to start with ~.
+func (opts ExtractOptions) homeRelative(s string) string {
+	home := strings.TrimSuffix(opts.HomeDir, "/")
+	if home != "" && (s == home || strings.HasPrefix(s, home+"/")) {
+		return "~" + s[len(home):]
+	}
+	if m := usersHome.FindString(s); m != "" && m != "/Users/Shared" {
+		return "~" + s[len(m):]
+	}
+	return s
+}
+
+func tomlKey(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+	return name
+}
@@ -0,0 +1,133 @@
+package profiles_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/validate"
+	"github.com/moinsen-dev/machinist/profiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seniorSnapshot() *domain.Snapshot {
+	snap := domain.NewSnapshot("alices-mbp", "15.1", "arm64", "0.3.0")
+	snap.Meta.Diagnostics = []domain.Diagnostic{{Severity: domain.SeverityWarning, Scanner: "homebrew", Message: "slow"}}
+	snap.Homebrew = &domain.HomebrewSection{
+		Taps:     []string{"homebrew/core"},
+		Formulae: []domain.Package{{Name: "git", Version: "2.47.0"}, {Name: "go", Version: "1.24.1"}},
+	}
+	snap.Node = &domain.NodeSection{Manager: "fnm", Versions: []string{"20.11.0", "22.3.0"}, DefaultVersion: "22.3.0"}
+	snap.Go = &domain.GoSection{Version: "1.24.1"}
+	snap.Git = &domain.GitSection{
+		ConfigFiles: []domain.ConfigFile{
+			{Source: ".gitconfig", BundlePath: "configs/git/.gitconfig", ContentHash: "sha256:abc"},
+			{Source: ".git-credentials", BundlePath: "configs/git/.git-credentials", Sensitive: true},
+		},
+		TemplateDir: "/Users/alice/.git-templates",
+	}
+	snap.GitRepos = &domain.GitReposSection{
+		SearchPaths:  []string{"/Users/alice/Code"},
+		Repositories: []domain.Repository{{Path: "/Users/alice/Code/api", Remote: "git@github.com:org/api.git", Branch: "main"}},
+	}
+	snap.Locale = &domain.LocaleSection{Timezone: "Europe/Berlin", ComputerName: "Alice's MacBook Pro", LocalHostname: "alices-mbp"}
+	snap.SSH = &domain.SSHSection{Keys: []string{"id_ed25519_work"}}
+	snap.GPG = &domain.GPGSection{}
+	snap.EnvFiles = &domain.EnvFilesSection{}
+	snap.Network = &domain.NetworkSection{PreferredWifi: []string{"Alice Home"}}
+	snap.HostsFile = &domain.HostsFileSection{}
+	return snap
+}
+
+func TestExtract(t *testing.T) {
+	snap := seniorSnapshot()
+
+	profile, err := profiles.Extract(snap, profiles.ExtractOptions{
+		Name: "backend-onboarding",
+		Info: domain.ProfileInfo{Description: "Backend team setup", Tags: []string{"backend"}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "profile:backend-onboarding", profile.Meta.SourceHostname)
+	assert.Equal(t, "profile", profile.Meta.MachinistVersion)
+	assert.WithinDuration(t, time.Now(), profile.Meta.CreatedAt, time.Minute)
+	assert.Empty(t, profile.Meta.Diagnostics)
+	assert.Empty(t, profile.Meta.SourceOSVersion)
+	require.NotNil(t, profile.Profile)
+	assert.Equal(t, "Backend team setup", profile.Profile.Description)
+
+	assert.Nil(t, profile.SSH)
+	assert.Nil(t, profile.GPG)
+	assert.Nil(t, profile.EnvFiles)
+	assert.Nil(t, profile.Network)
+	assert.Nil(t, profile.HostsFile)
+	assert.Equal(t, &domain.LocaleSection{Timezone: "Europe/Berlin"}, profile.Locale)
+
+	assert.Equal(t, []domain.Package{{Name: "git"}, {Name: "go"}}, profile.Homebrew.Formulae)
+	assert.Empty(t, profile.Node.Versions)
+	assert.Equal(t, "22.3.0", profile.Node.DefaultVersion, "the default runtime version is the profile's target")
+	assert.Equal(t, "1.24.1", profile.Go.Version)
+
+	assert.Equal(t, []domain.ConfigFile{{Source: ".gitconfig", BundlePath: "configs/git/.gitconfig"}}, profile.Git.ConfigFiles)
+	assert.Equal(t, "~/.git-templates", profile.Git.TemplateDir)
+	assert.Equal(t, []string{"~/Code"}, profile.GitRepos.SearchPaths)
+	assert.Equal(t, "~/Code/api", profile.GitRepos.Repositories[0].Path)
+
+	// The snapshot itself is untouched.
+	assert.NotNil(t, snap.SSH)
+	assert.Equal(t, "2.47.0", snap.Homebrew.Formulae[0].Version)
+	assert.Equal(t, "/Users/alice/Code/api", snap.GitRepos.Repositories[0].Path)
+}
+
+func TestExtract_Options(t *testing.T) {
+	snap := seniorSnapshot()
+	snap.Shell = &domain.ShellSection{DefaultShell: "/bin/zsh"}
+	snap.Git.TemplateDir = "/home/alice/.git-templates"
+
+	profile, err := profiles.Extract(snap, profiles.ExtractOptions{
+		Name:         "mine",
+		Sections:     []string{"homebrew", "node", "shell", "git"},
+		KeepVersions: true,
+		HomeDir:      "/home/alice",
+	})
+	require.NoError(t, err)
+
+	assert.Nil(t, profile.Go)
+	assert.Nil(t, profile.GitRepos)
+	assert.Equal(t, "2.47.0", profile.Homebrew.Formulae[0].Version)
+	assert.Equal(t, []string{"20.11.0", "22.3.0"}, profile.Node.Versions)
+	assert.Equal(t, "/bin/zsh", profile.Shell.DefaultShell, "paths outside the home directory stay")
+	assert.Equal(t, "~/.git-templates", profile.Git.TemplateDir)
+	assert.Equal(t, "Extracted from a machine snapshot: homebrew, git, shell, node", profile.Profile.Description,
+		"a profile without a description gets one naming its sections")
+
+	_, err = profiles.Extract(snap, profiles.ExtractOptions{Name: "x", Sections: []string{"ssh"}})
+	assert.ErrorContains(t, err, "machine-specific")
+	_, err = profiles.Extract(snap, profiles.ExtractOptions{Name: "x", Sections: []string{"nope"}})
+	assert.ErrorContains(t, err, "unknown section")
+	_, err = profiles.Extract(snap, profiles.ExtractOptions{})
+	assert.Error(t, err)
+}
+
+func TestExtract_LoadsAsProfile(t *testing.T) {
+	profile, err := profiles.Extract(seniorSnapshot(), profiles.ExtractOptions{
+		Name: "backend-onboarding",
+		Info: domain.ProfileInfo{Description: "Backend team setup", Tags: []string{"backend"}},
+	})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, domain.WriteManifest(profile, filepath.Join(dir, "backend-onboarding.toml")))
+	useSearchPaths(t, dir)
+
+	loaded, err := profiles.Get("backend-onboarding")
+	require.NoError(t, err)
+	assert.Equal(t, "Backend team setup", loaded.Profile.Description)
+
+	data, err := domain.MarshalManifest(profile)
+	require.NoError(t, err)
+	report := validate.Manifest(data, validate.Options{})
+	assert.True(t, report.Valid, "%v", report.Issues)
+}
@@ -0,0 +1,38 @@
+package profiles
+
+import "github.com/moinsen-dev/machinist/internal/domain"
+
+// Info describes an available profile: its name, the file it is read from
+// ("embedded" for built-in profiles) and its [profile] metadata.
+type Info struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	domain.ProfileInfo
+	// Error is set instead of the metadata when the profile does not parse.
+	Error string `json:"error,omitempty"`
+}
+
+// Search returns the metadata of every available profile, sorted by name.
+// A non-empty tag keeps only the profiles carrying it, ignoring case.
+func Search(tag string) ([]Info, error) {
+	names, err := List()
+	if err != nil {
+		return nil, err
+	}
+	infos := []Info{}
+	for _, name := range names {
+		info := Info{Name: name, Source: Source(name)}
+		snap, err := Get(name)
+		switch {
+		case err != nil:
+			info.Error = err.Error()
+		case snap.Profile != nil:
+			info.ProfileInfo = *snap.Profile
+		}
+		if tag != "" && !info.HasTag(tag) {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
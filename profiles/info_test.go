@@ -0,0 +1,60 @@
+package profiles_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/profiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	all, err := profiles.Search("")
+	require.NoError(t, err)
+	names, err := profiles.List()
+	require.NoError(t, err)
+	require.Len(t, all, len(names))
+
+	mobile, err := profiles.Search("Mobile")
+	require.NoError(t, err)
+	require.Len(t, mobile, 1)
+	assert.Equal(t, "flutter-ios", mobile[0].Name)
+	assert.Equal(t, "embedded", mobile[0].Source)
+	assert.Equal(t, "14.0", mobile[0].MinMacOS)
+
+	none, err := profiles.Search("no-such-tag")
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func TestSearch_InvalidProfile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "broken.toml"), "[profile\n")
+	useSearchPaths(t, dir)
+
+	infos, err := profiles.Search("")
+	require.NoError(t, err)
+	for _, info := range infos {
+		if info.Name == "broken" {
+			assert.NotEmpty(t, info.Error)
+			assert.Equal(t, filepath.Join(dir, "broken.toml"), info.Source)
+			return
+		}
+	}
+	t.Fatal("broken profile not listed")
+}
+
+func TestProfileBlockIsNotInherited(t *testing.T) {
+	snap, _, err := profiles.Compose([]string{"profile:go-dev"}, "")
+	require.NoError(t, err)
+	assert.Nil(t, snap.Profile, "a composed setup is not a profile")
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "team.toml"), "[meta]\nextends = [\"profile:go-dev\"]\n\n[profile]\ndescription = \"Team Go\"\n")
+	resolved, err := profiles.Load(filepath.Join(dir, "team.toml"))
+	require.NoError(t, err)
+	require.NotNil(t, resolved.Profile)
+	assert.Equal(t, "Team Go", resolved.Profile.Description)
+	assert.Empty(t, resolved.Profile.Tags, "the block is the manifest's own, not merged with go-dev's")
+}
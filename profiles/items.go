@@ -0,0 +1,200 @@
+package profiles
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+)
+
+// itemKind is one type of compose item, written "<prefix>:<value>".
+type itemKind struct {
+	prefix string
+	// path is the manifest key the item is stored under.
+	path string
+	help string
+	// add stores value in snap. base is the manifest being composed, for
+	// kinds that derive defaults from it.
+	add func(snap, base *domain.Snapshot, value string) error
+}
+
+// itemKinds lists the supported item types. Items without a prefix are
+// Homebrew formulae.
+var itemKinds = []itemKind{
+	{"formula", "homebrew.formulae", "Homebrew formula (the default)", func(snap, _ *domain.Snapshot, v string) error {
+		brew(snap).Formulae = append(brew(snap).Formulae, domain.Package{Name: v})
+		return nil
+	}},
+	{"cask", "homebrew.casks", "Homebrew cask", func(snap, _ *domain.Snapshot, v string) error {
+		brew(snap).Casks = append(brew(snap).Casks, domain.Package{Name: v})
+		return nil
+	}},
+	{"tap", "homebrew.taps", "Homebrew tap, e.g. tap:hashicorp/tap", func(snap, _ *domain.Snapshot, v string) error {
+		brew(snap).Taps = append(brew(snap).Taps, v)
+		return nil
+	}},
+	{"vscode", "vscode.extensions", "VS Code extension, e.g. vscode:golang.go", func(snap, _ *domain.Snapshot, v string) error {
+		if snap.VSCode == nil {
+			snap.VSCode = &domain.VSCodeSection{}
+		}
+		snap.VSCode.Extensions = append(snap.VSCode.Extensions, v)
+		return nil
+	}},
+	{"cursor", "cursor.extensions", "Cursor extension", func(snap, _ *domain.Snapshot, v string) error {
+		if snap.Cursor == nil {
+			snap.Cursor = &domain.CursorSection{}
+		}
+		snap.Cursor.Extensions = append(snap.Cursor.Extensions, v)
+		return nil
+	}},
+	{"npm", "node.global_packages", "global npm package, optionally with @version", func(snap, _ *domain.Snapshot, v string) error {
+		if snap.Node == nil {
+			snap.Node = &domain.NodeSection{}
+		}
+		snap.Node.GlobalPackages = append(snap.Node.GlobalPackages, versioned(v))
+		return nil
+	}},
+	{"cargo", "rust.cargo_packages", "cargo-installed crate, optionally with @version", func(snap, _ *domain.Snapshot, v string) error {
+		if snap.Rust == nil {
+			snap.Rust = &domain.RustSection{}
+		}
+		snap.Rust.CargoPackages = append(snap.Rust.CargoPackages, versioned(v))
+		return nil
+	}},
+	{"go", "go.global_packages", "go install path, optionally with @version", func(snap, _ *domain.Snapshot, v string) error {
+		if snap.Go == nil {
+			snap.Go = &domain.GoSection{}
+		}
+		snap.Go.GlobalPackages = append(snap.Go.GlobalPackages, versioned(v))
+		return nil
+	}},
+	{"repo", "git_repos.repositories", "git remote to clone, optionally =<path>", func(snap, base *domain.Snapshot, v string) error {
+		remote, dir, _ := strings.Cut(v, "=")
+		if dir == "" {
+			dir = repoPath(base, remote)
+		}
+		if snap.GitRepos == nil {
+			snap.GitRepos = &domain.GitReposSection{}
+		}
+		snap.GitRepos.Repositories = append(snap.GitRepos.Repositories, domain.Repository{Path: dir, Remote: remote})
+		return nil
+	}},
+	{"mas", "apps.app_store", "Mac App Store app ID, optionally =<name>", func(snap, _ *domain.Snapshot, v string) error {
+		idStr, name, _ := strings.Cut(v, "=")
+		id, err := strconv.Atoi(idStr)
+		if err != nil || id <= 0 {
+			return fmt.Errorf("mas:%s: app ID must be a number", v)
+		}
+		if snap.Apps == nil {
+			snap.Apps = &domain.AppsSection{}
+		}
+		snap.Apps.AppStore = append(snap.Apps.AppStore, domain.InstalledApp{Name: name, Source: "mas", ID: id})
+		return nil
+	}},
+}
+
+// ItemTypes describes the supported item prefixes, one per line, for help
+// texts.
+func ItemTypes() string {
+	var b strings.Builder
+	for _, k := range itemKinds {
+		fmt.Fprintf(&b, "  %-8s %s\n", k.prefix+":", k.help)
+	}
+	return b.String()
+}
+
+// AddItems returns snap with the given items merged in. Items are written
+// "<type>:<value>", e.g. "cask:docker" or "npm:pnpm"; a bare name is a
+// Homebrew formula.
+func AddItems(snap *domain.Snapshot, items []string) (*domain.Snapshot, error) {
+	over, _, err := itemsSnapshot(snap, items)
+	if err != nil {
+		return nil, err
+	}
+	return Merge(snap, over), nil
+}
+
+// RemoveItems returns snap without the given items, matched the same way
+// lists are merged: packages by name, repositories by remote.
+func RemoveItems(snap *domain.Snapshot, items []string) (*domain.Snapshot, error) {
+	over, paths, err := itemsSnapshot(snap, items)
+	if err != nil {
+		return nil, err
+	}
+	over.Meta.Merge = make(map[string]domain.MergeStrategy, len(paths))
+	for _, p := range paths {
+		over.Meta.Merge[p] = domain.MergeRemove
+	}
+	return Merge(snap, over), nil
+}
+
+// itemsSnapshot builds a snapshot holding only the given items and returns
+// the key paths they were stored under.
+func itemsSnapshot(base *domain.Snapshot, items []string) (*domain.Snapshot, []string, error) {
+	snap := &domain.Snapshot{}
+	var paths []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		kind, value, err := parseItem(item)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := kind.add(snap, base, value); err != nil {
+			return nil, nil, err
+		}
+		if !seen[kind.path] {
+			seen[kind.path] = true
+			paths = append(paths, kind.path)
+		}
+	}
+	return snap, paths, nil
+}
+
+func parseItem(item string) (itemKind, string, error) {
+	prefix, value, ok := strings.Cut(item, ":")
+	if !ok {
+		return itemKinds[0], item, nil
+	}
+	for _, k := range itemKinds {
+		if k.prefix == prefix {
+			if value == "" {
+				return itemKind{}, "", fmt.Errorf("item %q has no value", item)
+			}
+			return k, value, nil
+		}
+	}
+	names := make([]string, len(itemKinds))
+	for i, k := range itemKinds {
+		names[i] = k.prefix
+	}
+	return itemKind{}, "", fmt.Errorf("unknown item type %q in %q (supported: %s)", prefix, item, strings.Join(names, ", "))
+}
+
+func brew(snap *domain.Snapshot) *domain.HomebrewSection {
+	if snap.Homebrew == nil {
+		snap.Homebrew = &domain.HomebrewSection{}
+	}
+	return snap.Homebrew
+}
+
+// versioned splits "name@version" into a Package. A leading @ belongs to
+// an npm scope, not a version.
+func versioned(v string) domain.Package {
+	if i := strings.LastIndex(v, "@"); i > 0 {
+		return domain.Package{Name: v[:i], Version: v[i+1:]}
+	}
+	return domain.Package{Name: v}
+}
+
+// repoPath picks a clone location for a remote: the repository's name under
+// the manifest's first git search path, or under $HOME/Code.
+func repoPath(base *domain.Snapshot, remote string) string {
+	name := strings.TrimSuffix(path.Base(strings.ReplaceAll(remote, ":", "/")), ".git")
+	dir := "$HOME/Code"
+	if base != nil && base.GitRepos != nil && len(base.GitRepos.SearchPaths) > 0 {
+		dir = base.GitRepos.SearchPaths[0]
+	}
+	return dir + "/" + name
+}
@@ -0,0 +1,110 @@
+package profiles_test
+
+import (
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/profiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddItems_Sections(t *testing.T) {
+	base, err := profiles.Get("minimal")
+	require.NoError(t, err)
+
+	snap, err := profiles.AddItems(base, []string{
+		"wget",
+		"cask:docker",
+		"tap:hashicorp/tap",
+		"vscode:golang.go",
+		"cursor:golang.go",
+		"npm:@anthropic-ai/sdk@1.2.0",
+		"npm:pnpm",
+		"cargo:ripgrep@14.1.0",
+		"go:golang.org/x/tools/gopls@latest",
+		"repo:git@github.com:org/x.git",
+		"mas:497799835=Xcode",
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, formulaNames(snap), "wget")
+	assert.NotContains(t, formulaNames(snap), "docker", "cask items must not become formulae")
+	assert.Contains(t, snap.Homebrew.Casks, domain.Package{Name: "docker"})
+	assert.Contains(t, snap.Homebrew.Taps, "hashicorp/tap")
+	require.NotNil(t, snap.VSCode)
+	assert.Equal(t, []string{"golang.go"}, snap.VSCode.Extensions)
+	require.NotNil(t, snap.Cursor)
+	assert.Equal(t, []string{"golang.go"}, snap.Cursor.Extensions)
+	require.NotNil(t, snap.Node)
+	assert.Equal(t, []domain.Package{
+		{Name: "@anthropic-ai/sdk", Version: "1.2.0"},
+		{Name: "pnpm"},
+	}, snap.Node.GlobalPackages)
+	require.NotNil(t, snap.Rust)
+	assert.Equal(t, []domain.Package{{Name: "ripgrep", Version: "14.1.0"}}, snap.Rust.CargoPackages)
+	require.NotNil(t, snap.Go)
+	assert.Equal(t, []domain.Package{{Name: "golang.org/x/tools/gopls", Version: "latest"}}, snap.Go.GlobalPackages)
+	require.NotNil(t, snap.GitRepos)
+	assert.Equal(t, []domain.Repository{{Path: "$HOME/Code/x", Remote: "git@github.com:org/x.git"}}, snap.GitRepos.Repositories)
+	require.NotNil(t, snap.Apps)
+	assert.Equal(t, []domain.InstalledApp{{Name: "Xcode", Source: "mas", ID: 497799835}}, snap.Apps.AppStore)
+
+	// The base profile is left untouched.
+	assert.NotContains(t, formulaNames(base), "wget")
+}
+
+func TestAddItems_NoDuplicates(t *testing.T) {
+	base, err := profiles.Get("minimal")
+	require.NoError(t, err)
+
+	snap, err := profiles.AddItems(base, []string{"git", "formula:git", "cask:iterm2"})
+	require.NoError(t, err)
+	assert.Len(t, snap.Homebrew.Formulae, len(base.Homebrew.Formulae))
+	assert.Len(t, snap.Homebrew.Casks, len(base.Homebrew.Casks))
+}
+
+func TestAddItems_RepoUsesSearchPath(t *testing.T) {
+	base := &domain.Snapshot{GitRepos: &domain.GitReposSection{SearchPaths: []string{"~/src"}}}
+
+	snap, err := profiles.AddItems(base, []string{"repo:https://github.com/org/tool.git", "repo:git@github.com:org/y.git=~/work/y"})
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Repository{
+		{Path: "~/src/tool", Remote: "https://github.com/org/tool.git"},
+		{Path: "~/work/y", Remote: "git@github.com:org/y.git"},
+	}, snap.GitRepos.Repositories)
+}
+
+func TestAddItems_Errors(t *testing.T) {
+	base := &domain.Snapshot{}
+
+	_, err := profiles.AddItems(base, []string{"pip:requests"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unknown item type "pip"`)
+	assert.Contains(t, err.Error(), "cask")
+
+	_, err = profiles.AddItems(base, []string{"cask:"})
+	assert.Error(t, err)
+
+	_, err = profiles.AddItems(base, []string{"mas:xcode"})
+	assert.Error(t, err)
+}
+
+func TestRemoveItems(t *testing.T) {
+	base, err := profiles.Get("minimal")
+	require.NoError(t, err)
+	base.Apps = &domain.AppsSection{AppStore: []domain.InstalledApp{
+		{Name: "Xcode", Source: "mas", ID: 497799835},
+		{Name: "Things 3", Source: "mas", ID: 904280696},
+	}}
+
+	snap, err := profiles.RemoveItems(base, []string{"jq", "cask:iterm2", "tap:homebrew/cask", "mas:497799835"})
+	require.NoError(t, err)
+
+	assert.NotContains(t, formulaNames(snap), "jq")
+	assert.Contains(t, formulaNames(snap), "git")
+	assert.Equal(t, []domain.Package{{Name: "visual-studio-code"}}, snap.Homebrew.Casks)
+	assert.Equal(t, []string{"homebrew/core"}, snap.Homebrew.Taps)
+	assert.Equal(t, []domain.InstalledApp{{Name: "Things 3", Source: "mas", ID: 904280696}}, snap.Apps.AppStore)
+	assert.Empty(t, snap.Meta.Merge, "removal rules are not written to the manifest")
+}
@@ -1,11 +1,15 @@
 // Package profiles provides embedded preset TOML manifests that describe
 // typical developer setups. Each profile is a complete Snapshot that can
-// be used directly or merged with user overrides.
+// be used directly or merged with user overrides. Teams and users can add
+// their own profiles in directories on the search path; see SearchPaths.
 package profiles
 
 import (
 	"embed"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 
@@ -15,30 +19,46 @@ import (
 //go:embed *.toml
 var profileFS embed.FS
 
-// List returns names of all available profiles (without .toml extension), sorted.
+// List returns names of all available profiles (without .toml extension),
+// sorted: the embedded ones and those in the search paths.
 func List() ([]string, error) {
 	entries, err := profileFS.ReadDir(".")
 	if err != nil {
 		return nil, fmt.Errorf("reading embedded profiles: %w", err)
 	}
 
+	seen := make(map[string]bool)
 	var names []string
+	add := func(name string) {
+		if strings.HasSuffix(name, ".toml") && !seen[name] {
+			seen[name] = true
+			names = append(names, strings.TrimSuffix(name, ".toml"))
+		}
+	}
 	for _, e := range entries {
-		if e.IsDir() {
-			continue
+		if !e.IsDir() {
+			add(e.Name())
 		}
-		name := e.Name()
-		if strings.HasSuffix(name, ".toml") {
-			names = append(names, strings.TrimSuffix(name, ".toml"))
+	}
+	for _, dir := range SearchPaths() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue // a missing profile directory is fine
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				add(e.Name())
+			}
 		}
 	}
 	sort.Strings(names)
 	return names, nil
 }
 
-// Get returns the Snapshot for a named profile.
+// Get returns the Snapshot for a named profile. A profile in the search
+// paths shadows an embedded one of the same name.
 func Get(name string) (*domain.Snapshot, error) {
-	data, err := profileFS.ReadFile(name + ".toml")
+	data, err := read(name)
 	if err != nil {
 		return nil, fmt.Errorf("profile %q not found: %w", name, err)
 	}
@@ -49,253 +69,58 @@ func Get(name string) (*domain.Snapshot, error) {
 	return snap, nil
 }
 
-// Merge merges a base profile snapshot with overrides. The base provides
-// default values; override sections take precedence. For Homebrew, lists
-// (taps, formulae, casks) are merged with deduplication. For other pointer
-// sections, the override replaces the base if non-nil.
-func Merge(base, override *domain.Snapshot) *domain.Snapshot {
-	merged := *base // shallow copy
-
-	// Merge Homebrew specially: combine lists.
-	if override.Homebrew != nil {
-		if merged.Homebrew == nil {
-			merged.Homebrew = override.Homebrew
-		} else {
-			hw := *merged.Homebrew
-			hw.Taps = mergeStrings(hw.Taps, override.Homebrew.Taps)
-			hw.Formulae = mergePackages(hw.Formulae, override.Homebrew.Formulae)
-			hw.Casks = mergePackages(hw.Casks, override.Homebrew.Casks)
-			hw.Services = mergeServices(hw.Services, override.Homebrew.Services)
-			merged.Homebrew = &hw
-		}
-	}
-
-	// For all other pointer sections: override wins if non-nil.
-	if override.Node != nil {
-		merged.Node = override.Node
-	}
-	if override.Python != nil {
-		merged.Python = override.Python
-	}
-	if override.Rust != nil {
-		merged.Rust = override.Rust
-	}
-	if override.Java != nil {
-		merged.Java = override.Java
-	}
-	if override.Flutter != nil {
-		merged.Flutter = override.Flutter
-	}
-	if override.Go != nil {
-		merged.Go = override.Go
-	}
-	if override.Asdf != nil {
-		merged.Asdf = override.Asdf
-	}
-	if override.Deno != nil {
-		merged.Deno = override.Deno
-	}
-	if override.Bun != nil {
-		merged.Bun = override.Bun
-	}
-	if override.Ruby != nil {
-		merged.Ruby = override.Ruby
-	}
-	if override.Shell != nil {
-		merged.Shell = override.Shell
-	}
-	if override.Terminal != nil {
-		merged.Terminal = override.Terminal
-	}
-	if override.Tmux != nil {
-		merged.Tmux = override.Tmux
-	}
-	if override.Git != nil {
-		merged.Git = override.Git
-	}
-	if override.GitHubCLI != nil {
-		merged.GitHubCLI = override.GitHubCLI
-	}
-	if override.GitRepos != nil {
-		merged.GitRepos = override.GitRepos
-	}
-	if override.VSCode != nil {
-		merged.VSCode = override.VSCode
-	}
-	if override.Cursor != nil {
-		merged.Cursor = override.Cursor
-	}
-	if override.Neovim != nil {
-		merged.Neovim = override.Neovim
-	}
-	if override.JetBrains != nil {
-		merged.JetBrains = override.JetBrains
-	}
-	if override.Xcode != nil {
-		merged.Xcode = override.Xcode
-	}
-	if override.Docker != nil {
-		merged.Docker = override.Docker
-	}
-	if override.AWS != nil {
-		merged.AWS = override.AWS
-	}
-	if override.Kubernetes != nil {
-		merged.Kubernetes = override.Kubernetes
-	}
-	if override.Terraform != nil {
-		merged.Terraform = override.Terraform
-	}
-	if override.Vercel != nil {
-		merged.Vercel = override.Vercel
-	}
-	if override.GCP != nil {
-		merged.GCP = override.GCP
-	}
-	if override.Azure != nil {
-		merged.Azure = override.Azure
-	}
-	if override.Flyio != nil {
-		merged.Flyio = override.Flyio
+// Source returns the file a profile is read from, or "embedded" for a
+// built-in profile. It returns "" when there is no such profile.
+func Source(name string) string {
+	if path := find(name); path != "" {
+		return path
 	}
-	if override.Firebase != nil {
-		merged.Firebase = override.Firebase
+	if _, err := fs.Stat(profileFS, name+".toml"); err == nil {
+		return "embedded"
 	}
-	if override.CloudflareWrangler != nil {
-		merged.CloudflareWrangler = override.CloudflareWrangler
-	}
-	if override.MacOSDefaults != nil {
-		merged.MacOSDefaults = override.MacOSDefaults
-	}
-	if override.Locale != nil {
-		merged.Locale = override.Locale
-	}
-	if override.LoginItems != nil {
-		merged.LoginItems = override.LoginItems
-	}
-	if override.HostsFile != nil {
-		merged.HostsFile = override.HostsFile
-	}
-	if override.Apps != nil {
-		merged.Apps = override.Apps
-	}
-	if override.Raycast != nil {
-		merged.Raycast = override.Raycast
-	}
-	if override.Alfred != nil {
-		merged.Alfred = override.Alfred
-	}
-	if override.Karabiner != nil {
-		merged.Karabiner = override.Karabiner
-	}
-	if override.Rectangle != nil {
-		merged.Rectangle = override.Rectangle
-	}
-	if override.BetterTouchTool != nil {
-		merged.BetterTouchTool = override.BetterTouchTool
-	}
-	if override.OnePassword != nil {
-		merged.OnePassword = override.OnePassword
-	}
-	if override.SSH != nil {
-		merged.SSH = override.SSH
-	}
-	if override.GPG != nil {
-		merged.GPG = override.GPG
-	}
-	if override.XDGConfig != nil {
-		merged.XDGConfig = override.XDGConfig
-	}
-	if override.Folders != nil {
-		merged.Folders = override.Folders
-	}
-	if override.Fonts != nil {
-		merged.Fonts = override.Fonts
-	}
-	if override.EnvFiles != nil {
-		merged.EnvFiles = override.EnvFiles
-	}
-	if override.Crontab != nil {
-		merged.Crontab = override.Crontab
-	}
-	if override.LaunchAgents != nil {
-		merged.LaunchAgents = override.LaunchAgents
-	}
-	if override.Network != nil {
-		merged.Network = override.Network
-	}
-	if override.Browser != nil {
-		merged.Browser = override.Browser
-	}
-	if override.AITools != nil {
-		merged.AITools = override.AITools
-	}
-	if override.APITools != nil {
-		merged.APITools = override.APITools
-	}
-	if override.Databases != nil {
-		merged.Databases = override.Databases
-	}
-	if override.Registries != nil {
-		merged.Registries = override.Registries
-	}
-
-	return &merged
+	return ""
 }
 
-// mergeStrings combines two string slices, deduplicating by value.
-func mergeStrings(base, extra []string) []string {
-	seen := make(map[string]bool, len(base))
-	result := make([]string, 0, len(base)+len(extra))
-	for _, s := range base {
-		if !seen[s] {
-			seen[s] = true
-			result = append(result, s)
-		}
-	}
-	for _, s := range extra {
-		if !seen[s] {
-			seen[s] = true
-			result = append(result, s)
-		}
+func read(name string) ([]byte, error) {
+	if path := find(name); path != "" {
+		return os.ReadFile(path)
 	}
-	return result
+	return profileFS.ReadFile(name + ".toml")
 }
 
-// mergePackages combines two Package slices, deduplicating by Name.
-func mergePackages(base, extra []domain.Package) []domain.Package {
-	seen := make(map[string]bool, len(base))
-	result := make([]domain.Package, 0, len(base)+len(extra))
-	for _, p := range base {
-		if !seen[p.Name] {
-			seen[p.Name] = true
-			result = append(result, p)
+// find returns the profile file in the search paths with the highest
+// precedence, or "". Names never reach outside the directories.
+func find(name string) string {
+	if strings.ContainsAny(name, `/\`) {
+		return ""
+	}
+	dirs := SearchPaths()
+	for i := len(dirs) - 1; i >= 0; i-- {
+		path := filepath.Join(dirs[i], name+".toml")
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
 		}
 	}
-	for _, p := range extra {
-		if !seen[p.Name] {
-			seen[p.Name] = true
-			result = append(result, p)
-		}
-	}
-	return result
+	return ""
 }
 
-// mergeServices combines two ServiceEntry slices, deduplicating by Name.
-func mergeServices(base, extra []domain.ServiceEntry) []domain.ServiceEntry {
-	seen := make(map[string]bool, len(base))
-	result := make([]domain.ServiceEntry, 0, len(base)+len(extra))
-	for _, s := range base {
-		if !seen[s.Name] {
-			seen[s.Name] = true
-			result = append(result, s)
-		}
-	}
-	for _, s := range extra {
-		if !seen[s.Name] {
-			seen[s.Name] = true
-			result = append(result, s)
-		}
+// Merge merges a base profile snapshot with overrides. The base provides
+// default values; override values take precedence. Every section in
+// domain.Sections() is deep-merged: lists such as Homebrew formulae or VS Code
+// extensions are combined without duplicates, tables merge field by field and
+// named sections such as plugins merge per name. The override's [meta.merge]
+// rules can replace or remove values instead.
+func Merge(base, override *domain.Snapshot) *domain.Snapshot {
+	merged, _ := merge(base, override)
+	return merged
+}
+
+// merge is Merge, also returning the values override changed.
+func merge(base, override *domain.Snapshot) (*domain.Snapshot, []domain.Conflict) {
+	merged := *base // shallow copy
+	var conflicts []domain.Conflict
+	for _, s := range domain.Sections() {
+		conflicts = append(conflicts, s.Merge(&merged, override)...)
 	}
-	return result
+	return &merged, conflicts
 }
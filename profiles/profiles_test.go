@@ -117,3 +117,119 @@ func TestMerge_OverrideSection(t *testing.T) {
 	assert.Equal(t, "v22", merged.Node.DefaultVersion)
 	assert.Len(t, merged.Node.GlobalPackages, 1)
 }
+
+func TestMerge_Plugins(t *testing.T) {
+	base := &domain.Snapshot{Plugins: map[string]*domain.PluginSection{
+		"corp":  {Restore: []string{"corp login"}},
+		"proxy": {Packages: []domain.Package{{Name: "proxy-certs"}}},
+	}}
+	override := &domain.Snapshot{Plugins: map[string]*domain.PluginSection{
+		"corp": {Restore: []string{"corp login --sso"}},
+	}}
+
+	merged := profiles.Merge(base, override)
+
+	require.Len(t, merged.Plugins, 2)
+	assert.Equal(t, []string{"corp login", "corp login --sso"}, merged.Plugins["corp"].Restore)
+	assert.NotNil(t, merged.Plugins["proxy"], "plugins only in base are kept")
+	assert.Equal(t, []string{"corp login"}, base.Plugins["corp"].Restore, "base must not be mutated")
+
+	override.Meta.Merge = map[string]domain.MergeStrategy{"plugins.corp.restore": domain.MergeReplace}
+	merged = profiles.Merge(base, override)
+	assert.Equal(t, []string{"corp login --sso"}, merged.Plugins["corp"].Restore)
+}
+
+func TestMerge_DeepMergesEverySection(t *testing.T) {
+	base := &domain.Snapshot{
+		VSCode: &domain.VSCodeSection{
+			Extensions:  []string{"golang.go", "eamodio.gitlens"},
+			ConfigFiles: []domain.ConfigFile{{Source: "settings.json", BundlePath: "configs/vscode/settings.json"}},
+		},
+		GitRepos: &domain.GitReposSection{Repositories: []domain.Repository{{Path: "~/a", Remote: "git@x:a.git", Branch: "main"}}},
+		Node:     &domain.NodeSection{Manager: "nvm", GlobalPackages: []domain.Package{{Name: "pnpm", Version: "8"}}},
+	}
+	override := &domain.Snapshot{
+		VSCode: &domain.VSCodeSection{
+			Extensions:  []string{"rust-lang.rust-analyzer", "golang.go"},
+			ConfigFiles: []domain.ConfigFile{{Source: "settings.json", BundlePath: "configs/vscode/team-settings.json"}},
+		},
+		GitRepos: &domain.GitReposSection{Repositories: []domain.Repository{{Path: "~/code/a", Remote: "git@x:a.git"}}},
+		Node:     &domain.NodeSection{GlobalPackages: []domain.Package{{Name: "pnpm", Version: "9"}, {Name: "tsx"}}},
+	}
+
+	merged := profiles.Merge(base, override)
+
+	assert.Equal(t, []string{"golang.go", "eamodio.gitlens", "rust-lang.rust-analyzer"}, merged.VSCode.Extensions)
+	require.Len(t, merged.VSCode.ConfigFiles, 1, "config files are keyed by source")
+	assert.Equal(t, "configs/vscode/team-settings.json", merged.VSCode.ConfigFiles[0].BundlePath)
+
+	require.Len(t, merged.GitRepos.Repositories, 1, "repositories are keyed by remote")
+	assert.Equal(t, domain.Repository{Path: "~/code/a", Remote: "git@x:a.git", Branch: "main"}, merged.GitRepos.Repositories[0])
+
+	assert.Equal(t, "nvm", merged.Node.Manager, "empty override fields keep the base value")
+	assert.Equal(t, []domain.Package{{Name: "pnpm", Version: "9"}, {Name: "tsx"}}, merged.Node.GlobalPackages)
+
+	assert.Equal(t, []string{"golang.go", "eamodio.gitlens"}, base.VSCode.Extensions, "base must not be mutated")
+}
+
+func TestMerge_Strategies(t *testing.T) {
+	base := &domain.Snapshot{
+		Homebrew: &domain.HomebrewSection{
+			Formulae: []domain.Package{{Name: "git"}, {Name: "wget"}, {Name: "jq"}},
+			Casks:    []domain.Package{{Name: "iterm2"}},
+		},
+		VSCode: &domain.VSCodeSection{Extensions: []string{"a", "b"}},
+		Docker: &domain.DockerSection{Runtime: "colima"},
+		Shell:  &domain.ShellSection{DefaultShell: "/bin/zsh", Framework: "oh-my-zsh"},
+	}
+	override := &domain.Snapshot{
+		Meta: domain.Meta{Merge: map[string]domain.MergeStrategy{
+			"homebrew.formulae": domain.MergeRemove,
+			"homebrew.casks":    domain.MergeAppend,
+			"vscode.extensions": domain.MergeReplace,
+			"docker":            domain.MergeRemove,
+			"shell.framework":   domain.MergeReplace,
+		}},
+		Homebrew: &domain.HomebrewSection{
+			Formulae: []domain.Package{{Name: "wget"}},
+			Casks:    []domain.Package{{Name: "docker"}},
+		},
+		VSCode: &domain.VSCodeSection{Extensions: []string{"c"}},
+		Shell:  &domain.ShellSection{},
+	}
+
+	merged := profiles.Merge(base, override)
+
+	assert.Equal(t, []domain.Package{{Name: "git"}, {Name: "jq"}}, merged.Homebrew.Formulae)
+	assert.Equal(t, []domain.Package{{Name: "iterm2"}, {Name: "docker"}}, merged.Homebrew.Casks)
+	assert.Equal(t, []string{"c"}, merged.VSCode.Extensions)
+	assert.Nil(t, merged.Docker)
+	assert.Equal(t, "/bin/zsh", merged.Shell.DefaultShell)
+	assert.Empty(t, merged.Shell.Framework, "replace takes an empty override value")
+}
+
+func TestBuiltinProfilesHaveMetadata(t *testing.T) {
+	names, err := profiles.List()
+	require.NoError(t, err)
+	for _, name := range names {
+		snap, err := profiles.Get(name)
+		require.NoError(t, err)
+		require.NotNil(t, snap.Profile, "profile %s has no [profile] block", name)
+		assert.NotEmpty(t, snap.Profile.Description, "profile %s has no description", name)
+		assert.NotEmpty(t, snap.Profile.Tags, "profile %s has no tags", name)
+	}
+}
+
+func TestBuiltinProfilesAreCurrent(t *testing.T) {
+	names, err := profiles.List()
+	require.NoError(t, err)
+	for _, name := range names {
+		if profiles.Source(name) != "embedded" {
+			continue
+		}
+		snap, err := profiles.Get(name)
+		require.NoError(t, err)
+		assert.Zero(t, snap.Meta.MigratedFrom, "profile %s is migrated on every load", name)
+		assert.Equal(t, domain.CurrentSchemaVersion, snap.Meta.SchemaVersion, "profile %s", name)
+	}
+}
@@ -0,0 +1,123 @@
+package profiles
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/internal/util"
+)
+
+// ProfilePrefix marks an extends entry that names a built-in profile, e.g.
+// "profile:go-dev". Any other entry is a manifest file path.
+const ProfilePrefix = "profile:"
+
+// Load reads a manifest file and resolves its extends chain.
+func Load(path string) (*domain.Snapshot, error) {
+	snap, err := domain.ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveFile(snap, path)
+}
+
+// ResolveFile is Resolve for a manifest read from path: relative paths
+// resolve against its directory, and a chain leading back to it is reported
+// as a cycle starting at path.
+func ResolveFile(snap *domain.Snapshot, path string) (*domain.Snapshot, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	return resolve(snap, filepath.Dir(abs), []string{filepath.Clean(abs)})
+}
+
+// Resolve flattens a manifest that declares meta.extends. Each layer is
+// resolved recursively, then the layers are merged in order with Merge and
+// the manifest itself applied last; each layer's [meta.merge] rules apply
+// to the layers it extends. Relative file paths are resolved against dir
+// (the working directory when empty). The result keeps the manifest's own
+// [meta], with Extends and Merge cleared, and its own [profile].
+func Resolve(snap *domain.Snapshot, dir string) (*domain.Snapshot, error) {
+	if dir == "" {
+		dir = "."
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return resolve(snap, abs, nil)
+}
+
+// resolve merges the extends chain of snap. stack holds the layers being
+// resolved, outermost first, to detect cycles.
+func resolve(snap *domain.Snapshot, dir string, stack []string) (*domain.Snapshot, error) {
+	if len(snap.Meta.Extends) == 0 {
+		return snap, nil
+	}
+	var base *domain.Snapshot
+	for _, ref := range snap.Meta.Extends {
+		layer, err := loadLayer(ref, dir, stack)
+		if err != nil {
+			return nil, err
+		}
+		if base == nil {
+			base = layer
+		} else {
+			base = Merge(base, layer)
+		}
+	}
+	merged := Merge(base, snap)
+	merged.Meta = snap.Meta
+	merged.Profile = snap.Profile
+	merged.Meta.Extends = nil
+	merged.Meta.Merge = nil
+	return merged, nil
+}
+
+// loadLayer reads and resolves one extends entry.
+func loadLayer(ref, dir string, stack []string) (*domain.Snapshot, error) {
+	key, dir := layerKey(ref, dir)
+	if err := checkCycle(key, stack); err != nil {
+		return nil, err
+	}
+	snap, err := readLayer(key)
+	if err != nil {
+		return nil, fmt.Errorf("extends %q: %w", ref, err)
+	}
+	return resolve(snap, dir, append(stack[:len(stack):len(stack)], key))
+}
+
+// layerKey identifies a layer reference: "profile:<name>" for a built-in
+// profile, otherwise the cleaned absolute file path. dir is the directory
+// the layer's own relative references resolve against.
+func layerKey(ref, dir string) (key, layerDir string) {
+	if name, ok := strings.CutPrefix(ref, ProfilePrefix); ok {
+		return ProfilePrefix + strings.TrimPrefix(name, "//"), dir
+	}
+	path := util.ExpandHome(ref)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	key = filepath.Clean(path)
+	return key, filepath.Dir(key)
+}
+
+// readLayer reads the unresolved manifest a layer key names.
+func readLayer(key string) (*domain.Snapshot, error) {
+	if name, ok := strings.CutPrefix(key, ProfilePrefix); ok {
+		return Get(name)
+	}
+	return domain.ReadManifest(key)
+}
+
+func checkCycle(key string, stack []string) error {
+	for i, s := range stack {
+		if s == key {
+			chain := append(stack[i:len(stack):len(stack)], key)
+			return fmt.Errorf("extends cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package profiles_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/profiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func formulaNames(snap *domain.Snapshot) []string {
+	var names []string
+	for _, f := range snap.Homebrew.Formulae {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestLoad_ExtendsProfileAndFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "team", "base.toml"), `
+[homebrew]
+[[homebrew.formulae]]
+name = "jq"
+
+[shell]
+default_shell = "/bin/bash"
+`)
+	writeFile(t, filepath.Join(dir, "me", "setup.toml"), `
+[meta]
+source_hostname = "my-mac"
+extends = ["profile:go-dev", "../team/base.toml"]
+
+[[homebrew.formulae]]
+name = "ripgrep"
+
+[rust]
+default_toolchain = "stable"
+`)
+
+	snap, err := profiles.Load(filepath.Join(dir, "me", "setup.toml"))
+	require.NoError(t, err)
+
+	names := formulaNames(snap)
+	assert.Contains(t, names, "go", "from profile:go-dev")
+	assert.Contains(t, names, "jq", "from team/base.toml")
+	assert.Contains(t, names, "ripgrep", "from the manifest itself")
+	assert.Equal(t, "/bin/bash", snap.Shell.DefaultShell, "later layers replace earlier ones")
+	assert.Equal(t, "1.24", snap.Go.Version)
+	assert.Equal(t, "stable", snap.Rust.DefaultToolchain)
+
+	assert.Equal(t, "my-mac", snap.Meta.SourceHostname)
+	assert.Empty(t, snap.Meta.Extends, "the result is flattened")
+}
+
+func TestLoad_NestedExtendsAreRelativeToTheirFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a", "root.toml"), "[go]\nversion = \"1.22\"\n")
+	writeFile(t, filepath.Join(dir, "a", "b", "mid.toml"), "[meta]\nextends = [\"../root.toml\"]\n\n[rust]\ndefault_toolchain = \"nightly\"\n")
+	writeFile(t, filepath.Join(dir, "top.toml"), "[meta]\nextends = [\"a/b/mid.toml\"]\n")
+
+	snap, err := profiles.Load(filepath.Join(dir, "top.toml"))
+	require.NoError(t, err)
+	assert.Equal(t, "1.22", snap.Go.Version)
+	assert.Equal(t, "nightly", snap.Rust.DefaultToolchain)
+}
+
+func TestLoad_Cycle(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.toml"), "[meta]\nextends = [\"b.toml\"]\n")
+	writeFile(t, filepath.Join(dir, "b.toml"), "[meta]\nextends = [\"a.toml\"]\n")
+
+	_, err := profiles.Load(filepath.Join(dir, "a.toml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "extends cycle")
+	assert.Contains(t, err.Error(), "a.toml -> ")
+}
+
+func TestLoad_MissingLayer(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.toml"), "[meta]\nextends = [\"profile:nope\"]\n")
+
+	_, err := profiles.Load(filepath.Join(dir, "a.toml"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `extends "profile:nope"`)
+}
+
+func TestResolve_NoExtends(t *testing.T) {
+	snap := &domain.Snapshot{Go: &domain.GoSection{Version: "1.24"}}
+	resolved, err := profiles.Resolve(snap, "")
+	require.NoError(t, err)
+	assert.Same(t, snap, resolved)
+}
@@ -0,0 +1,42 @@
+package profiles
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/moinsen-dev/machinist/internal/util"
+)
+
+// PathEnv names the environment variable holding extra profile directories,
+// separated like $PATH. They take precedence over every other directory.
+const PathEnv = "MACHINIST_PROFILE_PATH"
+
+var (
+	searchMu   sync.RWMutex
+	searchDirs []string
+)
+
+// SetSearchPaths sets the directories searched for <name>.toml profiles in
+// addition to the embedded ones, lowest precedence first: a profile in a
+// later directory shadows one of the same name in an earlier directory or
+// among the embedded profiles.
+func SetSearchPaths(dirs []string) {
+	searchMu.Lock()
+	defer searchMu.Unlock()
+	searchDirs = append([]string(nil), dirs...)
+}
+
+// SearchPaths returns the profile directories, lowest precedence first: the
+// ones passed to SetSearchPaths followed by those in $MACHINIST_PROFILE_PATH.
+func SearchPaths() []string {
+	searchMu.RLock()
+	dirs := append([]string(nil), searchDirs...)
+	searchMu.RUnlock()
+	for _, dir := range filepath.SplitList(os.Getenv(PathEnv)) {
+		if dir != "" {
+			dirs = append(dirs, util.ExpandHome(dir))
+		}
+	}
+	return dirs
+}
@@ -0,0 +1,91 @@
+package profiles_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/moinsen-dev/machinist/internal/domain"
+	"github.com/moinsen-dev/machinist/profiles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func useSearchPaths(t *testing.T, dirs ...string) {
+	t.Helper()
+	profiles.SetSearchPaths(dirs)
+	t.Cleanup(func() { profiles.SetSearchPaths(nil) })
+}
+
+func TestSearchPaths_AddProfiles(t *testing.T) {
+	team := t.TempDir()
+	writeFile(t, filepath.Join(team, "backend-onboarding.toml"), "[go]\nversion = \"1.25\"\n")
+	writeFile(t, filepath.Join(team, "notes.md"), "not a profile")
+	useSearchPaths(t, team)
+
+	names, err := profiles.List()
+	require.NoError(t, err)
+	assert.Contains(t, names, "backend-onboarding")
+	assert.Contains(t, names, "minimal")
+	assert.NotContains(t, names, "notes")
+
+	snap, err := profiles.Get("backend-onboarding")
+	require.NoError(t, err)
+	assert.Equal(t, "1.25", snap.Go.Version)
+	assert.Equal(t, filepath.Join(team, "backend-onboarding.toml"), profiles.Source("backend-onboarding"))
+	assert.Equal(t, "embedded", profiles.Source("minimal"))
+	assert.Empty(t, profiles.Source("does-not-exist"))
+}
+
+func TestSearchPaths_Shadowing(t *testing.T) {
+	team, user, env := t.TempDir(), t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(team, "minimal.toml"), "[go]\nversion = \"team\"\n")
+	writeFile(t, filepath.Join(team, "shared.toml"), "[go]\nversion = \"team\"\n")
+	writeFile(t, filepath.Join(user, "shared.toml"), "[go]\nversion = \"user\"\n")
+	writeFile(t, filepath.Join(env, "shared.toml"), "[go]\nversion = \"env\"\n")
+	useSearchPaths(t, team, user)
+
+	snap, err := profiles.Get("minimal")
+	require.NoError(t, err)
+	assert.Equal(t, "team", snap.Go.Version, "a profile directory shadows the embedded profile")
+
+	snap, err = profiles.Get("shared")
+	require.NoError(t, err)
+	assert.Equal(t, "user", snap.Go.Version, "later directories shadow earlier ones")
+
+	t.Setenv(profiles.PathEnv, env)
+	assert.Equal(t, []string{team, user, env}, profiles.SearchPaths())
+	snap, err = profiles.Get("shared")
+	require.NoError(t, err)
+	assert.Equal(t, "env", snap.Go.Version, "$MACHINIST_PROFILE_PATH comes last")
+
+	names, err := profiles.List()
+	require.NoError(t, err)
+	count := 0
+	for _, n := range names {
+		if n == "shared" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count, "shadowed profiles are listed once")
+}
+
+func TestSearchPaths_NamesStayInsideDirectories(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "secret.toml"), "[go]\nversion = \"x\"\n")
+	useSearchPaths(t, filepath.Join(dir, "profiles"))
+
+	_, err := profiles.Get("../secret")
+	assert.Error(t, err)
+}
+
+func TestSearchPaths_ExtendsSeesProfiles(t *testing.T) {
+	team := t.TempDir()
+	writeFile(t, filepath.Join(team, "team-base.toml"), "[rust]\ndefault_toolchain = \"stable\"\n")
+	useSearchPaths(t, team)
+
+	manifest := &domain.Snapshot{Meta: domain.Meta{Extends: []string{"profile:team-base"}}}
+	snap, err := profiles.Resolve(manifest, "")
+	require.NoError(t, err)
+	require.NotNil(t, snap.Rust)
+	assert.Equal(t, "stable", snap.Rust.DefaultToolchain)
+}
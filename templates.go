@@ -2,5 +2,5 @@ package machinist
 
 import "embed"
 
-//go:embed templates/*.tmpl templates/stages/*.tmpl templates/groups/*.tmpl
+//go:embed templates/*.tmpl templates/stages/*.tmpl templates/groups/*.tmpl templates/report/*.tmpl
 var TemplateFS embed.FS